@@ -0,0 +1,61 @@
+// Copyright 2022 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"context"
+	"os"
+
+	"github.com/Bobgy/go-licenses/v2/licenses"
+	"github.com/spf13/cobra"
+)
+
+var (
+	bazelCmd = &cobra.Command{
+		Use:   "bazel <deps file>",
+		Short: "Prints all licenses that apply to the Go modules of a Bazel workspace",
+		Long: `Prints all licenses that apply to the Go modules of a Bazel workspace.
+
+Modules are read from go_repository rules of rules_go/Gazelle, e.g. in deps.bzl,
+WORKSPACE or the output of:
+
+  bazel query --output=build 'kind(go_repository, //external:*)'
+
+or from go_deps.module tags of the go_deps extension in MODULE.bazel, so that
+Bazel-built services are reported with the module versions Bazel actually
+builds. Modules that go_deps.from_file reads from go.mod are not declared in
+MODULE.bazel, and MODULE.bazel.lock is not read: report them with csv on the Go
+packages instead. Like for binaries, each module is reported as a single
+library.`,
+		Args: cobra.ExactArgs(1),
+		RunE: bazelMain,
+	}
+)
+
+func init() {
+	rootCmd.AddCommand(bazelCmd)
+}
+
+func bazelMain(_ *cobra.Command, args []string) error {
+	classifier, err := newClassifier(confidenceThreshold)
+	if err != nil {
+		return err
+	}
+	libs, err := licenses.BazelLibraries(context.Background(), classifier, libraryOptions(), args[0])
+	if err != nil {
+		return err
+	}
+	return writeCSV(os.Stdout, classifier, libs)
+}
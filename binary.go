@@ -0,0 +1,158 @@
+// Copyright 2022 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/Bobgy/go-licenses/v2/config"
+	"github.com/Bobgy/go-licenses/v2/licenses"
+	"github.com/Bobgy/go-licenses/v2/report"
+	"github.com/spf13/cobra"
+)
+
+var (
+	binaryCmd = &cobra.Command{
+		Use:   "binary [<path>...]",
+		Short: "Prints all licenses that apply to the modules embedded in Go binaries",
+		Long: `Prints all licenses that apply to the modules embedded in Go binaries.
+
+Binaries are passed as arguments or listed under "binaries" in the --config
+file, where each binary may also set an output path for its own report.
+When there are multiple binaries, the printed report is aggregated: each
+library is listed once, followed by the binaries using it.`,
+		RunE: binaryMain,
+	}
+)
+
+func init() {
+	rootCmd.AddCommand(binaryCmd)
+}
+
+func binaryMain(_ *cobra.Command, args []string) error {
+	binaries, err := binariesToReport(args)
+	if err != nil {
+		return err
+	}
+	return reportBinaries(binaries)
+}
+
+// reportBinaries writes the report of each binary to its output path, if any,
+// and prints a report for all of them.
+func reportBinaries(binaries []config.Binary) error {
+	classifier, err := newClassifier(confidenceThreshold)
+	if err != nil {
+		return err
+	}
+
+	// The same library is often embedded in several binaries, avoid
+	// identifying its license and validating its URL repeatedly. Libraries
+	// are keyed by name and license path, which differ by module version.
+	libraryKey := func(lib *licenses.Library) string {
+		return lib.Name() + "@" + lib.LicensePath
+	}
+	rowsByLibrary := make(map[string]report.Record)
+	binariesByLibrary := make(map[string][]string)
+	// listed records library keys and binary names already listed, because
+	// binaries may share a name, and libraries be reported twice for one.
+	listed := make(map[string]bool)
+	for _, binary := range binaries {
+		libs, err := licenses.BinaryLibraries(context.Background(), classifier, libraryOptions(), binary.Path)
+		if err != nil {
+			return err
+		}
+		var newLibs []*licenses.Library
+		for _, lib := range libs {
+			if _, ok := rowsByLibrary[libraryKey(lib)]; !ok {
+				newLibs = append(newLibs, lib)
+			}
+		}
+		for i, row := range libraryRows(classifier, newLibs) {
+			rowsByLibrary[libraryKey(newLibs[i])] = row
+		}
+		name := binary.Name
+		if name == "" {
+			name = binary.Path
+		}
+		var rows []report.Record
+		for _, lib := range libs {
+			key := libraryKey(lib)
+			rows = append(rows, rowsByLibrary[key])
+			if !listed[key+"\x00"+name] {
+				listed[key+"\x00"+name] = true
+				binariesByLibrary[key] = append(binariesByLibrary[key], name)
+			}
+		}
+		if binary.Output != "" {
+			if err := writeCSVFile(binary.Output, rows); err != nil {
+				return err
+			}
+		}
+		if len(binaries) == 1 {
+			return writeCSVRows(os.Stdout, rows)
+		}
+	}
+
+	var keys []string
+	for key := range binariesByLibrary {
+		keys = append(keys, key)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		a, b := rowsByLibrary[keys[i]], rowsByLibrary[keys[j]]
+		if a != b {
+			return recordLess(a, b)
+		}
+		return keys[i] < keys[j]
+	})
+	if err := writeHeader(os.Stdout); err != nil {
+		return err
+	}
+	for _, key := range keys {
+		if err := writeCSVRow(os.Stdout, append(rowColumns(rowsByLibrary[key]), strings.Join(binariesByLibrary[key], ";"))...); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// binariesToReport returns binaries passed as arguments, or otherwise the
+// binaries listed in the config file.
+func binariesToReport(args []string) ([]config.Binary, error) {
+	if len(args) > 0 {
+		var binaries []config.Binary
+		for _, arg := range args {
+			binaries = append(binaries, config.Binary{Path: arg})
+		}
+		return binaries, nil
+	}
+	if len(cfg.Binaries) == 0 {
+		return nil, fmt.Errorf("no binaries to report, pass them as arguments or list them under binaries in the --config file")
+	}
+	return cfg.Binaries, nil
+}
+
+// writeCSVFile writes rows of a csv report to a file at path.
+func writeCSVFile(path string, rows []report.Record) error {
+	var b bytes.Buffer
+	if err := writeCSVRows(&b, rows); err != nil {
+		return err
+	}
+	return writeFile(path, b.Bytes(), 0644)
+}
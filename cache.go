@@ -0,0 +1,166 @@
+// Copyright 2022 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	"github.com/Bobgy/go-licenses/v2/licenses"
+	"github.com/spf13/cobra"
+)
+
+var (
+	cacheCmd = &cobra.Command{
+		Use:   "cache",
+		Short: "Manages the cache kept across runs",
+		Long: `Manages the cache kept across runs.
+
+The cache directory is --cache_dir, cache.dir in the config file, or
+go-licenses in the user cache directory. It holds license classifications,
+module info queried from the module proxy, HTTP responses fetched to validate
+license URLs, source repositories and validated license URLs of module
+versions, results of previous runs reused by --incremental, and the license
+cache of --detect_license_changes.`,
+	}
+
+	cacheInfoCmd = &cobra.Command{
+		Use:   "info",
+		Short: "Prints the location, entries and size of the cache",
+		Args:  cobra.NoArgs,
+		RunE:  cacheInfoMain,
+	}
+
+	cacheClearCmd = &cobra.Command{
+		Use:   "clear",
+		Short: "Removes all entries of the cache, including the license cache",
+		Args:  cobra.NoArgs,
+		RunE:  cacheClearMain,
+	}
+
+	cachePruneCmd = &cobra.Command{
+		Use:   "prune",
+		Short: "Removes cache entries older than --older_than, defaulting to the cache TTL",
+		Args:  cobra.NoArgs,
+		RunE:  cachePruneMain,
+	}
+
+	// olderThan is the age of cache entries removed by cache prune.
+	olderThan time.Duration
+)
+
+func init() {
+	cachePruneCmd.Flags().DurationVar(&olderThan, "older_than", 0, "Remove cache entries written longer ago than this duration, e.g. 168h. Defaults to cache.ttl in the config file, or 24h.")
+
+	cacheCmd.AddCommand(cacheInfoCmd)
+	cacheCmd.AddCommand(cacheClearCmd)
+	cacheCmd.AddCommand(cachePruneCmd)
+	rootCmd.AddCommand(cacheCmd)
+}
+
+func cacheInfoMain(*cobra.Command, []string) error {
+	dir, err := cacheDirectory()
+	if err != nil {
+		return err
+	}
+	infos, err := licenses.ReadCacheInfo(dir)
+	if err != nil {
+		return err
+	}
+	ttl, err := cfg.Cache.ParseTTL()
+	if err != nil {
+		return err
+	}
+	writeCacheInfo(os.Stdout, dir, ttl, infos, time.Now())
+	return nil
+}
+
+// writeCacheInfo writes the location, TTL and entries of a cache.
+func writeCacheInfo(w io.Writer, dir string, ttl time.Duration, infos []licenses.CacheInfo, now time.Time) {
+	fmt.Fprintf(w, "Directory: %s\n", dir)
+	if ttl == 0 {
+		fmt.Fprintf(w, "TTL: none\n")
+	} else {
+		fmt.Fprintf(w, "TTL: %s\n", ttl)
+	}
+	var total int64
+	for _, info := range infos {
+		total += info.Size
+		age := ""
+		if !info.Oldest.IsZero() {
+			age = fmt.Sprintf(", oldest %s ago", now.Sub(info.Oldest).Round(time.Second))
+		}
+		fmt.Fprintf(w, "%s: %d entries, %s%s\n", info.Kind, info.Entries, formatSize(info.Size), age)
+	}
+	fmt.Fprintf(w, "Total: %s\n", formatSize(total))
+}
+
+// formatSize formats a size in bytes for humans, e.g. 1.5 MiB.
+func formatSize(size int64) string {
+	const unit = 1024
+	if size < unit {
+		return fmt.Sprintf("%d B", size)
+	}
+	div, exp := int64(unit), 0
+	for n := size / unit; n >= unit; n /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %ciB", float64(size)/float64(div), "KMGTPE"[exp])
+}
+
+func cacheClearMain(*cobra.Command, []string) error {
+	dir, err := cacheDirectory()
+	if err != nil {
+		return err
+	}
+	if dryRun {
+		dryRunf("would clear the cache in %s", dir)
+		return nil
+	}
+	if err := licenses.ClearCache(dir); err != nil {
+		return err
+	}
+	fmt.Printf("Cleared the cache in %s\n", dir)
+	return nil
+}
+
+func cachePruneMain(*cobra.Command, []string) error {
+	dir, err := cacheDirectory()
+	if err != nil {
+		return err
+	}
+	age := olderThan
+	if age == 0 {
+		if age, err = cfg.Cache.ParseTTL(); err != nil {
+			return err
+		}
+		if age == 0 {
+			return fmt.Errorf("the cache TTL is 0, which keeps entries forever, pass --older_than")
+		}
+	}
+	if dryRun {
+		dryRunf("would remove cache entries in %s older than %s", dir, age)
+		return nil
+	}
+	removed, err := licenses.PruneCache(dir, time.Now().Add(-age))
+	if err != nil {
+		return err
+	}
+	fmt.Printf("Removed %d cache entries older than %s from %s\n", removed, age, dir)
+	return nil
+}
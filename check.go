@@ -18,43 +18,490 @@ import (
 	"context"
 	"fmt"
 	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
 
+	"github.com/Bobgy/go-licenses/v2/config"
+	"github.com/Bobgy/go-licenses/v2/internal/logging"
+	"github.com/Bobgy/go-licenses/v2/internal/parallel"
 	"github.com/Bobgy/go-licenses/v2/licenses"
+	"github.com/Bobgy/go-licenses/v2/policy"
 	"github.com/spf13/cobra"
 )
 
 var (
 	checkCmd = &cobra.Command{
-		Use:   "check <package>",
+		Use:   "check <package>...",
 		Short: "Checks whether licenses for a package are not Forbidden.",
-		Args:  cobra.MinimumNArgs(1),
+		Args:  packageArgs,
 		RunE:  checkMain,
 	}
+
+	// errorThreshold is the number of error violations failing a check.
+	errorThreshold int
+	// warningThreshold is the number of warning violations failing a check.
+	warningThreshold int
+	// interactive asks for licenses that cannot be identified.
+	interactive bool
+	// githubActions reports violations as GitHub Actions annotations.
+	githubActions bool
+	// outputFormat is the format violations are written to stdout in, if any.
+	outputFormat string
+	// detectLicenseChanges compares licenses with previous module versions.
+	detectLicenseChanges bool
 )
 
 func init() {
+	checkCmd.Flags().IntVar(&errorThreshold, "error_threshold", 1, "Fail if the policy is violated with error severity at least this many times, 0 never fails")
+	checkCmd.Flags().IntVar(&warningThreshold, "warning_threshold", 0, "Fail if the policy is violated with warn severity at least this many times, 0 never fails")
+	checkCmd.Flags().BoolVar(&githubActions, "github_actions", false, "Also report policy violations as GitHub Actions annotations on go.mod and as a table in the job summary.")
+	checkCmd.Flags().StringVar(&outputFormat, "output_format", "", "Also write policy violations with their codes to stdout as json or sarif.")
+	checkCmd.Flags().BoolVar(&detectLicenseChanges, "detect_license_changes", false, "Fail if the license of a module differs from the license of its previous version seen by an earlier run, as recorded in --cache_dir.")
+	checkCmd.Flags().BoolVar(&interactive, "interactive", false, "Ask for the SPDX ID of each license that cannot be identified, and save answers as overrides in the --config file.")
+
 	rootCmd.AddCommand(checkCmd)
 }
 
 func checkMain(_ *cobra.Command, args []string) error {
-	classifier, err := licenses.NewClassifier(confidenceThreshold)
+	classifier, err := newClassifier(confidenceThreshold)
 	if err != nil {
 		return err
 	}
 
-	libs, err := licenses.Libraries(context.Background(), classifier, args...)
+	importPaths, err := expandPackages(context.Background(), args)
+	if err != nil {
+		return err
+	}
+	if outputFormat != "" && outputFormat != "json" && outputFormat != "sarif" {
+		return fmt.Errorf("unknown --output_format %q, must be json or sarif", outputFormat)
+	}
+	if porcelain && (outputFormat != "" || githubActions) {
+		return fmt.Errorf("--porcelain cannot be combined with --output_format or --github_actions, which also write to stdout")
+	}
+	if interactive && configPath == "" {
+		return fmt.Errorf("--interactive requires --config to save overrides to")
+	}
+	if len(cfg.Scopes) > 0 {
+		return checkScopes(classifier, importPaths)
+	}
+	libs, err := licenses.LibrariesWithOptions(context.Background(), classifier, libraryOptions(), importPaths...)
 	if err != nil {
 		return err
 	}
+	if interactive {
+		if err := reviewAndSaveLicenses(classifier, libs); err != nil {
+			return err
+		}
+	}
+	if cfg.Policy != nil {
+		violations := checkPolicy(classifier, withProjectLicense(classifier, cfg.Policy), libs, "")
+		violations = append(violations, checkNotices(classifier, cfg.Policy, libs, "")...)
+		changes, err := checkLicenseChanges(classifier, libs, "")
+		if err != nil {
+			return err
+		}
+		return reportViolations(append(violations, changes...))
+	}
+	changes, err := checkLicenseChanges(classifier, libs, "")
+	if err != nil {
+		return err
+	}
+	if len(changes) > 0 {
+		exit(cfg.ExitCode(string(policy.CodeLicenseChanged)))
+	}
 	for _, lib := range libs {
-		licenseName, licenseType, err := classifier.Identify(lib.LicensePath)
+		if lib.IntegrityError != nil {
+			logging.Fields{Module: lib.Name(), Code: string(policy.CodeIntegrity)}.Printf(logging.Error, "Cannot trust license of library %v: %s\n", lib, lib.IntegrityError)
+			exit(cfg.ExitCode(string(policy.CodeIntegrity)))
+		}
+		if lib.ModuleWarning != nil {
+			logging.Module(lib.Name()).Printf(logging.Warning, "Warning for library %v: %s\n", lib, lib.ModuleWarning)
+		}
+		// Libraries without a license file fail with ErrNoLicenseFound,
+		// unless --interactive recorded an override for them.
+		licenseName, licenseType, err := identifyLicense(classifier, lib)
 		if err != nil {
+			recordLibrary(lib.Name(), "Unknown")
 			return err
 		}
+		recordLibrary(lib.Name(), licenseName)
 		if licenseType == licenses.Forbidden {
-			fmt.Fprintf(os.Stderr, "Forbidden license type %s for library %v\n", licenseName, lib)
-			os.Exit(1)
+			logging.Fields{Module: lib.Name(), Code: string(policy.CodeForbiddenLicense)}.Printf(logging.Error, "Forbidden license type %s for library %v\n", licenseName, lib)
+			exit(cfg.ExitCode(string(policy.CodeForbiddenLicense)))
+		}
+	}
+	return nil
+}
+
+// checkScopes checks the dependencies of the packages in each scope of the
+// config file against the scope's policy. Packages in no scope are checked
+// against the top-level policy, if any.
+func checkScopes(classifier licenses.Classifier, importPaths []string) error {
+	ctx := context.Background()
+	dirs, err := licenses.PackageDirs(ctx, libraryOptions(), importPaths...)
+	if err != nil {
+		return err
+	}
+	wd, err := os.Getwd()
+	if err != nil {
+		return err
+	}
+	// Entry packages of each scope, by index in cfg.Scopes. Packages in no
+	// scope are at index len(cfg.Scopes).
+	entries := make([][]string, len(cfg.Scopes)+1)
+	for _, pkg := range sortedKeys(dirs) {
+		i := len(cfg.Scopes)
+		if rel, err := filepath.Rel(wd, dirs[pkg]); err == nil {
+			for j := range cfg.Scopes {
+				if cfg.Scopes[j].Matches(filepath.ToSlash(rel)) {
+					i = j
+					break
+				}
+			}
+		}
+		entries[i] = append(entries[i], pkg)
+	}
+	var violations []violation
+	for i, pkgs := range entries {
+		if len(pkgs) == 0 {
+			continue
+		}
+		var p *policy.Policy
+		var scope string
+		if i < len(cfg.Scopes) {
+			p, scope = &cfg.Scopes[i].Policy, cfg.Scopes[i].Name
+		} else if cfg.Policy != nil {
+			p = cfg.Policy
+		} else {
+			logging.Warningf("Skipping packages in no scope, because there is no top-level policy: %s", strings.Join(pkgs, ", "))
+			continue
+		}
+		libs, err := licenses.LibrariesWithOptions(ctx, classifier, libraryOptions(), pkgs...)
+		if err != nil {
+			return err
+		}
+		if interactive {
+			if err := reviewAndSaveLicenses(classifier, libs); err != nil {
+				return err
+			}
+		}
+		violations = append(violations, checkPolicy(classifier, withProjectLicense(classifier, p), libs, scope)...)
+		violations = append(violations, checkNotices(classifier, p, libs, scope)...)
+		changes, err := checkLicenseChanges(classifier, libs, scope)
+		if err != nil {
+			return err
+		}
+		violations = append(violations, changes...)
+	}
+	return reportViolations(violations)
+}
+
+// sortedKeys returns the keys of m in ascending order.
+func sortedKeys(m map[string]string) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// reviewAndSaveLicenses asks for licenses that cannot be identified, and saves
+// the answers as overrides in the config file.
+func reviewAndSaveLicenses(classifier licenses.Classifier, libs []*licenses.Library) error {
+	overrides, err := reviewLicenses(os.Stdin, os.Stderr, classifier, libs)
+	if err != nil {
+		return err
+	}
+	if len(overrides) == 0 {
+		return nil
+	}
+	content, err := config.WithOverrides(configPath, overrides)
+	if err != nil {
+		return err
+	}
+	if err := writeFile(configPath, content, 0644); err != nil {
+		return err
+	}
+	if !dryRun {
+		logging.Infof("Saved %d overrides to %s", len(overrides), configPath)
+	}
+	cfg.Overrides = append(cfg.Overrides, overrides...)
+	return nil
+}
+
+// withProjectLicense returns a copy of the policy whose ProjectLicense is
+// detected from the current directory when not set.
+func withProjectLicense(classifier licenses.Classifier, p *policy.Policy) *policy.Policy {
+	copied := *p
+	if copied.ProjectLicense == "" {
+		copied.ProjectLicense = detectProjectLicense(classifier)
+	}
+	return &copied
+}
+
+// violation is a library violating the policy of a scope. The scope is empty
+// for the top-level policy.
+type violation struct {
+	*policy.Result
+	scope string
+}
+
+// checkPolicy evaluates the license of each library against a policy, prints
+// violations and returns them. scope names the policy scope in messages, if
+// any.
+func checkPolicy(classifier licenses.Classifier, p *policy.Policy, libs []*licenses.Library, scope string) []violation {
+	in := ""
+	if scope != "" {
+		in = " in scope " + scope
+	}
+	// Identify licenses and check them, which may run policy hooks, of
+	// --jobs libraries concurrently.
+	results := make([]*policy.Result, len(libs))
+	var mu sync.Mutex
+	done := 0
+	parallel.For(numJobs(), len(libs), func(i int) {
+		results[i] = checkLibrary(classifier, p, libs[i])
+		mu.Lock()
+		defer mu.Unlock()
+		done++
+		reportProgress(licenses.Progress{Phase: phaseIdentifyingLicenses, Done: done, Total: len(libs)})
+	})
+	var violations []violation
+	for i, lib := range libs {
+		if lib.IntegrityError != nil {
+			logging.Fields{Module: lib.Name(), Code: string(policy.CodeIntegrity)}.Printf(logging.Error, "Cannot trust license of library %v: %s\n", lib, lib.IntegrityError)
+			violations = append(violations, violation{
+				Result: &policy.Result{
+					Library:  lib.Name(),
+					Verdict:  policy.Denied,
+					Rule:     fmt.Sprintf("verify_modules: %v", lib.IntegrityError),
+					Severity: policy.Error,
+					Code:     policy.CodeIntegrity,
+				},
+				scope: scope,
+			})
+		}
+		result := results[i]
+		if result.Verdict != policy.Allowed {
+			violations = append(violations, violation{Result: result, scope: scope})
 		}
+		switch {
+		case result.Verdict == policy.Allowed:
+			if result.Exception != nil {
+				resultFields(result).Infof("Allowed by exception%s: %s", in, result)
+			}
+		case result.Severity == policy.Info:
+			resultFields(result).Infof("Policy violation %s [%s]%s: %s", result.Code, result.Severity, in, result)
+		case result.Verdict == policy.Denied:
+			resultFields(result).Printf(severityLevel(result.Severity), "Policy violation %s [%s]%s: %s\n", result.Code, result.Severity, in, result)
+		case result.Verdict == policy.NeedsReview:
+			resultFields(result).Printf(severityLevel(result.Severity), "Needs review %s [%s]%s: %s\n", result.Code, result.Severity, in, result)
+		}
+	}
+	return violations
+}
+
+// checkLibrary identifies the license of a library and checks it against a
+// policy.
+func checkLibrary(classifier licenses.Classifier, p *policy.Policy, lib *licenses.Library) *policy.Result {
+	licenseName, licenseType := "Unknown", licenses.Unknown
+	if name, typ, err := identifyLicense(classifier, lib); err == nil {
+		licenseName, licenseType = name, typ
+	} else if lib.LicensePath != "" {
+		logging.Module(lib.Name()).Errorf("Error identifying license in %q: %v", lib.LicensePath, err)
+	}
+	recordLibrary(lib.Name(), licenseName)
+	policyLib := policy.Library{
+		Name:     lib.Name(),
+		License:  licenseName,
+		Category: strings.ToLower(licenseType.String()),
+	}
+	if m := lib.Module(); m != nil {
+		policyLib.Module = m.Path
+		policyLib.Version = m.Version
+		policyLib.Indirect = m.Indirect
+	}
+	if p.MinConfidence > 0 || len(p.Custom) > 0 {
+		policyLib.Confidence = licenseConfidence(classifier, lib)
+	}
+	return p.CheckLibrary(policyLib, time.Now())
+}
+
+// resultFields returns the log fields of a policy result.
+func resultFields(result *policy.Result) logging.Fields {
+	return logging.Fields{Module: result.Library, Code: string(result.Code)}
+}
+
+// severityLevel returns the log level of violations with a severity.
+func severityLevel(severity policy.Severity) logging.Level {
+	switch severity {
+	case policy.Error:
+		return logging.Error
+	case policy.Warn:
+		return logging.Warning
+	default:
+		return logging.Info
+	}
+}
+
+// checkNotices returns a violation for each NOTICE file of an Apache-2.0
+// library that the save command would not include next to its license.
+func checkNotices(classifier licenses.Classifier, p *policy.Policy, libs []*licenses.Library, scope string) []violation {
+	severity := p.NoticeSeverity
+	if severity == "" {
+		severity = policy.Error
+	}
+	var violations []violation
+	for _, lib := range libs {
+		name, typ, err := identifyLicense(classifier, lib)
+		if err != nil || !strings.EqualFold(name, "Apache-2.0") {
+			continue
+		}
+		notices, err := licenses.NoticeFiles(lib)
+		if err != nil {
+			logging.Module(lib.Name()).Errorf("Error finding NOTICE files of %s: %v", lib.Name(), err)
+			continue
+		}
+		if len(notices) == 0 {
+			logging.Module(lib.Name()).Infof("No NOTICE file found for %s", lib.Name())
+		}
+		for _, notice := range notices {
+			if filepath.Dir(notice) == filepath.Dir(lib.LicensePath) && noticeRegexp.MatchString(filepath.Base(notice)) {
+				continue
+			}
+			result := &policy.Result{
+				Library:  lib.Name(),
+				License:  name,
+				Category: strings.ToLower(typ.String()),
+				Verdict:  policy.Denied,
+				Rule:     fmt.Sprintf("noticeSeverity: %s would be dropped from the saved attribution", notice),
+				Severity: severity,
+				Code:     policy.CodeNoticeDropped,
+			}
+			if severity == policy.Info {
+				resultFields(result).Infof("NOTICE dropped %s [%s]: %s", result.Code, result.Severity, result)
+			} else {
+				resultFields(result).Printf(severityLevel(result.Severity), "NOTICE dropped %s [%s]: %s\n", result.Code, result.Severity, result)
+			}
+			violations = append(violations, violation{Result: result, scope: scope})
+		}
+	}
+	return violations
+}
+
+// checkLicenseChanges returns a violation for each library whose license
+// differs from the license of the previous version of its module in the
+// license cache, if --detect_license_changes is set. It records the licenses
+// of current module versions in the cache.
+func checkLicenseChanges(classifier licenses.Classifier, libs []*licenses.Library, scope string) ([]violation, error) {
+	if !detectLicenseChanges {
+		return nil, nil
+	}
+	dir, err := cacheDirectory()
+	if err != nil {
+		return nil, err
+	}
+	cache, err := licenses.LoadLicenseCache(dir)
+	if err != nil {
+		return nil, err
+	}
+	var violations []violation
+	for _, lib := range libs {
+		m := lib.Module()
+		if m == nil || m.Version == "" {
+			continue
+		}
+		name, typ, err := identifyLicense(classifier, lib)
+		if err != nil {
+			continue
+		}
+		if prevVersion, prevLicense, ok := cache.Previous(m.Path, m.Version); ok && !strings.EqualFold(prevLicense, name) {
+			result := &policy.Result{
+				Library:  lib.Name(),
+				License:  name,
+				Category: strings.ToLower(typ.String()),
+				Verdict:  policy.Denied,
+				Rule:     fmt.Sprintf("licenseChanged: %s in %s@%s", prevLicense, m.Path, prevVersion),
+				Severity: policy.Error,
+				Code:     policy.CodeLicenseChanged,
+			}
+			resultFields(result).Printf(severityLevel(result.Severity), "License changed %s [%s]: %s\n", result.Code, result.Severity, result)
+			violations = append(violations, violation{Result: result, scope: scope})
+		}
+		cache.Add(m.Path, m.Version, name)
+	}
+	if dryRun {
+		dryRunf("would record module licenses in the license cache in %s", dir)
+		return violations, nil
+	}
+	return violations, cache.Save()
+}
+
+// reportViolations prints the number of errors and warnings, also to GitHub
+// Actions, in --output_format or as --porcelain lines if requested, and exits with a non-zero status
+// if either reaches its threshold.
+func reportViolations(violations []violation) error {
+	sortViolations(violations)
+	if porcelain {
+		if err := writePorcelainViolations(os.Stdout, violations); err != nil {
+			return err
+		}
+	}
+	if outputFormat != "" {
+		if err := writeViolations(os.Stdout, outputFormat, violations); err != nil {
+			return err
+		}
+	}
+	if githubActions {
+		if err := reportGitHubActions(os.Stdout, violations); err != nil {
+			return err
+		}
+	}
+	count := make(map[policy.Severity]int)
+	for _, v := range violations {
+		count[v.Severity]++
+	}
+	numErrors, numWarnings := count[policy.Error], count[policy.Warn]
+	recordViolations(numErrors, numWarnings)
+	if numErrors > 0 || numWarnings > 0 {
+		logging.Fields{}.Printf(logging.Info, "Found %d errors and %d warnings\n", numErrors, numWarnings)
+	}
+	if errorThreshold > 0 && numErrors >= errorThreshold || warningThreshold > 0 && numWarnings >= warningThreshold {
+		exit(exitCode(violations))
 	}
 	return nil
 }
+
+// exitCode returns the highest exit code mapped by exitCodes in the config
+// file to the codes of errors and warnings, or 1.
+func exitCode(violations []violation) int {
+	exit := 0
+	for _, v := range violations {
+		if v.Severity == policy.Info {
+			continue
+		}
+		if e := cfg.ExitCode(string(v.Code)); e > exit {
+			exit = e
+		}
+	}
+	if exit == 0 {
+		return 1
+	}
+	return exit
+}
+
+// detectProjectLicense identifies the license file of the project in the
+// current directory. It returns "" if there is none.
+func detectProjectLicense(classifier licenses.Classifier) string {
+	candidates, err := licenses.FindAll(".", ".", classifier)
+	if err != nil {
+		logging.Infof("No project license detected, skipping license compatibility analysis: %v", err)
+		return ""
+	}
+	logging.Infof("Detected project license %s in %s", candidates[0].Name, candidates[0].Path)
+	return candidates[0].Name
+}
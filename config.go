@@ -0,0 +1,156 @@
+// Copyright 2022 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/Bobgy/go-licenses/v2/config"
+	"github.com/Bobgy/go-licenses/v2/licenses"
+	"github.com/spf13/cobra"
+)
+
+var (
+	configCmd = &cobra.Command{
+		Use:   "config",
+		Short: "Manages the go-licenses config file",
+		// Subcommands handle broken config files themselves, instead of
+		// failing to load them. Helpers reading the config, e.g.
+		// libraryOptions, see an empty one.
+		PersistentPreRunE: func(*cobra.Command, []string) error {
+			cfg = &config.Config{}
+			return nil
+		},
+	}
+
+	configValidateCmd = &cobra.Command{
+		Use:   "validate [<config file>]",
+		Short: "Strictly checks a config file, defaulting to --config",
+		Long: `Strictly checks a config file, defaulting to --config.
+
+Unlike other commands, it rejects unknown fields, e.g. misspelled ones, and
+reports every problem with its line number, e.g. overrides without spdxId or
+invalid policies.`,
+		Args: cobra.MaximumNArgs(1),
+		RunE: configValidateMain,
+	}
+
+	configInitCmd = &cobra.Command{
+		Use:   "init [<config file>]",
+		Short: "Writes a starter config file, defaulting to --config or licenses.yaml",
+		Long: `Writes a starter config file, defaulting to --config or licenses.yaml.
+
+It inspects the module in the current directory, and declares its path and
+version, i.e. its latest git tag, a binary in dist with a CSV report for each
+of its main packages, and empty overrides. It does not overwrite existing
+files.`,
+		Args: cobra.MaximumNArgs(1),
+		RunE: configInitMain,
+	}
+
+	configSchemaCmd = &cobra.Command{
+		Use:   "schema",
+		Short: "Prints the JSON Schema of config files",
+		Long: `Prints the JSON Schema of config files.
+
+Editors and CI can use it to validate config files before running go-licenses,
+e.g. with the YAML language server:
+
+  # yaml-language-server: $schema=schema.json`,
+		Args: cobra.NoArgs,
+		RunE: configSchemaMain,
+	}
+)
+
+func init() {
+	configCmd.AddCommand(configValidateCmd)
+	configCmd.AddCommand(configInitCmd)
+	configCmd.AddCommand(configSchemaCmd)
+	rootCmd.AddCommand(configCmd)
+}
+
+func configValidateMain(_ *cobra.Command, args []string) error {
+	path := configPath
+	if len(args) > 0 {
+		path = args[0]
+	}
+	if path == "" {
+		return fmt.Errorf("no config file, pass it as argument or with --config")
+	}
+	problems, err := config.Validate(path)
+	if err != nil {
+		return err
+	}
+	for _, p := range problems {
+		if p.Line == 0 {
+			fmt.Fprintf(os.Stderr, "%s: %s\n", path, p.Message)
+		} else {
+			fmt.Fprintf(os.Stderr, "%s:%d: %s\n", path, p.Line, p.Message)
+		}
+	}
+	if len(problems) > 0 {
+		return fmt.Errorf("config %s has %d problems", path, len(problems))
+	}
+	fmt.Printf("%s is valid\n", path)
+	return nil
+}
+
+func configInitMain(_ *cobra.Command, args []string) error {
+	path := configPath
+	if len(args) > 0 {
+		path = args[0]
+	}
+	if path == "" {
+		path = "licenses.yaml"
+	}
+	ctx := context.Background()
+	module, err := licenses.MainModule(ctx, libraryOptions())
+	if err != nil {
+		return err
+	}
+	mains, err := licenses.MainPackages(ctx, libraryOptions(), "./...")
+	if err != nil {
+		return err
+	}
+	version := latestTag(ctx)
+	if err := config.Init(path, module, version, mains); err != nil {
+		return err
+	}
+	fmt.Printf("Wrote %s for %s with %d binaries\n", path, module, len(mains))
+	return nil
+}
+
+// latestTag returns the version of the latest git tag reachable from HEAD in
+// the current directory, without the directory prefix of tags of modules in
+// subdirectories, e.g. v1.2.0 of sub/v1.2.0. It returns "" if there is none.
+func latestTag(ctx context.Context) string {
+	tag, err := gitOutput(ctx, "", "describe", "--tags", "--abbrev=0")
+	if err != nil {
+		return ""
+	}
+	return tag[strings.LastIndex(tag, "/")+1:]
+}
+
+func configSchemaMain(*cobra.Command, []string) error {
+	schema, err := config.Schema()
+	if err != nil {
+		return err
+	}
+	fmt.Println(string(schema))
+	return nil
+}
@@ -0,0 +1,436 @@
+// Copyright 2022 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package config defines the go-licenses configuration file.
+package config
+
+import (
+	"fmt"
+	"path"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/Bobgy/go-licenses/v2/policy"
+)
+
+// Config is the go-licenses configuration, usually stored in a YAML file.
+type Config struct {
+	// Include lists config files this one is layered over, e.g. an
+	// organization-wide policy maintained in one place. Each is a path,
+	// relative to the including file, or an https URL. Later files,
+	// and the including file last, take precedence.
+	Include []string `yaml:"include,omitempty"`
+	// Module is the main module the config belongs to, as written by config
+	// init. It is informational, e.g. for inventories of the configs of many
+	// repos.
+	Module *Module `yaml:"module,omitempty"`
+	// Binaries are Go binaries to report licenses for.
+	Binaries []Binary `yaml:"binaries,omitempty"`
+	// Ignore lists import path prefixes of packages and modules excluded
+	// from all reports, e.g. company-internal modules.
+	Ignore []string `yaml:"ignore,omitempty"`
+	// Policy decides which licenses are acceptable, used by the check
+	// command instead of the classifier's forbidden license type.
+	Policy *policy.Policy `yaml:"policy,omitempty"`
+	// Scopes apply different policies to the dependencies of packages in
+	// different directories, e.g. of a monorepo. Packages in no scope use
+	// Policy.
+	Scopes []Scope `yaml:"scopes,omitempty"`
+	// Overrides declare the licenses of libraries whose license cannot be
+	// identified.
+	Overrides []Override `yaml:"overrides,omitempty"`
+	// PreferredLicenses are SPDX IDs in order of preference, used to elect a
+	// license of dual licensed libraries.
+	PreferredLicenses []string `yaml:"preferredLicenses,omitempty"`
+	// ExitCodes map violation codes, e.g. "GL001", and ExitCodeError to the
+	// exit codes of failing commands. Unmapped failures exit with 1.
+	ExitCodes map[string]int `yaml:"exitCodes,omitempty"`
+	// Header is written at the top of generated CSV reports.
+	Header *Header `yaml:"header,omitempty"`
+	// Columns are the columns of CSV reports, in order, see Columns. They
+	// replace the columns selected by flags.
+	Columns []string `yaml:"columns,omitempty"`
+	// Jobs is the number of concurrent workers scanning packages,
+	// identifying licenses and validating license URLs. It defaults to the
+	// number of CPUs, --jobs takes precedence.
+	Jobs int `yaml:"jobs,omitempty"`
+	// Cache configures the cache of license classifications, module info and
+	// HTTP responses kept across runs.
+	Cache *Cache `yaml:"cache,omitempty"`
+}
+
+// Cache configures the cache directory.
+type Cache struct {
+	// Dir is the cache directory. It defaults to go-licenses in the user
+	// cache directory, --cache_dir takes precedence.
+	Dir string `yaml:"dir,omitempty"`
+	// TTL is how long cache entries are used, as a Go duration, e.g. 24h.
+	// It defaults to 24h, 0 keeps entries forever.
+	TTL string `yaml:"ttl,omitempty"`
+	// Disabled disables the cache, except for the license cache of
+	// --detect_license_changes.
+	Disabled bool `yaml:"disabled,omitempty"`
+}
+
+// DefaultCacheTTL is the default TTL of cache entries.
+const DefaultCacheTTL = 24 * time.Hour
+
+// ParseTTL returns the TTL of cache entries, or DefaultCacheTTL if unset.
+func (c *Cache) ParseTTL() (time.Duration, error) {
+	if c == nil || c.TTL == "" {
+		return DefaultCacheTTL, nil
+	}
+	ttl, err := time.ParseDuration(c.TTL)
+	if err != nil {
+		return 0, fmt.Errorf("invalid cache ttl %q, must be a duration like 24h: %w", c.TTL, err)
+	}
+	if ttl < 0 {
+		return 0, fmt.Errorf("cache ttl must not be negative, got %s", c.TTL)
+	}
+	return ttl, nil
+}
+
+// Columns of CSV reports.
+const (
+	ColumnLibrary       = "library"
+	ColumnURL           = "url"
+	ColumnLicense       = "license"
+	ColumnCategory      = "category"
+	ColumnConfidence    = "confidence"
+	ColumnModule        = "module"
+	ColumnVersion       = "version"
+	ColumnReplaced      = "replaced"
+	ColumnOriginalPath  = "originalPath"
+	ColumnCopyleftScope = "copyleftScope"
+	ColumnObligation    = "obligation"
+	ColumnNotes         = "notes"
+)
+
+// Columns are the names of all columns of CSV reports.
+var Columns = []string{
+	ColumnLibrary, ColumnURL, ColumnLicense, ColumnCategory, ColumnConfidence,
+	ColumnModule, ColumnVersion, ColumnReplaced, ColumnOriginalPath,
+	ColumnCopyleftScope, ColumnObligation, ColumnNotes,
+}
+
+// HasColumn reports whether Columns includes a column.
+func (c *Config) HasColumn(name string) bool {
+	for _, column := range c.Columns {
+		if column == name {
+			return true
+		}
+	}
+	return false
+}
+
+// validateColumns returns an error if Columns has an unknown column.
+func (c *Config) validateColumns() error {
+	for _, column := range c.Columns {
+		known := false
+		for _, k := range Columns {
+			known = known || k == column
+		}
+		if !known {
+			return fmt.Errorf("unknown column %q in columns, must be one of %s", column, strings.Join(Columns, ", "))
+		}
+	}
+	return nil
+}
+
+// ExitCodeError is the key of ExitCodes for errors aborting a command, e.g.
+// network errors, as opposed to violations found.
+const ExitCodeError = "error"
+
+// Keys of ExitCodes for kinds of errors aborting a command. They take
+// precedence over ExitCodeError.
+const (
+	ExitCodeNoLicenseFound     = "noLicenseFound"
+	ExitCodeUnsupportedHost    = "unsupportedHost"
+	ExitCodeRateLimited        = "rateLimited"
+	ExitCodeValidationMismatch = "validationMismatch"
+)
+
+// ErrorCodes are the keys of ExitCodes for errors aborting a command.
+var ErrorCodes = []string{ExitCodeError, ExitCodeNoLicenseFound, ExitCodeUnsupportedHost, ExitCodeRateLimited, ExitCodeValidationMismatch}
+
+// ExitCode returns the exit code mapped to a violation code or ExitCodeError
+// by ExitCodes, or 1.
+func (c *Config) ExitCode(code string) int {
+	if exit, ok := c.ExitCodes[code]; ok {
+		return exit
+	}
+	return 1
+}
+
+// knownCode reports whether code is a violation code, see policy.Codes, or
+// one of ErrorCodes.
+func knownCode(code string) bool {
+	for _, c := range policy.Codes {
+		if string(c) == code {
+			return true
+		}
+	}
+	for _, c := range ErrorCodes {
+		if c == code {
+			return true
+		}
+	}
+	return false
+}
+
+// validateExitCodes returns an error if ExitCodes maps an unknown code, or to
+// an exit code that is not a failure.
+func (c *Config) validateExitCodes() error {
+	codes := make([]string, 0, len(c.ExitCodes))
+	for code := range c.ExitCodes {
+		codes = append(codes, code)
+	}
+	sort.Strings(codes)
+	for _, code := range codes {
+		exit := c.ExitCodes[code]
+		if !knownCode(code) {
+			return fmt.Errorf("unknown code %q in exitCodes, must be a violation code like GL001 or one of %s", code, strings.Join(ErrorCodes, ", "))
+		}
+		if exit < 1 || exit > 125 {
+			return fmt.Errorf("exit code %d of %s in exitCodes must be between 1 and 125", exit, code)
+		}
+	}
+	return nil
+}
+
+// Override declares the license of a library.
+type Override struct {
+	// Name is the name of the library, as reported by go-licenses, or a
+	// pattern of library names, e.g. "github.com/mycorp/*". Elements of
+	// patterns are matched like path.Match, and "**" matches any number of
+	// elements. Patterns also match the libraries below matching paths.
+	Name string `yaml:"name"`
+	// SpdxID is the SPDX ID of the library's license, e.g. "MIT", or an
+	// expression of dual licenses, e.g. "MIT OR Apache-2.0".
+	SpdxID string `yaml:"spdxId,omitempty"`
+	// Licenses are the SPDX IDs or expressions of libraries with several
+	// license files, which all apply, instead of SpdxID.
+	Licenses []string `yaml:"licenses,omitempty"`
+	// URL is the URL of the library's license, reported instead of the
+	// discovered one.
+	URL string `yaml:"url,omitempty"`
+	// Submodules are overrides of related libraries, e.g. nested modules or
+	// the staging modules of a monorepo, by their full names. They inherit
+	// the license and URL of the override, unless they declare their own.
+	Submodules []Override `yaml:"submodules,omitempty"`
+}
+
+// flattenOverrides returns overrides followed by their submodules, which
+// inherit the license and URL of their parents.
+func flattenOverrides(overrides []Override) []Override {
+	var flat []Override
+	for _, o := range overrides {
+		submodules := o.Submodules
+		o.Submodules = nil
+		flat = append(flat, o)
+		for _, s := range submodules {
+			if s.SpdxID == "" && len(s.Licenses) == 0 {
+				s.SpdxID, s.Licenses = o.SpdxID, o.Licenses
+			}
+			if s.URL == "" {
+				s.URL = o.URL
+			}
+			flat = append(flat, flattenOverrides([]Override{s})...)
+		}
+	}
+	return flat
+}
+
+// Expression returns the SPDX expression of the license of the library, e.g.
+// "MIT AND (Apache-2.0 OR GPL-2.0)" for licenses "MIT" and
+// "Apache-2.0 OR GPL-2.0".
+func (o *Override) Expression() string {
+	if len(o.Licenses) == 0 {
+		return o.SpdxID
+	}
+	if len(o.Licenses) == 1 {
+		return o.Licenses[0]
+	}
+	parts := make([]string, len(o.Licenses))
+	for i, l := range o.Licenses {
+		if strings.Contains(l, " OR ") || strings.Contains(l, " AND ") {
+			l = "(" + l + ")"
+		}
+		parts[i] = l
+	}
+	return strings.Join(parts, " AND ")
+}
+
+// Override returns the override for the library with name, or nil if there
+// is none. Overrides with the exact name take precedence over patterns, which
+// are matched in order.
+func (c *Config) Override(name string) *Override {
+	for i := range c.Overrides {
+		if c.Overrides[i].Name == name {
+			return &c.Overrides[i]
+		}
+	}
+	elems := strings.Split(name, "/")
+	for i := range c.Overrides {
+		o := &c.Overrides[i]
+		if !isPattern(o.Name) {
+			continue
+		}
+		pattern := strings.Split(strings.TrimSuffix(o.Name, "/"), "/")
+		// Match the library or any of its parents.
+		for n := len(elems); n > 0; n-- {
+			if matchElems(pattern, elems[:n]) {
+				return o
+			}
+		}
+	}
+	return nil
+}
+
+// validatePattern returns an error if an override name is a malformed
+// pattern.
+func validatePattern(name string) error {
+	_, err := path.Match(name, "")
+	return err
+}
+
+// isPattern reports whether an override name is a pattern rather than a
+// library name.
+func isPattern(name string) bool {
+	return strings.ContainsAny(name, "*?[\\")
+}
+
+// Scope is a policy for the dependencies of packages in some directories.
+type Scope struct {
+	// Name identifies the scope in messages.
+	Name string `yaml:"name"`
+	// Paths are patterns of package directories relative to the current
+	// directory, e.g. "services/public/**". Elements are matched like
+	// path.Match, and "**" matches any number of directories.
+	Paths []string `yaml:"paths"`
+	// Policy applies to the dependencies of packages in the scope.
+	Policy policy.Policy `yaml:"policy"`
+}
+
+// Matches reports whether a slash-separated directory, relative to the
+// current directory, is in the scope.
+func (s *Scope) Matches(dir string) bool {
+	elems := strings.Split(path.Clean(dir), "/")
+	for _, pattern := range s.Paths {
+		if matchElems(strings.Split(path.Clean(pattern), "/"), elems) {
+			return true
+		}
+	}
+	return false
+}
+
+// matchElems matches path elements against pattern elements, where "**"
+// matches any number of elements.
+func matchElems(pattern, elems []string) bool {
+	if len(pattern) == 0 {
+		return len(elems) == 0
+	}
+	if pattern[0] == "**" {
+		for i := 0; i <= len(elems); i++ {
+			if matchElems(pattern[1:], elems[i:]) {
+				return true
+			}
+		}
+		return false
+	}
+	if len(elems) == 0 {
+		return false
+	}
+	if ok, err := path.Match(pattern[0], elems[0]); err != nil || !ok {
+		return false
+	}
+	return matchElems(pattern[1:], elems[1:])
+}
+
+// Module identifies a main module.
+type Module struct {
+	// Path is the module path, e.g. github.com/foo/bar.
+	Path string `yaml:"path"`
+	// Version is the version of the module being released, e.g. v1.2.0.
+	Version string `yaml:"version,omitempty"`
+}
+
+// Binary is a Go binary to report licenses for.
+type Binary struct {
+	// Path of the binary.
+	Path string `yaml:"path"`
+	// Name identifies the binary in the aggregated report. Defaults to Path.
+	Name string `yaml:"name,omitempty"`
+	// Output is the path of the CSV report for this binary. When empty, the
+	// binary only contributes to the aggregated report.
+	Output string `yaml:"output,omitempty"`
+}
+
+// Load reads the config file at path, layered over the config files it
+// includes.
+func Load(path string) (*Config, error) {
+	return LoadLayers(path)
+}
+
+// LoadLayers reads config files layered in order, e.g. a base, a
+// per-environment and a local config file. Later files take precedence, see
+// Include for how configs are layered.
+func LoadLayers(paths ...string) (*Config, error) {
+	config := &Config{}
+	for _, path := range paths {
+		layer, err := load(path, nil)
+		if err != nil {
+			return nil, err
+		}
+		config.merge(layer)
+	}
+	config.Overrides = flattenOverrides(config.Overrides)
+	name := strings.Join(paths, ", ")
+	for _, o := range config.Overrides {
+		if o.Name == "" || (o.SpdxID == "") == (len(o.Licenses) == 0) {
+			return nil, fmt.Errorf("config %s: overrides require name and either spdxId or licenses, got %+v", name, o)
+		}
+		if err := validatePattern(o.Name); err != nil {
+			return nil, fmt.Errorf("config %s: invalid override name pattern %q: %w", name, o.Name, err)
+		}
+	}
+	if config.Policy != nil {
+		if err := config.Policy.Validate(); err != nil {
+			return nil, fmt.Errorf("config %s: %w", name, err)
+		}
+	}
+	if err := config.validateExitCodes(); err != nil {
+		return nil, fmt.Errorf("config %s: %w", name, err)
+	}
+	if err := config.validateColumns(); err != nil {
+		return nil, fmt.Errorf("config %s: %w", name, err)
+	}
+	if config.Jobs < 0 {
+		return nil, fmt.Errorf("config %s: jobs must not be negative, got %d", name, config.Jobs)
+	}
+	if _, err := config.Cache.ParseTTL(); err != nil {
+		return nil, fmt.Errorf("config %s: %w", name, err)
+	}
+	for i := range config.Scopes {
+		s := &config.Scopes[i]
+		if s.Name == "" || len(s.Paths) == 0 {
+			return nil, fmt.Errorf("config %s: scopes require name and paths", name)
+		}
+		if err := s.Policy.Validate(); err != nil {
+			return nil, fmt.Errorf("config %s: scope %s: %w", name, s.Name, err)
+		}
+	}
+	return config, nil
+}
@@ -0,0 +1,238 @@
+// Copyright 2022 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package config
+
+import (
+	"testing"
+
+	"github.com/Bobgy/go-licenses/v2/policy"
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestLoad(t *testing.T) {
+	for _, test := range []struct {
+		desc       string
+		path       string
+		wantConfig *Config
+		wantErr    bool
+	}{
+		{
+			desc: "Binaries",
+			path: "testdata/binaries.yaml",
+			wantConfig: &Config{
+				Binaries: []Binary{
+					{Path: "dist/server", Output: "dist/server.licenses.csv"},
+					{Path: "dist/client"},
+				},
+			},
+		},
+		{
+			desc: "Ignore",
+			path: "testdata/ignore.yaml",
+			wantConfig: &Config{
+				Ignore: []string{"github.com/mycorp", "example.com/internal/"},
+			},
+		},
+		{
+			desc: "Policy",
+			path: "testdata/policy.yaml",
+			wantConfig: &Config{
+				Policy: &policy.Policy{
+					Allowed:   policy.Rules{Categories: []string{"notice", "permissive"}},
+					Forbidden: policy.Rules{Licenses: []string{"AGPL-3.0"}, Categories: []string{"forbidden"}},
+					Review:    policy.Rules{Licenses: []string{"MPL-2.0"}},
+					Exceptions: []policy.Exception{{
+						Module:        "github.com/foo/bar",
+						License:       "GPL-3.0",
+						Justification: "Only used by an internal admin tool.",
+						Approver:      "legal@example.com",
+						Expires:       "2023-01-31",
+					}},
+				},
+			},
+		},
+		{
+			desc: "Exit codes",
+			path: "testdata/exit_codes.yaml",
+			wantConfig: &Config{
+				ExitCodes: map[string]int{"GL001": 3, "GL002": 4, "error": 5},
+			},
+		},
+		{
+			desc:    "Unknown exit code",
+			path:    "testdata/invalid_exit_codes.yaml",
+			wantErr: true,
+		},
+		{
+			desc:    "Unknown policy category",
+			path:    "testdata/invalid_policy.yaml",
+			wantErr: true,
+		},
+		{
+			desc:    "Non-existent file",
+			path:    "testdata/non-existent.yaml",
+			wantErr: true,
+		},
+	} {
+		t.Run(test.desc, func(t *testing.T) {
+			config, err := Load(test.path)
+			if gotErr := err != nil; gotErr != test.wantErr {
+				t.Fatalf("Load(%q) = (_, %q), want err? %t", test.path, err, test.wantErr)
+			} else if gotErr {
+				return
+			}
+			if diff := cmp.Diff(test.wantConfig, config); diff != "" {
+				t.Errorf("Load(%q): diff (-want +got)\n%s", test.path, diff)
+			}
+		})
+	}
+}
+
+func TestScopeMatches(t *testing.T) {
+	s := &Scope{Name: "public", Paths: []string{"services/public/**", "cmd/*-server"}}
+	for _, test := range []struct {
+		dir  string
+		want bool
+	}{
+		{dir: "services/public", want: true},
+		{dir: "services/public/api/v1", want: true},
+		{dir: "services/publicity", want: false},
+		{dir: "internal/tools", want: false},
+		{dir: "cmd/api-server", want: true},
+		{dir: "cmd/api-server/sub", want: false},
+		{dir: "../other/services/public", want: false},
+	} {
+		if got := s.Matches(test.dir); got != test.want {
+			t.Errorf("Matches(%q) = %t, want %t", test.dir, got, test.want)
+		}
+	}
+}
+
+func TestExitCode(t *testing.T) {
+	c := &Config{ExitCodes: map[string]int{"GL001": 3, ExitCodeError: 5}}
+	for code, want := range map[string]int{"GL001": 3, "GL002": 1, ExitCodeError: 5} {
+		if got := c.ExitCode(code); got != want {
+			t.Errorf("ExitCode(%q) = %d, want %d", code, got, want)
+		}
+	}
+}
+
+func TestValidateExitCodes(t *testing.T) {
+	for _, test := range []struct {
+		exitCodes map[string]int
+		wantErr   bool
+	}{
+		{exitCodes: map[string]int{"GL001": 3, ExitCodeError: 5}},
+		{exitCodes: map[string]int{ExitCodeRateLimited: 75, ExitCodeNoLicenseFound: 4}},
+		{exitCodes: map[string]int{"rateLimit": 75}, wantErr: true},
+		{exitCodes: map[string]int{ExitCodeError: 0}, wantErr: true},
+	} {
+		c := &Config{ExitCodes: test.exitCodes}
+		if err := c.validateExitCodes(); (err != nil) != test.wantErr {
+			t.Errorf("validateExitCodes(%v) = %v, want error: %t", test.exitCodes, err, test.wantErr)
+		}
+	}
+}
+
+func TestValidateColumns(t *testing.T) {
+	for _, tc := range []struct {
+		columns []string
+		wantErr bool
+	}{
+		{columns: nil},
+		{columns: Columns},
+		{columns: []string{ColumnModule, ColumnVersion, ColumnLicense}},
+		{columns: []string{ColumnLicense, "spdx"}, wantErr: true},
+	} {
+		c := &Config{Columns: tc.columns}
+		if err := c.validateColumns(); (err != nil) != tc.wantErr {
+			t.Errorf("validateColumns(%q) = %v, want error: %v", tc.columns, err, tc.wantErr)
+		}
+	}
+}
+
+func TestHasColumn(t *testing.T) {
+	c := &Config{Columns: []string{ColumnModule, ColumnConfidence}}
+	if !c.HasColumn(ColumnConfidence) {
+		t.Errorf("HasColumn(%q) = false, want true", ColumnConfidence)
+	}
+	if c.HasColumn(ColumnNotes) {
+		t.Errorf("HasColumn(%q) = true, want false", ColumnNotes)
+	}
+}
+
+func TestOverride(t *testing.T) {
+	c := &Config{Overrides: []Override{
+		{Name: "github.com/mycorp/*", SpdxID: "Proprietary"},
+		{Name: "github.com/mycorp/oss", SpdxID: "MIT"},
+		{Name: "example.com/**/vendored", SpdxID: "BSD-3-Clause"},
+	}}
+	for _, test := range []struct {
+		name string
+		want string
+	}{
+		{name: "github.com/mycorp/oss", want: "MIT"},
+		{name: "github.com/mycorp/app", want: "Proprietary"},
+		{name: "github.com/mycorp/app/internal/lib", want: "Proprietary"},
+		{name: "github.com/mycorp", want: ""},
+		{name: "example.com/a/b/vendored", want: "BSD-3-Clause"},
+		{name: "github.com/other/app", want: ""},
+	} {
+		got := ""
+		if o := c.Override(test.name); o != nil {
+			got = o.SpdxID
+		}
+		if got != test.want {
+			t.Errorf("Override(%q) = %q, want %q", test.name, got, test.want)
+		}
+	}
+}
+
+func TestOverrideExpression(t *testing.T) {
+	for _, test := range []struct {
+		override Override
+		want     string
+	}{
+		{override: Override{SpdxID: "MIT OR Apache-2.0"}, want: "MIT OR Apache-2.0"},
+		{override: Override{Licenses: []string{"MIT"}}, want: "MIT"},
+		{override: Override{Licenses: []string{"MIT", "BSD-3-Clause"}}, want: "MIT AND BSD-3-Clause"},
+		{override: Override{Licenses: []string{"MIT", "Apache-2.0 OR GPL-2.0"}}, want: "MIT AND (Apache-2.0 OR GPL-2.0)"},
+	} {
+		if got := test.override.Expression(); got != test.want {
+			t.Errorf("%+v.Expression() = %q, want %q", test.override, got, test.want)
+		}
+	}
+}
+
+func TestFlattenOverrides(t *testing.T) {
+	overrides := []Override{{
+		Name:   "k8s.io/kubernetes",
+		SpdxID: "Apache-2.0",
+		URL:    "https://github.com/kubernetes/kubernetes/blob/master/LICENSE",
+		Submodules: []Override{
+			{Name: "k8s.io/api"},
+			{Name: "k8s.io/third_party", SpdxID: "BSD-3-Clause", Submodules: []Override{{Name: "k8s.io/third_party/forked"}}},
+		},
+	}}
+	want := []Override{
+		{Name: "k8s.io/kubernetes", SpdxID: "Apache-2.0", URL: "https://github.com/kubernetes/kubernetes/blob/master/LICENSE"},
+		{Name: "k8s.io/api", SpdxID: "Apache-2.0", URL: "https://github.com/kubernetes/kubernetes/blob/master/LICENSE"},
+		{Name: "k8s.io/third_party", SpdxID: "BSD-3-Clause", URL: "https://github.com/kubernetes/kubernetes/blob/master/LICENSE"},
+		{Name: "k8s.io/third_party/forked", SpdxID: "BSD-3-Clause", URL: "https://github.com/kubernetes/kubernetes/blob/master/LICENSE"},
+	}
+	if diff := cmp.Diff(want, flattenOverrides(overrides)); diff != "" {
+		t.Errorf("flattenOverrides() diff (-want +got):\n%s", diff)
+	}
+}
@@ -0,0 +1,73 @@
+// Copyright 2022 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package config
+
+import (
+	"fmt"
+	"os"
+	"regexp"
+
+	"gopkg.in/yaml.v3"
+)
+
+// envRegexp matches references to environment variables in config values,
+// e.g. "${HOME}" or "${GOOS:-linux}" with a default value, and escaped
+// references, e.g. "$${HOME}".
+var envRegexp = regexp.MustCompile(`\$?\$\{([A-Za-z_][A-Za-z0-9_]*)(?::-([^}]*))?\}`)
+
+// expandEnv replaces references to environment variables in the scalar values
+// of a YAML node and its children, see envRegexp. Escaped references are
+// unescaped instead. It returns an error for variables that are not set and
+// have no default value. References in remote config files are an error
+// instead, so that they cannot send environment variables, e.g. CI secrets,
+// to the URLs they include.
+func expandEnv(n *yaml.Node, remote bool) error {
+	if n.Kind == yaml.ScalarNode {
+		var err error
+		n.Value = envRegexp.ReplaceAllStringFunc(n.Value, func(ref string) string {
+			if ref[1] == '$' {
+				return ref[1:]
+			}
+			m := envRegexp.FindStringSubmatch(ref)
+			if remote {
+				if err == nil {
+					err = fmt.Errorf("line %d: environment variable %s is not allowed in remote config files", n.Line, m[1])
+				}
+				return ref
+			}
+			if value, ok := os.LookupEnv(m[1]); ok {
+				return value
+			}
+			if len(ref) > len(m[1])+3 {
+				return m[2]
+			}
+			if err == nil {
+				err = fmt.Errorf("line %d: environment variable %s is not set, set it or give a default value like ${%s:-value}", n.Line, m[1], m[1])
+			}
+			return ref
+		})
+		return err
+	}
+	for i, c := range n.Content {
+		// Only expand values of mappings, not their keys.
+		if n.Kind == yaml.MappingNode && i%2 == 0 {
+			continue
+		}
+		if err := expandEnv(c, remote); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,81 @@
+// Copyright 2022 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package config
+
+import (
+	"os"
+	"testing"
+
+	"gopkg.in/yaml.v3"
+)
+
+func TestExpandEnv(t *testing.T) {
+	os.Setenv("GO_LICENSES_TEST_DIST", "/tmp/dist")
+	defer os.Unsetenv("GO_LICENSES_TEST_DIST")
+	os.Unsetenv("GO_LICENSES_TEST_UNSET")
+	for _, test := range []struct {
+		value   string
+		want    string
+		wantErr bool
+	}{
+		{value: "${GO_LICENSES_TEST_DIST}/server", want: "/tmp/dist/server"},
+		{value: "${GO_LICENSES_TEST_UNSET:-dist}/server", want: "dist/server"},
+		{value: "${GO_LICENSES_TEST_UNSET:-}server", want: "server"},
+		{value: "$${GO_LICENSES_TEST_DIST}", want: "${GO_LICENSES_TEST_DIST}"},
+		{value: `matches(license, "GPL$")`, want: `matches(license, "GPL$")`},
+		{value: "${GO_LICENSES_TEST_UNSET}", wantErr: true},
+	} {
+		n := &yaml.Node{Kind: yaml.MappingNode, Content: []*yaml.Node{
+			{Kind: yaml.ScalarNode, Value: "${GO_LICENSES_TEST_DIST}"},
+			{Kind: yaml.ScalarNode, Value: test.value},
+		}}
+		err := expandEnv(n, false)
+		if gotErr := err != nil; gotErr != test.wantErr {
+			t.Errorf("expandEnv(%q) = %v, want error? %t", test.value, err, test.wantErr)
+			continue
+		}
+		if !test.wantErr && n.Content[1].Value != test.want {
+			t.Errorf("expandEnv(%q) = %q, want %q", test.value, n.Content[1].Value, test.want)
+		}
+		if n.Content[0].Value != "${GO_LICENSES_TEST_DIST}" {
+			t.Errorf("expandEnv() expanded key to %q, want keys unchanged", n.Content[0].Value)
+		}
+	}
+}
+
+func TestExpandEnvRemote(t *testing.T) {
+	os.Setenv("GO_LICENSES_TEST_TOKEN", "secret")
+	defer os.Unsetenv("GO_LICENSES_TEST_TOKEN")
+	for _, test := range []struct {
+		value   string
+		want    string
+		wantErr bool
+	}{
+		{value: "https://example.com/policy.yaml", want: "https://example.com/policy.yaml"},
+		{value: "$${GO_LICENSES_TEST_TOKEN}", want: "${GO_LICENSES_TEST_TOKEN}"},
+		{value: "https://evil.example/x?${GO_LICENSES_TEST_TOKEN}", wantErr: true},
+		{value: "${GO_LICENSES_TEST_UNSET:-dist}", wantErr: true},
+	} {
+		n := &yaml.Node{Kind: yaml.SequenceNode, Content: []*yaml.Node{{Kind: yaml.ScalarNode, Value: test.value}}}
+		err := expandEnv(n, true)
+		if gotErr := err != nil; gotErr != test.wantErr {
+			t.Errorf("expandEnv(%q, true) = %v, want error? %t", test.value, err, test.wantErr)
+			continue
+		}
+		if !test.wantErr && n.Content[0].Value != test.want {
+			t.Errorf("expandEnv(%q, true) = %q, want %q", test.value, n.Content[0].Value, test.want)
+		}
+	}
+}
@@ -0,0 +1,70 @@
+// Copyright 2022 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package config
+
+import (
+	"crypto/sha256"
+	"fmt"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Header is a comment block written at the top of generated reports, so that
+// consumers can trace which run produced them.
+type Header struct {
+	// Text is a banner, e.g. "Generated by go-licenses. DO NOT EDIT.". Each
+	// of its lines becomes a comment line.
+	Text string `yaml:"text,omitempty"`
+	// Version adds the go-licenses version.
+	Version bool `yaml:"version,omitempty"`
+	// ConfigHash adds the SHA-256 hash of the effective config, see Hash.
+	ConfigHash bool `yaml:"configHash,omitempty"`
+	// Timestamp adds the time of the run.
+	Timestamp bool `yaml:"timestamp,omitempty"`
+}
+
+// Comment returns the header as lines starting with "# ", given the version of
+// go-licenses, the hash of the config and the time of the run. Metadata not
+// enabled by the header is left out.
+func (h *Header) Comment(version, hash string, now time.Time) string {
+	var b strings.Builder
+	if h.Text != "" {
+		for _, line := range strings.Split(strings.TrimRight(h.Text, "\n"), "\n") {
+			b.WriteString(strings.TrimRight("# "+line, " ") + "\n")
+		}
+	}
+	if h.Version {
+		fmt.Fprintf(&b, "# version: %s\n", version)
+	}
+	if h.ConfigHash {
+		fmt.Fprintf(&b, "# config: sha256:%s\n", hash)
+	}
+	if h.Timestamp {
+		fmt.Fprintf(&b, "# timestamp: %s\n", now.UTC().Format(time.RFC3339))
+	}
+	return b.String()
+}
+
+// Hash returns the hex encoded SHA-256 hash of the config, after layering its
+// includes. Configs that only differ in formatting or comments hash the same.
+func (c *Config) Hash() (string, error) {
+	content, err := yaml.Marshal(c)
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("%x", sha256.Sum256(content)), nil
+}
@@ -0,0 +1,54 @@
+// Copyright 2022 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package config
+
+import (
+	"testing"
+	"time"
+)
+
+func TestHeaderComment(t *testing.T) {
+	now := time.Date(2022, 3, 4, 5, 6, 7, 0, time.FixedZone("CET", 3600))
+	for _, test := range []struct {
+		desc   string
+		header Header
+		want   string
+	}{
+		{
+			desc: "empty",
+		},
+		{
+			desc:   "banner",
+			header: Header{Text: "Generated by go-licenses.\n\nDO NOT EDIT.\n"},
+			want:   "# Generated by go-licenses.\n#\n# DO NOT EDIT.\n",
+		},
+		{
+			desc:   "metadata",
+			header: Header{Text: "Generated by go-licenses.", Version: true, ConfigHash: true, Timestamp: true},
+			want:   "# Generated by go-licenses.\n# version: v2.0.0\n# config: sha256:abc123\n# timestamp: 2022-03-04T04:06:07Z\n",
+		},
+		{
+			desc:   "metadata only",
+			header: Header{Version: true},
+			want:   "# version: v2.0.0\n",
+		},
+	} {
+		t.Run(test.desc, func(t *testing.T) {
+			if got := test.header.Comment("v2.0.0", "abc123", now); got != test.want {
+				t.Errorf("Comment() = %q, want %q", got, test.want)
+			}
+		})
+	}
+}
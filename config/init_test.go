@@ -0,0 +1,87 @@
+// Copyright 2022 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package config
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestStarter(t *testing.T) {
+	got := string(Starter("example.com/app/v2", "v2.1.0", []string{"example.com/app/v2", "example.com/app/v2/cmd/server"}))
+	for _, want := range []string{
+		"# go-licenses config of example.com/app/v2,",
+		"\nmodule:\n  path: example.com/app/v2\n  version: v2.1.0\n",
+		"  - path: dist/app\n    output: dist/app.licenses.csv\n",
+		"  - path: dist/server\n    output: dist/server.licenses.csv\n",
+		"\noverrides: []\n",
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("Starter() = %q, want it to contain %q", got, want)
+		}
+	}
+	got = string(Starter("example.com/lib", "", nil))
+	if !strings.Contains(got, "\nbinaries: []\n") {
+		t.Errorf("Starter() without main packages = %q, want empty binaries", got)
+	}
+	if !strings.Contains(got, "  # version: ") {
+		t.Errorf("Starter() without version = %q, want a commented out version", got)
+	}
+}
+
+func TestInit(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "licenses.yaml")
+	if err := Init(path, "example.com/app", "", nil); err != nil {
+		t.Fatalf("Init() = %v, want nil", err)
+	}
+	if err := Init(path, "example.com/app", "", nil); err == nil {
+		t.Error("Init() of existing file = nil, want error")
+	}
+}
+
+func TestStarterIsValid(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "licenses.yaml")
+	if err := Init(path, "example.com/app", "v1.0.0", []string{"example.com/app"}); err != nil {
+		t.Fatal(err)
+	}
+	problems, err := Validate(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(problems) > 0 {
+		t.Errorf("Validate() of starter = %v, want no problems", problems)
+	}
+}
+
+func TestStarterLoads(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "licenses.yaml")
+	if err := ioutil.WriteFile(path, Starter("example.com/app", "v1.0.0", []string{"example.com/app"}), 0644); err != nil {
+		t.Fatal(err)
+	}
+	config, err := Load(path)
+	if err != nil {
+		t.Fatalf("Load() of starter = %v, want nil", err)
+	}
+	if diff := cmp.Diff(&Module{Path: "example.com/app", Version: "v1.0.0"}, config.Module); diff != "" {
+		t.Errorf("Load() of starter module diff (-want +got):\n%s", diff)
+	}
+	if diff := cmp.Diff([]Binary{{Path: "dist/app", Output: "dist/app.licenses.csv"}}, config.Binaries); diff != "" {
+		t.Errorf("Load() of starter binaries diff (-want +got):\n%s", diff)
+	}
+}
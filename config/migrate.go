@@ -0,0 +1,115 @@
+// Copyright 2022 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package config
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/Bobgy/go-licenses/v2/policy"
+)
+
+// Migrate converts a v1 invocation of go-licenses, e.g.
+// ["check", "./...", "--ignore=github.com/mycorp"], into an equivalent config.
+// It returns the arguments without an equivalent in the config, e.g. the
+// command, packages and other flags, to keep passing on the command line.
+func Migrate(args []string) (*Config, []string, error) {
+	c := &Config{}
+	var rest []string
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+		if !strings.HasPrefix(arg, "-") {
+			if arg == "check" && len(rest) == 0 {
+				// v1 check fails on licenses of the forbidden type.
+				c.Policy = &policy.Policy{Forbidden: policy.Rules{Categories: []string{"forbidden"}}}
+			}
+			rest = append(rest, arg)
+			continue
+		}
+		name := strings.TrimLeft(arg, "-")
+		value, hasValue := "", false
+		if j := strings.Index(name, "="); j >= 0 {
+			name, value, hasValue = name[:j], name[j+1:], true
+		}
+		switch name {
+		case "ignore", "disallowed_types", "allowed_licenses":
+		default:
+			rest = append(rest, arg)
+			continue
+		}
+		if !hasValue {
+			if i+1 == len(args) {
+				return nil, nil, fmt.Errorf("flag --%s without value", name)
+			}
+			i++
+			value = args[i]
+		}
+		values := strings.Split(value, ",")
+		switch name {
+		case "ignore":
+			c.Ignore = append(c.Ignore, values...)
+		case "disallowed_types":
+			if c.Policy == nil {
+				c.Policy = &policy.Policy{}
+			}
+			c.Policy.Forbidden.Categories = nil
+			for _, t := range values {
+				c.Policy.Forbidden.Categories = append(c.Policy.Forbidden.Categories, strings.ToLower(strings.TrimSpace(t)))
+			}
+		case "allowed_licenses":
+			if c.Policy == nil {
+				c.Policy = &policy.Policy{}
+			}
+			for _, l := range values {
+				c.Policy.Allowed.Licenses = append(c.Policy.Allowed.Licenses, strings.TrimSpace(l))
+			}
+		}
+	}
+	return c, rest, nil
+}
+
+// MigrateCSV converts a csv report saved by go-licenses, e.g. one whose
+// licenses were reviewed or corrected by hand, into overrides pinning the
+// license and URL of each library. Libraries with unknown licenses are
+// skipped.
+func MigrateCSV(r io.Reader) ([]Override, error) {
+	reader := csv.NewReader(r)
+	reader.TrimLeadingSpace = true
+	// Rows have additional columns with some flags.
+	reader.FieldsPerRecord = -1
+	var overrides []Override
+	for {
+		row, err := reader.Read()
+		if err == io.EOF {
+			return overrides, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		if len(row) < 3 {
+			return nil, fmt.Errorf("csv row %q: want library, license URL and license columns", row)
+		}
+		o := Override{Name: row[0], SpdxID: row[2]}
+		if o.SpdxID == "" || o.SpdxID == "Unknown" {
+			continue
+		}
+		if row[1] != "Unknown" {
+			o.URL = row[1]
+		}
+		overrides = append(overrides, o)
+	}
+}
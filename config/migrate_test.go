@@ -0,0 +1,88 @@
+// Copyright 2022 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package config
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/Bobgy/go-licenses/v2/policy"
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestMigrate(t *testing.T) {
+	for _, test := range []struct {
+		desc     string
+		args     []string
+		want     *Config
+		wantRest []string
+	}{
+		{
+			desc:     "Check",
+			args:     []string{"check", "./...", "--ignore", "github.com/mycorp", "--confidence_threshold=0.8"},
+			want:     &Config{Ignore: []string{"github.com/mycorp"}, Policy: &policy.Policy{Forbidden: policy.Rules{Categories: []string{"forbidden"}}}},
+			wantRest: []string{"check", "./...", "--confidence_threshold=0.8"},
+		},
+		{
+			desc: "Disallowed types and allowed licenses",
+			args: []string{"check", "--disallowed_types=Forbidden,restricted", "--allowed_licenses=MIT,Apache-2.0", "./..."},
+			want: &Config{Policy: &policy.Policy{
+				Allowed:   policy.Rules{Licenses: []string{"MIT", "Apache-2.0"}},
+				Forbidden: policy.Rules{Categories: []string{"forbidden", "restricted"}},
+			}},
+			wantRest: []string{"check", "./..."},
+		},
+		{
+			desc:     "CSV",
+			args:     []string{"csv", "--ignore=github.com/a,github.com/b", "./..."},
+			want:     &Config{Ignore: []string{"github.com/a", "github.com/b"}},
+			wantRest: []string{"csv", "./..."},
+		},
+	} {
+		t.Run(test.desc, func(t *testing.T) {
+			got, rest, err := Migrate(test.args)
+			if err != nil {
+				t.Fatalf("Migrate() = (_, _, %v), want (_, _, nil)", err)
+			}
+			if diff := cmp.Diff(test.want, got); diff != "" {
+				t.Errorf("Migrate() config diff (-want +got):\n%s", diff)
+			}
+			if diff := cmp.Diff(test.wantRest, rest); diff != "" {
+				t.Errorf("Migrate() rest diff (-want +got):\n%s", diff)
+			}
+		})
+	}
+	if _, _, err := Migrate([]string{"csv", "--ignore"}); err == nil {
+		t.Error("Migrate() with flag without value = nil error, want error")
+	}
+}
+
+func TestMigrateCSV(t *testing.T) {
+	report := `github.com/foo/bar, https://github.com/foo/bar/blob/master/LICENSE, MIT
+github.com/foo/unknown, Unknown, Unknown
+github.com/foo/nourl, Unknown, BSD-3-Clause, v1.0.0
+`
+	want := []Override{
+		{Name: "github.com/foo/bar", SpdxID: "MIT", URL: "https://github.com/foo/bar/blob/master/LICENSE"},
+		{Name: "github.com/foo/nourl", SpdxID: "BSD-3-Clause"},
+	}
+	got, err := MigrateCSV(strings.NewReader(report))
+	if err != nil {
+		t.Fatalf("MigrateCSV() = (_, %v), want (_, nil)", err)
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("MigrateCSV() diff (-want +got):\n%s", diff)
+	}
+}
@@ -0,0 +1,89 @@
+// Copyright 2022 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package config
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"os"
+
+	"gopkg.in/yaml.v3"
+)
+
+// AddOverrides appends overrides to the config file at path, creating it if
+// it does not exist. The rest of the file, including comments, is kept.
+func AddOverrides(path string, overrides []Override) error {
+	content, err := WithOverrides(path, overrides)
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(path, content, 0644)
+}
+
+// WithOverrides returns the content of the config file at path with overrides
+// appended, see AddOverrides, without changing the file.
+func WithOverrides(path string, overrides []Override) ([]byte, error) {
+	var doc yaml.Node
+	data, err := ioutil.ReadFile(path)
+	if err != nil && !os.IsNotExist(err) {
+		return nil, fmt.Errorf("reading config: %w", err)
+	}
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return nil, fmt.Errorf("parsing config %s: %w", path, err)
+	}
+	if doc.Kind == 0 {
+		// An empty or new file.
+		doc = yaml.Node{Kind: yaml.DocumentNode, Content: []*yaml.Node{{Kind: yaml.MappingNode}}}
+	}
+	root := doc.Content[0]
+	if root.Kind != yaml.MappingNode {
+		return nil, fmt.Errorf("config %s: not a mapping", path)
+	}
+	var seq *yaml.Node
+	for i := 0; i+1 < len(root.Content); i += 2 {
+		if root.Content[i].Value == "overrides" {
+			seq = root.Content[i+1]
+		}
+	}
+	if seq == nil {
+		seq = &yaml.Node{Kind: yaml.SequenceNode}
+		root.Content = append(root.Content, &yaml.Node{Kind: yaml.ScalarNode, Value: "overrides"}, seq)
+	}
+	if seq.Kind == yaml.ScalarNode && seq.Tag == "!!null" {
+		// An empty "overrides:" key.
+		*seq = yaml.Node{Kind: yaml.SequenceNode}
+	}
+	if seq.Kind != yaml.SequenceNode {
+		return nil, fmt.Errorf("config %s: overrides is not a list", path)
+	}
+	for _, o := range overrides {
+		n := &yaml.Node{}
+		if err := n.Encode(o); err != nil {
+			return nil, err
+		}
+		seq.Content = append(seq.Content, n)
+	}
+	var buf bytes.Buffer
+	enc := yaml.NewEncoder(&buf)
+	enc.SetIndent(2)
+	if err := enc.Encode(&doc); err != nil {
+		return nil, err
+	}
+	if err := enc.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
@@ -0,0 +1,76 @@
+// Copyright 2022 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package config
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestAddOverrides(t *testing.T) {
+	for _, test := range []struct {
+		desc    string
+		content string
+		want    []Override
+	}{
+		{
+			desc: "New file",
+			want: []Override{{Name: "github.com/foo/bar", SpdxID: "MIT"}},
+		},
+		{
+			desc:    "Existing overrides",
+			content: "# Reviewed by legal.\nignore:\n  - github.com/mycorp\noverrides:\n  - name: github.com/foo/baz\n    spdxId: BSD-3-Clause\n",
+			want: []Override{
+				{Name: "github.com/foo/baz", SpdxID: "BSD-3-Clause"},
+				{Name: "github.com/foo/bar", SpdxID: "MIT"},
+			},
+		},
+		{
+			desc:    "Empty overrides",
+			content: "overrides:\n",
+			want:    []Override{{Name: "github.com/foo/bar", SpdxID: "MIT"}},
+		},
+	} {
+		t.Run(test.desc, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "licenses.yaml")
+			if test.content != "" {
+				if err := ioutil.WriteFile(path, []byte(test.content), 0644); err != nil {
+					t.Fatal(err)
+				}
+			}
+			if err := AddOverrides(path, []Override{{Name: "github.com/foo/bar", SpdxID: "MIT"}}); err != nil {
+				t.Fatalf("AddOverrides() = %v", err)
+			}
+			config, err := Load(path)
+			if err != nil {
+				t.Fatalf("Load() = %v", err)
+			}
+			if diff := cmp.Diff(test.want, config.Overrides); diff != "" {
+				t.Errorf("Overrides after AddOverrides(): diff (-want +got)\n%s", diff)
+			}
+			data, err := ioutil.ReadFile(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if strings.HasPrefix(test.content, "#") && !strings.Contains(string(data), "# Reviewed by legal.") {
+				t.Errorf("AddOverrides() dropped comment, got:\n%s", data)
+			}
+		})
+	}
+}
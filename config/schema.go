@@ -0,0 +1,107 @@
+// Copyright 2022 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package config
+
+import (
+	"encoding/json"
+	"reflect"
+	"strings"
+
+	"github.com/Bobgy/go-licenses/v2/policy"
+)
+
+// schemaEnums lists the values of string types with a fixed set of values.
+var schemaEnums = map[reflect.Type][]string{
+	reflect.TypeOf(policy.Severity("")): {string(policy.Info), string(policy.Warn), string(policy.Error)},
+	reflect.TypeOf(policy.Verdict("")):  {string(policy.Allowed), string(policy.NeedsReview), string(policy.Denied)},
+}
+
+// Schema returns a JSON Schema of config files, generated from Config, so
+// that editors and CI can validate config files. Fields without omitempty are
+// required, and unknown fields are rejected.
+func Schema() ([]byte, error) {
+	defs := make(map[string]interface{})
+	root := schemaOf(reflect.TypeOf(Config{}), defs, true)
+	root["$schema"] = "https://json-schema.org/draft/2020-12/schema"
+	root["title"] = "go-licenses config"
+	root["$defs"] = defs
+	return json.MarshalIndent(root, "", "  ")
+}
+
+// schemaOf returns the schema of a type. Struct types are added to defs and
+// referenced, except for the root.
+func schemaOf(t reflect.Type, defs map[string]interface{}, root bool) map[string]interface{} {
+	if enum, ok := schemaEnums[t]; ok {
+		return map[string]interface{}{"type": "string", "enum": enum}
+	}
+	switch t.Kind() {
+	case reflect.Ptr:
+		return schemaOf(t.Elem(), defs, root)
+	case reflect.String:
+		return map[string]interface{}{"type": "string"}
+	case reflect.Bool:
+		return map[string]interface{}{"type": "boolean"}
+	case reflect.Int, reflect.Int32, reflect.Int64:
+		return map[string]interface{}{"type": "integer"}
+	case reflect.Float32, reflect.Float64:
+		return map[string]interface{}{"type": "number"}
+	case reflect.Slice:
+		return map[string]interface{}{"type": "array", "items": schemaOf(t.Elem(), defs, false)}
+	case reflect.Map:
+		return map[string]interface{}{"type": "object", "additionalProperties": schemaOf(t.Elem(), defs, false)}
+	case reflect.Struct:
+		ref := map[string]interface{}{"$ref": "#/$defs/" + t.Name()}
+		if _, ok := defs[t.Name()]; ok && !root {
+			return ref
+		}
+		if !root {
+			// Reserve the definition of recursive types.
+			defs[t.Name()] = nil
+		}
+		properties := make(map[string]interface{})
+		var required []string
+		for i := 0; i < t.NumField(); i++ {
+			f := t.Field(i)
+			tag := f.Tag.Get("yaml")
+			if f.PkgPath != "" || tag == "-" {
+				continue
+			}
+			parts := strings.Split(tag, ",")
+			name := parts[0]
+			if name == "" {
+				name = strings.ToLower(f.Name)
+			}
+			properties[name] = schemaOf(f.Type, defs, false)
+			if len(parts) == 1 && f.Type.Kind() != reflect.Struct {
+				required = append(required, name)
+			}
+		}
+		s := map[string]interface{}{
+			"type":                 "object",
+			"properties":           properties,
+			"additionalProperties": false,
+		}
+		if len(required) > 0 {
+			s["required"] = required
+		}
+		if root {
+			return s
+		}
+		defs[t.Name()] = s
+		return ref
+	default:
+		return map[string]interface{}{}
+	}
+}
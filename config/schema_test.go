@@ -0,0 +1,44 @@
+// Copyright 2022 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package config
+
+import (
+	"flag"
+	"io/ioutil"
+	"testing"
+)
+
+var updateSchema = flag.Bool("update_schema", false, "Update schema.json")
+
+// TestSchema checks that schema.json, shipped for editors, is up to date.
+func TestSchema(t *testing.T) {
+	got, err := Schema()
+	if err != nil {
+		t.Fatalf("Schema() = (_, %v), want (_, nil)", err)
+	}
+	got = append(got, '\n')
+	if *updateSchema {
+		if err := ioutil.WriteFile("schema.json", got, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	want, err := ioutil.ReadFile("schema.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(got) != string(want) {
+		t.Errorf("schema.json is out of date, update it with go test ./config -run TestSchema -update_schema")
+	}
+}
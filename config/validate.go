@@ -0,0 +1,258 @@
+// Copyright 2022 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package config
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/Bobgy/go-licenses/v2/policy"
+	"gopkg.in/yaml.v3"
+)
+
+// Problem is an error in a config file, found by Validate.
+type Problem struct {
+	// Line is the line of the config file with the problem, or 0 if unknown.
+	Line int
+	// Message describes the problem and how to fix it.
+	Message string
+}
+
+func (p Problem) String() string {
+	if p.Line == 0 {
+		return p.Message
+	}
+	return fmt.Sprintf("line %d: %s", p.Line, p.Message)
+}
+
+// yamlErrorRegexp matches the line number of YAML errors.
+var yamlErrorRegexp = regexp.MustCompile(`^(?:yaml: )?line (\d+): (.*)$`)
+
+// Validate strictly checks the config file at path. Unlike Load, it rejects
+// unknown fields, e.g. misspelled ones, and it reports all problems found
+// instead of the first. It returns an error only if the file cannot be read.
+func Validate(path string) ([]Problem, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("reading config: %w", err)
+	}
+	var doc yaml.Node
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return []Problem{yamlProblem(err.Error())}, nil
+	}
+	var problems []Problem
+	dec := yaml.NewDecoder(bytes.NewReader(data))
+	dec.KnownFields(true)
+	if err := dec.Decode(&Config{}); err != nil && err != io.EOF {
+		var typeErr *yaml.TypeError
+		if !errors.As(err, &typeErr) {
+			return append(problems, yamlProblem(err.Error())), nil
+		}
+		// The rest of the config is still decoded, check it too.
+		for _, e := range typeErr.Errors {
+			problems = append(problems, yamlProblem(e))
+		}
+	}
+	// Check the config as loaded, with environment variables expanded.
+	if err := expandEnv(&doc, false); err != nil {
+		problems = append(problems, yamlProblem(err.Error()))
+	}
+	config := &Config{}
+	if doc.Kind != 0 {
+		// Type errors were reported above, check the rest of the config.
+		_ = doc.Decode(config)
+	}
+	v := &validator{doc: &doc}
+	for i, include := range config.Include {
+		location, err := resolveInclude(path, include)
+		if err == nil {
+			_, err = load(location, []string{path})
+		}
+		if err != nil {
+			v.addf(v.line("include", i), "include %s: %v", include, err)
+		}
+	}
+	v.overrides(config)
+	if config.Policy != nil {
+		if err := config.Policy.Validate(); err != nil {
+			v.addf(v.line("policy"), "%v", err)
+		}
+	}
+	if err := config.validateExitCodes(); err != nil {
+		v.addf(v.line("exitCodes"), "%v", err)
+	}
+	if err := config.validateColumns(); err != nil {
+		v.addf(v.line("columns"), "%v", err)
+	}
+	if config.Jobs < 0 {
+		v.addf(v.line("jobs"), "jobs must not be negative, got %d", config.Jobs)
+	}
+	if _, err := config.Cache.ParseTTL(); err != nil {
+		v.addf(v.line("cache", "ttl"), "%v", err)
+	}
+	scopes := make(map[string]int)
+	for i := range config.Scopes {
+		s := &config.Scopes[i]
+		line := v.line("scopes", i)
+		switch {
+		case s.Name == "":
+			v.addf(line, "scope without name, add a name to identify it in messages")
+		case scopes[s.Name] != 0:
+			v.addf(line, "duplicate scope %s, first declared on line %d", s.Name, scopes[s.Name])
+		default:
+			scopes[s.Name] = line
+		}
+		if len(s.Paths) == 0 {
+			v.addf(line, "scope %s without paths, e.g. paths: [services/**]", s.Name)
+		}
+		if err := s.Policy.Validate(); err != nil {
+			v.addf(v.line("scopes", i, "policy"), "scope %s: %v", s.Name, err)
+		}
+	}
+	for i, prefix := range config.Ignore {
+		if prefix == "" {
+			v.addf(v.line("ignore", i), "empty ignore prefix would ignore all packages")
+		}
+	}
+	for i, b := range config.Binaries {
+		if b.Path == "" {
+			v.addf(v.line("binaries", i), "binary without path")
+		}
+	}
+	return append(problems, v.problems...), nil
+}
+
+// yamlProblem returns the problem of a YAML error message.
+func yamlProblem(msg string) Problem {
+	m := yamlErrorRegexp.FindStringSubmatch(msg)
+	if m == nil {
+		return Problem{Message: msg}
+	}
+	line, _ := strconv.Atoi(m[1])
+	return Problem{Line: line, Message: m[2]}
+}
+
+// validator collects problems of a config, locating them in its YAML document.
+type validator struct {
+	doc      *yaml.Node
+	problems []Problem
+}
+
+func (v *validator) addf(line int, format string, args ...interface{}) {
+	v.problems = append(v.problems, Problem{Line: line, Message: fmt.Sprintf(format, args...)})
+}
+
+// line returns the line of the node at a path of mapping keys and sequence
+// indices, or of its closest ancestor that exists.
+func (v *validator) line(path ...interface{}) int {
+	n := v.doc
+	if n.Kind == yaml.DocumentNode && len(n.Content) > 0 {
+		n = n.Content[0]
+	}
+	line := n.Line
+	for _, elem := range path {
+		var next *yaml.Node
+		switch elem := elem.(type) {
+		case string:
+			if n.Kind != yaml.MappingNode {
+				return line
+			}
+			for i := 0; i+1 < len(n.Content); i += 2 {
+				if n.Content[i].Value == elem {
+					// Report problems of values on the line of their key.
+					line = n.Content[i].Line
+					next = n.Content[i+1]
+					break
+				}
+			}
+		case int:
+			if n.Kind != yaml.SequenceNode || elem >= len(n.Content) {
+				return line
+			}
+			next = n.Content[elem]
+			line = next.Line
+		}
+		if next == nil {
+			return line
+		}
+		n = next
+	}
+	return line
+}
+
+// overrides checks that overrides are complete, unique and consistent with
+// preferredLicenses.
+func (v *validator) overrides(config *Config) {
+	names := make(map[string]int)
+	for i, o := range config.Overrides {
+		v.override(config, names, o, "overrides", i)
+	}
+}
+
+// override checks an override and its submodules, given the path of its node.
+func (v *validator) override(config *Config, names map[string]int, o Override, path ...interface{}) {
+	at := func(elems ...interface{}) int {
+		return v.line(append(append([]interface{}(nil), path...), elems...)...)
+	}
+	line := at()
+	switch {
+	case o.Name == "":
+		v.addf(line, "override without name, the library name as reported by go-licenses")
+	case validatePattern(o.Name) != nil:
+		v.addf(line, "invalid override name pattern %q: %v", o.Name, validatePattern(o.Name))
+	case names[o.Name] != 0:
+		v.addf(line, "duplicate override for %s, first declared on line %d", o.Name, names[o.Name])
+	default:
+		names[o.Name] = line
+	}
+	for j, s := range o.Submodules {
+		// Submodules inherit the license of their parent.
+		if s.SpdxID == "" && len(s.Licenses) == 0 {
+			s.SpdxID, s.Licenses = o.SpdxID, o.Licenses
+		}
+		v.override(config, names, s, append(append([]interface{}(nil), path...), "submodules", j)...)
+	}
+	switch {
+	case o.SpdxID == "" && len(o.Licenses) == 0:
+		v.addf(line, "override for %s without spdxId, e.g. spdxId: MIT", o.Name)
+		return
+	case o.SpdxID != "" && len(o.Licenses) > 0:
+		v.addf(line, "override for %s with both spdxId and licenses, use spdxId for a single license or licenses for several license files", o.Name)
+		return
+	}
+	licenses, lines := o.Licenses, make([]int, len(o.Licenses))
+	for j, l := range o.Licenses {
+		lines[j] = at("licenses", j)
+		if strings.TrimSpace(l) == "" {
+			v.addf(lines[j], "empty license in licenses of override for %s", o.Name)
+		}
+	}
+	if len(licenses) == 0 {
+		licenses, lines = []string{o.SpdxID}, []int{at("spdxId")}
+	}
+	for j, l := range licenses {
+		if len(policy.Alternatives(l)) > 1 {
+			if _, ok := policy.Elect(l, config.PreferredLicenses); !ok {
+				v.addf(lines[j], "none of the licenses of %s for %s is in preferredLicenses", l, o.Name)
+			}
+		}
+	}
+}
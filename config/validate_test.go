@@ -0,0 +1,61 @@
+// Copyright 2022 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package config
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestValidate(t *testing.T) {
+	for _, test := range []struct {
+		desc string
+		path string
+		want []Problem
+	}{
+		{
+			desc: "Valid",
+			path: "testdata/policy.yaml",
+		},
+		{
+			desc: "Typos",
+			path: "testdata/typos.yaml",
+			want: []Problem{
+				{Line: 4, Message: "field spdxID not found in type config.Override"},
+				{Line: 3, Message: "override for github.com/foo/bar without spdxId, e.g. spdxId: MIT"},
+				{Line: 6, Message: "none of the licenses of MIT OR GPL-2.0 for github.com/foo/baz is in preferredLicenses"},
+				{Line: 7, Message: "duplicate override for github.com/foo/baz, first declared on line 5"},
+				{Line: 9, Message: `policy: unknown category "notise" in allowed.categories, must be one of restricted, reciprocal, notice, permissive, unencumbered, forbidden, unknown`},
+			},
+		},
+	} {
+		t.Run(test.desc, func(t *testing.T) {
+			got, err := Validate(test.path)
+			if err != nil {
+				t.Fatalf("Validate(%q) = (_, %v), want (_, nil)", test.path, err)
+			}
+			if diff := cmp.Diff(test.want, got); diff != "" {
+				t.Errorf("Validate(%q) problems diff (-want +got):\n%s", test.path, diff)
+			}
+		})
+	}
+}
+
+func TestValidateMissingFile(t *testing.T) {
+	if _, err := Validate("testdata/non-existent.yaml"); err == nil {
+		t.Error("Validate() of missing file = nil error, want error")
+	}
+}
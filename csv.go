@@ -15,70 +15,492 @@
 package main
 
 import (
+	"bytes"
 	"context"
+	"encoding/csv"
 	"fmt"
+	"io"
+	"io/ioutil"
 	"os"
+	"runtime/debug"
+	"strconv"
 	"strings"
+	"sync"
 
-	"github.com/golang/glog"
+	"github.com/Bobgy/go-licenses/v2/config"
+	"github.com/Bobgy/go-licenses/v2/internal/logging"
+	"github.com/Bobgy/go-licenses/v2/internal/parallel"
 	"github.com/Bobgy/go-licenses/v2/licenses"
+	"github.com/Bobgy/go-licenses/v2/policy"
+	"github.com/Bobgy/go-licenses/v2/report"
 	"github.com/spf13/cobra"
 )
 
 var (
 	csvCmd = &cobra.Command{
-		Use:   "csv <package>",
+		Use:   "csv <package>...",
 		Short: "Prints all licenses that apply to a Go package and its dependencies",
-		Args:  cobra.MinimumNArgs(1),
+		Args:  packageArgs,
 		RunE:  csvMain,
 	}
 
 	gitRemotes []string
+	// nonGoOutput is where the report of non-Go components is written to.
+	nonGoOutput string
+	// reportFormat is the format of the report: csv, json or yaml.
+	reportFormat string
 )
 
 func init() {
 	csvCmd.Flags().StringArrayVar(&gitRemotes, "git_remote", []string{"origin", "upstream"}, "Remote Git repositories to try")
+	csvCmd.Flags().StringVar(&reportFormat, "format", "csv", "Format of the report: csv, or json or yaml with all fields of each library and a schema version.")
+	csvCmd.Flags().BoolVar(&incremental, "incremental", false, "Reuse the rows of the previous run, recorded in --cache_dir, for modules whose go.sum hashes did not change, and only scan added or changed modules.")
+	csvCmd.Flags().StringVar(&sinceRev, "since", "", "Like --incremental, but only scan modules whose go.sum hashes changed since a git revision, e.g. origin/main.")
+	csvCmd.Flags().StringVar(&nonGoOutput, "non_go_output", "", "Path to write a csv report of packages using cgo, linked native libraries or bundled non-Go sources, which require manual review")
 
 	rootCmd.AddCommand(csvCmd)
 }
 
 func csvMain(_ *cobra.Command, args []string) error {
-	classifier, err := licenses.NewClassifier(confidenceThreshold)
+	if reportFormat != "csv" && reportFormat != "json" && reportFormat != "yaml" {
+		return fmt.Errorf("unknown --format %q, must be csv, json or yaml", reportFormat)
+	}
+	if porcelain && reportFormat != "csv" {
+		return fmt.Errorf("--porcelain only applies to --format=csv")
+	}
+	classifier, err := newClassifier(confidenceThreshold)
 	if err != nil {
 		return err
 	}
 
-	libs, err := licenses.Libraries(context.Background(), classifier, args...)
+	importPaths, err := expandPackages(context.Background(), args)
 	if err != nil {
 		return err
 	}
-	for _, lib := range libs {
-		licenseName := "Unknown"
-		licenseURL := "Unknown"
-		if lib.LicensePath != "" {
-			name, _, err := classifier.Identify(lib.LicensePath)
-			if err == nil {
-				licenseName = name
-			} else {
-				glog.Errorf("Error identifying license in %q: %v", lib.LicensePath, err)
+	if incrementalScan, err = startIncremental(context.Background()); err != nil {
+		return err
+	}
+	if incrementalScan == nil {
+		return streamReport(os.Stdout, classifier, importPaths)
+	}
+	libs, err := licenses.LibrariesWithOptions(context.Background(), classifier, libraryOptions(), importPaths...)
+	if err != nil {
+		return err
+	}
+	if nonGoOutput != "" {
+		if err := writeNonGoFile(nonGoOutput, libs); err != nil {
+			return err
+		}
+	}
+	return writeCSV(os.Stdout, classifier, libs)
+}
+
+// streamReport writes the report of the libraries of importPaths while their
+// licenses are identified, so that only the rows of about --jobs libraries are
+// held in memory at a time. Rows are written in order of library names, like
+// writeCSV, and their URLs are discovered concurrently.
+func streamReport(w io.Writer, classifier licenses.Classifier, importPaths []string) error {
+	rw, err := newReportWriter(w)
+	if err != nil {
+		return err
+	}
+	// Each row is sent as a channel of the row, in order, before the row is
+	// done, which bounds the rows in flight to the capacity of rows.
+	rows := make(chan chan report.Record, numJobs())
+	failed := make(chan struct{})
+	written := make(chan error, 1)
+	go func() {
+		var err error
+		for row := range rows {
+			r := <-row
+			if err != nil {
+				continue
 			}
-			url, err := lib.LicenseURL(context.Background())
-			if err == nil {
-				licenseURL = url
-			} else {
-				glog.Warningf("Error discovering license URL: %s", err)
+			if err = rw.Write(r); err != nil {
+				close(failed)
 			}
 		}
-		// Using ", " to join words makes vscode/terminal recognize the
-		// correct license URL. Otherwise, if there's no space after
-		// comma, vscode interprets the URL as concatenated with the
-		// license name after it.
-		// Also, the extra spaces does not affect csv syntax much, we
-		// can still copy the csv text and paste into Excel / Google
-		// Sheets.
-		if _, err := fmt.Fprintln(os.Stdout, strings.Join([]string{lib.Name(), licenseURL, licenseName}, ", ")); err != nil {
+		written <- err
+	}()
+	var nonGo []*licenses.Library
+	err = licenses.WalkLibraries(context.Background(), classifier, libraryOptions(), func(lib *licenses.Library) error {
+		select {
+		case <-failed:
+			return licenses.StopWalk
+		default:
+		}
+		if len(lib.NonGoComponents) > 0 {
+			nonGo = append(nonGo, lib)
+		}
+		row := make(chan report.Record, 1)
+		rows <- row
+		go func() {
+			row <- libraryRow(classifier, lib)
+		}()
+		return nil
+	}, importPaths...)
+	close(rows)
+	if writeErr := <-written; err == nil {
+		err = writeErr
+	}
+	if err != nil {
+		return err
+	}
+	if nonGoOutput != "" {
+		if err := writeNonGoFile(nonGoOutput, nonGo); err != nil {
 			return err
 		}
 	}
+	return rw.Close()
+}
+
+// writeNonGoFile writes a csv report of the non-Go components of libraries to
+// a file at path. Each row lists a package, whether it uses cgo, the native
+// libraries it links and its non-Go source files.
+func writeNonGoFile(path string, libs []*licenses.Library) error {
+	var b bytes.Buffer
+	for _, lib := range libs {
+		for _, c := range lib.NonGoComponents {
+			err := writeCSVRow(&b, c.Package, strconv.FormatBool(c.Cgo), strings.Join(c.LinkedLibraries, ";"), strings.Join(c.Sources, ";"))
+			if err != nil {
+				return err
+			}
+		}
+	}
+	return writeFile(path, b.Bytes(), 0644)
+}
+
+// libraryRow identifies the license of a library and discovers its URL.
+func libraryRow(classifier licenses.Classifier, lib *licenses.Library) report.Record {
+	row := report.Record{
+		Library: lib.Name(),
+		URL:     "Unknown",
+		License: "Unknown",
+	}
+	if m := lib.Module(); m != nil {
+		row.Module = m.Path
+		row.Version = m.Version
+		row.Replaced = m.Replaced()
+		row.OriginalPath = m.OriginalPath
+	}
+	row.ModuleWarning, row.Notes = libraryNotes(lib)
+	if row.ModuleWarning != "" {
+		logging.Module(lib.Name()).Warningf("%s: %s", lib.Name(), row.ModuleWarning)
+	}
+	row.Category = licenses.Unknown.String()
+	if name, typ, err := identifyLicense(classifier, lib); err == nil {
+		row.License = name
+		row.Category = strings.ToLower(typ.String())
+		row.CopyleftScope = policy.StaticLinkingScope(name)
+		row.Obligation = row.CopyleftScope.Obligation()
+		if cfg.HasColumn(config.ColumnConfidence) {
+			row.Confidence = licenseConfidence(classifier, lib)
+		}
+	} else if lib.LicensePath != "" {
+		logging.Module(lib.Name()).Errorf("Error identifying license in %q: %v", lib.LicensePath, err)
+	}
+	if o := cfg.Override(lib.Name()); o != nil && o.URL != "" {
+		row.URL = o.URL
+	} else if lib.LicensePath != "" {
+		url, err := lib.LicenseURL(context.Background())
+		if err == nil {
+			row.URL = url
+		} else {
+			logging.Module(lib.Name()).Warningf("Error discovering license URL: %s", err)
+		}
+	}
+	recordLibrary(row.Library, row.License)
+	return row
+}
+
+// libraryNotes returns the warning about the module version of a library, if
+// any, and the notes of its row, i.e. the module warning and integrity error.
+func libraryNotes(lib *licenses.Library) (moduleWarning, notes string) {
+	var all []string
+	if lib.ModuleWarning != nil {
+		moduleWarning = lib.ModuleWarning.String()
+		all = append(all, moduleWarning)
+	}
+	if lib.IntegrityError != nil {
+		all = append(all, lib.IntegrityError.Error())
+	}
+	return moduleWarning, strings.Join(all, "; ")
+}
+
+// libraryRows returns the rows of libraries, see libraryRow, identifying
+// licenses and discovering URLs of --jobs libraries concurrently.
+func libraryRows(classifier licenses.Classifier, libs []*licenses.Library) []report.Record {
+	rows := make([]report.Record, len(libs))
+	var mu sync.Mutex
+	done := 0
+	parallel.For(numJobs(), len(libs), func(i int) {
+		rows[i] = libraryRow(classifier, libs[i])
+		mu.Lock()
+		defer mu.Unlock()
+		done++
+		reportProgress(licenses.Progress{Phase: phaseIdentifyingLicenses, Done: done, Total: len(libs)})
+	})
+	return rows
+}
+
+// rowColumns returns the columns of a row, as configured by columns in the
+// config file, or otherwise by flags.
+func rowColumns(row report.Record) []string {
+	if len(cfg.Columns) > 0 {
+		columns := make([]string, len(cfg.Columns))
+		for i, c := range cfg.Columns {
+			columns[i] = row.Column(c)
+		}
+		return columns
+	}
+	columns := []string{row.Library, row.URL, row.License}
+	if moduleColumns {
+		columns = append(columns, row.Version, strconv.FormatBool(row.Replaced), row.OriginalPath)
+	}
+	if moduleWarnings {
+		columns = append(columns, row.ModuleWarning)
+	}
+	if staticLinking {
+		columns = append(columns, string(row.CopyleftScope), row.Obligation)
+	}
+	return columns
+}
+
+// rowRecord returns the record of the columns of a row written by
+// rowColumns with the same config file and flags, e.g. to merge reports. Only
+// the fields of the columns are set.
+func rowRecord(columns []string) (report.Record, error) {
+	var row report.Record
+	if len(cfg.Columns) > 0 {
+		if len(columns) != len(cfg.Columns) {
+			return row, fmt.Errorf("row has %d columns, want %d columns of the config file", len(columns), len(cfg.Columns))
+		}
+		for i, c := range cfg.Columns {
+			if err := row.SetColumn(c, columns[i]); err != nil {
+				return row, err
+			}
+		}
+		return row, nil
+	}
+	want := 3
+	if moduleColumns {
+		want += 3
+	}
+	if moduleWarnings {
+		want++
+	}
+	if staticLinking {
+		want += 2
+	}
+	if len(columns) != want {
+		return row, fmt.Errorf("row has %d columns, want %d: pass the flags selecting columns of the csv runs, e.g. --module_columns", len(columns), want)
+	}
+	row.Library, row.URL, row.License = columns[0], columns[1], columns[2]
+	columns = columns[3:]
+	if moduleColumns {
+		row.Version, row.OriginalPath = columns[0], columns[2]
+		if err := row.SetColumn(config.ColumnReplaced, columns[1]); err != nil {
+			return row, err
+		}
+		columns = columns[3:]
+	}
+	if moduleWarnings {
+		row.ModuleWarning = columns[0]
+		columns = columns[1:]
+	}
+	if staticLinking {
+		row.CopyleftScope, row.Obligation = policy.CopyleftScope(columns[0]), columns[1]
+	}
+	return row, nil
+}
+
+// recordLess orders records like reports of a single run, by library, see
+// licenses.Libraries, then by their columns.
+func recordLess(a, b report.Record) bool {
+	if a.Library != b.Library {
+		return a.Library < b.Library
+	}
+	return rowLess(a, b)
+}
+
+// rowLess orders rows by their columns, so that reports are stable.
+func rowLess(a, b report.Record) bool {
+	ac, bc := rowColumns(a), rowColumns(b)
+	for i := range ac {
+		if i >= len(bc) {
+			return false
+		}
+		if ac[i] != bc[i] {
+			return ac[i] < bc[i]
+		}
+	}
+	return len(ac) < len(bc)
+}
+
+// writeCSVRow writes columns of a csv row. With --porcelain, columns are
+// separated by tabs instead, see porcelainRow.
+func writeCSVRow(w io.Writer, columns ...string) error {
+	if porcelain {
+		_, err := fmt.Fprintln(w, porcelainRow(columns...))
+		return err
+	}
+	// Using ", " to join words makes vscode/terminal recognize the
+	// correct license URL. Otherwise, if there's no space after
+	// comma, vscode interprets the URL as concatenated with the
+	// license name after it.
+	// Also, the extra spaces does not affect csv syntax much, we
+	// can still copy the csv text and paste into Excel / Google
+	// Sheets.
+	quoted := make([]string, len(columns))
+	for i, c := range columns {
+		quoted[i] = csvField(c)
+	}
+	_, err := fmt.Fprintln(w, strings.Join(quoted, ", "))
+	return err
+}
+
+// csvField quotes a csv column like encoding/csv if it contains commas,
+// quotes or line breaks, or starts with a space, so that rows can be read back
+// by readCSVRows, e.g. messages of module warnings.
+func csvField(column string) string {
+	if !strings.ContainsAny(column, ",\"\r\n") && !strings.HasPrefix(column, " ") {
+		return column
+	}
+	return `"` + strings.ReplaceAll(column, `"`, `""`) + `"`
+}
+
+// readCSVRows returns the columns of the rows of a csv report written by
+// writeCSVRow. Comment lines, e.g. the header of the config file, are
+// skipped.
+func readCSVRows(r io.Reader) ([][]string, error) {
+	if porcelain {
+		data, err := ioutil.ReadAll(r)
+		if err != nil {
+			return nil, err
+		}
+		var rows [][]string
+		for _, line := range strings.Split(string(data), "\n") {
+			if strings.TrimSpace(line) != "" {
+				rows = append(rows, strings.Split(line, "\t"))
+			}
+		}
+		return rows, nil
+	}
+	cr := csv.NewReader(r)
+	cr.Comment = '#'
+	cr.TrimLeadingSpace = true
+	cr.FieldsPerRecord = -1
+	return cr.ReadAll()
+}
+
+// writeCSV writes the header of the config file, if any, and one row per
+// library with its name, license URL and license name.
+// With --module_columns, it also writes the module version, whether the module is
+// replaced and the original module path. With --module_warnings, a last column
+// holds warnings about the library's module version. With --static_linking, the
+// last two columns hold the copyleft scope of the license and its obligation.
+// With --format json or yaml, it writes a report with all fields instead.
+// With --incremental or --since, rows of unchanged modules are reused.
+func writeCSV(w io.Writer, classifier licenses.Classifier, libs []*licenses.Library) error {
+	var rows []report.Record
+	if incrementalScan != nil {
+		rows = incrementalScan.rows(classifier, libs)
+	} else {
+		rows = libraryRows(classifier, libs)
+	}
+	rw, err := newReportWriter(w)
+	if err != nil {
+		return err
+	}
+	return report.WriteRecords(rw, rows)
+}
+
+// newReportWriter writes the header of the config file, if the format has
+// one, and returns a Writer of the report in the format of --format.
+func newReportWriter(w io.Writer) (report.Writer, error) {
+	if reportFormat == "json" {
+		return report.NewJSONWriter(w), nil
+	}
+	if err := writeHeader(w); err != nil {
+		return nil, err
+	}
+	if reportFormat == "yaml" {
+		return report.NewYAMLWriter(w), nil
+	}
+	return csvWriter{w: w}, nil
+}
+
+// csvWriter is a report.Writer of csv rows, see rowColumns.
+type csvWriter struct {
+	w io.Writer
+}
+
+func (c csvWriter) Write(row report.Record) error {
+	return writeCSVRow(c.w, rowColumns(row)...)
+}
+
+func (csvWriter) Close() error {
 	return nil
 }
+
+// writeJSONReport writes rows as an indented JSON report, see report.Report.
+// Rows are streamed one by one instead of marshalling the whole report.
+func writeJSONReport(w io.Writer, rows []report.Record) error {
+	return report.WriteRecords(report.NewJSONWriter(w), rows)
+}
+
+// writeYAMLReport writes rows as a YAML report after the header of the config
+// file, see report.Report. Rows are streamed like writeJSONReport.
+func writeYAMLReport(w io.Writer, rows []report.Record) error {
+	if err := writeHeader(w); err != nil {
+		return err
+	}
+	return report.WriteRecords(report.NewYAMLWriter(w), rows)
+}
+
+// writeCSVRows writes rows of a csv report, after the header of the config file.
+func writeCSVRows(w io.Writer, rows []report.Record) error {
+	if err := writeHeader(w); err != nil {
+		return err
+	}
+	return report.WriteRecords(csvWriter{w: w}, rows)
+}
+
+// writeHeader writes the header of the config file, if any. Porcelain output
+// has no header.
+func writeHeader(w io.Writer) error {
+	if cfg.Header == nil || porcelain {
+		return nil
+	}
+	hash := ""
+	if cfg.Header.ConfigHash {
+		var err error
+		if hash, err = cfg.Hash(); err != nil {
+			return err
+		}
+	}
+	_, err := io.WriteString(w, cfg.Header.Comment(toolVersion(), hash, startTime))
+	return err
+}
+
+// toolVersion returns the module version go-licenses was built from, or
+// "(devel)" for builds outside the module cache.
+func toolVersion() string {
+	if info, ok := debug.ReadBuildInfo(); ok && info.Main.Version != "" {
+		return info.Main.Version
+	}
+	return "(devel)"
+}
+
+// porcelainReplacer replaces tabs and line breaks, which would break porcelain
+// rows, by spaces.
+var porcelainReplacer = strings.NewReplacer("\t", " ", "\r\n", " ", "\n", " ", "\r", " ")
+
+// porcelainRow joins columns by tabs, one record per line.
+func porcelainRow(columns ...string) string {
+	escaped := make([]string, len(columns))
+	for i, c := range columns {
+		escaped[i] = porcelainReplacer.Replace(c)
+	}
+	return strings.Join(escaped, "\t")
+}
@@ -0,0 +1,58 @@
+// Copyright 2022 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+
+	"github.com/Bobgy/go-licenses/v2/internal/diff"
+)
+
+// dryRunOutput is where --dry_run reports files that would be written, kept
+// apart from reports printed to stdout.
+var dryRunOutput io.Writer = os.Stderr
+
+// dryRunf reports an action skipped by --dry_run.
+func dryRunf(format string, args ...interface{}) {
+	fmt.Fprintf(dryRunOutput, "dry run: "+format+"\n", args...)
+}
+
+// writeFile writes content to a file at path. With --dry_run, it reports
+// whether the file would be created or changed instead, with a diff of the
+// changes to an existing file.
+func writeFile(path string, content []byte, perm os.FileMode) error {
+	if !dryRun {
+		return ioutil.WriteFile(path, content, perm)
+	}
+	old, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		dryRunf("would create %s (%d bytes)", path, len(content))
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if bytes.Equal(old, content) {
+		dryRunf("%s is unchanged", path)
+		return nil
+	}
+	dryRunf("would change %s", path)
+	_, err = io.WriteString(dryRunOutput, diff.Unified(path, string(old), string(content)))
+	return err
+}
@@ -22,6 +22,7 @@ import (
 	"os"
 	"os/exec"
 	"path/filepath"
+	"strings"
 	"testing"
 
 	"github.com/google/go-cmp/cmp"
@@ -29,6 +30,9 @@ import (
 
 var update = flag.Bool("update", false, "update golden files")
 
+// startDir is the working directory when tests start, i.e. the repo root.
+var startDir, _ = os.Getwd()
+
 func TestCsvCommandE2E(t *testing.T) {
 	var tests = []struct {
 		workdir string
@@ -96,3 +100,36 @@ func TestCsvCommandE2E(t *testing.T) {
 		})
 	}
 }
+
+func TestConfigInitE2E(t *testing.T) {
+	// Other tests change the working directory.
+	cmd := exec.Command("go", "install", ".")
+	cmd.Dir = startDir
+	if out, err := cmd.CombinedOutput(); err != nil {
+		t.Fatalf("installing go-licenses:\n%s", out)
+	}
+	workdir := filepath.Join(startDir, "testdata/modules/cli02")
+	path := filepath.Join(t.TempDir(), "licenses.yaml")
+	cmd = exec.Command("go-licenses", "config", "init", path)
+	cmd.Dir = workdir
+	if out, err := cmd.CombinedOutput(); err != nil {
+		t.Fatalf("running go-licenses config init: %s\n%s", err, out)
+	}
+	content, err := ioutil.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{
+		"module:\n  path: github.com/google/go-licenses/testdata/modules/cli02\n",
+		"binaries:\n  - path: dist/cli02\n",
+	} {
+		if !strings.Contains(string(content), want) {
+			t.Errorf("config init wrote:\n%s\nwant it to contain %q", content, want)
+		}
+	}
+	cmd = exec.Command("go-licenses", "config", "validate", path)
+	cmd.Dir = workdir
+	if out, err := cmd.CombinedOutput(); err != nil {
+		t.Errorf("running go-licenses config validate on the starter config: %s\n%s", err, out)
+	}
+}
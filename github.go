@@ -0,0 +1,103 @@
+// Copyright 2022 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/Bobgy/go-licenses/v2/policy"
+)
+
+// reportGitHubActions writes a GitHub Actions workflow command to w for each
+// violation, which annotates go.mod on pull requests. When run in GitHub
+// Actions, it also appends a table of violations to the job summary.
+// ref: https://docs.github.com/en/actions/using-workflows/workflow-commands-for-github-actions
+func reportGitHubActions(w io.Writer, violations []violation) error {
+	for _, v := range violations {
+		if _, err := fmt.Fprintln(w, githubAnnotation(v)); err != nil {
+			return err
+		}
+	}
+	summaryPath := os.Getenv("GITHUB_STEP_SUMMARY")
+	if summaryPath == "" {
+		return nil
+	}
+	if dryRun {
+		dryRunf("would append a table of %d violations to %s", len(violations), summaryPath)
+		return nil
+	}
+	f, err := os.OpenFile(summaryPath, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return err
+	}
+	if err := writeGitHubSummary(f, violations); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// githubAnnotation returns the workflow command annotating go.mod with a
+// violation, at the level matching its severity.
+func githubAnnotation(v violation) string {
+	level := "error"
+	switch v.Severity {
+	case policy.Warn:
+		level = "warning"
+	case policy.Info:
+		level = "notice"
+	}
+	title := "License policy"
+	if v.scope != "" {
+		title += " (" + v.scope + ")"
+	}
+	return fmt.Sprintf("::%s file=go.mod,title=%s::%s", level, escapeGitHubProperty(title), escapeGitHubData(v.Result.String()))
+}
+
+// writeGitHubSummary writes a Markdown table of violations.
+func writeGitHubSummary(w io.Writer, violations []violation) error {
+	var b strings.Builder
+	b.WriteString("## License policy\n\n")
+	if len(violations) == 0 {
+		b.WriteString("No violations.\n")
+	} else {
+		b.WriteString("| Severity | Library | License | Verdict | Rule | Scope |\n")
+		b.WriteString("| --- | --- | --- | --- | --- | --- |\n")
+		for _, v := range violations {
+			fmt.Fprintf(&b, "| %s | %s | %s | %s | %s | %s |\n",
+				v.Severity, escapeMarkdownCell(v.Library), escapeMarkdownCell(v.License), v.Verdict, escapeMarkdownCell(v.Rule), escapeMarkdownCell(v.scope))
+		}
+	}
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// escapeGitHubData escapes the message of a workflow command.
+func escapeGitHubData(s string) string {
+	return strings.NewReplacer("%", "%25", "\r", "%0D", "\n", "%0A").Replace(s)
+}
+
+// escapeGitHubProperty escapes a property value of a workflow command.
+func escapeGitHubProperty(s string) string {
+	return strings.NewReplacer("%", "%25", "\r", "%0D", "\n", "%0A", ":", "%3A", ",", "%2C").Replace(s)
+}
+
+// escapeMarkdownCell escapes a Markdown table cell.
+func escapeMarkdownCell(s string) string {
+	return strings.NewReplacer("|", "\\|", "\n", " ").Replace(s)
+}
@@ -3,8 +3,9 @@ module github.com/Bobgy/go-licenses/v2
 go 1.16
 
 require (
-	github.com/golang/glog v0.0.0-20160126235308-23def4e6c14b
+	github.com/golang/glog v1.0.0
 	github.com/golangci/golangci-lint v1.29.0
+	github.com/google/cel-go v0.10.1
 	github.com/google/go-cmp v0.5.6
 	github.com/google/go-replayers/httpreplay v1.0.0
 	github.com/google/licenseclassifier v0.0.0-20210722185704-3043a050f148
@@ -15,5 +16,7 @@ require (
 	golang.org/x/mod v0.5.1
 	golang.org/x/net v0.0.0-20211015210444-4f30a5c0130f
 	golang.org/x/tools v0.1.8
+	google.golang.org/genproto v0.0.0-20211208223120-3a66f561d7aa
 	gopkg.in/src-d/go-git.v4 v4.13.1
+	gopkg.in/yaml.v3 v3.0.0-20210107192922-496545a6307b
 )
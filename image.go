@@ -0,0 +1,74 @@
+// Copyright 2022 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	"github.com/Bobgy/go-licenses/v2/licenses"
+	"github.com/spf13/cobra"
+)
+
+var (
+	imageCmd = &cobra.Command{
+		Use:   "image <image>",
+		Short: "Prints all licenses that apply to the Go binaries in a container image",
+		Long: `Prints all licenses that apply to the Go binaries in a container image.
+
+The image is pulled and exported with docker, so the docker CLI must be
+installed, a docker daemon must be running, and it must be authenticated to
+the registry. The image is never run, so images without a shell or command,
+e.g. scratch and distroless images, are supported. All Go binaries in the image filesystem are
+reported together like the scan-dir command does, referred to by their paths
+inside the image.`,
+		Args: cobra.ExactArgs(1),
+		RunE: imageMain,
+	}
+
+	// imageOutputDir is where per-binary reports are written to.
+	imageOutputDir string
+)
+
+func init() {
+	imageCmd.Flags().StringVar(&imageOutputDir, "output_dir", "", "Directory into which a csv report for each binary is written")
+
+	rootCmd.AddCommand(imageCmd)
+}
+
+func imageMain(_ *cobra.Command, args []string) error {
+	dir, err := ioutil.TempDir("", "go-licenses-image")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(dir)
+	if err := licenses.ExtractImage(context.Background(), args[0], dir); err != nil {
+		return err
+	}
+	binaries, err := binariesInDir(dir, imageOutputDir)
+	if err != nil {
+		return err
+	}
+	for i := range binaries {
+		rel, err := filepath.Rel(dir, binaries[i].Path)
+		if err != nil {
+			return err
+		}
+		binaries[i].Name = "/" + filepath.ToSlash(rel)
+	}
+	return reportBinaries(binaries)
+}
@@ -0,0 +1,185 @@
+// Copyright 2022 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/Bobgy/go-licenses/v2/internal/logging"
+	"github.com/Bobgy/go-licenses/v2/licenses"
+	"github.com/Bobgy/go-licenses/v2/report"
+)
+
+var (
+	// incremental reuses the rows of the previous run for modules whose
+	// go.sum hashes did not change.
+	incremental bool
+	// sinceRev compares go.sum against a git revision instead of the previous
+	// run, implying incremental.
+	sinceRev string
+	// incrementalScan is the incremental run of the csv command, if any.
+	incrementalScan *incrementalRun
+)
+
+// incrementalState is the state of a run recorded for the next incremental
+// run.
+type incrementalState struct {
+	// Sums are the go.sum hashes of module versions, see licenses.ReadGoSum.
+	Sums map[string]string `json:"sums"`
+	// Records are the report records of libraries by name.
+	Records map[string]report.Record `json:"records"`
+}
+
+// incrementalRun reuses the rows of a previous run for libraries of modules
+// that did not change, and records the rows of this run.
+type incrementalRun struct {
+	cache *licenses.Cache
+	// key identifies runs of the same packages and settings in the cache.
+	key      string
+	previous incrementalState
+	// baseline are the go.sum hashes modules are compared against, either
+	// of the previous run or of --since.
+	baseline map[string]string
+	sums     map[string]string
+}
+
+// startIncremental returns the incremental run, or nil if neither
+// --incremental nor --since is set.
+func startIncremental(ctx context.Context) (*incrementalRun, error) {
+	if !incremental && sinceRev == "" {
+		return nil, nil
+	}
+	cache := libraryCache()
+	if cache == nil {
+		logging.Warningf("Not scanning incrementally, because the cache is disabled")
+		return nil, nil
+	}
+	goSum, err := licenses.GoSumFile(ctx, libraryOptions(), "")
+	if err != nil {
+		return nil, err
+	}
+	if goSum == "" {
+		return nil, fmt.Errorf("--incremental and --since require a main module with go.sum")
+	}
+	run := &incrementalRun{cache: cache}
+	if run.sums, err = licenses.ReadGoSum(goSum); err != nil && !os.IsNotExist(err) {
+		return nil, err
+	}
+	if run.key, err = incrementalKey(); err != nil {
+		return nil, err
+	}
+	if data, ok := cache.Get(licenses.CacheRuns, run.key); ok {
+		if err := json.Unmarshal(data, &run.previous); err != nil {
+			logging.Warningf("Ignoring the state of the previous run: %v", err)
+			run.previous = incrementalState{}
+		}
+	}
+	run.baseline = run.previous.Sums
+	if sinceRev != "" {
+		if run.baseline, err = licenses.ReadGoSumAt(ctx, goSum, sinceRev); err != nil {
+			return nil, err
+		}
+	}
+	changed := licenses.ChangedModules(run.baseline, run.sums)
+	logging.Infof("%d module versions changed since %s", len(changed), run.since())
+	return run, nil
+}
+
+// since describes what modules are compared against.
+func (r *incrementalRun) since() string {
+	if sinceRev != "" {
+		return sinceRev
+	}
+	return "the previous run"
+}
+
+// incrementalKey identifies runs in the current directory whose rows are
+// computed the same way, i.e. with the same classifier and config file, so
+// that only their rows are reused. Rows of libraries do not depend on the
+// packages scanned.
+func incrementalKey() (string, error) {
+	wd, err := os.Getwd()
+	if err != nil {
+		return "", err
+	}
+	hash, err := cfg.Hash()
+	if err != nil {
+		return "", err
+	}
+	return strings.Join([]string{
+		wd,
+		fmt.Sprintf("classifier=%s threshold=%g", classifierName, confidenceThreshold),
+		hash,
+		// Each shard records its own rows.
+		"shard=" + shard.String(),
+	}, "\x00"), nil
+}
+
+// reusable returns the row of a library recorded by the previous run, if its
+// module version has the same go.sum hash as the baseline. Notes about the
+// module, e.g. whether it has been retracted since, are updated.
+func (r *incrementalRun) reusable(lib *licenses.Library) (report.Record, bool) {
+	m := lib.Module()
+	if m == nil {
+		return report.Record{}, false
+	}
+	key := m.SumKey()
+	sum := r.sums[key]
+	if key == "" || sum == "" || r.baseline[key] != sum {
+		return report.Record{}, false
+	}
+	row, ok := r.previous.Records[lib.Name()]
+	if !ok || row.Module != m.Path || row.Version != m.Version {
+		return report.Record{}, false
+	}
+	row.ModuleWarning, row.Notes = libraryNotes(lib)
+	return row, true
+}
+
+// rows returns the rows of libraries, reusing rows of the previous run for
+// unchanged modules and scanning the rest, and records them for the next
+// run.
+func (r *incrementalRun) rows(classifier licenses.Classifier, libs []*licenses.Library) []report.Record {
+	rows := make([]report.Record, len(libs))
+	var scanned []*licenses.Library
+	var scannedIndices []int
+	for i, lib := range libs {
+		if row, ok := r.reusable(lib); ok {
+			rows[i] = row
+			recordLibrary(row.Library, row.License)
+			continue
+		}
+		scanned = append(scanned, lib)
+		scannedIndices = append(scannedIndices, i)
+	}
+	logging.Infof("Reusing %d of %d libraries, scanning %d changed since %s", len(libs)-len(scanned), len(libs), len(scanned), r.since())
+	for i, row := range libraryRows(classifier, scanned) {
+		rows[scannedIndices[i]] = row
+	}
+	state := incrementalState{Sums: r.sums, Records: make(map[string]report.Record, len(rows))}
+	for _, row := range rows {
+		state.Records[row.Library] = row
+	}
+	if data, err := json.Marshal(state); err != nil {
+		logging.Warningf("Not recording the state of this run: %v", err)
+	} else if err := r.cache.Put(licenses.CacheRuns, r.key, data); err != nil {
+		logging.Warningf("Not recording the state of this run: %v", err)
+	}
+	return rows
+}
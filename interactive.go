@@ -0,0 +1,87 @@
+// Copyright 2022 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"strings"
+
+	"github.com/Bobgy/go-licenses/v2/config"
+	"github.com/Bobgy/go-licenses/v2/licenses"
+)
+
+// maxPreviewLines is the number of lines of a candidate license file shown
+// during interactive review.
+const maxPreviewLines = 30
+
+// reviewLicenses asks for the SPDX ID of each library whose license cannot be
+// identified, showing its candidate license file if there is one. Answers are
+// read from in, line by line: an empty line skips a library and "q" stops the
+// review. It returns the accepted licenses as overrides.
+func reviewLicenses(in io.Reader, out io.Writer, classifier licenses.Classifier, libs []*licenses.Library) ([]config.Override, error) {
+	var overrides []config.Override
+	scanner := bufio.NewScanner(in)
+	for _, lib := range libs {
+		if _, _, err := identifyLicense(classifier, lib); err == nil {
+			continue
+		}
+		fmt.Fprintf(out, "\n%s\n", lib.Name())
+		if lib.LicensePath == "" {
+			fmt.Fprintln(out, "No license file found.")
+		} else {
+			preview, err := licensePreview(lib.LicensePath)
+			if err != nil {
+				return nil, err
+			}
+			fmt.Fprintf(out, "Unidentified license file %s:\n%s\n", lib.LicensePath, preview)
+		}
+		fmt.Fprint(out, "SPDX ID of the license (empty to skip, q to quit): ")
+		if !scanner.Scan() {
+			fmt.Fprintln(out)
+			break
+		}
+		answer := strings.TrimSpace(scanner.Text())
+		if answer == "q" {
+			break
+		}
+		if answer == "" {
+			continue
+		}
+		if licenses.LicenseType(answer) == licenses.Unknown {
+			fmt.Fprintf(out, "Warning: %s is not a known license, its type will be unknown.\n", answer)
+		}
+		overrides = append(overrides, config.Override{Name: lib.Name(), SpdxID: answer})
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return overrides, nil
+}
+
+// licensePreview returns the first lines of a license file.
+func licensePreview(path string) (string, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return "", err
+	}
+	lines := strings.Split(strings.TrimSpace(string(data)), "\n")
+	if len(lines) > maxPreviewLines {
+		lines = append(lines[:maxPreviewLines], fmt.Sprintf("... (%d more lines)", len(lines)-maxPreviewLines))
+	}
+	return strings.Join(lines, "\n"), nil
+}
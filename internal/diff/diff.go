@@ -0,0 +1,141 @@
+// Copyright 2022 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package diff computes line diffs of file contents.
+package diff
+
+import (
+	"fmt"
+	"strings"
+)
+
+// diffContext is the number of unchanged lines around changes in diffs.
+const diffContext = 3
+
+// maxDiffCells limits the size of files being diffed, since the diff takes
+// time and memory proportional to the product of their numbers of lines.
+const maxDiffCells = 1 << 22
+
+// diffLine is a line of a diff. Op is ' ' for an unchanged line, '-' for a
+// removed line and '+' for an added line. A and b are the line numbers of the
+// old and new file before the line.
+type diffLine struct {
+	op   byte
+	text string
+	a, b int
+}
+
+// Unified returns a unified diff of the lines of old and new content of a
+// file at path. Binary files and files too large to diff are only reported as
+// different.
+func Unified(path, old, new string) string {
+	if strings.Contains(old, "\x00") || strings.Contains(new, "\x00") {
+		return fmt.Sprintf("Binary file %s differs\n", path)
+	}
+	a, b := splitLines(old), splitLines(new)
+	if (len(a)+1)*(len(b)+1) > maxDiffCells {
+		return fmt.Sprintf("File %s differs, too large to diff\n", path)
+	}
+	// lcs[i][j] is the length of the longest common subsequence of a[i:]
+	// and b[j:].
+	lcs := make([][]int, len(a)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(b)+1)
+	}
+	for i := len(a) - 1; i >= 0; i-- {
+		for j := len(b) - 1; j >= 0; j-- {
+			if a[i] == b[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else if lcs[i+1][j] >= lcs[i][j+1] {
+				lcs[i][j] = lcs[i+1][j]
+			} else {
+				lcs[i][j] = lcs[i][j+1]
+			}
+		}
+	}
+	var lines []diffLine
+	i, j := 0, 0
+	for i < len(a) || j < len(b) {
+		switch {
+		case i < len(a) && j < len(b) && a[i] == b[j]:
+			lines = append(lines, diffLine{' ', a[i], i, j})
+			i, j = i+1, j+1
+		case j == len(b) || (i < len(a) && lcs[i+1][j] >= lcs[i][j+1]):
+			lines = append(lines, diffLine{'-', a[i], i, j})
+			i++
+		default:
+			lines = append(lines, diffLine{'+', b[j], i, j})
+			j++
+		}
+	}
+
+	var out strings.Builder
+	fmt.Fprintf(&out, "--- %s\n+++ %s\n", path, path)
+	for start := 0; start < len(lines); {
+		// Find the next change, and extend its hunk over changes separated
+		// by at most twice the context.
+		first := start
+		for first < len(lines) && lines[first].op == ' ' {
+			first++
+		}
+		if first == len(lines) {
+			break
+		}
+		last := first
+		for k := first; k < len(lines) && k <= last+2*diffContext; k++ {
+			if lines[k].op != ' ' {
+				last = k
+			}
+		}
+		from, to := first-diffContext, last+diffContext+1
+		if from < start {
+			from = start
+		}
+		if to > len(lines) {
+			to = len(lines)
+		}
+		var aCount, bCount int
+		for _, l := range lines[from:to] {
+			if l.op != '+' {
+				aCount++
+			}
+			if l.op != '-' {
+				bCount++
+			}
+		}
+		fmt.Fprintf(&out, "@@ -%s +%s @@\n", hunkRange(lines[from].a, aCount), hunkRange(lines[from].b, bCount))
+		for _, l := range lines[from:to] {
+			fmt.Fprintf(&out, "%c%s\n", l.op, l.text)
+		}
+		start = to
+	}
+	return out.String()
+}
+
+// hunkRange formats the range of lines of a hunk, given the number of lines
+// before it and its number of lines.
+func hunkRange(before, count int) string {
+	if count == 0 {
+		return fmt.Sprintf("%d,0", before)
+	}
+	return fmt.Sprintf("%d,%d", before+1, count)
+}
+
+// splitLines splits content into lines without their line endings.
+func splitLines(content string) []string {
+	if content == "" {
+		return nil
+	}
+	return strings.Split(strings.TrimSuffix(content, "\n"), "\n")
+}
@@ -0,0 +1,95 @@
+// Copyright 2022 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package diff
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestUnified(t *testing.T) {
+	for _, test := range []struct {
+		desc, old, new, want string
+	}{
+		{
+			desc: "added line",
+			old:  "a\nb\nc\n",
+			new:  "a\nb\nc\nd\n",
+			want: "@@ -1,3 +1,4 @@\n a\n b\n c\n+d\n",
+		},
+		{
+			desc: "removed line",
+			old:  "a\nb\nc\n",
+			new:  "a\nc\n",
+			want: "@@ -1,3 +1,2 @@\n a\n-b\n c\n",
+		},
+		{
+			desc: "changed line",
+			old:  "1\n2\n3\n4\n5\n6\n7\n",
+			new:  "1\n2\n3\nx\n5\n6\n7\n",
+			want: "@@ -1,7 +1,7 @@\n 1\n 2\n 3\n-4\n+x\n 5\n 6\n 7\n",
+		},
+		{
+			desc: "changes within twice the context share a hunk",
+			old:  "1\n2\nA\n3\n4\n5\n6\n7\nB\n8\n",
+			new:  "1\n2\na\n3\n4\n5\n6\n7\nb\n8\n",
+			want: "@@ -1,10 +1,10 @@\n 1\n 2\n-A\n+a\n 3\n 4\n 5\n 6\n 7\n-B\n+b\n 8\n",
+		},
+		{
+			desc: "adjacent hunks",
+			old:  "1\n2\nA\n3\n4\n5\n6\n7\n8\n9\nB\n10\n",
+			new:  "1\n2\na\n3\n4\n5\n6\n7\n8\n9\nb\n10\n",
+			want: "@@ -1,6 +1,6 @@\n 1\n 2\n-A\n+a\n 3\n 4\n 5\n@@ -8,5 +8,5 @@\n 7\n 8\n 9\n-B\n+b\n 10\n",
+		},
+		{
+			desc: "empty old file",
+			old:  "",
+			new:  "a\nb\n",
+			want: "@@ -0,0 +1,2 @@\n+a\n+b\n",
+		},
+		{
+			desc: "empty new file",
+			old:  "a\nb\n",
+			new:  "",
+			want: "@@ -1,2 +0,0 @@\n-a\n-b\n",
+		},
+		{
+			desc: "no changes",
+			old:  "a\n",
+			new:  "a\n",
+			want: "",
+		},
+		{
+			desc: "empty files",
+		},
+	} {
+		t.Run(test.desc, func(t *testing.T) {
+			want := "--- f.csv\n+++ f.csv\n" + test.want
+			if got := Unified("f.csv", test.old, test.new); got != want {
+				t.Errorf("Unified(%q, %q) = %q, want %q", test.old, test.new, got, want)
+			}
+		})
+	}
+}
+
+func TestUnifiedUndiffable(t *testing.T) {
+	if got, want := Unified("f.bin", "a\x00", "b"), "Binary file f.bin differs\n"; got != want {
+		t.Errorf("Unified() of binary file = %q, want %q", got, want)
+	}
+	large := strings.Repeat("a\n", 1<<12)
+	if got, want := Unified("f.csv", large, large+"b\n"), "File f.csv differs, too large to diff\n"; got != want {
+		t.Errorf("Unified() of large file = %q, want %q", got, want)
+	}
+}
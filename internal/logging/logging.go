@@ -0,0 +1,199 @@
+// Copyright 2022 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package logging writes diagnostics of go-licenses, as glog text logs, as
+// JSON lines with consistent fields for log aggregation systems, or to a
+// Logger of programs embedding go-licenses.
+package logging
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/golang/glog"
+)
+
+// Level is the level of a log entry.
+type Level string
+
+// Log levels
+const (
+	Info    = Level("info")
+	Warning = Level("warning")
+	Error   = Level("error")
+)
+
+// Fields are structured fields of a log entry. Empty fields are omitted.
+type Fields struct {
+	// Module is the module, package or library the entry is about.
+	Module string `json:"module,omitempty"`
+	// Phase is the phase of the scan, e.g. "finding licenses". It defaults
+	// to the phase set by SetPhase.
+	Phase string `json:"phase,omitempty"`
+	// Code is the code of a policy violation, e.g. "GL001", or "error" for
+	// errors aborting a command.
+	Code string `json:"code,omitempty"`
+}
+
+// Logger receives log entries, e.g. to route them into the logging stack of a
+// program embedding go-licenses. It may be called concurrently.
+type Logger interface {
+	Log(level Level, fields Fields, msg string)
+}
+
+// entry is a log entry in JSON format.
+type entry struct {
+	Time    string `json:"time"`
+	Level   Level  `json:"level"`
+	Message string `json:"msg"`
+	Fields
+}
+
+var (
+	mu sync.Mutex
+	// jsonOutput is where JSON lines are written to, or nil for glog.
+	jsonOutput io.Writer
+	// phase is the current phase of the scan.
+	phase string
+	// logger receives log entries instead of glog and JSON lines, if set.
+	logger Logger
+)
+
+// SetLogger sends log entries to l instead of glog or JSON lines, or stops
+// doing so if l is nil.
+func SetLogger(l Logger) {
+	mu.Lock()
+	defer mu.Unlock()
+	logger = l
+}
+
+// SetJSON writes log entries as JSON lines to w, or to glog if w is nil.
+func SetJSON(w io.Writer) {
+	mu.Lock()
+	defer mu.Unlock()
+	jsonOutput = w
+}
+
+// SetPhase sets the current phase of the scan, added to JSON log entries.
+func SetPhase(p string) {
+	mu.Lock()
+	defer mu.Unlock()
+	phase = p
+}
+
+// Module returns fields of an entry about a module, package or library.
+func Module(path string) Fields {
+	return Fields{Module: path}
+}
+
+// Infof logs an info message.
+func Infof(format string, args ...interface{}) {
+	Fields{}.log(Info, format, args...)
+}
+
+// Warningf logs a warning.
+func Warningf(format string, args ...interface{}) {
+	Fields{}.log(Warning, format, args...)
+}
+
+// Errorf logs an error.
+func Errorf(format string, args ...interface{}) {
+	Fields{}.log(Error, format, args...)
+}
+
+// Infof logs an info message with fields.
+func (f Fields) Infof(format string, args ...interface{}) {
+	f.log(Info, format, args...)
+}
+
+// Warningf logs a warning with fields.
+func (f Fields) Warningf(format string, args ...interface{}) {
+	f.log(Warning, format, args...)
+}
+
+// Errorf logs an error with fields.
+func (f Fields) Errorf(format string, args ...interface{}) {
+	f.log(Error, format, args...)
+}
+
+// Printf writes a message for users to stderr as is, e.g. a policy violation,
+// or as a JSON entry at level.
+func (f Fields) Printf(level Level, format string, args ...interface{}) {
+	if !f.write(level, fmt.Sprintf(format, args...)) {
+		fmt.Fprintf(os.Stderr, format, args...)
+	}
+}
+
+// log logs a message to glog, attributed to the caller of the exported
+// logging function, or to the logger, or as a JSON entry.
+func (f Fields) log(level Level, format string, args ...interface{}) {
+	msg := fmt.Sprintf(format, args...)
+	if f.write(level, msg) {
+		return
+	}
+	// Skip log and the exported logging function.
+	const depth = 2
+	switch level {
+	case Info:
+		glog.InfoDepth(depth, msg)
+	case Warning:
+		glog.WarningDepth(depth, msg)
+	default:
+		glog.ErrorDepth(depth, msg)
+	}
+}
+
+// write sends an entry to the logger or writes it as JSON line, unless log
+// entries are written to glog. It returns whether it did.
+func (f Fields) write(level Level, msg string) bool {
+	mu.Lock()
+	l, w := logger, jsonOutput
+	if f.Phase == "" {
+		f.Phase = phase
+	}
+	mu.Unlock()
+	if l != nil {
+		// Not holding mu, so that slow loggers do not block each other.
+		l.Log(level, f, trimNewline(msg))
+		return true
+	}
+	if w == nil {
+		return false
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	line, err := json.Marshal(entry{
+		Time:    time.Now().UTC().Format(time.RFC3339Nano),
+		Level:   level,
+		Message: trimNewline(msg),
+		Fields:  f,
+	})
+	if err != nil {
+		return false
+	}
+	fmt.Fprintf(w, "%s\n", line)
+	return true
+}
+
+// trimNewline removes a trailing line break of a message.
+func trimNewline(msg string) string {
+	if n := len(msg); n > 0 && msg[n-1] == '\n' {
+		return msg[:n-1]
+	}
+	return msg
+}
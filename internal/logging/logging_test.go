@@ -0,0 +1,85 @@
+// Copyright 2022 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package logging
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestJSON(t *testing.T) {
+	var buf bytes.Buffer
+	SetJSON(&buf)
+	defer SetJSON(nil)
+	SetPhase("finding licenses")
+	defer SetPhase("")
+
+	Module("github.com/foo/bar").Warningf("Error discovering license URL: %s", "no remote")
+	Fields{Module: "github.com/foo/gpl", Code: "GL001", Phase: "checking"}.Printf(Error, "Policy violation %s\n", "GL001")
+	Infof("Found %d binaries", 2)
+
+	var got []entry
+	for _, line := range strings.Split(strings.TrimSuffix(buf.String(), "\n"), "\n") {
+		var e entry
+		if err := json.Unmarshal([]byte(line), &e); err != nil {
+			t.Fatalf("Unmarshal(%q) = %v", line, err)
+		}
+		if e.Time == "" {
+			t.Errorf("entry %q has no time", line)
+		}
+		e.Time = ""
+		got = append(got, e)
+	}
+	want := []entry{
+		{Level: Warning, Message: "Error discovering license URL: no remote", Fields: Fields{Module: "github.com/foo/bar", Phase: "finding licenses"}},
+		{Level: Error, Message: "Policy violation GL001", Fields: Fields{Module: "github.com/foo/gpl", Phase: "checking", Code: "GL001"}},
+		{Level: Info, Message: "Found 2 binaries", Fields: Fields{Phase: "finding licenses"}},
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("JSON entries: diff (-want +got):\n%s", diff)
+	}
+}
+
+// loggerFunc records log entries.
+type loggerFunc func(level Level, fields Fields, msg string)
+
+func (f loggerFunc) Log(level Level, fields Fields, msg string) {
+	f(level, fields, msg)
+}
+
+func TestSetLogger(t *testing.T) {
+	var got []entry
+	SetLogger(loggerFunc(func(level Level, fields Fields, msg string) {
+		got = append(got, entry{Level: level, Message: msg, Fields: fields})
+	}))
+	defer SetLogger(nil)
+	SetPhase("checking")
+	defer SetPhase("")
+
+	Module("github.com/foo/bar").Warningf("Error discovering license URL: %s\n", "no remote")
+	Infof("Found %d binaries", 2)
+
+	want := []entry{
+		{Level: Warning, Message: "Error discovering license URL: no remote", Fields: Fields{Module: "github.com/foo/bar", Phase: "checking"}},
+		{Level: Info, Message: "Found 2 binaries", Fields: Fields{Phase: "checking"}},
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("logger entries: diff (-want +got):\n%s", diff)
+	}
+}
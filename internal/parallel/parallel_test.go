@@ -0,0 +1,61 @@
+// Copyright 2022 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package parallel
+
+import (
+	"sync"
+	"testing"
+)
+
+func TestFor(t *testing.T) {
+	for _, test := range []struct {
+		jobs, n int
+	}{
+		{jobs: 1, n: 10},
+		{jobs: 4, n: 100},
+		{jobs: 8, n: 3},
+		{jobs: 0, n: 5},
+		{jobs: 4, n: 0},
+	} {
+		var mu sync.Mutex
+		running, maxRunning := 0, 0
+		calls := make([]int, test.n)
+		For(test.jobs, test.n, func(i int) {
+			mu.Lock()
+			running++
+			if running > maxRunning {
+				maxRunning = running
+			}
+			calls[i]++
+			mu.Unlock()
+
+			mu.Lock()
+			running--
+			mu.Unlock()
+		})
+		for i, c := range calls {
+			if c != 1 {
+				t.Errorf("For(%d, %d) called f(%d) %d times, want once", test.jobs, test.n, i, c)
+			}
+		}
+		limit := test.jobs
+		if limit < 1 {
+			limit = 1
+		}
+		if maxRunning > limit {
+			t.Errorf("For(%d, %d) ran %d calls concurrently, want at most %d", test.jobs, test.n, maxRunning, limit)
+		}
+	}
+}
@@ -15,6 +15,7 @@
 package source
 
 import (
+	"net/http"
 	"path"
 	"strings"
 )
@@ -69,3 +70,27 @@ func (i *Info) RepoRawURL(pathname string) string {
 		"file":   pathname,
 	})
 }
+
+// Commit returns the tag or ID of the commit corresponding to the module's
+// version.
+func (i *Info) Commit() string {
+	if i == nil {
+		return ""
+	}
+	return i.commit
+}
+
+// RepoPath returns the path of a file relative to the repo's home directory,
+// given its pathname relative to the module's directory.
+func (i *Info) RepoPath(pathname string) string {
+	if i == nil {
+		return ""
+	}
+	return path.Join(i.moduleDir, pathname)
+}
+
+// NewClientFromHTTPClient constructs a *Client making requests with c, so that users can inject
+// a client with a proxy, auth or a stubbed transport.
+func NewClientFromHTTPClient(c *http.Client) *Client {
+	return &Client{httpClient: c}
+}
@@ -0,0 +1,173 @@
+// Copyright 2022 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package licenses
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"regexp"
+
+	"github.com/Bobgy/go-licenses/v2/internal/logging"
+)
+
+var (
+	// bazelRuleRegexp matches go_repository rules of WORKSPACE files and
+	// go_deps.module tags of MODULE.bazel files.
+	bazelRuleRegexp = regexp.MustCompile(`(?m)^\s*(go_repository|go_deps\.module)\(`)
+	bazelAttrRegexp = regexp.MustCompile(`(\w+)\s*=\s*(?:"([^"]*)"|'([^']*)')`)
+)
+
+// ReadBazelDeps parses go_repository rules of rules_go/Gazelle in a Bazel
+// file, e.g. deps.bzl, WORKSPACE or the output of
+// `bazel query --output=build 'kind(go_repository, //external:*)'`, and
+// go_deps.module tags of the go_deps extension in MODULE.bazel, and returns
+// the modules they declare. Modules that go_deps reads from go.mod with
+// go_deps.from_file are not declared in the file, and MODULE.bazel.lock is not
+// read. Dir of each module is set when the module exists in modCache.
+// Repositories fetched from version control instead of a module proxy, i.e.
+// without version, are skipped.
+func ReadBazelDeps(path string, modCache string) ([]*Module, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return parseBazelDeps(string(data), modCache)
+}
+
+// parseBazelDeps parses go_repository rules and go_deps.module tags like:
+//
+//	go_repository(
+//	    name = "com_github_spf13_cobra",
+//	    importpath = "github.com/spf13/cobra",
+//	    sum = "h1:...",
+//	    version = "v1.3.0",
+//	)
+//	go_deps.module(
+//	    path = "github.com/spf13/cobra",
+//	    sum = "h1:...",
+//	    version = "v1.3.0",
+//	)
+func parseBazelDeps(content string, modCache string) ([]*Module, error) {
+	content = stripBazelComments(content)
+	var mods []*Module
+	for _, loc := range bazelRuleRegexp.FindAllStringSubmatchIndex(content, -1) {
+		kind := content[loc[2]:loc[3]]
+		rule := content[loc[1]:]
+		end := ruleEnd(rule)
+		if end < 0 {
+			return nil, fmt.Errorf("unterminated %s rule at offset %d", kind, loc[0])
+		}
+		attrs := make(map[string]string)
+		for _, m := range bazelAttrRegexp.FindAllStringSubmatch(rule[:end], -1) {
+			attrs[m[1]] = m[2] + m[3]
+		}
+		name, path := attrs["name"], attrs["importpath"]
+		if kind == "go_deps.module" {
+			name, path = attrs["path"], attrs["path"]
+		}
+		if path == "" {
+			return nil, fmt.Errorf("%s %q has no module path", kind, name)
+		}
+		if attrs["version"] == "" {
+			logging.Warningf("Skipping %s %q, because it has no module version", kind, name)
+			continue
+		}
+		var m *Module
+		if replace := attrs["replace"]; replace != "" {
+			m = cachedModule(replace, attrs["version"], modCache)
+			m.OriginalPath = path
+		} else {
+			m = cachedModule(path, attrs["version"], modCache)
+		}
+		m.Sum = attrs["sum"]
+		mods = append(mods, m)
+	}
+	return mods, nil
+}
+
+// stripBazelComments replaces comments of a Bazel file by spaces, keeping
+// offsets and line breaks, so that commented out attributes and rules are
+// ignored. Strings may contain #.
+func stripBazelComments(s string) string {
+	b := []byte(s)
+	for i := 0; i < len(b); i++ {
+		switch b[i] {
+		case '"', '\'':
+			i += stringEnd(s[i:])
+		case '#':
+			for ; i < len(b) && b[i] != '\n'; i++ {
+				b[i] = ' '
+			}
+		}
+	}
+	return string(b)
+}
+
+// stringEnd returns the index of the quote closing a string started at s[0],
+// or the index of the last byte if the string is not closed. Escaped quotes
+// are skipped.
+func stringEnd(s string) int {
+	for i := 1; i < len(s); i++ {
+		switch s[i] {
+		case '\\':
+			i++
+		case s[0]:
+			return i
+		}
+	}
+	return len(s) - 1
+}
+
+// ruleEnd returns the index of the parenthesis closing a rule started before
+// s, or -1 if the rule is not closed. s has no comments, see
+// stripBazelComments, and parentheses in strings are ignored.
+func ruleEnd(s string) int {
+	depth := 1
+	for i := 0; i < len(s); i++ {
+		switch s[i] {
+		case '"', '\'':
+			j := stringEnd(s[i:])
+			if j == 0 || s[i+j] != s[i] {
+				return -1
+			}
+			i += j
+		case '(':
+			depth++
+		case ')':
+			depth--
+			if depth == 0 {
+				return i
+			}
+		}
+	}
+	return -1
+}
+
+// BazelLibraries returns the collection of libraries of the Go modules
+// declared by go_repository rules in a Bazel file, see ReadBazelDeps.
+// Like for binaries, each module becomes a single library whose license is
+// searched for at the root of the module.
+func BazelLibraries(ctx context.Context, classifier Classifier, opts Options, path string) ([]*Library, error) {
+	modCache, err := goEnv(ctx, opts, "", "GOMODCACHE")
+	if err != nil {
+		return nil, err
+	}
+	deps, err := ReadBazelDeps(path, modCache)
+	if err != nil {
+		return nil, err
+	}
+	return moduleLibraries(ctx, classifier, opts, deps)
+}
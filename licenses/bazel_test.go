@@ -0,0 +1,81 @@
+// Copyright 2022 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package licenses
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestReadBazelDeps(t *testing.T) {
+	for _, test := range []struct {
+		path string
+		want []*Module
+	}{
+		{
+			path: "testdata/deps.bzl",
+			want: []*Module{
+				{Path: "github.com/spf13/cobra", Version: "v1.3.0", Sum: "h1:abc="},
+				{Path: "github.com/example/kubernetes", Version: "v1.11.1", OriginalPath: "k8s.io/kubernetes", Sum: "h1:def="},
+			},
+		},
+		{
+			path: "testdata/MODULE.bazel",
+			want: []*Module{
+				{Path: "github.com/spf13/cobra", Version: "v1.3.0", Sum: "h1:abc="},
+				{Path: "github.com/example/quoted", Version: "v0.2.0"},
+			},
+		},
+	} {
+		t.Run(test.path, func(t *testing.T) {
+			mods, err := ReadBazelDeps(test.path, "")
+			if err != nil {
+				t.Fatalf("ReadBazelDeps() = (_, %q), want (_, nil)", err)
+			}
+			if diff := cmp.Diff(test.want, mods); diff != "" {
+				t.Errorf("ReadBazelDeps(): diff (-want +got)\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestParseBazelDepsUnterminated(t *testing.T) {
+	for _, content := range []string{
+		"go_repository(\n    name = \"foo\",\n",
+		"go_repository(\n    name = \"foo\",\n    version = \"v1.0.0\",  # )",
+		"go_deps.module(\n    path = \"example.com/foo)\n",
+	} {
+		if _, err := parseBazelDeps(content, ""); err == nil {
+			t.Errorf("parseBazelDeps(%q) = (_, nil), want (_, error)", content)
+		}
+	}
+}
+
+func TestStripBazelComments(t *testing.T) {
+	for _, test := range []struct {
+		content, want string
+	}{
+		{content: "a = 1  # b = 2\nc = 3", want: "a = 1         \nc = 3"},
+		{content: `a = "#1"  # 2`, want: `a = "#1"     `},
+		{content: `a = 'x\'#'  # 2`, want: `a = 'x\'#'     `},
+		{content: "# end", want: "     "},
+		{content: `a = "#`, want: `a = "#`},
+	} {
+		if got := stripBazelComments(test.content); got != test.want {
+			t.Errorf("stripBazelComments(%q) = %q, want %q", test.content, got, test.want)
+		}
+	}
+}
@@ -0,0 +1,410 @@
+// Copyright 2022 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package licenses
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Bobgy/go-licenses/v2/internal/logging"
+	"github.com/Bobgy/go-licenses/v2/internal/parallel"
+	"golang.org/x/mod/module"
+	"golang.org/x/mod/semver"
+)
+
+// BinaryInfo is the build info embedded in a Go binary.
+type BinaryInfo struct {
+	// GoVersion is the version of Go that built the binary, e.g. "go1.18.2".
+	GoVersion string
+	// Path is the import path of the binary's main package.
+	Path string
+	// Main is the module of the main package, or nil if the binary was not
+	// built in module mode.
+	Main *Module
+	// Deps are the modules the binary depends on, with replacements applied.
+	// Dir of a dependency is its location in the module cache, or empty
+	// when the module has not been downloaded.
+	Deps []*Module
+	// Settings are the build settings of the binary, e.g. build flags,
+	// GOOS, GOARCH and version control info, in the order recorded.
+	Settings []BuildSetting
+}
+
+// BuildSetting is a key-value build setting of a binary, e.g. "-trimpath"
+// with value "true", or "vcs.revision" with a commit hash.
+type BuildSetting struct {
+	Key, Value string
+}
+
+// Setting returns the value of a build setting, or "" if it is not set.
+func (b *BinaryInfo) Setting(key string) string {
+	for _, s := range b.Settings {
+		if s.Key == key {
+			return s.Value
+		}
+	}
+	return ""
+}
+
+// VCSRevision returns the version control revision the binary was built
+// from, e.g. a git commit hash, or "" if unknown.
+func (b *BinaryInfo) VCSRevision() string {
+	return b.Setting("vcs.revision")
+}
+
+// VCSModified reports whether the source tree the binary was built from had
+// local modifications.
+func (b *BinaryInfo) VCSModified() bool {
+	return b.Setting("vcs.modified") == "true"
+}
+
+// Trimpath reports whether the binary was built with -trimpath, i.e. without
+// file system paths of its sources.
+func (b *BinaryInfo) Trimpath() bool {
+	return b.Setting("-trimpath") == "true"
+}
+
+// ReadBinaryInfo reads the build info embedded in a Go binary. Build settings
+// are only recorded by Go 1.18 and later, which also read build info without
+// running the go command.
+func ReadBinaryInfo(ctx context.Context, opts Options, binaryPath string) (*BinaryInfo, error) {
+	modCache, err := goEnv(ctx, opts, "", "GOMODCACHE")
+	if err != nil {
+		return nil, err
+	}
+	return readBinaryInfo(ctx, opts, binaryPath, modCache)
+}
+
+// ModulesInBinary lists modules embedded in the build info of a Go binary, see
+// ReadBinaryInfo. The main module is returned separately from its
+// dependencies.
+func ModulesInBinary(ctx context.Context, opts Options, binaryPath string) (main *Module, deps []*Module, err error) {
+	info, err := ReadBinaryInfo(ctx, opts, binaryPath)
+	if err != nil {
+		return nil, nil, err
+	}
+	return info.Main, info.Deps, nil
+}
+
+// FindBinaries returns paths of all Go binaries in the directory tree at dir,
+// as found by `go version <dir>`.
+func FindBinaries(ctx context.Context, opts Options, dir string) ([]string, error) {
+	out, err := opts.goCommand(ctx, "", "version", dir).Output()
+	if err != nil {
+		return nil, fmt.Errorf("go version %s: %w", dir, err)
+	}
+	return parseBinaryPaths(string(out)), nil
+}
+
+// parseBinaryPaths parses output of `go version <dir>`, which has a line per
+// binary like:
+//
+//	/path/to/dir/bin/server: go1.17.6
+func parseBinaryPaths(out string) []string {
+	var paths []string
+	for _, line := range strings.Split(out, "\n") {
+		i := strings.LastIndex(line, ": ")
+		if i <= 0 || strings.HasPrefix(line, "\t") {
+			continue
+		}
+		paths = append(paths, line[:i])
+	}
+	return paths
+}
+
+// parseBinaryInfo parses output of `go version -m`, which looks like:
+//
+//	/path/to/binary: go1.17.6
+//		path	github.com/foo/bar/cmd/bar
+//		mod	github.com/foo/bar	(devel)
+//		dep	github.com/spf13/cobra	v1.3.0	h1:...
+//		dep	k8s.io/kubernetes	v0.17.9
+//		=>	k8s.io/kubernetes	v1.11.1	h1:...
+//		build	-compiler=gc
+func parseBinaryInfo(out string, modCache string) (*BinaryInfo, error) {
+	info := &BinaryInfo{}
+	scanner := bufio.NewScanner(strings.NewReader(out))
+	for scanner.Scan() {
+		line := scanner.Text()
+		if !strings.HasPrefix(line, "\t") {
+			// The header line with binary path and Go version.
+			if i := strings.LastIndex(line, ": "); i >= 0 {
+				info.GoVersion = line[i+2:]
+			}
+			continue
+		}
+		fields := strings.Split(strings.TrimPrefix(line, "\t"), "\t")
+		switch fields[0] {
+		case "path":
+			if len(fields) >= 2 {
+				info.Path = fields[1]
+			}
+		case "mod":
+			if len(fields) < 2 {
+				return nil, fmt.Errorf("invalid build info line %q", line)
+			}
+			info.Main = &Module{Path: fields[1]}
+			if len(fields) >= 3 && fields[2] != "(devel)" {
+				info.Main.Version = fields[2]
+			}
+		case "dep":
+			if len(fields) < 3 {
+				return nil, fmt.Errorf("invalid build info line %q", line)
+			}
+			m := cachedModule(fields[1], fields[2], modCache)
+			if len(fields) >= 4 {
+				m.Sum = fields[3]
+			}
+			info.Deps = append(info.Deps, m)
+		case "=>":
+			// Replaces the previous dep.
+			if len(info.Deps) == 0 || len(fields) < 2 {
+				return nil, fmt.Errorf("invalid build info line %q", line)
+			}
+			version, sum := "", ""
+			if len(fields) >= 3 {
+				version = fields[2]
+			}
+			if len(fields) >= 4 {
+				sum = fields[3]
+			}
+			last := len(info.Deps) - 1
+			info.Deps[last] = replaceModule(info.Deps[last], fields[1], version, sum, modCache)
+		case "build":
+			if len(fields) < 2 {
+				return nil, fmt.Errorf("invalid build info line %q", line)
+			}
+			key, value := fields[1], ""
+			if i := strings.Index(key, "="); i >= 0 {
+				key, value = key[:i], key[i+1:]
+			}
+			info.Settings = append(info.Settings, BuildSetting{Key: key, Value: value})
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return info, nil
+}
+
+// replaceModule returns the module replacing original by a replace directive
+// to path@version, or to the directory path if version is empty.
+func replaceModule(original *Module, path, version, sum, modCache string) *Module {
+	if version == "" {
+		// Replaced by a local directory.
+		dir := path
+		if !filepath.IsAbs(dir) {
+			logging.Module(original.Path).Warningf("module %s is replaced by relative directory %s, which cannot be located from a binary", original.Path, dir)
+			dir = ""
+		}
+		return &Module{Path: original.Path, Dir: dir, OriginalPath: original.Path, OriginalVersion: original.Version}
+	}
+	replacement := cachedModule(path, version, modCache)
+	replacement.OriginalPath = original.Path
+	replacement.OriginalVersion = original.Version
+	replacement.Sum = sum
+	return replacement
+}
+
+// cachedModule returns module info for path@version, whose Dir is set when the
+// module exists in the module cache.
+func cachedModule(path, version, modCache string) *Module {
+	m := &Module{
+		Path: path,
+		// The +incompatible suffix does not affect module version.
+		// ref: https://golang.org/ref/mod#incompatible-versions
+		Version: strings.TrimSuffix(version, "+incompatible"),
+	}
+	if modCache == "" {
+		return m
+	}
+	escapedPath, err := module.EscapePath(path)
+	if err != nil {
+		return m
+	}
+	escapedVersion, err := module.EscapeVersion(moduleVersion(path, version))
+	if err != nil {
+		return m
+	}
+	dir := filepath.Join(modCache, escapedPath+"@"+escapedVersion)
+	if _, err := os.Stat(dir); err == nil {
+		m.Dir = dir
+	}
+	return m
+}
+
+// cachedModuleZip returns the path of the zip of a module version in the
+// download cache of the module cache modCache, or "" if it is not there.
+// version is the version known by the go command, see moduleVersion.
+func cachedModuleZip(modCache, path, version string) string {
+	if modCache == "" {
+		return ""
+	}
+	escapedPath, err := module.EscapePath(path)
+	if err != nil {
+		return ""
+	}
+	escapedVersion, err := module.EscapeVersion(version)
+	if err != nil {
+		return ""
+	}
+	zip := filepath.Join(modCache, "cache", "download", escapedPath, "@v", escapedVersion+".zip")
+	if _, err := os.Stat(zip); err != nil {
+		return ""
+	}
+	return zip
+}
+
+// moduleVersion returns the version of a module as known by the go command,
+// i.e. including the +incompatible suffix trimmed from Module.Version.
+func moduleVersion(path, version string) string {
+	if strings.HasSuffix(version, "+incompatible") {
+		return version
+	}
+	major := semver.Major(version)
+	if major == "" || major == "v0" || major == "v1" || strings.HasPrefix(path, "gopkg.in/") {
+		return version
+	}
+	if _, pathMajor, ok := module.SplitPathVersion(path); ok && pathMajor == "" {
+		return version + "+incompatible"
+	}
+	return version
+}
+
+// BinaryLibraries returns the collection of libraries embedded in a Go binary.
+// Build info only records modules, so each dependency module becomes a single
+// library whose license is searched for at the root of the module.
+// The main module is not included, because its source is not known.
+// Options only relevant to loading packages are ignored.
+func BinaryLibraries(ctx context.Context, classifier Classifier, opts Options, binaryPath string) ([]*Library, error) {
+	start := time.Now()
+	main, deps, err := ModulesInBinary(ctx, opts, binaryPath)
+	opts.measure(PhaseListingModules, start, len(deps), err)
+	if err != nil {
+		return nil, err
+	}
+	if main != nil {
+		logging.Infof("Skipping main module %s of binary %s", main.Path, binaryPath)
+	}
+	return moduleLibraries(ctx, classifier, opts, deps)
+}
+
+// ModuleLibraries returns a library for each module already listed by the
+// caller, e.g. by `go list -m -json all` or a build system, instead of
+// listing them with the go command. Like with BinaryLibraries, the license of
+// each library is searched for at the root of its module, and modules without
+// Dir are downloaded if opts.DownloadModules is set, which sets their Dir.
+func ModuleLibraries(ctx context.Context, classifier Classifier, opts Options, modules []*Module) ([]*Library, error) {
+	return moduleLibraries(ctx, classifier, opts, modules)
+}
+
+// moduleLibraries returns a library for each module, see
+// findModuleLibraries, and identifies their licenses.
+func moduleLibraries(ctx context.Context, classifier Classifier, opts Options, deps []*Module) ([]*Library, error) {
+	libraries, err := findModuleLibraries(ctx, classifier, opts, deps)
+	if err != nil {
+		return nil, err
+	}
+	if err := identifyLibraries(ctx, classifier, opts, libraries, nil); err != nil {
+		return nil, err
+	}
+	return libraries, nil
+}
+
+// findModuleLibraries returns a library for each module sorted by name,
+// whose license is found in the module's directory, without identifying
+// their licenses. Modules without Dir are downloaded if opts.DownloadModules
+// is set. Licenses of opts.Jobs modules are found concurrently.
+func findModuleLibraries(ctx context.Context, classifier Classifier, opts Options, deps []*Module) ([]*Library, error) {
+	if err := opts.Shard.validate(); err != nil {
+		return nil, err
+	}
+	var mods []*Module
+	for _, m := range deps {
+		if !opts.ignored(m.Path) && opts.Shard.Contains(m.Path) {
+			mods = append(mods, m)
+		}
+	}
+	var modCache string
+	for _, m := range mods {
+		if m.Dir == "" && m.Version != "" && opts.DownloadModules {
+			var err error
+			if modCache, err = goEnv(ctx, opts, "", "GOMODCACHE"); err != nil {
+				return nil, err
+			}
+			break
+		}
+	}
+	classifier = newMemoClassifier(classifier)
+	libraries := make([]*Library, len(mods))
+	errs := make([]error, len(mods))
+	var mu sync.Mutex
+	done := 0
+	start := time.Now()
+	parallel.For(opts.jobs(), len(mods), func(i int) {
+		libraries[i], errs[i] = moduleLibrary(ctx, classifier, opts, modCache, mods[i])
+		mu.Lock()
+		defer mu.Unlock()
+		done++
+		opts.progress(PhaseFindingLicenses, done, len(mods))
+	})
+	for _, err := range errs {
+		if err != nil {
+			return nil, err
+		}
+	}
+	opts.measure(PhaseFindingLicenses, start, len(libraries), nil)
+	if opts.VerifyModules {
+		verifyLibraries(libraries)
+	}
+	useOptions(libraries, opts)
+	sortLibraries(libraries)
+	return libraries, nil
+}
+
+// moduleLibrary returns the library of a module, whose license is found in
+// the module's directory, downloading the module into modCache first if
+// needed.
+func moduleLibrary(ctx context.Context, classifier Classifier, opts Options, modCache string, m *Module) (*Library, error) {
+	if m.Dir == "" && m.Version != "" && opts.DownloadModules {
+		start := time.Now()
+		dir, err := downloadModule(ctx, opts, modCache, m.Path, moduleVersion(m.Path, m.Version))
+		opts.measure(PhaseDownloadingModules, start, 1, err)
+		if err != nil {
+			return nil, err
+		}
+		m.Dir = dir
+	}
+	lib := &Library{
+		Packages: []string{m.Path},
+		module:   m,
+	}
+	if m.Dir == "" {
+		logging.Module(m.Path).Errorf("Failed to find license for %s: module %s@%s is not in the module cache", m.Path, m.Path, m.Version)
+	} else if candidates, err := FindAll(m.Dir, m.Dir, classifier); err != nil {
+		lib.LicenseCandidates = candidates
+		logging.Module(m.Path).Errorf("Failed to find license for %s: %v", m.Path, err)
+	} else {
+		lib.LicensePath = candidates[0].Path
+		lib.LicenseCandidates = candidates
+	}
+	return lib, nil
+}
@@ -0,0 +1,188 @@
+// Copyright 2022 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package licenses
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestParseBinaryInfo(t *testing.T) {
+	out := "/tmp/bar: go1.18.2\n" +
+		"\tpath\tgithub.com/foo/bar/cmd/bar\n" +
+		"\tmod\tgithub.com/foo/bar\t(devel)\t\n" +
+		"\tdep\tgithub.com/spf13/cobra\tv1.3.0\th1:abc=\n" +
+		"\tdep\tgithub.com/example/old\tv2.0.0+incompatible\th1:def=\n" +
+		"\tdep\tk8s.io/kubernetes\tv0.17.9\t\n" +
+		"\t=>\tk8s.io/kubernetes\tv1.11.1\th1:ghi=\n" +
+		"\tdep\texample.com/local\tv0.0.0-00010101000000-000000000000\t\n" +
+		"\t=>\t/src/local\t\t\n" +
+		"\tbuild\t-compiler=gc\n" +
+		"\tbuild\t-trimpath=true\n" +
+		"\tbuild\tvcs.revision=0123abc\n" +
+		"\tbuild\tvcs.modified=true\n"
+	got, err := parseBinaryInfo(out, "")
+	if err != nil {
+		t.Fatalf("parseBinaryInfo() = (_, %q), want (_, nil)", err)
+	}
+	if diff := cmp.Diff(wantBinaryInfo, got); diff != "" {
+		t.Errorf("parseBinaryInfo(): diff (-want +got)\n%s", diff)
+	}
+	if !got.Trimpath() || got.VCSRevision() != "0123abc" || !got.VCSModified() {
+		t.Errorf("parseBinaryInfo() = (trimpath %t, revision %q, modified %t), want (true, 0123abc, true)", got.Trimpath(), got.VCSRevision(), got.VCSModified())
+	}
+}
+
+// wantBinaryInfo is the build info of a binary built with -trimpath, with
+// replaced dependencies.
+var wantBinaryInfo = &BinaryInfo{
+	GoVersion: "go1.18.2",
+	Path:      "github.com/foo/bar/cmd/bar",
+	Main:      &Module{Path: "github.com/foo/bar"},
+	Deps: []*Module{
+		{Path: "github.com/spf13/cobra", Version: "v1.3.0", Sum: "h1:abc="},
+		{Path: "github.com/example/old", Version: "v2.0.0", Sum: "h1:def="},
+		{Path: "k8s.io/kubernetes", Version: "v1.11.1", OriginalPath: "k8s.io/kubernetes", OriginalVersion: "v0.17.9", Sum: "h1:ghi="},
+		{Path: "example.com/local", Dir: "/src/local", OriginalPath: "example.com/local", OriginalVersion: "v0.0.0-00010101000000-000000000000"},
+	},
+	Settings: []BuildSetting{
+		{Key: "-compiler", Value: "gc"},
+		{Key: "-trimpath", Value: "true"},
+		{Key: "vcs.revision", Value: "0123abc"},
+		{Key: "vcs.modified", Value: "true"},
+	},
+}
+
+func TestParseBinaryPaths(t *testing.T) {
+	out := "dist/server: go1.17.6\n" +
+		"dist/tools/my: cli: go1.18\n"
+	want := []string{"dist/server", "dist/tools/my: cli"}
+	if diff := cmp.Diff(want, parseBinaryPaths(out)); diff != "" {
+		t.Errorf("parseBinaryPaths(): diff (-want +got)\n%s", diff)
+	}
+}
+
+func TestModuleVersion(t *testing.T) {
+	for _, test := range []struct {
+		path        string
+		version     string
+		wantVersion string
+	}{
+		{path: "github.com/spf13/cobra", version: "v1.3.0", wantVersion: "v1.3.0"},
+		{path: "github.com/example/old", version: "v2.0.0", wantVersion: "v2.0.0+incompatible"},
+		{path: "github.com/example/old", version: "v2.0.0+incompatible", wantVersion: "v2.0.0+incompatible"},
+		{path: "github.com/example/new/v2", version: "v2.0.0", wantVersion: "v2.0.0"},
+		{path: "gopkg.in/yaml.v3", version: "v3.0.0", wantVersion: "v3.0.0"},
+	} {
+		if got := moduleVersion(test.path, test.version); got != test.wantVersion {
+			t.Errorf("moduleVersion(%q, %q) = %q, want %q", test.path, test.version, got, test.wantVersion)
+		}
+	}
+}
+
+func TestCachedModuleZip(t *testing.T) {
+	modCache := t.TempDir()
+	zip := filepath.Join(modCache, "cache", "download", "github.com", "!burnt!sushi", "toml", "@v", "v1.0.0.zip")
+	if err := os.MkdirAll(filepath.Dir(zip), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(zip, nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+	for _, test := range []struct {
+		modCache string
+		path     string
+		version  string
+		want     string
+	}{
+		{modCache: modCache, path: "github.com/BurntSushi/toml", version: "v1.0.0", want: zip},
+		{modCache: modCache, path: "github.com/BurntSushi/toml", version: "v1.1.0"},
+		{modCache: modCache, path: "github.com/burntsushi/toml", version: "v1.0.0"},
+		{path: "github.com/BurntSushi/toml", version: "v1.0.0"},
+	} {
+		if got := cachedModuleZip(test.modCache, test.path, test.version); got != test.want {
+			t.Errorf("cachedModuleZip(%q, %q, %q) = %q, want %q", test.modCache, test.path, test.version, got, test.want)
+		}
+	}
+}
+
+func TestModuleLibraries(t *testing.T) {
+	dir := t.TempDir()
+	withLicense := filepath.Join(dir, "github.com", "foo", "bar@v1.0.0")
+	if err := os.MkdirAll(withLicense, 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(filepath.Join(withLicense, "LICENSE"), []byte("MIT License"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	modules := []*Module{
+		{Path: "github.com/foo/bar", Version: "v1.0.0", Dir: withLicense},
+		{Path: "github.com/foo/missing", Version: "v0.1.0"},
+		{Path: "github.com/foo/ignored", Version: "v0.2.0"},
+	}
+	libs, err := ModuleLibraries(context.Background(), &countingClassifier{}, Options{Jobs: 1, Ignore: []string{"github.com/foo/ignored"}}, modules)
+	if err != nil {
+		t.Fatalf("ModuleLibraries() = %v", err)
+	}
+	var got []string
+	for _, lib := range libs {
+		got = append(got, lib.Name()+" "+lib.LicenseName)
+	}
+	want := []string{"github.com/foo/bar MIT", "github.com/foo/missing "}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("ModuleLibraries() diff (-want +got):\n%s", diff)
+	}
+}
+
+func TestModuleLibrariesConcurrent(t *testing.T) {
+	dir := t.TempDir()
+	classifier := confidenceStub{dir: dir, results: make(map[string]LicenseCandidate)}
+	var modules []*Module
+	var want []string
+	for i := 0; i < 20; i++ {
+		path := fmt.Sprintf("example.com/mod%02d", 19-i)
+		modDir := filepath.Join(dir, path)
+		if err := os.MkdirAll(modDir, 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := ioutil.WriteFile(filepath.Join(modDir, "LICENSE"), nil, 0644); err != nil {
+			t.Fatal(err)
+		}
+		license := "MIT"
+		if i%2 == 0 {
+			license = "Apache-2.0"
+		}
+		classifier.results[filepath.Join(path, "LICENSE")] = LicenseCandidate{Name: license, Type: Notice, Confidence: 1}
+		modules = append(modules, &Module{Path: path, Version: "v1.0.0", Dir: modDir})
+		want = append([]string{path + " " + license}, want...)
+	}
+	libs, err := ModuleLibraries(context.Background(), classifier, Options{Jobs: 4}, modules)
+	if err != nil {
+		t.Fatalf("ModuleLibraries() = %v", err)
+	}
+	var got []string
+	for _, lib := range libs {
+		got = append(got, lib.Name()+" "+lib.LicenseName)
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("ModuleLibraries() diff (-want +got):\n%s", diff)
+	}
+}
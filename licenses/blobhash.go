@@ -0,0 +1,156 @@
+// Copyright 2022 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package licenses
+
+import (
+	"crypto/sha1"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"strings"
+
+	"github.com/Bobgy/go-licenses/v2/internal/logging"
+	"github.com/Bobgy/go-licenses/v2/internal/third_party/pkgsite/source"
+)
+
+// githubAPI is the URL of the GitHub REST API, replaced by tests.
+var githubAPI = "https://api.github.com"
+
+// gitBlobHash returns the git object ID of a blob with content, as listed by
+// git ls-tree and hosting APIs.
+func gitBlobHash(content []byte) string {
+	h := sha1.New()
+	fmt.Fprintf(h, "blob %d\x00", len(content))
+	h.Write(content)
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// blobHashURL returns the URL of the API of the host of a repo exposing the
+// git blob hash of a file at repoPath, and whether the API lists the
+// directory of the file (GitHub) or returns the hash in a header of a HEAD
+// request (GitLab). It returns "" for other hosts.
+func blobHashURL(remote *source.Info, repoPath string) (apiURL string, listsDir bool) {
+	repo, err := url.Parse(remote.RepoURL())
+	if err != nil || remote.Commit() == "" {
+		return "", false
+	}
+	project := strings.Trim(repo.Path, "/")
+	ref := url.QueryEscape(remote.Commit())
+	switch {
+	case repo.Host == "github.com":
+		dir := path.Dir(repoPath)
+		if dir == "." {
+			dir = ""
+		}
+		return fmt.Sprintf("%s/repos/%s/contents/%s?ref=%s", githubAPI, project, dir, ref), true
+	case repo.Host == "gitlab.com" || strings.HasPrefix(repo.Host, "gitlab."):
+		return fmt.Sprintf("%s://%s/api/v4/projects/%s/repository/files/%s?ref=%s", repo.Scheme, repo.Host, url.PathEscape(project), url.PathEscape(repoPath), ref), false
+	}
+	return "", false
+}
+
+// remoteBlobHash returns the git blob hash of a file at repoPath in the repo
+// of remote, queried from the API of its host. It returns "" without error
+// for hosts without such an API. Hashes are cached, because commits are
+// immutable.
+func remoteBlobHash(client *http.Client, cache *Cache, remote *source.Info, repoPath string) (string, error) {
+	apiURL, listsDir := blobHashURL(remote, repoPath)
+	if apiURL == "" {
+		return "", nil
+	}
+	key := apiURL + "\x00" + repoPath
+	if hash, ok := cache.Get(CacheHTTP, key); ok {
+		return string(hash), nil
+	}
+	method := http.MethodHead
+	if listsDir {
+		method = http.MethodGet
+	}
+	req, err := http.NewRequest(method, apiURL, nil)
+	if err != nil {
+		return "", err
+	}
+	if token := os.Getenv("GITHUB_TOKEN"); token != "" && listsDir {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("querying blob hash of %s: %w", repoPath, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 400 {
+		return "", &HTTPError{URL: apiURL, StatusCode: resp.StatusCode}
+	}
+	var hash string
+	if listsDir {
+		body, err := ioutil.ReadAll(resp.Body)
+		if err != nil {
+			return "", fmt.Errorf("querying blob hash of %s: %w", repoPath, err)
+		}
+		var entries []struct {
+			Name string `json:"name"`
+			Type string `json:"type"`
+			SHA  string `json:"sha"`
+		}
+		if err := json.Unmarshal(body, &entries); err != nil {
+			return "", fmt.Errorf("parsing directory listing %s: %w", apiURL, err)
+		}
+		for _, e := range entries {
+			if e.Name == path.Base(repoPath) && e.Type == "file" {
+				hash = e.SHA
+			}
+		}
+		if hash == "" {
+			return "", &HTTPError{URL: apiURL + "#" + path.Base(repoPath), StatusCode: http.StatusNotFound}
+		}
+	} else if hash = resp.Header.Get("X-Gitlab-Blob-Id"); hash == "" {
+		return "", fmt.Errorf("querying blob hash of %s: no X-Gitlab-Blob-Id header in response of %s", repoPath, apiURL)
+	}
+	if err := cache.Put(CacheHTTP, key, []byte(hash)); err != nil {
+		logging.Warningf("Failed to cache blob hash of %s: %v", repoPath, err)
+	}
+	return hash, nil
+}
+
+// validateHash validates that the file at repoPath in the repo of remote has
+// localContent by comparing git blob hashes, without downloading the remote
+// file. ok is false if the host exposes no hashes or the query failed, e.g.
+// because of API rate limits, and the file must be downloaded instead.
+func validateHash(client *http.Client, cache *Cache, remote *source.Info, repoPath string, localContent []byte) (ok bool, err error) {
+	hash, err := remoteBlobHash(client, cache, remote, repoPath)
+	var httpErr *HTTPError
+	if errors.As(err, &httpErr) && httpErr.StatusCode == http.StatusNotFound {
+		// The file does not exist at the commit, so would not download
+		// either.
+		return true, err
+	}
+	if err != nil {
+		logging.Infof("Validating %s by downloading it: %v", repoPath, err)
+		return false, nil
+	}
+	if hash == "" {
+		return false, nil
+	}
+	if hash != gitBlobHash(localContent) {
+		return true, fmt.Errorf("%w %s (git blob %s)", ErrValidationMismatch, remote.RepoFileURL(repoPath), hash)
+	}
+	return true, nil
+}
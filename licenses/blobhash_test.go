@@ -0,0 +1,132 @@
+// Copyright 2022 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package licenses
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/Bobgy/go-licenses/v2/internal/third_party/pkgsite/source"
+)
+
+func TestGitBlobHash(t *testing.T) {
+	// echo -n "hello world" | git hash-object --stdin
+	if got, want := gitBlobHash([]byte("hello world")), "95d09f2b10159347eece71399a7e2e907ea3df4f"; got != want {
+		t.Errorf("gitBlobHash() = %s, want %s", got, want)
+	}
+}
+
+func TestBlobHashURL(t *testing.T) {
+	for _, test := range []struct {
+		module, version, repoPath string
+		want                      string
+		wantListsDir              bool
+	}{
+		{
+			module: "github.com/foo/bar", version: "v1.0.0", repoPath: "LICENSE",
+			want: "https://api.github.com/repos/foo/bar/contents/?ref=v1.0.0", wantListsDir: true,
+		},
+		{
+			module: "github.com/foo/bar/sub", version: "v1.0.0", repoPath: "sub/LICENSE",
+			want: "https://api.github.com/repos/foo/bar/contents/sub?ref=sub%2Fv1.0.0", wantListsDir: true,
+		},
+		{
+			module: "gitlab.com/foo/bar", version: "v1.0.0", repoPath: "docs/LICENSE",
+			want: "https://gitlab.com/api/v4/projects/foo%2Fbar/repository/files/docs%2FLICENSE?ref=v1.0.0",
+		},
+		{module: "bitbucket.org/foo/bar", version: "v1.0.0", repoPath: "LICENSE"},
+	} {
+		remote, err := source.ModuleInfo(context.Background(), source.NewClientForTesting(), test.module, test.version)
+		if err != nil {
+			t.Fatalf("ModuleInfo(%s) = %v", test.module, err)
+		}
+		got, listsDir := blobHashURL(remote, test.repoPath)
+		if got != test.want || listsDir != test.wantListsDir {
+			t.Errorf("blobHashURL(%s, %s) = (%q, %t), want (%q, %t)", test.module, test.repoPath, got, listsDir, test.want, test.wantListsDir)
+		}
+	}
+}
+
+func TestValidateHash(t *testing.T) {
+	requests := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		switch r.URL.Path {
+		case "/repos/foo/bar/contents/":
+			w.Write([]byte(`[{"name": "LICENSE", "type": "file", "sha": "95d09f2b10159347eece71399a7e2e907ea3df4f"}, {"name": "docs", "type": "dir", "sha": "0"}]`))
+		case "/repos/foo/limited/contents/":
+			w.WriteHeader(http.StatusForbidden)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+	defer func(api string) { githubAPI = api }(githubAPI)
+	githubAPI = server.URL
+
+	for _, test := range []struct {
+		desc     string
+		module   string
+		repoPath string
+		content  string
+		wantOK   bool
+		wantErr  error
+	}{
+		{desc: "match", module: "github.com/foo/bar", repoPath: "LICENSE", content: "hello world", wantOK: true},
+		{desc: "mismatch", module: "github.com/foo/bar", repoPath: "LICENSE", content: "hello world\r\n", wantOK: true, wantErr: ErrValidationMismatch},
+		{desc: "missing file", module: "github.com/foo/bar", repoPath: "NOTICE", content: "hello world", wantOK: true, wantErr: &HTTPError{}},
+		{desc: "rate limited", module: "github.com/foo/limited", repoPath: "LICENSE", content: "hello world"},
+		{desc: "unsupported host", module: "bitbucket.org/foo/bar", repoPath: "LICENSE", content: "hello world"},
+	} {
+		t.Run(test.desc, func(t *testing.T) {
+			remote, err := source.ModuleInfo(context.Background(), source.NewClientForTesting(), test.module, "v1.0.0")
+			if err != nil {
+				t.Fatal(err)
+			}
+			ok, err := validateHash(server.Client(), nil, remote, test.repoPath, []byte(test.content))
+			if ok != test.wantOK {
+				t.Errorf("validateHash() = (%t, %v), want ok %t", ok, err, test.wantOK)
+			}
+			var httpErr *HTTPError
+			switch {
+			case test.wantErr == nil && err != nil:
+				t.Errorf("validateHash() = (_, %v), want nil error", err)
+			case test.wantErr == ErrValidationMismatch && !errors.Is(err, ErrValidationMismatch):
+				t.Errorf("validateHash() = (_, %v), want ErrValidationMismatch", err)
+			case test.wantErr != nil && test.wantErr != ErrValidationMismatch && !errors.As(err, &httpErr):
+				t.Errorf("validateHash() = (_, %v), want HTTPError", err)
+			}
+		})
+	}
+
+	// Hashes are cached across runs.
+	cache := NewCache(t.TempDir(), 0)
+	remote, err := source.ModuleInfo(context.Background(), source.NewClientForTesting(), "github.com/foo/bar", "v1.0.0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	before := requests
+	for i := 0; i < 2; i++ {
+		if ok, err := validateHash(server.Client(), cache, remote, "LICENSE", []byte("hello world")); !ok || err != nil {
+			t.Fatalf("validateHash() = (%t, %v), want (true, nil)", ok, err)
+		}
+	}
+	if requests-before != 1 {
+		t.Errorf("validateHash() made %d requests, want 1", requests-before)
+	}
+}
@@ -0,0 +1,57 @@
+// Copyright 2022 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+//go:build go1.18
+// +build go1.18
+
+package licenses
+
+import (
+	"context"
+	"debug/buildinfo"
+	"fmt"
+	"runtime/debug"
+)
+
+// readBinaryInfo reads the build info of a binary with debug/buildinfo.
+func readBinaryInfo(_ context.Context, _ Options, binaryPath string, modCache string) (*BinaryInfo, error) {
+	bi, err := buildinfo.ReadFile(binaryPath)
+	if err != nil {
+		return nil, fmt.Errorf("reading build info of %s: %w", binaryPath, err)
+	}
+	return newBinaryInfo(bi, modCache), nil
+}
+
+// newBinaryInfo converts build info read by debug/buildinfo.
+func newBinaryInfo(bi *debug.BuildInfo, modCache string) *BinaryInfo {
+	info := &BinaryInfo{GoVersion: bi.GoVersion, Path: bi.Path}
+	if bi.Main.Path != "" {
+		info.Main = &Module{Path: bi.Main.Path, Sum: bi.Main.Sum}
+		if bi.Main.Version != "(devel)" {
+			info.Main.Version = bi.Main.Version
+		}
+	}
+	for _, dep := range bi.Deps {
+		m := cachedModule(dep.Path, dep.Version, modCache)
+		m.Sum = dep.Sum
+		if r := dep.Replace; r != nil {
+			m = replaceModule(m, r.Path, r.Version, r.Sum, modCache)
+		}
+		info.Deps = append(info.Deps, m)
+	}
+	for _, s := range bi.Settings {
+		info.Settings = append(info.Settings, BuildSetting{Key: s.Key, Value: s.Value})
+	}
+	return info
+}
@@ -0,0 +1,33 @@
+// Copyright 2022 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+//go:build !go1.18
+// +build !go1.18
+
+package licenses
+
+import (
+	"context"
+	"fmt"
+)
+
+// readBinaryInfo reads the build info of a binary with `go version -m`,
+// because debug/buildinfo requires Go 1.18.
+func readBinaryInfo(ctx context.Context, opts Options, binaryPath string, modCache string) (*BinaryInfo, error) {
+	out, err := opts.goCommand(ctx, "", "version", "-m", binaryPath).Output()
+	if err != nil {
+		return nil, fmt.Errorf("go version -m %s: %w", binaryPath, err)
+	}
+	return parseBinaryInfo(string(out), modCache)
+}
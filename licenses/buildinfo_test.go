@@ -0,0 +1,48 @@
+// Copyright 2022 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+//go:build go1.18
+// +build go1.18
+
+package licenses
+
+import (
+	"runtime/debug"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestNewBinaryInfo(t *testing.T) {
+	bi := &debug.BuildInfo{
+		GoVersion: "go1.18.2",
+		Path:      "github.com/foo/bar/cmd/bar",
+		Main:      debug.Module{Path: "github.com/foo/bar", Version: "(devel)"},
+		Deps: []*debug.Module{
+			{Path: "github.com/spf13/cobra", Version: "v1.3.0", Sum: "h1:abc="},
+			{Path: "github.com/example/old", Version: "v2.0.0+incompatible", Sum: "h1:def="},
+			{Path: "k8s.io/kubernetes", Version: "v0.17.9", Replace: &debug.Module{Path: "k8s.io/kubernetes", Version: "v1.11.1", Sum: "h1:ghi="}},
+			{Path: "example.com/local", Version: "v0.0.0-00010101000000-000000000000", Replace: &debug.Module{Path: "/src/local"}},
+		},
+		Settings: []debug.BuildSetting{
+			{Key: "-compiler", Value: "gc"},
+			{Key: "-trimpath", Value: "true"},
+			{Key: "vcs.revision", Value: "0123abc"},
+			{Key: "vcs.modified", Value: "true"},
+		},
+	}
+	if diff := cmp.Diff(wantBinaryInfo, newBinaryInfo(bi, "")); diff != "" {
+		t.Errorf("newBinaryInfo(): diff (-want +got)\n%s", diff)
+	}
+}
@@ -0,0 +1,97 @@
+// Copyright 2022 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package licenses
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	"golang.org/x/mod/semver"
+)
+
+// licenseCacheFile is the name of the license cache in a cache directory.
+const licenseCacheFile = "licenses.json"
+
+// LicenseCache records the licenses identified for module versions, so that
+// later runs can tell when the license of a module changes between versions.
+type LicenseCache struct {
+	path string
+	// Modules maps module paths to the license of each module version.
+	Modules map[string]map[string]string `json:"modules"`
+}
+
+// DefaultCacheDir returns the default directory for go-licenses caches in the
+// user's cache directory.
+func DefaultCacheDir() (string, error) {
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "go-licenses"), nil
+}
+
+// LoadLicenseCache reads the license cache in cacheDir. A missing cache is
+// empty.
+func LoadLicenseCache(cacheDir string) (*LicenseCache, error) {
+	c := &LicenseCache{path: filepath.Join(cacheDir, licenseCacheFile)}
+	data, err := ioutil.ReadFile(c.path)
+	if os.IsNotExist(err) {
+		c.Modules = make(map[string]map[string]string)
+		return c, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(data, c); err != nil {
+		return nil, err
+	}
+	if c.Modules == nil {
+		c.Modules = make(map[string]map[string]string)
+	}
+	return c, nil
+}
+
+// Previous returns the license of the highest cached version of a module
+// lower than version.
+func (c *LicenseCache) Previous(path, version string) (prevVersion, license string, ok bool) {
+	for v, l := range c.Modules[path] {
+		if semver.Compare(v, version) < 0 && (prevVersion == "" || semver.Compare(v, prevVersion) > 0) {
+			prevVersion, license = v, l
+		}
+	}
+	return prevVersion, license, prevVersion != ""
+}
+
+// Add records the license of a module version.
+func (c *LicenseCache) Add(path, version, license string) {
+	if c.Modules[path] == nil {
+		c.Modules[path] = make(map[string]string)
+	}
+	c.Modules[path][version] = license
+}
+
+// Save writes the cache back to its directory.
+func (c *LicenseCache) Save() error {
+	data, err := json.MarshalIndent(c, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(c.path), 0755); err != nil {
+		return err
+	}
+	return ioutil.WriteFile(c.path, data, 0644)
+}
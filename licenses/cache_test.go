@@ -0,0 +1,52 @@
+// Copyright 2022 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package licenses
+
+import (
+	"testing"
+)
+
+func TestLicenseCache(t *testing.T) {
+	dir := t.TempDir()
+	c, err := LoadLicenseCache(dir)
+	if err != nil {
+		t.Fatalf("LoadLicenseCache() = %v", err)
+	}
+	c.Add("github.com/foo/bar", "v1.0.0", "Apache-2.0")
+	c.Add("github.com/foo/bar", "v1.2.0", "Apache-2.0")
+	c.Add("github.com/foo/bar", "v2.0.0", "BUSL-1.1")
+	if err := c.Save(); err != nil {
+		t.Fatalf("Save() = %v", err)
+	}
+	c, err = LoadLicenseCache(dir)
+	if err != nil {
+		t.Fatalf("LoadLicenseCache() = %v", err)
+	}
+	for _, test := range []struct {
+		version         string
+		wantPrevVersion string
+		wantLicense     string
+		wantOK          bool
+	}{
+		{version: "v1.0.0", wantOK: false},
+		{version: "v1.1.0", wantPrevVersion: "v1.0.0", wantLicense: "Apache-2.0", wantOK: true},
+		{version: "v2.1.0", wantPrevVersion: "v2.0.0", wantLicense: "BUSL-1.1", wantOK: true},
+	} {
+		prevVersion, license, ok := c.Previous("github.com/foo/bar", test.version)
+		if prevVersion != test.wantPrevVersion || license != test.wantLicense || ok != test.wantOK {
+			t.Errorf("Previous(%q) = (%q, %q, %t), want (%q, %q, %t)", test.version, prevVersion, license, ok, test.wantPrevVersion, test.wantLicense, test.wantOK)
+		}
+	}
+}
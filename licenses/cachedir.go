@@ -0,0 +1,310 @@
+// Copyright 2022 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package licenses
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// Kinds of entries in a cache directory, each stored in a subdirectory.
+const (
+	// CacheClassifications caches license classifications by the content of
+	// license files.
+	CacheClassifications = "classifications"
+	// CacheModules caches module info queried from the module proxy, e.g.
+	// retracted and deprecated versions.
+	CacheModules = "modules"
+	// CacheHTTP caches HTTP responses, e.g. remote license files fetched to
+	// validate license URLs.
+	CacheHTTP = "http"
+	// CacheSources caches source repository info of module versions, used to
+	// construct license URLs.
+	CacheSources = "sources"
+	// CacheURLs caches validated license URLs of module versions, which are
+	// immutable, by the module version and the content of the license file.
+	CacheURLs = "urls"
+	// CacheRuns caches the results of previous runs, reused by incremental
+	// runs for modules that did not change.
+	CacheRuns = "runs"
+)
+
+// CacheKinds are the kinds of entries in a cache directory.
+var CacheKinds = []string{CacheClassifications, CacheModules, CacheHTTP, CacheSources, CacheURLs, CacheRuns}
+
+// DefaultMaxMemoryEntries is the number of entries a Cache keeps in memory
+// when MaxMemoryEntries is zero.
+const DefaultMaxMemoryEntries = 4096
+
+// Cache stores results of slow operations in memory and, if Dir is set,
+// across runs. Entries on disk are files named by the hash of their key, in
+// a subdirectory per kind. A Cache is safe for concurrent use, so a single
+// Cache may be shared by all scans of a long-running process. A nil Cache
+// caches nothing.
+type Cache struct {
+	// Dir is the cache directory, e.g. DefaultCacheDir. Empty means entries
+	// are only kept in memory.
+	Dir string
+	// TTL is how long entries are used after they were written. Zero means
+	// forever.
+	TTL time.Duration
+	// MaxMemoryEntries is the number of entries kept in memory, evicting the
+	// oldest first. Zero means DefaultMaxMemoryEntries, negative disables
+	// the memory layer.
+	MaxMemoryEntries int
+
+	mu     sync.Mutex
+	memory map[memoryKey]memoryEntry
+}
+
+// NewCache returns a cache of entries in dir, or only in memory if dir is
+// empty, used for ttl after they were written.
+func NewCache(dir string, ttl time.Duration) *Cache {
+	return &Cache{Dir: dir, TTL: ttl}
+}
+
+type memoryKey struct {
+	kind, key string
+}
+
+type memoryEntry struct {
+	data    []byte
+	written time.Time
+}
+
+// expired reports whether an entry written at a time must no longer be used.
+func (c *Cache) expired(written time.Time) bool {
+	return c.TTL > 0 && time.Since(written) > c.TTL
+}
+
+// maxMemoryEntries returns the number of entries kept in memory.
+func (c *Cache) maxMemoryEntries() int {
+	if c.MaxMemoryEntries == 0 {
+		return DefaultMaxMemoryEntries
+	}
+	return c.MaxMemoryEntries
+}
+
+// getMemory returns the data of an entry kept in memory.
+func (c *Cache) getMemory(kind, key string) ([]byte, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	e, ok := c.memory[memoryKey{kind, key}]
+	if !ok {
+		return nil, false
+	}
+	if c.expired(e.written) {
+		delete(c.memory, memoryKey{kind, key})
+		return nil, false
+	}
+	return e.data, true
+}
+
+// putMemory keeps the data of an entry in memory, evicting the oldest entry
+// if the memory layer is full.
+func (c *Cache) putMemory(kind, key string, data []byte, written time.Time) {
+	max := c.maxMemoryEntries()
+	if max < 0 {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.memory == nil {
+		c.memory = make(map[memoryKey]memoryEntry)
+	}
+	k := memoryKey{kind, key}
+	if _, ok := c.memory[k]; !ok && len(c.memory) >= max {
+		var oldest memoryKey
+		var oldestWritten time.Time
+		for mk, e := range c.memory {
+			if oldestWritten.IsZero() || e.written.Before(oldestWritten) {
+				oldest, oldestWritten = mk, e.written
+			}
+		}
+		delete(c.memory, oldest)
+	}
+	c.memory[k] = memoryEntry{data: data, written: written}
+}
+
+// path returns the file of an entry.
+func (c *Cache) path(kind, key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return filepath.Join(c.Dir, kind, hex.EncodeToString(sum[:]))
+}
+
+// Get returns the data of an entry, if it exists and has not expired. Entries
+// are looked up in memory first, then on disk. The returned data must not be
+// modified.
+func (c *Cache) Get(kind, key string) ([]byte, bool) {
+	if c == nil {
+		return nil, false
+	}
+	if data, ok := c.getMemory(kind, key); ok {
+		return data, true
+	}
+	if c.Dir == "" {
+		return nil, false
+	}
+	path := c.path(kind, key)
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, false
+	}
+	if c.expired(info.ModTime()) {
+		return nil, false
+	}
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, false
+	}
+	c.putMemory(kind, key, data, info.ModTime())
+	return data, true
+}
+
+// Put writes the data of an entry to memory and, if Dir is set, to disk.
+// Concurrent writers of the same entry do not corrupt it, because it is
+// written to a temporary file first. The data must not be modified after.
+func (c *Cache) Put(kind, key string, data []byte) error {
+	if c == nil {
+		return nil
+	}
+	c.putMemory(kind, key, data, time.Now())
+	if c.Dir == "" {
+		return nil
+	}
+	path := c.path(kind, key)
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	f, err := ioutil.TempFile(filepath.Dir(path), ".tmp-")
+	if err != nil {
+		return err
+	}
+	if _, err := f.Write(data); err != nil {
+		f.Close()
+		os.Remove(f.Name())
+		return err
+	}
+	if err := f.Close(); err != nil {
+		os.Remove(f.Name())
+		return err
+	}
+	return os.Rename(f.Name(), path)
+}
+
+// CacheInfo describes the entries of a kind in a cache directory.
+type CacheInfo struct {
+	// Kind is the kind of the entries, e.g. CacheHTTP, or "licenses" for the
+	// license cache, see LoadLicenseCache.
+	Kind string
+	// Entries is the number of entries.
+	Entries int
+	// Size is the total size of the entries in bytes.
+	Size int64
+	// Oldest is the time the oldest entry was written, zero if there are no
+	// entries.
+	Oldest time.Time
+}
+
+// ReadCacheInfo describes the entries of each kind in a cache directory. A
+// missing directory has no entries.
+func ReadCacheInfo(dir string) ([]CacheInfo, error) {
+	var infos []CacheInfo
+	licenses := CacheInfo{Kind: "licenses"}
+	if fi, err := os.Stat(filepath.Join(dir, licenseCacheFile)); err == nil {
+		licenses.Entries, licenses.Size, licenses.Oldest = 1, fi.Size(), fi.ModTime()
+	} else if !os.IsNotExist(err) {
+		return nil, err
+	}
+	infos = append(infos, licenses)
+	for _, kind := range CacheKinds {
+		info := CacheInfo{Kind: kind}
+		err := walkCache(dir, kind, func(path string, fi os.FileInfo) error {
+			info.Entries++
+			info.Size += fi.Size()
+			if info.Oldest.IsZero() || fi.ModTime().Before(info.Oldest) {
+				info.Oldest = fi.ModTime()
+			}
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+		infos = append(infos, info)
+	}
+	return infos, nil
+}
+
+// ClearCache removes all entries of a cache directory, including the license
+// cache. Other files in the directory are kept.
+func ClearCache(dir string) error {
+	if err := os.Remove(filepath.Join(dir, licenseCacheFile)); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	for _, kind := range CacheKinds {
+		if err := os.RemoveAll(filepath.Join(dir, kind)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// PruneCache removes entries of a cache directory written before a time, and
+// returns how many it removed. The license cache is kept, because it records
+// the history of module licenses.
+func PruneCache(dir string, before time.Time) (int, error) {
+	removed := 0
+	for _, kind := range CacheKinds {
+		err := walkCache(dir, kind, func(path string, fi os.FileInfo) error {
+			if !fi.ModTime().Before(before) {
+				return nil
+			}
+			if err := os.Remove(path); err != nil {
+				return err
+			}
+			removed++
+			return nil
+		})
+		if err != nil {
+			return removed, err
+		}
+	}
+	return removed, nil
+}
+
+// walkCache calls f for each entry of a kind in a cache directory.
+func walkCache(dir, kind string, f func(path string, fi os.FileInfo) error) error {
+	fis, err := ioutil.ReadDir(filepath.Join(dir, kind))
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	for _, fi := range fis {
+		if fi.IsDir() || fi.Name()[0] == '.' {
+			continue
+		}
+		if err := f(filepath.Join(dir, kind, fi.Name()), fi); err != nil {
+			return err
+		}
+	}
+	return nil
+}
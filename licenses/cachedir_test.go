@@ -0,0 +1,239 @@
+// Copyright 2022 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package licenses
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestCache(t *testing.T) {
+	// Without the memory layer, entries expire by the time of their file.
+	c := &Cache{Dir: t.TempDir(), TTL: time.Hour, MaxMemoryEntries: -1}
+	if _, ok := c.Get(CacheHTTP, "https://example.com/LICENSE"); ok {
+		t.Errorf("Get() of missing entry = true, want false")
+	}
+	if err := c.Put(CacheHTTP, "https://example.com/LICENSE", []byte("MIT")); err != nil {
+		t.Fatalf("Put() = %v", err)
+	}
+	if data, ok := c.Get(CacheHTTP, "https://example.com/LICENSE"); !ok || string(data) != "MIT" {
+		t.Errorf("Get() = (%q, %t), want (%q, true)", data, ok, "MIT")
+	}
+	if _, ok := c.Get(CacheModules, "https://example.com/LICENSE"); ok {
+		t.Errorf("Get() of other kind = true, want false")
+	}
+	old := time.Now().Add(-2 * time.Hour)
+	if err := os.Chtimes(c.path(CacheHTTP, "https://example.com/LICENSE"), old, old); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := c.Get(CacheHTTP, "https://example.com/LICENSE"); ok {
+		t.Errorf("Get() of expired entry = true, want false")
+	}
+	var nilCache *Cache
+	if err := nilCache.Put(CacheHTTP, "key", []byte("data")); err != nil {
+		t.Errorf("Put() on nil cache = %v", err)
+	}
+	if _, ok := nilCache.Get(CacheHTTP, "key"); ok {
+		t.Errorf("Get() on nil cache = true, want false")
+	}
+}
+
+func TestMemoryCache(t *testing.T) {
+	c := &Cache{MaxMemoryEntries: 2}
+	for _, key := range []string{"a", "b", "c"} {
+		if err := c.Put(CacheClassifications, key, []byte(key)); err != nil {
+			t.Fatalf("Put(%q) = %v", key, err)
+		}
+		time.Sleep(time.Millisecond)
+	}
+	if _, ok := c.Get(CacheClassifications, "a"); ok {
+		t.Errorf("Get() of evicted oldest entry = true, want false")
+	}
+	for _, key := range []string{"b", "c"} {
+		if data, ok := c.Get(CacheClassifications, key); !ok || string(data) != key {
+			t.Errorf("Get(%q) = (%q, %t), want (%q, true)", key, data, ok, key)
+		}
+	}
+
+	// Entries read from disk are kept in memory.
+	dir := t.TempDir()
+	if err := NewCache(dir, 0).Put(CacheHTTP, "url", []byte("MIT")); err != nil {
+		t.Fatal(err)
+	}
+	c = NewCache(dir, 0)
+	if data, ok := c.Get(CacheHTTP, "url"); !ok || string(data) != "MIT" {
+		t.Fatalf("Get() = (%q, %t), want (%q, true)", data, ok, "MIT")
+	}
+	if err := ClearCache(dir); err != nil {
+		t.Fatal(err)
+	}
+	if data, ok := c.Get(CacheHTTP, "url"); !ok || string(data) != "MIT" {
+		t.Errorf("Get() from memory = (%q, %t), want (%q, true)", data, ok, "MIT")
+	}
+}
+
+func TestCacheConcurrency(t *testing.T) {
+	c := &Cache{Dir: t.TempDir(), MaxMemoryEntries: 10}
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			for j := 0; j < 50; j++ {
+				key := strconv.Itoa(j % 20)
+				if err := c.Put(CacheModules, key, []byte(key)); err != nil {
+					t.Errorf("Put(%q) = %v", key, err)
+				}
+				if data, ok := c.Get(CacheModules, key); ok && string(data) != key {
+					t.Errorf("Get(%q) = %q, want %q", key, data, key)
+				}
+			}
+		}(i)
+	}
+	wg.Wait()
+}
+
+func TestPruneCache(t *testing.T) {
+	dir := t.TempDir()
+	c := &Cache{Dir: dir}
+	for _, key := range []string{"old", "new"} {
+		if err := c.Put(CacheClassifications, key, []byte(key)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := ioutil.WriteFile(filepath.Join(dir, licenseCacheFile), []byte("{}"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	old := time.Now().Add(-48 * time.Hour)
+	if err := os.Chtimes(c.path(CacheClassifications, "old"), old, old); err != nil {
+		t.Fatal(err)
+	}
+	removed, err := PruneCache(dir, time.Now().Add(-24*time.Hour))
+	if err != nil || removed != 1 {
+		t.Fatalf("PruneCache() = (%d, %v), want (1, nil)", removed, err)
+	}
+	if _, ok := c.Get(CacheClassifications, "new"); !ok {
+		t.Errorf("PruneCache() removed a new entry")
+	}
+	infos, err := ReadCacheInfo(dir)
+	if err != nil {
+		t.Fatalf("ReadCacheInfo() = %v", err)
+	}
+	entries := make(map[string]int)
+	for _, info := range infos {
+		entries[info.Kind] = info.Entries
+	}
+	want := map[string]int{"licenses": 1, CacheClassifications: 1, CacheModules: 0, CacheHTTP: 0}
+	for kind, n := range want {
+		if entries[kind] != n {
+			t.Errorf("ReadCacheInfo() has %d %s entries, want %d", entries[kind], kind, n)
+		}
+	}
+	if err := ClearCache(dir); err != nil {
+		t.Fatalf("ClearCache() = %v", err)
+	}
+	infos, err = ReadCacheInfo(dir)
+	if err != nil {
+		t.Fatalf("ReadCacheInfo() = %v", err)
+	}
+	for _, info := range infos {
+		if info.Entries != 0 {
+			t.Errorf("ReadCacheInfo() after ClearCache() has %d %s entries, want 0", info.Entries, info.Kind)
+		}
+	}
+}
+
+// countingClassifier identifies every license as MIT, counting calls.
+type countingClassifier struct {
+	calls int
+}
+
+func (c *countingClassifier) Identify(licensePath string) (string, Type, error) {
+	name, typ, _, err := c.IdentifyConfidence(licensePath)
+	return name, typ, err
+}
+
+func (c *countingClassifier) IdentifyConfidence(licensePath string) (string, Type, float64, error) {
+	c.calls++
+	return "MIT", Notice, 0.9, nil
+}
+
+func TestCachedClassifier(t *testing.T) {
+	dir := t.TempDir()
+	licensePath := filepath.Join(dir, "LICENSE")
+	if err := ioutil.WriteFile(licensePath, []byte("MIT License"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	cache := &Cache{Dir: filepath.Join(dir, "cache")}
+	counting := &countingClassifier{}
+	for i := 0; i < 2; i++ {
+		c := NewCachedClassifier(counting, cache, "threshold=0.8")
+		name, typ, confidence, err := c.IdentifyConfidence(licensePath)
+		if err != nil || name != "MIT" || typ != Notice || confidence != 0.9 {
+			t.Errorf("IdentifyConfidence() = (%q, %q, %v, %v), want (MIT, notice, 0.9, nil)", name, typ, confidence, err)
+		}
+	}
+	if counting.calls != 1 {
+		t.Errorf("classifier called %d times, want 1", counting.calls)
+	}
+	c := NewCachedClassifier(counting, cache, "threshold=0.5")
+	if _, _, err := c.Identify(licensePath); err != nil {
+		t.Fatal(err)
+	}
+	if counting.calls != 2 {
+		t.Errorf("classifier with other key called %d times, want 2", counting.calls)
+	}
+}
+
+// versionedClassifier is a countingClassifier with a license database.
+type versionedClassifier struct {
+	*countingClassifier
+	version string
+}
+
+func (c versionedClassifier) DatabaseVersion() string {
+	return c.version
+}
+
+func TestCachedClassifierDatabaseVersion(t *testing.T) {
+	dir := t.TempDir()
+	licensePath := filepath.Join(dir, "LICENSE")
+	if err := ioutil.WriteFile(licensePath, []byte("MIT License"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	cache := &Cache{Dir: filepath.Join(dir, "cache")}
+	counting := &countingClassifier{}
+	for _, test := range []struct {
+		version   string
+		wantCalls int
+	}{
+		{version: "v1.0.0", wantCalls: 1},
+		{version: "v1.0.0", wantCalls: 1},
+		{version: "v1.1.0", wantCalls: 2},
+	} {
+		c := NewCachedClassifier(versionedClassifier{counting, test.version}, cache, "threshold=0.8")
+		if _, _, err := c.Identify(licensePath); err != nil {
+			t.Fatal(err)
+		}
+		if counting.calls != test.wantCalls {
+			t.Errorf("classifier with database %s called %d times, want %d", test.version, counting.calls, test.wantCalls)
+		}
+	}
+}
@@ -0,0 +1,89 @@
+// Copyright 2022 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package licenses
+
+import (
+	"sort"
+
+	"golang.org/x/tools/go/packages"
+)
+
+// importChains returns the shortest chain of imports from a root package to
+// each package imported by roots, by import path. Chains start with a root
+// package and end with the package. Generated main packages of tests are
+// skipped, so that the packages they test are roots.
+func importChains(roots []*packages.Package) map[string][]string {
+	chains := make(map[string][]string)
+	var queue []*packages.Package
+	visit := func(p *packages.Package, parent []string) {
+		if _, ok := chains[p.PkgPath]; ok || isStdLib(p) {
+			return
+		}
+		chain := make([]string, len(parent), len(parent)+1)
+		copy(chain, parent)
+		chains[p.PkgPath] = append(chain, p.PkgPath)
+		queue = append(queue, p)
+	}
+	for _, p := range roots {
+		if isTestMain(p) {
+			for _, imp := range sortedImports(p) {
+				visit(imp, nil)
+			}
+			continue
+		}
+		visit(p, nil)
+	}
+	// Breadth-first, so that the first chain found to a package is one of
+	// the shortest.
+	for len(queue) > 0 {
+		p := queue[0]
+		queue = queue[1:]
+		for _, imp := range sortedImports(p) {
+			visit(imp, chains[p.PkgPath])
+		}
+	}
+	return chains
+}
+
+// sortedImports returns the packages imported by p, sorted by import path so
+// that chains are deterministic.
+func sortedImports(p *packages.Package) []*packages.Package {
+	imports := make([]*packages.Package, 0, len(p.Imports))
+	for _, imp := range p.Imports {
+		imports = append(imports, imp)
+	}
+	sort.Slice(imports, func(i, j int) bool {
+		return imports[i].PkgPath < imports[j].PkgPath
+	})
+	return imports
+}
+
+// setImportChains sets the ImportChain of each library to the shortest chain
+// to one of its packages, or to the package embedding it.
+func setImportChains(roots []*packages.Package, libraries []*Library) {
+	chains := importChains(roots)
+	for _, lib := range libraries {
+		pkgs := lib.Packages
+		if lib.EmbeddedBy != "" {
+			pkgs = []string{lib.EmbeddedBy}
+		}
+		for _, pkg := range pkgs {
+			chain, ok := chains[pkg]
+			if ok && (lib.ImportChain == nil || len(chain) < len(lib.ImportChain)) {
+				lib.ImportChain = chain
+			}
+		}
+	}
+}
@@ -0,0 +1,59 @@
+// Copyright 2022 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package licenses
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"golang.org/x/tools/go/packages"
+)
+
+func TestSetImportChains(t *testing.T) {
+	pkg := func(path string, imports ...*packages.Package) *packages.Package {
+		p := &packages.Package{PkgPath: path, Name: "lib", Imports: make(map[string]*packages.Package)}
+		for _, imp := range imports {
+			p.Imports[imp.PkgPath] = imp
+		}
+		return p
+	}
+	deep := pkg("example.com/deep/pkg")
+	yaml := pkg("example.com/yaml", deep)
+	cli := pkg("example.com/cli", yaml)
+	server := pkg("example.com/app/server", deep)
+	app := pkg("example.com/app", cli, server)
+	test := &packages.Package{PkgPath: "example.com/tool.test", Name: "main", Imports: map[string]*packages.Package{"example.com/tool": pkg("example.com/tool", yaml)}}
+
+	libraries := []*Library{
+		{Packages: []string{"example.com/deep/pkg"}},
+		{Packages: []string{"example.com/yaml"}},
+		{Packages: []string{"example.com/app/server/assets"}, EmbeddedBy: "example.com/app/server"},
+		{Packages: []string{"example.com/unreachable"}},
+	}
+	setImportChains([]*packages.Package{app, test}, libraries)
+	var got [][]string
+	for _, lib := range libraries {
+		got = append(got, lib.ImportChain)
+	}
+	want := [][]string{
+		{"example.com/app", "example.com/app/server", "example.com/deep/pkg"},
+		{"example.com/tool", "example.com/yaml"},
+		{"example.com/app", "example.com/app/server"},
+		nil,
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("setImportChains(): diff (-want +got)\n%s", diff)
+	}
+}
@@ -15,9 +15,15 @@
 package licenses
 
 import (
+	"encoding/json"
+	"errors"
 	"fmt"
 	"io/ioutil"
+	"runtime/debug"
+	"strings"
+	"sync"
 
+	"github.com/Bobgy/go-licenses/v2/internal/logging"
 	"github.com/google/licenseclassifier"
 )
 
@@ -63,39 +69,292 @@ func (t Type) String() string {
 	}
 }
 
+// typeOrder orders license types from the most to the least restrictive.
+var typeOrder = []Type{Forbidden, Restricted, Reciprocal, Notice, Permissive, Unencumbered}
+
+// LicenseType returns the type of a license by its name, e.g. an SPDX ID. The
+// type of an expression of licenses that all apply, e.g. "MIT AND GPL-3.0", is
+// the most restrictive type of its licenses, or Unknown if any is unknown.
+func LicenseType(name string) Type {
+	if !strings.Contains(name, " AND ") || strings.Contains(name, " OR ") || strings.Contains(name, "(") {
+		return Type(licenseclassifier.LicenseType(name))
+	}
+	types := make(map[Type]bool)
+	for _, n := range strings.Split(name, " AND ") {
+		types[Type(licenseclassifier.LicenseType(strings.TrimSpace(n)))] = true
+	}
+	if types[Unknown] {
+		return Unknown
+	}
+	for _, t := range typeOrder {
+		if types[t] {
+			return t
+		}
+	}
+	return Unknown
+}
+
 // Classifier can detect the type of a software license.
 type Classifier interface {
 	Identify(licensePath string) (string, Type, error)
 }
 
+// ConfidenceClassifier is a Classifier that also reports the confidence of the
+// licenses it identifies.
+type ConfidenceClassifier interface {
+	Classifier
+	// IdentifyConfidence is like Identify, and also returns the confidence of
+	// the classification, between 0 and 1.
+	IdentifyConfidence(licensePath string) (string, Type, float64, error)
+}
+
+// googleClassifier identifies licenses with licenseclassifier. Compiling its
+// license database takes a while, so verbatim copies of known licenses are
+// looked up in an embedded license index first, and the database is only
+// compiled when the first other file is identified: runs whose
+// classifications are all indexed or cached, see NewCachedClassifier, never
+// compile it.
 type googleClassifier struct {
-	classifier *licenseclassifier.License
+	confidenceThreshold float64
+	once                sync.Once
+	classifier          *licenseclassifier.License
+	err                 error
+}
+
+// compileLicenseDatabase compiles the license database of licenseclassifier.
+// Tests replace it to observe compilations.
+var compileLicenseDatabase = func(confidenceThreshold float64) (*licenseclassifier.License, error) {
+	return licenseclassifier.New(confidenceThreshold)
+}
+
+// databaseError is an error compiling a license database. Unlike errors
+// identifying a file, it is not cached by NewCachedClassifier.
+type databaseError struct {
+	err error
+}
+
+func (e *databaseError) Error() string {
+	return "compiling license database: " + e.err.Error()
+}
+
+func (e *databaseError) Unwrap() error {
+	return e.err
+}
+
+// license returns the compiled license database, compiling it first if
+// needed.
+func (c *googleClassifier) license() (*licenseclassifier.License, error) {
+	c.once.Do(func() {
+		c.classifier, c.err = compileLicenseDatabase(c.confidenceThreshold)
+	})
+	return c.classifier, c.err
 }
 
 // NewClassifier creates a classifier that requires a specified confidence threshold
-// in order to return a positive license classification.
+// in order to return a positive license classification. It is the
+// DefaultClassifier, see NewNamedClassifier for others.
 func NewClassifier(confidenceThreshold float64) (Classifier, error) {
-	c, err := licenseclassifier.New(confidenceThreshold)
-	if err != nil {
-		return nil, err
-	}
-	return &googleClassifier{classifier: c}, nil
+	return NewNamedClassifier(DefaultClassifier, confidenceThreshold)
 }
 
 // Identify returns the name and type of a license, given its file path.
 // An empty license path results in an empty name and Unknown type.
 func (c *googleClassifier) Identify(licensePath string) (string, Type, error) {
+	name, typ, _, err := c.IdentifyConfidence(licensePath)
+	return name, typ, err
+}
+
+// IdentifyConfidence returns the name, type and confidence of a license, given
+// its file path.
+func (c *googleClassifier) IdentifyConfidence(licensePath string) (string, Type, float64, error) {
 	if licensePath == "" {
-		return "", Unknown, nil
+		return "", Unknown, 0, nil
 	}
 	content, err := ioutil.ReadFile(licensePath)
 	if err != nil {
-		return "", "", err
+		return "", "", 0, err
+	}
+	matches, err := c.IdentifyContent(licensePath, content)
+	if err != nil {
+		return "", "", 0, err
 	}
-	matches := c.classifier.MultipleMatch(string(content), true)
 	if len(matches) == 0 {
-		return "", "", fmt.Errorf("unknown license")
+		return "", "", 0, fmt.Errorf("unknown license")
+	}
+	return matches[0].Name, matches[0].Type, matches[0].Confidence, nil
+}
+
+// IdentifyContent returns the licenses matching the contents of a file, best
+// first.
+func (c *googleClassifier) IdentifyContent(_ string, contents []byte) ([]Match, error) {
+	if matches, ok := embeddedLicenseIndex().lookup(string(contents), c.confidenceThreshold); ok {
+		return matches, nil
+	}
+	classifier, err := c.license()
+	if err != nil {
+		return nil, &databaseError{err: err}
+	}
+	var matches []Match
+	for _, m := range classifier.MultipleMatch(string(contents), true) {
+		matches = append(matches, Match{Name: m.Name, Type: Type(licenseclassifier.LicenseType(m.Name)), Confidence: m.Confidence})
+	}
+	return matches, nil
+}
+
+// licenseclassifierModule is the module of the license database of the
+// DefaultClassifier.
+const licenseclassifierModule = "github.com/google/licenseclassifier"
+
+// DatabaseVersion returns the version and checksum of the licenseclassifier
+// module this program is built with, which embeds its license database, or
+// "" if unknown.
+func (c *googleClassifier) DatabaseVersion() string {
+	info, ok := debug.ReadBuildInfo()
+	if !ok {
+		return ""
+	}
+	for _, m := range info.Deps {
+		if m.Replace != nil {
+			m = m.Replace
+		}
+		if m.Path == licenseclassifierModule {
+			return m.Version + " " + m.Sum
+		}
+	}
+	return ""
+}
+
+// VersionedClassifier is implemented by classifiers whose classifications
+// depend on a versioned license database, e.g. the DefaultClassifier. Cached
+// classifications of other versions are not used, see NewCachedClassifier.
+type VersionedClassifier interface {
+	// DatabaseVersion identifies the license database, e.g. by a version or
+	// a hash of its contents, or returns "" if it is unknown.
+	DatabaseVersion() string
+}
+
+// databaseVersion returns the version of the license database of c, or "" if
+// unknown.
+func databaseVersion(c interface{}) string {
+	if v, ok := c.(VersionedClassifier); ok {
+		return v.DatabaseVersion()
+	}
+	return ""
+}
+
+// cachedClassifier caches classifications of a classifier by the content of
+// license files.
+type cachedClassifier struct {
+	classifier ConfidenceClassifier
+	cache      *Cache
+	key        string
+}
+
+// classification is a cache entry of a cachedClassifier.
+type classification struct {
+	Name       string  `json:"name"`
+	Type       Type    `json:"type"`
+	Confidence float64 `json:"confidence"`
+	Error      string  `json:"error,omitempty"`
+}
+
+// NewCachedClassifier returns a classifier caching the classifications of c in
+// cache. The key distinguishes classifiers with different settings, e.g.
+// confidence thresholds. Classifications of a VersionedClassifier are also
+// keyed by the version of its license database, so that they are not reused
+// once it changes.
+func NewCachedClassifier(c ConfidenceClassifier, cache *Cache, key string) ConfidenceClassifier {
+	if cache == nil {
+		return c
+	}
+	if v := databaseVersion(c); v != "" {
+		key += " database=" + v
+	}
+	return &cachedClassifier{classifier: c, cache: cache, key: key}
+}
+
+// Identify returns the name and type of a license, given its file path.
+func (c *cachedClassifier) Identify(licensePath string) (string, Type, error) {
+	name, typ, _, err := c.IdentifyConfidence(licensePath)
+	return name, typ, err
+}
+
+// IdentifyConfidence returns the name, type and confidence of a license, given
+// its file path.
+func (c *cachedClassifier) IdentifyConfidence(licensePath string) (string, Type, float64, error) {
+	if licensePath == "" {
+		return c.classifier.IdentifyConfidence(licensePath)
+	}
+	content, err := ioutil.ReadFile(licensePath)
+	if err != nil {
+		return "", "", 0, err
+	}
+	key := c.key + "\x00" + string(content)
+	var cached classification
+	if data, ok := c.cache.Get(CacheClassifications, key); ok && json.Unmarshal(data, &cached) == nil {
+		if cached.Error != "" {
+			return "", "", 0, errors.New(cached.Error)
+		}
+		return cached.Name, cached.Type, cached.Confidence, nil
+	}
+	name, typ, confidence, err := c.classifier.IdentifyConfidence(licensePath)
+	var dbErr *databaseError
+	if errors.As(err, &dbErr) {
+		return name, typ, confidence, err
+	}
+	cached = classification{Name: name, Type: typ, Confidence: confidence}
+	if err != nil {
+		cached.Error = err.Error()
+	}
+	if data, merr := json.Marshal(cached); merr == nil {
+		if perr := c.cache.Put(CacheClassifications, key, data); perr != nil {
+			logging.Warningf("Failed to cache license classification of %s: %v", licensePath, perr)
+		}
+	}
+	return name, typ, confidence, err
+}
+
+// memoClassifier memoizes the classifications of a classifier by file path,
+// so that license files shared by many packages are classified once.
+type memoClassifier struct {
+	classifier Classifier
+	mu         sync.Mutex
+	results    map[string]memoResult
+}
+
+type memoResult struct {
+	name       string
+	typ        Type
+	confidence float64
+	err        error
+}
+
+func newMemoClassifier(c Classifier) *memoClassifier {
+	if m, ok := c.(*memoClassifier); ok {
+		return m
+	}
+	return &memoClassifier{classifier: c, results: make(map[string]memoResult)}
+}
+
+// Identify returns the name and type of a license, given its file path.
+func (c *memoClassifier) Identify(licensePath string) (string, Type, error) {
+	name, typ, _, err := c.IdentifyConfidence(licensePath)
+	return name, typ, err
+}
+
+// IdentifyConfidence returns the name, type and confidence of a license, given
+// its file path.
+func (c *memoClassifier) IdentifyConfidence(licensePath string) (string, Type, float64, error) {
+	c.mu.Lock()
+	r, ok := c.results[licensePath]
+	c.mu.Unlock()
+	if !ok {
+		// Not holding mu, so that different files are classified
+		// concurrently.
+		r.name, r.typ, r.confidence, r.err = identify(c.classifier, licensePath)
+		c.mu.Lock()
+		c.results[licensePath] = r
+		c.mu.Unlock()
 	}
-	licenseName := matches[0].Name
-	return licenseName, Type(licenseclassifier.LicenseType(licenseName)), nil
+	return r.name, r.typ, r.confidence, r.err
 }
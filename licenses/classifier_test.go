@@ -15,10 +15,14 @@
 package licenses
 
 import (
+	"errors"
 	"fmt"
+	"io/ioutil"
 	"os"
 	"path/filepath"
 	"testing"
+
+	"github.com/google/licenseclassifier"
 )
 
 // Useful in other tests in this package
@@ -101,3 +105,49 @@ func TestIdentify(t *testing.T) {
 		})
 	}
 }
+
+func TestDefaultClassifierCompilesLazily(t *testing.T) {
+	compiles := 0
+	defer func(compile func(float64) (*licenseclassifier.License, error)) {
+		compileLicenseDatabase = compile
+	}(compileLicenseDatabase)
+	compileLicenseDatabase = func(float64) (*licenseclassifier.License, error) {
+		compiles++
+		return nil, errors.New("no license database")
+	}
+
+	dir := t.TempDir()
+	mitPath, otherPath := filepath.Join(dir, "LICENSE"), filepath.Join(dir, "COPYING")
+	for _, path := range []string{mitPath, otherPath} {
+		if err := ioutil.WriteFile(path, []byte(filepath.Base(path)), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	cache := &Cache{Dir: filepath.Join(dir, "cache")}
+	c, err := NewNamedClassifier(DefaultClassifier, 0.8)
+	if err != nil {
+		t.Fatalf("NewNamedClassifier() = %v", err)
+	}
+	// Classify LICENSE into the cache with the same license database.
+	warm := NewCachedClassifier(versionedClassifier{&countingClassifier{}, databaseVersion(c)}, cache, "threshold=0.8")
+	if _, _, err := warm.Identify(mitPath); err != nil {
+		t.Fatal(err)
+	}
+
+	cached := NewCachedClassifier(c, cache, "threshold=0.8")
+	if name, _, err := cached.Identify(mitPath); err != nil || name != "MIT" {
+		t.Errorf("Identify(LICENSE) = (%q, _, %v), want (MIT, _, nil)", name, err)
+	}
+	if compiles != 0 {
+		t.Errorf("license database compiled %d times for cached classifications, want 0", compiles)
+	}
+	for i := 1; i <= 2; i++ {
+		// Errors compiling the database are returned, but not cached.
+		if _, _, err := NewCachedClassifier(c, cache, "threshold=0.8").Identify(otherPath); err == nil {
+			t.Errorf("Identify(COPYING) = nil error, want error compiling the license database")
+		}
+	}
+	if compiles != 1 {
+		t.Errorf("license database compiled %d times, want once", compiles)
+	}
+}
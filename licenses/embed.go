@@ -0,0 +1,155 @@
+// Copyright 2022 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package licenses
+
+import (
+	"fmt"
+	"go/parser"
+	"go/token"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// embedPatterns returns the patterns of //go:embed directives in Go files.
+func embedPatterns(goFiles []string) ([]string, error) {
+	var patterns []string
+	for _, path := range goFiles {
+		f, err := parser.ParseFile(token.NewFileSet(), path, nil, parser.ParseComments)
+		if err != nil {
+			return nil, err
+		}
+		for _, group := range f.Comments {
+			for _, c := range group.List {
+				if !strings.HasPrefix(c.Text, "//go:embed ") {
+					continue
+				}
+				args, err := parseEmbedArgs(strings.TrimPrefix(c.Text, "//go:embed "))
+				if err != nil {
+					return nil, fmt.Errorf("%s: %w", path, err)
+				}
+				patterns = append(patterns, args...)
+			}
+		}
+	}
+	return patterns, nil
+}
+
+// parseEmbedArgs splits arguments of a //go:embed directive, which are
+// separated by spaces and may be quoted as Go string literals.
+func parseEmbedArgs(s string) ([]string, error) {
+	var args []string
+	for {
+		s = strings.TrimSpace(s)
+		if s == "" {
+			return args, nil
+		}
+		end := strings.IndexAny(s, " \t")
+		if s[0] == '"' || s[0] == '`' {
+			quote := s[0]
+			i := strings.IndexByte(s[1:], quote)
+			for quote == '"' && i > 0 && s[i] == '\\' {
+				// An escaped quote.
+				next := strings.IndexByte(s[i+2:], quote)
+				if next < 0 {
+					i = -1
+					break
+				}
+				i += next + 1
+			}
+			if i < 0 {
+				return nil, fmt.Errorf("invalid quoted string in //go:embed: %s", s)
+			}
+			arg, err := strconv.Unquote(s[:i+2])
+			if err != nil {
+				return nil, fmt.Errorf("invalid quoted string in //go:embed: %s", s)
+			}
+			args = append(args, arg)
+			s = s[i+2:]
+			continue
+		}
+		if end < 0 {
+			end = len(s)
+		}
+		args = append(args, s[:end])
+		s = s[end:]
+	}
+}
+
+// embeddedLicenses returns paths of license files in the files embedded by
+// //go:embed patterns of a package in pkgDir. Embedded directories are
+// searched recursively, except for paths ignored by the IgnoreFileName file
+// of the module in rootDir, and directories or files exceeding limits.
+func embeddedLicenses(pkgDir, rootDir string, patterns []string, classifier Classifier, limits ScanLimits) ([]string, error) {
+	ignore, err := loadIgnoreFile(rootDir)
+	if err != nil {
+		return nil, err
+	}
+	found := make(map[string]bool)
+	for _, pattern := range patterns {
+		pattern = strings.TrimPrefix(pattern, "all:")
+		matches, err := filepath.Glob(filepath.Join(pkgDir, filepath.FromSlash(pattern)))
+		if err != nil {
+			return nil, fmt.Errorf("invalid //go:embed pattern %q: %w", pattern, err)
+		}
+		for _, match := range matches {
+			err := filepath.Walk(match, func(path string, info os.FileInfo, err error) error {
+				if err != nil {
+					return err
+				}
+				if ignore.ignored(path, info.IsDir()) {
+					if info.IsDir() {
+						return filepath.SkipDir
+					}
+					return nil
+				}
+				if info.IsDir() {
+					if path != match && (limits.skipped(info.Name()) || limits.tooDeep(depth(match, path))) {
+						return filepath.SkipDir
+					}
+					return nil
+				}
+				if !licenseRegexp.MatchString(info.Name()) || limits.tooLarge(path, info.Size()) {
+					return nil
+				}
+				if _, _, err := classifier.Identify(path); err == nil {
+					found[path] = true
+				}
+				return nil
+			})
+			if err != nil {
+				return nil, err
+			}
+		}
+	}
+	var paths []string
+	for path := range found {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+	return paths, nil
+}
+
+// depth returns the number of directories between dir and path below it,
+// e.g. 1 for a directory in dir.
+func depth(dir, path string) int {
+	rel, err := filepath.Rel(dir, path)
+	if err != nil || rel == "." {
+		return 0
+	}
+	return strings.Count(filepath.ToSlash(rel), "/") + 1
+}
@@ -0,0 +1,77 @@
+// Copyright 2022 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package licenses
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestParseEmbedArgs(t *testing.T) {
+	for _, test := range []struct {
+		args    string
+		want    []string
+		wantErr bool
+	}{
+		{args: "static", want: []string{"static"}},
+		{args: "static/*.html  images", want: []string{"static/*.html", "images"}},
+		{args: `"my files" ` + "`raw dir`" + ` all:hidden`, want: []string{"my files", "raw dir", "all:hidden"}},
+		{args: `"with \" quote"`, want: []string{`with " quote`}},
+		{args: `"unterminated`, wantErr: true},
+	} {
+		got, err := parseEmbedArgs(test.args)
+		if gotErr := err != nil; gotErr != test.wantErr {
+			t.Errorf("parseEmbedArgs(%q) = (_, %q), want err? %t", test.args, err, test.wantErr)
+			continue
+		}
+		if diff := cmp.Diff(test.want, got); diff != "" {
+			t.Errorf("parseEmbedArgs(%q): diff (-want +got)\n%s", test.args, diff)
+		}
+	}
+}
+
+func TestEmbeddedLicenses(t *testing.T) {
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatalf("Cannot get working directory: %v", err)
+	}
+	classifier := classifierStub{
+		licenseNames: map[string]string{
+			"testdata/embed/static/fonts/LICENSE": "OFL-1.1",
+		},
+		licenseTypes: map[string]Type{
+			"testdata/embed/static/fonts/LICENSE": Reciprocal,
+		},
+	}
+	patterns, err := embedPatterns([]string{"testdata/embed/embed.go"})
+	if err != nil {
+		t.Fatalf("embedPatterns() = (_, %q), want (_, nil)", err)
+	}
+	if diff := cmp.Diff([]string{"static", "index.html"}, patterns); diff != "" {
+		t.Errorf("embedPatterns(): diff (-want +got)\n%s", diff)
+	}
+	pkgDir := filepath.Join(wd, "testdata/embed")
+	licensePaths, err := embeddedLicenses(pkgDir, pkgDir, patterns, classifier, ScanLimits{})
+	if err != nil {
+		t.Fatalf("embeddedLicenses() = (_, %q), want (_, nil)", err)
+	}
+	want := []string{filepath.Join(pkgDir, "static/fonts/LICENSE")}
+	if diff := cmp.Diff(want, licensePaths); diff != "" {
+		t.Errorf("embeddedLicenses(): diff (-want +got)\n%s", diff)
+	}
+}
@@ -0,0 +1,65 @@
+// Copyright 2022 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package licenses
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+)
+
+// Kinds of errors, to be tested with errors.Is. Errors returned by this
+// package wrap them with details, e.g. the library or URL concerned.
+var (
+	// ErrNoLicenseFound means that no license file was found for a package
+	// or library.
+	ErrNoLicenseFound = errors.New("no license found")
+	// ErrUnsupportedHost means that the repository of a module is hosted
+	// where the URLs of files cannot be determined.
+	ErrUnsupportedHost = errors.New("unsupported repository host")
+	// ErrRateLimited means that a host rejected a request because too many
+	// requests were made, so it may succeed later.
+	ErrRateLimited = errors.New("rate limited")
+	// ErrValidationMismatch means that a remote license file differs from the
+	// local license file it should be the URL of.
+	ErrValidationMismatch = errors.New("local license file content does not match remote license URL")
+)
+
+// HTTPError is an HTTP response with an unsuccessful status code.
+type HTTPError struct {
+	// URL is the requested URL.
+	URL string
+	// StatusCode is the status code of the response, e.g. 404.
+	StatusCode int
+}
+
+func (e *HTTPError) Error() string {
+	return fmt.Sprintf("download(%q): response status code %v not OK", e.URL, e.StatusCode)
+}
+
+// Is reports whether the response means ErrRateLimited.
+func (e *HTTPError) Is(target error) bool {
+	return target == ErrRateLimited && e.StatusCode == http.StatusTooManyRequests
+}
+
+// notFoundError is returned by findUpwards and FindAll when no file matches.
+type notFoundError struct {
+	pattern string
+	start   string
+}
+
+func (e *notFoundError) Error() string {
+	return fmt.Sprintf("no file/directory matching regexp %q found for %s", e.pattern, e.start)
+}
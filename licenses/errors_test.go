@@ -0,0 +1,48 @@
+// Copyright 2022 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package licenses
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"testing"
+)
+
+func TestHTTPError(t *testing.T) {
+	for _, test := range []struct {
+		statusCode      int
+		wantRateLimited bool
+	}{
+		{statusCode: http.StatusTooManyRequests, wantRateLimited: true},
+		{statusCode: http.StatusNotFound, wantRateLimited: false},
+	} {
+		err := fmt.Errorf("validating: %w", &HTTPError{URL: "https://example.com/LICENSE", StatusCode: test.statusCode})
+		if got := errors.Is(err, ErrRateLimited); got != test.wantRateLimited {
+			t.Errorf("errors.Is(%v, ErrRateLimited) = %t, want %t", err, got, test.wantRateLimited)
+		}
+		var httpErr *HTTPError
+		if !errors.As(err, &httpErr) || httpErr.StatusCode != test.statusCode {
+			t.Errorf("errors.As(%v, *HTTPError) = %v, want status code %d", err, httpErr, test.statusCode)
+		}
+	}
+}
+
+func TestFindNoLicense(t *testing.T) {
+	_, err := Find("testdata/ignorefile/fixtures", "testdata/ignorefile/fixtures", classifierStub{})
+	if !errors.Is(err, ErrNoLicenseFound) {
+		t.Errorf("Find() = %v, want ErrNoLicenseFound", err)
+	}
+}
@@ -15,40 +15,317 @@
 package licenses
 
 import (
+	"errors"
 	"fmt"
+	"io/fs"
 	"io/ioutil"
+	"path"
 	"path/filepath"
 	"regexp"
+	"sort"
 	"strings"
+	"sync"
 )
 
 var (
 	licenseRegexp = regexp.MustCompile(`^(?i)(LICEN(S|C)E|COPYING|README|NOTICE).*$`)
 )
 
-// Find returns the file path of the license for this package.
+// LicenseCandidate is a file that may contain the license of a package, with
+// its classification.
+type LicenseCandidate struct {
+	// Path is the absolute path of the file.
+	Path string
+	// Name is the name of the license in the file, e.g. "MIT". It is empty
+	// if the license is not identified.
+	Name string
+	// Type is the type of the license, Unknown if it is not identified.
+	Type Type
+	// Confidence is the confidence of the classifier in Name, between 0 and
+	// 1. Classifiers not reporting confidence have confidence 1.
+	Confidence float64
+	// Depth is the number of directories between the package and the file,
+	// e.g. 0 for a file in the package directory.
+	Depth int
+	// Err is the error identifying the license, or nil if it is identified.
+	Err error
+}
+
+// Find returns the file path of the license for this package, i.e. the best
+// candidate of FindAll.
 //
 // dir is path of the directory where we want to find a license.
 // rootDir is path of the module containing this package. Find will not search out of the
-// rootDir.
+// rootDir, and skips files ignored by the IgnoreFileName file of the rootDir.
 func Find(dir string, rootDir string, classifier Classifier) (string, error) {
-	dir, err := filepath.Abs(dir)
+	candidates, err := FindAll(dir, rootDir, classifier)
 	if err != nil {
 		return "", err
 	}
+	return candidates[0].Path, nil
+}
+
+// FindAll returns the files that may contain the license for this package,
+// from dir up to rootDir, ranked best first: identified licenses first, then
+// files closer to dir, then files with higher confidence. It returns an error
+// wrapping ErrNoLicenseFound if no license is identified. Files whose license
+// cannot be identified are returned last, with Err set.
+func FindAll(dir string, rootDir string, classifier Classifier) ([]LicenseCandidate, error) {
+	return newFinder(classifier, ScanLimits{}).findAll(dir, rootDir)
+}
+
+// finder finds license candidates like FindAll, memoizing the candidates of
+// each directory and the ignore file of each root directory, so that packages
+// sharing a directory tree walk each directory once. It is created per run,
+// so that changes to files or to the configuration are seen by later runs.
+// A finder is safe for concurrent use.
+type finder struct {
+	classifier Classifier
+	limits     ScanLimits
+	mu         sync.Mutex
+	// dirs are the candidates of directories, keyed by root and directory.
+	dirs map[[2]string]dirCandidates
+	// ignores are the ignore files of root directories.
+	ignores map[string]ignoreResult
+}
+
+type dirCandidates struct {
+	candidates []LicenseCandidate
+	err        error
+}
+
+type ignoreResult struct {
+	ignore *ignoreFile
+	err    error
+}
+
+func newFinder(classifier Classifier, limits ScanLimits) *finder {
+	return &finder{
+		classifier: classifier,
+		limits:     limits,
+		dirs:       make(map[[2]string]dirCandidates),
+		ignores:    make(map[string]ignoreResult),
+	}
+}
+
+// findAll returns the license candidates from dir up to rootDir, see FindAll.
+func (f *finder) findAll(dir string, rootDir string) ([]LicenseCandidate, error) {
+	dir, err := filepath.Abs(dir)
+	if err != nil {
+		return nil, err
+	}
 	rootDir, err = filepath.Abs(rootDir)
 	if err != nil {
-		return "", err
+		return nil, err
 	}
 	if !strings.HasPrefix(dir, rootDir) {
-		return "", fmt.Errorf("licenses.Find: rootDir %s should contain dir %s", rootDir, dir)
+		return nil, fmt.Errorf("licenses.Find: rootDir %s should contain dir %s", rootDir, dir)
+	}
+	ignore, err := f.ignoreFile(rootDir)
+	if err != nil {
+		return nil, err
+	}
+	return findAll(dir, candidateSearch{
+		readDir: ioutil.ReadDir,
+		join:    filepath.Join,
+		parent:  filepath.Dir,
+		within:  func(d string) bool { return strings.HasPrefix(d, rootDir) },
+		ignore:  ignore,
+		limits:  f.limits,
+		identify: func(path string) (string, Type, float64, error) {
+			return identify(f.classifier, path)
+		},
+		memo: func(d string, scan func() ([]LicenseCandidate, error)) ([]LicenseCandidate, error) {
+			key := [2]string{rootDir, d}
+			f.mu.Lock()
+			r, ok := f.dirs[key]
+			f.mu.Unlock()
+			if !ok {
+				// Not holding mu, so that different directories are
+				// scanned concurrently.
+				r.candidates, r.err = scan()
+				f.mu.Lock()
+				f.dirs[key] = r
+				f.mu.Unlock()
+			}
+			return r.candidates, r.err
+		},
+	})
+}
+
+// ignoreFile returns the ignore file of rootDir, or nil if there is none.
+func (f *finder) ignoreFile(rootDir string) (*ignoreFile, error) {
+	f.mu.Lock()
+	r, ok := f.ignores[rootDir]
+	f.mu.Unlock()
+	if !ok {
+		r.ignore, r.err = loadIgnoreFile(rootDir)
+		f.mu.Lock()
+		f.ignores[rootDir] = r
+		f.mu.Unlock()
+	}
+	return r.ignore, r.err
+}
+
+// FindFS is like Find, but searches a file system, e.g. a module zip opened
+// with archive/zip, an embedded file system or a test fixture, without files
+// on disk. See FindAllFS.
+func FindFS(fsys fs.FS, dir, rootDir string, classifier ContentClassifier) (string, error) {
+	candidates, err := FindAllFS(fsys, dir, rootDir, classifier)
+	if err != nil {
+		return "", err
+	}
+	return candidates[0].Path, nil
+}
+
+// FindAllFS is like FindAll, but searches a file system, e.g. a module zip
+// opened with archive/zip, an embedded file system or a test fixture, without
+// files on disk. dir and rootDir are slash-separated paths in fsys, e.g.
+// "example.com/mod@v1.0.0/pkg" and "example.com/mod@v1.0.0" in a module zip,
+// or "." for the root of fsys. Paths of candidates are paths in fsys, whose
+// contents are identified by classifier, see FromContentClassifier.
+func FindAllFS(fsys fs.FS, dir, rootDir string, classifier ContentClassifier) ([]LicenseCandidate, error) {
+	if !fs.ValidPath(dir) || !fs.ValidPath(rootDir) {
+		return nil, fmt.Errorf("licenses.FindAllFS: invalid path %q or %q", dir, rootDir)
 	}
-	return findUpwards(dir, licenseRegexp, rootDir, func(path string) bool {
-		// TODO(RJPercival): Return license details
-		if _, _, err := classifier.Identify(path); err != nil {
-			return false
+	within := func(d string) bool {
+		return rootDir == "." || d == rootDir || strings.HasPrefix(d, rootDir+"/")
+	}
+	if !within(dir) {
+		return nil, fmt.Errorf("licenses.FindAllFS: rootDir %s should contain dir %s", rootDir, dir)
+	}
+	var ignore *ignoreFile
+	content, err := fs.ReadFile(fsys, path.Join(rootDir, IgnoreFileName))
+	if err == nil {
+		ignore = parseIgnoreFile(rootDir, string(content))
+	} else if !errors.Is(err, fs.ErrNotExist) {
+		return nil, err
+	}
+	return findAll(dir, candidateSearch{
+		readDir: func(d string) ([]fs.FileInfo, error) {
+			entries, err := fs.ReadDir(fsys, d)
+			if err != nil {
+				return nil, err
+			}
+			fis := make([]fs.FileInfo, len(entries))
+			for i, e := range entries {
+				if fis[i], err = e.Info(); err != nil {
+					return nil, err
+				}
+			}
+			return fis, nil
+		},
+		join:   path.Join,
+		parent: path.Dir,
+		within: within,
+		ignore: ignore,
+		identify: func(p string) (string, Type, float64, error) {
+			contents, err := fs.ReadFile(fsys, p)
+			if err != nil {
+				return "", "", 0, err
+			}
+			return identifyContent(classifier, p, contents)
+		},
+	})
+}
+
+// candidateSearch is the file system searched for license candidates by
+// findAll, either on disk or an fs.FS.
+type candidateSearch struct {
+	// readDir returns the entries of a directory.
+	readDir func(dir string) ([]fs.FileInfo, error)
+	join    func(elem ...string) string
+	// parent returns the parent of a directory, or the directory itself at
+	// the root of the file system.
+	parent func(dir string) string
+	// within reports whether a directory is within the root directory.
+	within   func(dir string) bool
+	ignore   *ignoreFile
+	limits   ScanLimits
+	identify func(path string) (string, Type, float64, error)
+	// memo, if set, memoizes the candidates of a directory, returning the
+	// result of scan the first time a directory is searched.
+	memo func(dir string, scan func() ([]LicenseCandidate, error)) ([]LicenseCandidate, error)
+}
+
+// findAll returns the license candidates from dir up to the root directory,
+// see FindAll.
+func findAll(dir string, search candidateSearch) ([]LicenseCandidate, error) {
+	var candidates []LicenseCandidate
+	for depth, d := 0, dir; search.within(d); depth++ {
+		var found []LicenseCandidate
+		var err error
+		if search.memo != nil {
+			found, err = search.memo(d, func() ([]LicenseCandidate, error) { return search.dirCandidates(d) })
+		} else {
+			found, err = search.dirCandidates(d)
+		}
+		if err != nil {
+			return nil, err
+		}
+		for _, c := range found {
+			c.Depth = depth
+			candidates = append(candidates, c)
+		}
+		parent := search.parent(d)
+		if parent == d {
+			// Can't go any higher up the directory tree.
+			break
+		}
+		d = parent
+	}
+	sortCandidates(candidates)
+	if len(candidates) == 0 || candidates[0].Err != nil {
+		err := &notFoundError{pattern: licenseRegexp.String(), start: dir}
+		return candidates, fmt.Errorf("%w: %v", ErrNoLicenseFound, err)
+	}
+	return candidates, nil
+}
+
+// dirCandidates returns the license candidates in a directory, with depth 0.
+func (search candidateSearch) dirCandidates(dir string) ([]LicenseCandidate, error) {
+	fis, err := search.readDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	var candidates []LicenseCandidate
+	for _, fi := range fis {
+		name := fi.Name()
+		if !licenseRegexp.MatchString(name) {
+			continue
+		}
+		path := search.join(dir, name)
+		if search.ignore.ignored(path, false) {
+			continue
+		}
+		if !fi.IsDir() && search.limits.tooLarge(path, fi.Size()) {
+			continue
+		}
+		c := LicenseCandidate{Path: path, Type: Unknown}
+		if name, typ, confidence, err := search.identify(path); err != nil {
+			c.Err = err
+		} else {
+			c.Name, c.Type, c.Confidence = name, typ, confidence
+		}
+		candidates = append(candidates, c)
+	}
+	return candidates, nil
+}
+
+// sortCandidates sorts license candidates best first.
+func sortCandidates(candidates []LicenseCandidate) {
+	sort.SliceStable(candidates, func(i, j int) bool {
+		a, b := candidates[i], candidates[j]
+		if (a.Err == nil) != (b.Err == nil) {
+			return a.Err == nil
+		}
+		if a.Depth != b.Depth {
+			return a.Depth < b.Depth
+		}
+		if a.Confidence != b.Confidence {
+			return a.Confidence > b.Confidence
 		}
-		return true
+		return a.Path < b.Path
 	})
 }
 
@@ -81,5 +358,5 @@ func findUpwards(dir string, r *regexp.Regexp, stopAt string, predicate func(pat
 		}
 		dir = parent
 	}
-	return "", fmt.Errorf("no file/directory matching regexp %q found for %s", r, start)
+	return "", &notFoundError{pattern: r.String(), start: start}
 }
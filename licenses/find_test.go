@@ -15,9 +15,14 @@
 package licenses
 
 import (
+	"errors"
+	"io/ioutil"
 	"os"
 	"path/filepath"
 	"testing"
+	"testing/fstest"
+
+	"github.com/google/go-cmp/cmp"
 )
 
 func TestFind(t *testing.T) {
@@ -108,3 +113,177 @@ func TestFind(t *testing.T) {
 		})
 	}
 }
+
+func TestFindIgnoreFile(t *testing.T) {
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatalf("Cannot get working directory: %v", err)
+	}
+	classifier := classifierStub{
+		licenseNames: map[string]string{
+			"testdata/ignorefile/LICENSE":          "MIT",
+			"testdata/ignorefile/fixtures/LICENSE": "GPL-3.0",
+		},
+		licenseTypes: map[string]Type{
+			"testdata/ignorefile/LICENSE":          Notice,
+			"testdata/ignorefile/fixtures/LICENSE": Restricted,
+		},
+	}
+	want := filepath.Join(wd, "testdata/ignorefile/LICENSE")
+	licensePath, err := Find("testdata/ignorefile/fixtures", "testdata/ignorefile", classifier)
+	if err != nil || licensePath != want {
+		t.Fatalf("Find() = (%#v, %q), want (%q, nil)", licensePath, err, want)
+	}
+}
+
+// confidenceStub identifies licenses by file path relative to its dir.
+type confidenceStub struct {
+	dir     string
+	results map[string]LicenseCandidate
+}
+
+func (c confidenceStub) Identify(licensePath string) (string, Type, error) {
+	name, typ, _, err := c.IdentifyConfidence(licensePath)
+	return name, typ, err
+}
+
+func (c confidenceStub) IdentifyConfidence(licensePath string) (string, Type, float64, error) {
+	relPath, err := filepath.Rel(c.dir, licensePath)
+	if err != nil {
+		return "", Unknown, 0, err
+	}
+	r, ok := c.results[relPath]
+	if !ok {
+		return "", Unknown, 0, errors.New("unknown license")
+	}
+	return r.Name, r.Type, r.Confidence, nil
+}
+
+func TestFindAll(t *testing.T) {
+	dir := t.TempDir()
+	for _, path := range []string{"LICENSE", "README.md", "pkg/COPYING", "pkg/LICENSE", "pkg/NOTICE", "pkg/main.go"} {
+		path = filepath.Join(dir, filepath.FromSlash(path))
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := ioutil.WriteFile(path, nil, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	classifier := confidenceStub{dir: dir, results: map[string]LicenseCandidate{
+		"LICENSE":     {Name: "Apache-2.0", Type: Notice, Confidence: 1},
+		"pkg/COPYING": {Name: "GPL-3.0", Type: Restricted, Confidence: 0.8},
+		"pkg/LICENSE": {Name: "MIT", Type: Notice, Confidence: 0.95},
+	}}
+	candidates, err := FindAll(filepath.Join(dir, "pkg"), dir, classifier)
+	if err != nil {
+		t.Fatalf("FindAll() = (_, %v), want (_, nil)", err)
+	}
+	type summary struct {
+		Path, Name string
+		Depth      int
+		Identified bool
+	}
+	var got []summary
+	for _, c := range candidates {
+		relPath, err := filepath.Rel(dir, c.Path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		got = append(got, summary{filepath.ToSlash(relPath), c.Name, c.Depth, c.Err == nil})
+	}
+	want := []summary{
+		{"pkg/LICENSE", "MIT", 0, true},
+		{"pkg/COPYING", "GPL-3.0", 0, true},
+		{"LICENSE", "Apache-2.0", 1, true},
+		{"pkg/NOTICE", "", 0, false},
+		{"README.md", "", 1, false},
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("FindAll(): diff (-want +got)\n%s", diff)
+	}
+
+	candidates, err = FindAll(filepath.Join(dir, "pkg"), filepath.Join(dir, "pkg"), confidenceStub{dir: dir})
+	if !errors.Is(err, ErrNoLicenseFound) || len(candidates) != 3 {
+		t.Errorf("FindAll() of unidentified licenses = (%d candidates, %v), want (3 candidates, ErrNoLicenseFound)", len(candidates), err)
+	}
+}
+
+func TestFinder(t *testing.T) {
+	dir := t.TempDir()
+	for _, path := range []string{"LICENSE", "a/a.go", "b/b.go"} {
+		path = filepath.Join(dir, filepath.FromSlash(path))
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := ioutil.WriteFile(path, nil, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	classifier := &countingClassifier{}
+	f := newFinder(classifier, ScanLimits{})
+	for _, test := range []struct {
+		dir       string
+		wantDepth int
+	}{
+		{dir: "a", wantDepth: 1},
+		{dir: "b", wantDepth: 1},
+		{dir: ".", wantDepth: 0},
+		{dir: "a", wantDepth: 1},
+	} {
+		candidates, err := f.findAll(filepath.Join(dir, test.dir), dir)
+		if err != nil {
+			t.Fatalf("findAll(%s) = (_, %v), want (_, nil)", test.dir, err)
+		}
+		if len(candidates) != 1 || candidates[0].Path != filepath.Join(dir, "LICENSE") || candidates[0].Depth != test.wantDepth {
+			t.Errorf("findAll(%s) = %+v, want LICENSE at depth %d", test.dir, candidates, test.wantDepth)
+		}
+	}
+	if classifier.calls != 1 {
+		t.Errorf("findAll() classified %d files, want the shared LICENSE once", classifier.calls)
+	}
+}
+
+func TestFindAllFS(t *testing.T) {
+	mit := &fstest.MapFile{Data: []byte("Permission is hereby granted")}
+	fsys := fstest.MapFS{
+		"example.com/mod@v1.0.0/LICENSE":                  mit,
+		"example.com/mod@v1.0.0/.golicensesignore":        {Data: []byte("third_party/\n")},
+		"example.com/mod@v1.0.0/pkg/README.md":            {Data: []byte("# pkg")},
+		"example.com/mod@v1.0.0/pkg/main.go":              {Data: []byte("package pkg")},
+		"example.com/mod@v1.0.0/third_party/lib/LICENSE":  mit,
+		"example.com/mod@v1.0.0/third_party/lib/lib.go":   {Data: []byte("package lib")},
+		"example.com/other@v1.0.0/LICENSE":                mit,
+		"example.com/other@v1.0.0/internal/broken/NOTICE": {Data: []byte("broken")},
+	}
+	candidates, err := FindAllFS(fsys, "example.com/mod@v1.0.0/pkg", "example.com/mod@v1.0.0", keywordClassifier{})
+	if err != nil {
+		t.Fatalf("FindAllFS() = (_, %v), want (_, nil)", err)
+	}
+	type summary struct {
+		Path, Name string
+		Depth      int
+		Identified bool
+	}
+	var got []summary
+	for _, c := range candidates {
+		got = append(got, summary{c.Path, c.Name, c.Depth, c.Err == nil})
+	}
+	want := []summary{
+		{"example.com/mod@v1.0.0/LICENSE", "MIT", 1, true},
+		{"example.com/mod@v1.0.0/pkg/README.md", "", 0, false},
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("FindAllFS(): diff (-want +got)\n%s", diff)
+	}
+
+	if path, err := FindFS(fsys, "example.com/mod@v1.0.0/third_party/lib", "example.com/mod@v1.0.0", keywordClassifier{}); err != nil || path != "example.com/mod@v1.0.0/LICENSE" {
+		t.Errorf("FindFS() skipping ignored license = (%q, %v), want (%q, nil)", path, err, "example.com/mod@v1.0.0/LICENSE")
+	}
+	if path, err := FindFS(fsys, "example.com/other@v1.0.0/internal/broken", ".", keywordClassifier{}); err != nil || path != "example.com/other@v1.0.0/LICENSE" {
+		t.Errorf("FindFS() = (%q, %v), want (%q, nil)", path, err, "example.com/other@v1.0.0/LICENSE")
+	}
+	if _, err := FindFS(fsys, "example.com/other@v1.0.0", "example.com/mod@v1.0.0", keywordClassifier{}); err == nil {
+		t.Errorf("FindFS() outside rootDir = nil error, want error")
+	}
+}
@@ -0,0 +1,187 @@
+// Copyright 2022 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package licenses
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"strings"
+
+	"github.com/Bobgy/go-licenses/v2/internal/logging"
+	"golang.org/x/tools/go/packages"
+)
+
+// environ returns the environment of go commands run with these options.
+func (o Options) environ() []string {
+	env := os.Environ()
+	if o.GOOS != "" {
+		env = append(env, "GOOS="+o.GOOS)
+	}
+	if o.GOARCH != "" {
+		env = append(env, "GOARCH="+o.GOARCH)
+	}
+	// Later values take precedence over earlier ones.
+	return append(env, o.Env...)
+}
+
+// goCommand returns a go command run in dir with the environment of these
+// options. An empty dir means Dir of these options.
+func (o Options) goCommand(ctx context.Context, dir string, args ...string) *exec.Cmd {
+	if dir == "" {
+		dir = o.Dir
+	}
+	cmd := exec.CommandContext(ctx, "go", args...)
+	cmd.Dir = dir
+	cmd.Env = o.environ()
+	return cmd
+}
+
+// goEnv returns the value of a Go environment variable, as seen from dir.
+// An empty dir means Options.Dir.
+func goEnv(ctx context.Context, opts Options, dir string, name string) (string, error) {
+	out, err := opts.goCommand(ctx, dir, "env", name).Output()
+	if err != nil {
+		return "", fmt.Errorf("go env %s: %w", name, err)
+	}
+	return strings.TrimSpace(string(out)), nil
+}
+
+// PackageDirs returns the directory of each package matching patterns, by
+// import path.
+func PackageDirs(ctx context.Context, opts Options, patterns ...string) (map[string]string, error) {
+	args := append([]string{"list", "-f", "{{.ImportPath}}\t{{.Dir}}"}, opts.packagesConfig(ctx).BuildFlags...)
+	args = append(append(args, "--"), patterns...)
+	out, err := opts.goCommand(ctx, "", args...).Output()
+	if err != nil {
+		return nil, fmt.Errorf("go list %s: %w", strings.Join(patterns, " "), err)
+	}
+	dirs := make(map[string]string)
+	for _, line := range strings.Split(string(out), "\n") {
+		if i := strings.Index(line, "\t"); i > 0 {
+			dirs[line[:i]] = line[i+1:]
+		}
+	}
+	return dirs, nil
+}
+
+// ListModules lists the modules in the build list of the main module in dir,
+// with `go list -m -json all`, including the main module. Replace directives
+// are applied, see Module.
+func ListModules(ctx context.Context, opts Options, dir string) ([]*Module, error) {
+	args := []string{"list", "-m", "-json"}
+	if mod := opts.mod(); mod != "" {
+		args = append(args, "-mod="+mod)
+	}
+	out, err := opts.goCommand(ctx, dir, append(args, "all")...).Output()
+	if err != nil {
+		return nil, fmt.Errorf("go list -m -json all: %w", err)
+	}
+	return parseModules(out)
+}
+
+// parseModules parses the output of `go list -m -json`.
+func parseModules(out []byte) ([]*Module, error) {
+	var modules []*Module
+	dec := json.NewDecoder(bytes.NewReader(out))
+	for {
+		var m packages.Module
+		if err := dec.Decode(&m); err == io.EOF {
+			break
+		} else if err != nil {
+			return nil, fmt.Errorf("parsing go list output: %w", err)
+		}
+		if m.Error != nil {
+			logging.Module(m.Path).Warningf("module %s: %s", m.Path, m.Error.Err)
+		}
+		modules = append(modules, newModule(&m))
+	}
+	return modules, nil
+}
+
+// MainModule returns the path of the main module, e.g. the module in the
+// current directory.
+func MainModule(ctx context.Context, opts Options) (string, error) {
+	out, err := opts.goCommand(ctx, "", "list", "-m", "-f", "{{.Path}}").Output()
+	if err != nil {
+		return "", fmt.Errorf("go list -m: %w", err)
+	}
+	return strings.TrimSpace(strings.SplitN(string(out), "\n", 2)[0]), nil
+}
+
+// MainPackages returns the import paths of the main packages matching
+// patterns, e.g. "./...".
+func MainPackages(ctx context.Context, opts Options, patterns ...string) ([]string, error) {
+	args := append([]string{"list", "-f", `{{if eq .Name "main"}}{{.ImportPath}}{{end}}`}, opts.packagesConfig(ctx).BuildFlags...)
+	args = append(append(args, "--"), patterns...)
+	out, err := opts.goCommand(ctx, "", args...).Output()
+	if err != nil {
+		return nil, fmt.Errorf("go list %s: %w", strings.Join(patterns, " "), err)
+	}
+	var mains []string
+	for _, line := range strings.Split(string(out), "\n") {
+		if line != "" {
+			mains = append(mains, line)
+		}
+	}
+	return mains, nil
+}
+
+// downloadModule downloads a module into the module cache by running
+// `go mod download`, and returns the module's directory. When the zip of the
+// module is in the download cache of modCache, e.g. restored by CI from a
+// previous run, it is extracted without network access first, and only
+// downloaded if that fails.
+func downloadModule(ctx context.Context, opts Options, modCache, path, version string) (string, error) {
+	if zip := cachedModuleZip(modCache, path, version); zip != "" {
+		offline := opts
+		offline.Env = append(append([]string(nil), opts.Env...), "GOPROXY=off")
+		dir, err := goModDownload(ctx, offline, path, version)
+		if err == nil {
+			return dir, nil
+		}
+		logging.Module(path).Infof("Downloading %s@%s, extracting %s failed: %v", path, version, zip, err)
+	}
+	return goModDownload(ctx, opts, path, version)
+}
+
+// goModDownload runs `go mod download` for a module, and returns the
+// module's directory.
+func goModDownload(ctx context.Context, opts Options, path, version string) (string, error) {
+	out, err := opts.goCommand(ctx, "", "mod", "download", "-json", path+"@"+version).Output()
+	// go mod download reports errors in the Error field with a non-zero
+	// exit code, so parse the output before checking err.
+	var result struct {
+		Dir   string
+		Error string
+	}
+	if jsonErr := json.Unmarshal(out, &result); jsonErr != nil {
+		if err != nil {
+			return "", fmt.Errorf("go mod download %s@%s: %w", path, version, err)
+		}
+		return "", fmt.Errorf("go mod download %s@%s: parsing output: %w", path, version, jsonErr)
+	}
+	if result.Error != "" {
+		return "", fmt.Errorf("go mod download %s@%s: %s", path, version, result.Error)
+	}
+	if err != nil {
+		return "", fmt.Errorf("go mod download %s@%s: %w", path, version, err)
+	}
+	return result.Dir, nil
+}
@@ -0,0 +1,218 @@
+// Copyright 2022 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package licenses
+
+import (
+	"context"
+	"io"
+	"math"
+	"net"
+	"net/http"
+	"sync"
+	"time"
+
+	"go.opencensus.io/plugin/ochttp"
+)
+
+const (
+	// DefaultMaxRequestsPerHost is the number of concurrent requests of the
+	// default HTTP client to a single host, e.g. github.com.
+	DefaultMaxRequestsPerHost = 8
+	// DefaultRequestsPerSecond is the rate of requests of the default HTTP
+	// client to a single host, e.g. raw.githubusercontent.com, which throttles
+	// or temporarily blocks clients exceeding its limits.
+	DefaultRequestsPerSecond = 10
+	// httpTimeout is the timeout of requests of the default HTTP client.
+	httpTimeout = 20 * time.Second
+)
+
+var (
+	sharedHTTPClientsMu sync.Mutex
+	// sharedHTTPClients are the default HTTP clients by rate of requests.
+	sharedHTTPClients = make(map[float64]*http.Client)
+)
+
+// sharedHTTPClient returns the default HTTP client making requestsPerSecond
+// requests to each host, see Options.RequestsPerSecond. It is shared by all
+// libraries whose options do not set Options.HTTPClient, so that connections
+// and rate limits are shared for the whole run.
+func sharedHTTPClient(requestsPerSecond float64) *http.Client {
+	if requestsPerSecond == 0 {
+		requestsPerSecond = DefaultRequestsPerSecond
+	}
+	sharedHTTPClientsMu.Lock()
+	defer sharedHTTPClientsMu.Unlock()
+	c, ok := sharedHTTPClients[requestsPerSecond]
+	if !ok {
+		c = NewHTTPClient(DefaultMaxRequestsPerHost, requestsPerSecond)
+		sharedHTTPClients[requestsPerSecond] = c
+	}
+	return c
+}
+
+// NewHTTPClient returns an HTTP client for Options.HTTPClient, which pools
+// connections with keep-alives and HTTP/2. It makes at most maxPerHost
+// concurrent requests and requestsPerSecond requests per second to a single
+// host, or unlimited if they are not positive. Requests time out after 20
+// seconds.
+func NewHTTPClient(maxPerHost int, requestsPerSecond float64) *http.Client {
+	transport := &http.Transport{
+		Proxy: http.ProxyFromEnvironment,
+		DialContext: (&net.Dialer{
+			Timeout:   30 * time.Second,
+			KeepAlive: 30 * time.Second,
+		}).DialContext,
+		ForceAttemptHTTP2:     true,
+		MaxIdleConns:          100,
+		MaxIdleConnsPerHost:   DefaultMaxRequestsPerHost,
+		IdleConnTimeout:       90 * time.Second,
+		TLSHandshakeTimeout:   10 * time.Second,
+		ExpectContinueTimeout: time.Second,
+	}
+	if maxPerHost > 0 {
+		transport.MaxIdleConnsPerHost = maxPerHost
+	}
+	return &http.Client{
+		Transport: &hostLimiter{
+			base:  &ochttp.Transport{Base: transport},
+			limit: maxPerHost,
+			rate:  requestsPerSecond,
+			hosts: make(map[string]*hostLimit),
+		},
+		Timeout: httpTimeout,
+	}
+}
+
+// hostLimiter limits the number and rate of requests to each host. Unlike
+// http.Transport.MaxConnsPerHost, it also limits requests multiplexed over a
+// single HTTP/2 connection.
+type hostLimiter struct {
+	base http.RoundTripper
+	// limit is the number of concurrent requests to a host, unlimited if
+	// not positive.
+	limit int
+	// rate is the number of requests per second to a host, unlimited if
+	// not positive.
+	rate  float64
+	mu    sync.Mutex
+	hosts map[string]*hostLimit
+}
+
+// hostLimit are the limits of requests to a host.
+type hostLimit struct {
+	// sem is a semaphore with a slot per concurrent request, or nil.
+	sem chan struct{}
+	// bucket limits the rate of requests, or is nil.
+	bucket *tokenBucket
+}
+
+func (l *hostLimiter) RoundTrip(req *http.Request) (*http.Response, error) {
+	if l.limit <= 0 && l.rate <= 0 {
+		return l.base.RoundTrip(req)
+	}
+	l.mu.Lock()
+	h, ok := l.hosts[req.URL.Host]
+	if !ok {
+		h = &hostLimit{}
+		if l.limit > 0 {
+			h.sem = make(chan struct{}, l.limit)
+		}
+		if l.rate > 0 {
+			h.bucket = newTokenBucket(l.rate)
+		}
+		l.hosts[req.URL.Host] = h
+	}
+	l.mu.Unlock()
+	if h.bucket != nil {
+		if err := h.bucket.wait(req.Context()); err != nil {
+			return nil, err
+		}
+	}
+	if h.sem == nil {
+		return l.base.RoundTrip(req)
+	}
+	select {
+	case h.sem <- struct{}{}:
+	case <-req.Context().Done():
+		return nil, req.Context().Err()
+	}
+	resp, err := l.base.RoundTrip(req)
+	if err != nil {
+		<-h.sem
+		return nil, err
+	}
+	// The request is in flight until its body is closed.
+	resp.Body = &releasingBody{ReadCloser: resp.Body, release: func() { <-h.sem }}
+	return resp, nil
+}
+
+// tokenBucket is a token bucket rate limiter, allowing bursts of up to one
+// second of requests.
+type tokenBucket struct {
+	mu sync.Mutex
+	// rate is the number of tokens added per second.
+	rate float64
+	// burst is the capacity of the bucket.
+	burst float64
+	// tokens are the tokens in the bucket at last, negative when requests
+	// have reserved future tokens.
+	tokens float64
+	last   time.Time
+}
+
+func newTokenBucket(rate float64) *tokenBucket {
+	burst := math.Max(1, math.Floor(rate))
+	return &tokenBucket{rate: rate, burst: burst, tokens: burst, last: time.Now()}
+}
+
+// wait takes a token from the bucket, waiting until one is available or ctx
+// is done.
+func (b *tokenBucket) wait(ctx context.Context) error {
+	b.mu.Lock()
+	now := time.Now()
+	b.tokens = math.Min(b.burst, b.tokens+now.Sub(b.last).Seconds()*b.rate)
+	b.last = now
+	b.tokens--
+	delay := time.Duration(-b.tokens / b.rate * float64(time.Second))
+	b.mu.Unlock()
+	if delay <= 0 {
+		return nil
+	}
+	t := time.NewTimer(delay)
+	defer t.Stop()
+	select {
+	case <-t.C:
+		return nil
+	case <-ctx.Done():
+		// Return the reserved token.
+		b.mu.Lock()
+		b.tokens++
+		b.mu.Unlock()
+		return ctx.Err()
+	}
+}
+
+// releasingBody is a response body that calls release once when closed.
+type releasingBody struct {
+	io.ReadCloser
+	once    sync.Once
+	release func()
+}
+
+func (b *releasingBody) Close() error {
+	err := b.ReadCloser.Close()
+	b.once.Do(b.release)
+	return err
+}
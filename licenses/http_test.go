@@ -0,0 +1,109 @@
+// Copyright 2022 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package licenses
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestHostLimiter(t *testing.T) {
+	var mu sync.Mutex
+	inFlight, maxInFlight := 0, 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		inFlight++
+		if inFlight > maxInFlight {
+			maxInFlight = inFlight
+		}
+		mu.Unlock()
+		time.Sleep(10 * time.Millisecond)
+		mu.Lock()
+		inFlight--
+		mu.Unlock()
+		w.Write([]byte("MIT License"))
+	}))
+	defer server.Close()
+
+	client := &http.Client{Transport: &hostLimiter{
+		base:  http.DefaultTransport,
+		limit: 2,
+		hosts: make(map[string]*hostLimit),
+	}}
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			content, err := download(client, server.URL)
+			if err != nil || content != "MIT License" {
+				t.Errorf("download() = (%q, %v), want (%q, nil)", content, err, "MIT License")
+			}
+		}()
+	}
+	wg.Wait()
+	if maxInFlight > 2 {
+		t.Errorf("%d concurrent requests to a host, want at most 2", maxInFlight)
+	}
+	if maxInFlight == 0 {
+		t.Errorf("no requests reached the server")
+	}
+}
+
+func TestHostLimiterReleasesOnError(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.NotFound(w, r)
+	}))
+	defer server.Close()
+	client := &http.Client{Transport: &hostLimiter{
+		base:  http.DefaultTransport,
+		limit: 1,
+		hosts: make(map[string]*hostLimit),
+	}}
+	// A request still holding the only slot would block the next ones.
+	for i := 0; i < 3; i++ {
+		if _, err := download(client, server.URL); err == nil {
+			t.Fatalf("download() of a missing page = nil error, want error")
+		}
+	}
+}
+
+func TestTokenBucket(t *testing.T) {
+	b := newTokenBucket(100)
+	start := time.Now()
+	// A burst of one second of requests is allowed, the next ones wait.
+	for i := 0; i < 110; i++ {
+		if err := b.wait(context.Background()); err != nil {
+			t.Fatalf("wait() = %v", err)
+		}
+	}
+	if elapsed := time.Since(start); elapsed < 90*time.Millisecond {
+		t.Errorf("110 requests at 100 per second took %v, want at least 100ms", elapsed)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	slow := newTokenBucket(0.1)
+	if err := slow.wait(ctx); err != nil {
+		t.Errorf("wait() of the first token = %v, want nil", err)
+	}
+	if err := slow.wait(ctx); err != context.Canceled {
+		t.Errorf("wait() of a cancelled request = %v, want %v", err, context.Canceled)
+	}
+}
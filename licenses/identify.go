@@ -0,0 +1,121 @@
+// Copyright 2022 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package licenses
+
+import (
+	"context"
+	"regexp"
+	"sync"
+	"time"
+
+	"github.com/Bobgy/go-licenses/v2/internal/logging"
+	"github.com/Bobgy/go-licenses/v2/internal/parallel"
+)
+
+// spdxIDRegexp matches license names that are well-formed SPDX license IDs,
+// unlike names of licenses without one, e.g. "Custom License".
+var spdxIDRegexp = regexp.MustCompile(`^[A-Za-z0-9.+-]+$`)
+
+// identifyLibraries identifies the license of each library concurrently,
+// setting its LicenseName, SPDXID, LicenseType and Confidence. It calls f, if
+// set, with each library once it and all libraries before it are identified,
+// one at a time and in order. The first error of f or ctx skips the remaining
+// libraries and is returned.
+func identifyLibraries(ctx context.Context, classifier Classifier, opts Options, libraries []*Library, f func(*Library) error) error {
+	var mu sync.Mutex
+	var err error
+	done := 0
+	// identified libraries from next on wait for the libraries before them
+	// before being passed to f.
+	identified := make([]bool, len(libraries))
+	next := 0
+	start := time.Now()
+	parallel.For(opts.jobs(), len(libraries), func(i int) {
+		mu.Lock()
+		stopped := err != nil
+		mu.Unlock()
+		if stopped {
+			return
+		}
+		identifyLibrary(classifier, libraries[i])
+		mu.Lock()
+		defer mu.Unlock()
+		if err != nil {
+			return
+		}
+		done++
+		opts.progress(PhaseClassifyingLicenses, done, len(libraries))
+		if err = ctx.Err(); err != nil || f == nil {
+			return
+		}
+		identified[i] = true
+		for ; next < len(libraries) && identified[next] && err == nil; next++ {
+			err = f(libraries[next])
+		}
+	})
+	if err == StopWalk {
+		opts.measure(PhaseClassifyingLicenses, start, done, nil)
+	} else {
+		opts.measure(PhaseClassifyingLicenses, start, done, err)
+	}
+	return err
+}
+
+// identify returns the name, type and confidence of the license in a file.
+// Classifiers not reporting confidence have confidence 1.
+func identify(classifier Classifier, licensePath string) (string, Type, float64, error) {
+	if c, ok := classifier.(ConfidenceClassifier); ok {
+		return c.IdentifyConfidence(licensePath)
+	}
+	name, typ, err := classifier.Identify(licensePath)
+	return name, typ, 1, err
+}
+
+// identifyLibrary identifies the license of a library, reusing the
+// classification of its best license candidate, if any.
+func identifyLibrary(classifier Classifier, lib *Library) {
+	lib.LicenseType = Unknown
+	if lib.LicensePath == "" {
+		return
+	}
+	var (
+		name       string
+		typ        Type
+		confidence float64
+		err        error
+	)
+	if c := lib.LicenseCandidates; len(c) > 0 && c[0].Path == lib.LicensePath {
+		name, typ, confidence, err = c[0].Name, c[0].Type, c[0].Confidence, c[0].Err
+	} else {
+		name, typ, confidence, err = identify(classifier, lib.LicensePath)
+	}
+	if err != nil {
+		logging.Module(lib.Name()).Infof("Cannot identify license in %s: %v", lib.LicensePath, err)
+		return
+	}
+	lib.LicenseName, lib.LicenseType, lib.Confidence = name, typ, confidence
+	if spdxIDRegexp.MatchString(name) {
+		lib.SPDXID = name
+	}
+}
+
+// Identify returns the name and type of the license of a library, as
+// identified by Libraries, or otherwise by classifier.
+func (l *Library) Identify(classifier Classifier) (string, Type, error) {
+	if l.LicenseName != "" {
+		return l.LicenseName, l.LicenseType, nil
+	}
+	return classifier.Identify(l.LicensePath)
+}
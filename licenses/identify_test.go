@@ -0,0 +1,143 @@
+// Copyright 2022 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package licenses
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestIdentifyLibraries(t *testing.T) {
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	classifier := classifierStub{
+		licenseNames: map[string]string{"mit/LICENSE": "MIT", "custom/LICENSE": "Custom License"},
+		licenseTypes: map[string]Type{"mit/LICENSE": Notice, "custom/LICENSE": Restricted},
+		errors:       map[string]error{"unknown/LICENSE": errors.New("unknown license")},
+	}
+	libs := []*Library{
+		{LicensePath: filepath.Join(wd, "mit/LICENSE"), Packages: []string{"example.com/mit"}},
+		{LicensePath: filepath.Join(wd, "custom/LICENSE"), Packages: []string{"example.com/custom"}},
+		{LicensePath: filepath.Join(wd, "unknown/LICENSE"), Packages: []string{"example.com/unknown"}},
+		{Packages: []string{"example.com/none"}},
+	}
+	if err := identifyLibraries(context.Background(), classifier, Options{Jobs: 2}, libs, nil); err != nil {
+		t.Fatalf("identifyLibraries() = %v", err)
+	}
+	for _, test := range []struct {
+		lib            *Library
+		wantName       string
+		wantSPDXID     string
+		wantType       Type
+		wantConfidence float64
+	}{
+		{lib: libs[0], wantName: "MIT", wantSPDXID: "MIT", wantType: Notice, wantConfidence: 1},
+		{lib: libs[1], wantName: "Custom License", wantType: Restricted, wantConfidence: 1},
+		{lib: libs[2], wantType: Unknown},
+		{lib: libs[3], wantType: Unknown},
+	} {
+		lib := test.lib
+		if lib.LicenseName != test.wantName || lib.SPDXID != test.wantSPDXID || lib.LicenseType != test.wantType || lib.Confidence != test.wantConfidence {
+			t.Errorf("identifyLibraries() set %s to (%q, %q, %q, %v), want (%q, %q, %q, %v)", lib.Name(), lib.LicenseName, lib.SPDXID, lib.LicenseType, lib.Confidence, test.wantName, test.wantSPDXID, test.wantType, test.wantConfidence)
+		}
+	}
+	if name, typ, err := libs[0].Identify(classifierStub{}); err != nil || name != "MIT" || typ != Notice {
+		t.Errorf("Identify() = (%q, %q, %v), want identified license (MIT, notice, nil)", name, typ, err)
+	}
+	if _, _, err := libs[2].Identify(classifier); err == nil {
+		t.Errorf("Identify() of unidentified license = nil error, want error")
+	}
+}
+
+func TestIdentifyLibrariesStop(t *testing.T) {
+	var libs []*Library
+	for i := 0; i < 10; i++ {
+		libs = append(libs, &Library{Packages: []string{fmt.Sprintf("example.com/lib%d", i)}})
+	}
+	var walked []*Library
+	err := identifyLibraries(context.Background(), classifierStub{}, Options{Jobs: 1}, libs, func(lib *Library) error {
+		walked = append(walked, lib)
+		if len(walked) == 3 {
+			return StopWalk
+		}
+		return nil
+	})
+	if err != StopWalk {
+		t.Errorf("identifyLibraries() = %v, want StopWalk", err)
+	}
+	if len(walked) != 3 {
+		t.Errorf("identifyLibraries() walked %d libraries, want 3", len(walked))
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if err := identifyLibraries(ctx, classifierStub{}, Options{}, libs, nil); err != context.Canceled {
+		t.Errorf("identifyLibraries() with canceled context = %v, want %v", err, context.Canceled)
+	}
+}
+
+func TestIdentifyLibrariesOrder(t *testing.T) {
+	var libs []*Library
+	for i := 0; i < 20; i++ {
+		libs = append(libs, &Library{Packages: []string{fmt.Sprintf("example.com/lib%02d", i)}})
+	}
+	var walked []*Library
+	err := identifyLibraries(context.Background(), classifierStub{}, Options{Jobs: 4}, libs, func(lib *Library) error {
+		walked = append(walked, lib)
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("identifyLibraries() = %v", err)
+	}
+	if len(walked) != len(libs) {
+		t.Fatalf("identifyLibraries() walked %d libraries, want %d", len(walked), len(libs))
+	}
+	for i, lib := range walked {
+		if lib != libs[i] {
+			t.Errorf("identifyLibraries() walked %s at %d, want %s", lib.Name(), i, libs[i].Name())
+		}
+	}
+}
+
+func TestIdentifyLibrariesMetrics(t *testing.T) {
+	var libs []*Library
+	for i := 0; i < 5; i++ {
+		libs = append(libs, &Library{Packages: []string{fmt.Sprintf("example.com/lib%d", i)}})
+	}
+	var got []Measurement
+	opts := Options{Jobs: 2, Metrics: func(m Measurement) {
+		got = append(got, m)
+	}}
+	err := identifyLibraries(context.Background(), classifierStub{}, opts, libs, func(lib *Library) error {
+		if lib == libs[2] {
+			return StopWalk
+		}
+		return nil
+	})
+	if err != StopWalk {
+		t.Fatalf("identifyLibraries() = %v, want StopWalk", err)
+	}
+	if len(got) != 1 {
+		t.Fatalf("identifyLibraries() reported %d measurements, want 1: %+v", len(got), got)
+	}
+	if m := got[0]; m.Phase != PhaseClassifyingLicenses || m.Items == 0 || m.Items > len(libs) || m.Err != nil || m.Duration < 0 {
+		t.Errorf("identifyLibraries() reported %+v, want %q of 1 to %d items without error", m, PhaseClassifyingLicenses, len(libs))
+	}
+}
@@ -0,0 +1,158 @@
+// Copyright 2022 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package licenses
+
+import (
+	"io/ioutil"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// IgnoreFileName is the name of files in module directories listing paths,
+// relative to the module directory, that are not searched for licenses, e.g.
+// test fixtures or generated data with license-looking text. Patterns follow
+// the syntax of .gitignore files.
+const IgnoreFileName = ".golicensesignore"
+
+// ignorePattern is a pattern of an ignore file.
+type ignorePattern struct {
+	// elems are the slash-separated elements of the pattern.
+	elems []string
+	// anchored patterns only match paths relative to the module directory,
+	// others match at any depth.
+	anchored bool
+	// dirOnly patterns, with a trailing slash, only match directories.
+	dirOnly bool
+	// negated patterns, starting with "!", include paths again.
+	negated bool
+}
+
+// ignoreFile holds the patterns of an ignore file of a module directory.
+type ignoreFile struct {
+	dir      string
+	patterns []ignorePattern
+}
+
+// loadIgnoreFile reads the ignore file of a module directory. It returns nil
+// if there is none.
+func loadIgnoreFile(dir string) (*ignoreFile, error) {
+	content, err := ioutil.ReadFile(filepath.Join(dir, IgnoreFileName))
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return parseIgnoreFile(dir, string(content)), nil
+}
+
+// parseIgnoreFile parses the content of an ignore file of a directory.
+func parseIgnoreFile(dir, content string) *ignoreFile {
+	f := &ignoreFile{dir: dir}
+	for _, line := range strings.Split(content, "\n") {
+		line = strings.TrimRight(line, " \t\r")
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		var p ignorePattern
+		if strings.HasPrefix(line, "!") {
+			p.negated, line = true, line[1:]
+		} else if strings.HasPrefix(line, `\`) {
+			// An escaped leading "!" or "#".
+			line = line[1:]
+		}
+		if strings.HasSuffix(line, "/") {
+			p.dirOnly, line = true, strings.TrimRight(line, "/")
+		}
+		// A pattern with a slash, other than a trailing one, is relative to
+		// the directory of the ignore file.
+		p.anchored = strings.Contains(line, "/")
+		line = strings.TrimPrefix(line, "/")
+		if line == "" {
+			continue
+		}
+		p.elems = strings.Split(line, "/")
+		f.patterns = append(f.patterns, p)
+	}
+	return f
+}
+
+// ignored reports whether a path is ignored. Paths in ignored directories
+// are ignored too, like with .gitignore files.
+func (f *ignoreFile) ignored(p string, isDir bool) bool {
+	if f == nil {
+		return false
+	}
+	rel, err := filepath.Rel(f.dir, p)
+	if err != nil || rel == "." || strings.HasPrefix(rel, "..") {
+		return false
+	}
+	elems := strings.Split(filepath.ToSlash(rel), "/")
+	for i := 1; i <= len(elems); i++ {
+		if f.match(elems[:i], i < len(elems) || isDir) {
+			return true
+		}
+	}
+	return false
+}
+
+// match reports whether the last of the patterns matching a path excludes it.
+func (f *ignoreFile) match(elems []string, isDir bool) bool {
+	ignored := false
+	for _, p := range f.patterns {
+		if p.dirOnly && !isDir {
+			continue
+		}
+		if p.matches(elems) {
+			ignored = !p.negated
+		}
+	}
+	return ignored
+}
+
+// matches reports whether the pattern matches a path, as path elements.
+func (p ignorePattern) matches(elems []string) bool {
+	if p.anchored {
+		return matchIgnoreElems(p.elems, elems)
+	}
+	// Patterns without a slash match the name of a file or directory.
+	ok, _ := path.Match(p.elems[0], elems[len(elems)-1])
+	return ok
+}
+
+// matchIgnoreElems matches path elements by pattern elements, where "**"
+// matches any number of elements.
+func matchIgnoreElems(pattern, elems []string) bool {
+	if len(pattern) == 0 {
+		return len(elems) == 0
+	}
+	if pattern[0] == "**" {
+		for i := 0; i <= len(elems); i++ {
+			if matchIgnoreElems(pattern[1:], elems[i:]) {
+				return true
+			}
+		}
+		return false
+	}
+	if len(elems) == 0 {
+		return false
+	}
+	if ok, _ := path.Match(pattern[0], elems[0]); !ok {
+		return false
+	}
+	return matchIgnoreElems(pattern[1:], elems[1:])
+}
@@ -0,0 +1,63 @@
+// Copyright 2022 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package licenses
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+func TestIgnoreFile(t *testing.T) {
+	f := parseIgnoreFile("/mod", `# Test fixtures.
+testdata/
+/generated/*.txt
+**/golden/LICENSE*
+COPYING
+*.gen
+!keep.gen
+\#hash
+`)
+	for _, test := range []struct {
+		path  string
+		isDir bool
+		want  bool
+	}{
+		{path: "testdata", isDir: true, want: true},
+		{path: "testdata/LICENSE", want: true},
+		{path: "pkg/testdata/fixture/LICENSE", want: true},
+		{path: "testdata", want: false},
+		{path: "generated/LICENSE.txt", want: true},
+		{path: "pkg/generated/LICENSE.txt", want: false},
+		{path: "golden/LICENSE", want: true},
+		{path: "a/b/golden/LICENSE.md", want: true},
+		{path: "a/golden/NOTICE", want: false},
+		{path: "COPYING", want: true},
+		{path: "third_party/COPYING", want: true},
+		{path: "data.gen", want: true},
+		{path: "keep.gen", want: false},
+		{path: "#hash", want: true},
+		{path: "LICENSE", want: false},
+		{path: "../LICENSE", want: false},
+	} {
+		path := filepath.Join("/mod", filepath.FromSlash(test.path))
+		if got := f.ignored(path, test.isDir); got != test.want {
+			t.Errorf("ignored(%q, %t) = %t, want %t", test.path, test.isDir, got, test.want)
+		}
+	}
+	var none *ignoreFile
+	if none.ignored("/mod/testdata", true) {
+		t.Errorf("ignored() without ignore file = true, want false")
+	}
+}
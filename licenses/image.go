@@ -0,0 +1,113 @@
+// Copyright 2022 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package licenses
+
+import (
+	"archive/tar"
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"github.com/Bobgy/go-licenses/v2/internal/logging"
+)
+
+// placeholderCommand is the command of containers created to export images.
+// Containers are never started, but docker create fails without a command
+// for images without one, e.g. scratch and distroless images.
+const placeholderCommand = "/go-licenses-placeholder"
+
+// ExtractImage pulls a container image with docker, if it is not present
+// locally, and extracts the executable files of its filesystem into dir.
+// Only executables are extracted, because Go binaries are all that is
+// reported for an image. It requires a running docker daemon.
+func ExtractImage(ctx context.Context, image string, dir string) error {
+	var stderr bytes.Buffer
+	create := exec.CommandContext(ctx, "docker", "create", image, placeholderCommand)
+	create.Stderr = &stderr
+	out, err := create.Output()
+	if err != nil {
+		return fmt.Errorf("docker create %s: %w: %s", image, err, stderr.String())
+	}
+	container := strings.TrimSpace(string(out))
+	defer func() {
+		if err := exec.Command("docker", "rm", container).Run(); err != nil {
+			logging.Warningf("Failed to remove container %s: %v", container, err)
+		}
+	}()
+
+	stderr.Reset()
+	export := exec.CommandContext(ctx, "docker", "export", container)
+	export.Stderr = &stderr
+	r, err := export.StdoutPipe()
+	if err != nil {
+		return err
+	}
+	if err := export.Start(); err != nil {
+		return fmt.Errorf("docker export %s: %w", container, err)
+	}
+	if err := extractExecutables(r, dir); err != nil {
+		// Unblock docker export, which may still be writing.
+		io.Copy(ioutil.Discard, r)
+		export.Wait()
+		return fmt.Errorf("extracting image %s: %w", image, err)
+	}
+	if err := export.Wait(); err != nil {
+		return fmt.Errorf("docker export %s: %w: %s", container, err, stderr.String())
+	}
+	return nil
+}
+
+// extractExecutables extracts regular files with any executable bit set from
+// a tar archive into dir. Other entries, including symlinks, are skipped.
+func extractExecutables(r io.Reader, dir string) error {
+	tr := tar.NewReader(r)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if hdr.Typeflag != tar.TypeReg || hdr.FileInfo().Mode().Perm()&0111 == 0 {
+			continue
+		}
+		name := filepath.Clean(filepath.FromSlash(hdr.Name))
+		if filepath.IsAbs(name) || name == ".." || strings.HasPrefix(name, ".."+string(filepath.Separator)) {
+			return fmt.Errorf("invalid path %q in archive", hdr.Name)
+		}
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			return err
+		}
+		f, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0755)
+		if err != nil {
+			return err
+		}
+		_, err = io.Copy(f, tr)
+		if closeErr := f.Close(); err == nil {
+			err = closeErr
+		}
+		if err != nil {
+			return err
+		}
+	}
+}
@@ -0,0 +1,121 @@
+// Copyright 2022 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package licenses
+
+import (
+	"crypto/sha256"
+	_ "embed" // for the license index
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"sync"
+
+	"github.com/Bobgy/go-licenses/v2/internal/logging"
+	"github.com/google/licenseclassifier"
+)
+
+// licenseIndexJSON is the license index of the licenseclassifier module in
+// go.mod, generated at release time with
+// go test ./licenses -run TestLicenseIndex -update_license_index.
+//
+//go:embed license_index.json
+var licenseIndexJSON []byte
+
+// licenseIndex maps hashes of the normalized texts of the licenses in the
+// license database of licenseclassifier to their classifications, so that
+// verbatim copies of known licenses are identified without compiling the
+// database.
+type licenseIndex struct {
+	// Archive is the hex SHA-256 hash of the license database the index was
+	// built from.
+	Archive string `json:"archive"`
+	// Threshold is the confidence threshold of the classifications.
+	Threshold float64 `json:"threshold"`
+	// Licenses maps hex SHA-256 hashes of normalized license texts to their
+	// matches, best first.
+	Licenses map[string][]indexedMatch `json:"licenses"`
+}
+
+// indexedMatch is a match in a licenseIndex.
+type indexedMatch struct {
+	Name       string  `json:"name"`
+	Confidence float64 `json:"confidence"`
+}
+
+// loadLicenseIndex parses a license index, and checks that it was built from
+// the license database archive.
+func loadLicenseIndex(data, archive []byte) (*licenseIndex, error) {
+	var index licenseIndex
+	if err := json.Unmarshal(data, &index); err != nil {
+		return nil, fmt.Errorf("parsing license index: %w", err)
+	}
+	if want := hashText(string(archive)); index.Archive != want {
+		return nil, fmt.Errorf("license index was built from license database %s, not %s", index.Archive, want)
+	}
+	return &index, nil
+}
+
+var (
+	embeddedIndexOnce sync.Once
+	embeddedIndex     *licenseIndex
+)
+
+// embeddedLicenseIndex returns the embedded license index, or nil if it was
+// not built from the license database of licenseclassifier, e.g. because
+// the licenseclassifier module was upgraded without regenerating it.
+func embeddedLicenseIndex() *licenseIndex {
+	embeddedIndexOnce.Do(func() {
+		archive, err := licenseclassifier.ReadLicenseFile(licenseclassifier.LicenseArchive)
+		if err == nil {
+			embeddedIndex, err = loadLicenseIndex(licenseIndexJSON, archive)
+		}
+		if err != nil {
+			logging.Infof("Not using the license index, the license database will be compiled: %v", err)
+		}
+	})
+	return embeddedIndex
+}
+
+// lookup returns the matches of contents with a confidence threshold, and
+// whether they are indexed.
+func (x *licenseIndex) lookup(contents string, threshold float64) ([]Match, bool) {
+	if x == nil || threshold != x.Threshold {
+		return nil, false
+	}
+	indexed, ok := x.Licenses[hashText(normalizeLicenseText(contents))]
+	if !ok {
+		return nil, false
+	}
+	var matches []Match
+	for _, m := range indexed {
+		matches = append(matches, Match{Name: m.Name, Type: Type(licenseclassifier.LicenseType(m.Name)), Confidence: m.Confidence})
+	}
+	return matches, true
+}
+
+// normalizeLicenseText normalizes a license text like licenseclassifier does
+// before matching it.
+func normalizeLicenseText(s string) string {
+	for _, normalize := range licenseclassifier.Normalizers {
+		s = normalize(s)
+	}
+	return s
+}
+
+// hashText returns the hex SHA-256 hash of s.
+func hashText(s string) string {
+	sum := sha256.Sum256([]byte(s))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,278 @@
+// Copyright 2022 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package licenses
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"errors"
+	"flag"
+	"io"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/licenseclassifier"
+)
+
+var updateLicenseIndex = flag.Bool("update_license_index", false, "Update license_index.json")
+
+// licenseIndexThreshold is the confidence threshold of the license index, the
+// default of --confidence_threshold.
+const licenseIndexThreshold = 0.9
+
+// archivedLicenses returns the texts of the licenses in a license database
+// archive of licenseclassifier by name, including their ".header" variants.
+// Entries of the archive alternate between the normalized text of a license
+// and its precomputed hashes.
+func archivedLicenses(archive []byte) (map[string]string, error) {
+	gr, err := gzip.NewReader(bytes.NewReader(archive))
+	if err != nil {
+		return nil, err
+	}
+	defer gr.Close()
+	tr := tar.NewReader(gr)
+	texts := make(map[string]string)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			return texts, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		text, err := ioutil.ReadAll(tr)
+		if err != nil {
+			return nil, err
+		}
+		texts[strings.TrimSuffix(hdr.Name, ".txt")] = string(text)
+		if _, err := tr.Next(); err != nil {
+			return nil, err
+		}
+	}
+}
+
+// buildLicenseIndex builds the license index of a license database archive,
+// classifying each license text with the compiled database.
+func buildLicenseIndex(archive []byte, threshold float64) (*licenseIndex, error) {
+	classifier, err := licenseclassifier.New(threshold, licenseclassifier.ArchiveBytes(archive))
+	if err != nil {
+		return nil, err
+	}
+	texts, err := archivedLicenses(archive)
+	if err != nil {
+		return nil, err
+	}
+	index := &licenseIndex{
+		Archive:   hashText(string(archive)),
+		Threshold: threshold,
+		Licenses:  make(map[string][]indexedMatch),
+	}
+	for _, text := range texts {
+		norm := normalizeLicenseText(text)
+		// Files normalizing to norm are classified like norm itself only if
+		// normalizing it again leaves it unchanged.
+		if normalizeLicenseText(norm) != norm {
+			continue
+		}
+		key := hashText(norm)
+		if _, ok := index.Licenses[key]; ok {
+			continue
+		}
+		matches := []indexedMatch{}
+		for _, m := range classifier.MultipleMatch(norm, true) {
+			matches = append(matches, indexedMatch{Name: m.Name, Confidence: m.Confidence})
+		}
+		index.Licenses[key] = matches
+	}
+	return index, nil
+}
+
+// TestLicenseIndex checks that license_index.json, embedded in go-licenses, is
+// up to date with the license database of licenseclassifier, and classifies
+// licenses like it.
+func TestLicenseIndex(t *testing.T) {
+	archive, err := licenseclassifier.ReadLicenseFile(licenseclassifier.LicenseArchive)
+	if err != nil {
+		t.Fatal(err)
+	}
+	index, err := buildLicenseIndex(archive, licenseIndexThreshold)
+	if err != nil {
+		t.Fatalf("buildLicenseIndex() = %v", err)
+	}
+	got, err := json.MarshalIndent(index, "", "  ")
+	if err != nil {
+		t.Fatal(err)
+	}
+	got = append(got, '\n')
+	if *updateLicenseIndex {
+		if err := ioutil.WriteFile("license_index.json", got, 0644); err != nil {
+			t.Fatal(err)
+		}
+	} else if string(got) != string(licenseIndexJSON) {
+		t.Errorf("license_index.json is out of date, update it with go test ./licenses -run TestLicenseIndex -update_license_index")
+	}
+
+	texts, err := archivedLicenses(archive)
+	if err != nil {
+		t.Fatal(err)
+	}
+	classifier, err := licenseclassifier.New(licenseIndexThreshold)
+	if err != nil {
+		t.Fatal(err)
+	}
+	indexed := 0
+	for name, text := range texts {
+		got, ok := index.lookup(text, licenseIndexThreshold)
+		if !ok {
+			continue
+		}
+		indexed++
+		var want []Match
+		for _, m := range classifier.MultipleMatch(text, true) {
+			want = append(want, Match{Name: m.Name, Type: Type(licenseclassifier.LicenseType(m.Name)), Confidence: m.Confidence})
+		}
+		if diff := cmp.Diff(want, got); diff != "" {
+			t.Errorf("lookup(%s) matches differ from the compiled license database (-want +got):\n%s", name, diff)
+		}
+	}
+	for _, name := range []string{"Apache-2.0", "BSD-3-Clause", "MIT", "MPL-2.0"} {
+		if _, ok := index.lookup(texts[name], licenseIndexThreshold); !ok {
+			t.Errorf("license index has no %s license", name)
+		}
+	}
+	t.Logf("%d of %d license texts are indexed", indexed, len(texts))
+}
+
+func TestLoadLicenseIndex(t *testing.T) {
+	archive := []byte("license database")
+	data, err := json.Marshal(licenseIndex{
+		Archive:   hashText(string(archive)),
+		Threshold: 0.9,
+		Licenses: map[string][]indexedMatch{
+			hashText(normalizeLicenseText("Some license text.")): {{Name: "MIT", Confidence: 1}},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := loadLicenseIndex(data, []byte("another license database")); err == nil {
+		t.Errorf("loadLicenseIndex() of another license database = nil error, want error")
+	}
+	if _, err := loadLicenseIndex([]byte("{"), archive); err == nil {
+		t.Errorf("loadLicenseIndex() of invalid JSON = nil error, want error")
+	}
+	index, err := loadLicenseIndex(data, archive)
+	if err != nil {
+		t.Fatalf("loadLicenseIndex() = %v", err)
+	}
+
+	for _, test := range []struct {
+		desc      string
+		contents  string
+		threshold float64
+		want      []Match
+		wantOK    bool
+	}{
+		{
+			desc:      "indexed text",
+			contents:  "Some license text.",
+			threshold: 0.9,
+			want:      []Match{{Name: "MIT", Type: Notice, Confidence: 1}},
+			wantOK:    true,
+		},
+		{
+			desc:      "normalized text",
+			contents:  "  SOME license\n\ttext",
+			threshold: 0.9,
+			want:      []Match{{Name: "MIT", Type: Notice, Confidence: 1}},
+			wantOK:    true,
+		},
+		{
+			desc:      "other text",
+			contents:  "Some other license text.",
+			threshold: 0.9,
+		},
+		{
+			desc:      "other threshold",
+			contents:  "Some license text.",
+			threshold: 0.8,
+		},
+	} {
+		t.Run(test.desc, func(t *testing.T) {
+			got, ok := index.lookup(test.contents, test.threshold)
+			if ok != test.wantOK {
+				t.Fatalf("lookup(%q, %v) = (_, %t), want (_, %t)", test.contents, test.threshold, ok, test.wantOK)
+			}
+			if diff := cmp.Diff(test.want, got); diff != "" {
+				t.Errorf("lookup(%q, %v) matches (-want +got):\n%s", test.contents, test.threshold, diff)
+			}
+		})
+	}
+}
+
+func TestDefaultClassifierUsesLicenseIndex(t *testing.T) {
+	compiles := 0
+	defer func(compile func(float64) (*licenseclassifier.License, error)) {
+		compileLicenseDatabase = compile
+	}(compileLicenseDatabase)
+	compileLicenseDatabase = func(float64) (*licenseclassifier.License, error) {
+		compiles++
+		return nil, errors.New("no license database")
+	}
+	if embeddedLicenseIndex() == nil {
+		t.Fatal("embedded license index is not used, update it with go test ./licenses -run TestLicenseIndex -update_license_index")
+	}
+
+	archive, err := licenseclassifier.ReadLicenseFile(licenseclassifier.LicenseArchive)
+	if err != nil {
+		t.Fatal(err)
+	}
+	texts, err := archivedLicenses(archive)
+	if err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(t.TempDir(), "LICENSE")
+	if err := ioutil.WriteFile(path, []byte(texts["MIT"]), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	c, err := NewNamedClassifier(DefaultClassifier, licenseIndexThreshold)
+	if err != nil {
+		t.Fatalf("NewNamedClassifier() = %v", err)
+	}
+	if name, typ, err := c.Identify(path); err != nil || name != "MIT" || typ != Notice {
+		t.Errorf("Identify(LICENSE) = (%q, %q, %v), want (MIT, notice, nil)", name, typ, err)
+	}
+	if compiles != 0 {
+		t.Errorf("license database compiled %d times for an indexed license, want 0", compiles)
+	}
+
+	// Other confidence thresholds are not indexed.
+	c, err = NewNamedClassifier(DefaultClassifier, 0.8)
+	if err != nil {
+		t.Fatalf("NewNamedClassifier() = %v", err)
+	}
+	if _, _, err := c.Identify(path); err == nil {
+		t.Errorf("Identify(LICENSE) with threshold 0.8 = nil error, want error compiling the license database")
+	}
+	if compiles != 1 {
+		t.Errorf("license database compiled %d times, want 1", compiles)
+	}
+}
@@ -16,6 +16,10 @@ package licenses
 
 import (
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
 	"fmt"
 	"go/build"
 	"io/ioutil"
@@ -23,10 +27,14 @@ import (
 	"path/filepath"
 	"sort"
 	"strings"
+	"sync"
 	"time"
 
-	"github.com/golang/glog"
+	"github.com/Bobgy/go-licenses/v2/internal/logging"
+	"github.com/Bobgy/go-licenses/v2/internal/parallel"
+	"github.com/Bobgy/go-licenses/v2/internal/third_party/pkgsite/derrors"
 	"github.com/Bobgy/go-licenses/v2/internal/third_party/pkgsite/source"
+	"github.com/Bobgy/go-licenses/v2/internal/third_party/pkgsite/stdlib"
 	"golang.org/x/tools/go/packages"
 )
 
@@ -37,8 +45,64 @@ type Library struct {
 	// Packages contains import paths for Go packages in this library.
 	// It may not be the complete set of all packages in the library.
 	Packages []string
+	// ModuleWarning reports whether the library's module version is retracted
+	// or deprecated. It is only set when Options.ModuleWarnings is enabled.
+	ModuleWarning *ModuleWarning
+	// NonGoComponents lists packages of the library using non-Go code, e.g.
+	// cgo, which need manual review.
+	NonGoComponents []*NonGoComponent
+	// EmbeddedBy is the import path of the package embedding this library
+	// with //go:embed, if the library consists of embedded assets with their
+	// own license, e.g. fonts or JavaScript bundles.
+	EmbeddedBy string
+	// IntegrityError is set when the module directory of the library does
+	// not match its checksum, so the library's license may not describe
+	// the code actually built. Only checked when Options.VerifyModules is set.
+	IntegrityError error
+	// LicenseName is the name of the license in LicensePath, as identified
+	// by the classifier passed to Libraries, e.g. "Apache-2.0". It is empty
+	// if the license is not identified.
+	LicenseName string
+	// SPDXID is the SPDX ID of the license, or empty if the license is not
+	// identified or has no SPDX ID.
+	SPDXID string
+	// LicenseType is the type of the license, Unknown if it is not
+	// identified.
+	LicenseType Type
+	// Confidence is the confidence of the classifier in LicenseName, between
+	// 0 and 1. Classifiers not reporting confidence have confidence 1.
+	Confidence float64
+	// LicenseCandidates are the files that may contain the library's
+	// license, ranked best first, as found by FindAll for the library's
+	// top-level package. LicensePath is the first one, unless its license is
+	// not identified.
+	LicenseCandidates []LicenseCandidate
+	// ImportChain is the shortest chain of imports from a package passed to
+	// Libraries to a package of the library, e.g. to explain why the library
+	// is a dependency. It starts with the root package and ends with the
+	// library's package, or the package embedding it. It is only set when
+	// Options.ImportChains is enabled, and not for libraries of binaries.
+	ImportChain []string
 	// Parent go module.
 	module *Module
+	// cache caches remote license files fetched by LicenseURL.
+	cache *Cache
+	// metrics measures LicenseURL, see Options.Metrics.
+	metrics func(Measurement)
+	// httpClient makes HTTP requests of LicenseURL, if set.
+	httpClient *http.Client
+	// validateHashes compares git blob hashes in LicenseURL, see
+	// Options.ValidateHashes.
+	validateHashes bool
+}
+
+// client returns the HTTP client of LicenseURL, the shared default client
+// unless Options.HTTPClient is set.
+func (l *Library) client() *http.Client {
+	if l.httpClient != nil {
+		return l.httpClient
+	}
+	return sharedHTTPClient(0)
 }
 
 // PackagesError aggregates all Packages[].Errors into a single error.
@@ -57,56 +121,469 @@ func (e PackagesError) Error() string {
 	return str.String()
 }
 
+// PackageErrorKind is the kind of a PackageError.
+type PackageErrorKind string
+
+// Kinds of package errors.
+const (
+	// PackageErrorList is an error reported by the go command, e.g. a
+	// missing module or a package that does not exist.
+	PackageErrorList PackageErrorKind = "list"
+	// PackageErrorParse is a syntax error in a Go file.
+	PackageErrorParse PackageErrorKind = "parse"
+	// PackageErrorType is a type checking error.
+	PackageErrorType PackageErrorKind = "type"
+	// PackageErrorUnknown is any other error.
+	PackageErrorUnknown PackageErrorKind = "unknown"
+)
+
+// PackageError is an error loading a package, one of the errors of a
+// PackagesError.
+type PackageError struct {
+	// Package is the import path of the package.
+	Package string
+	// Kind is the kind of error, e.g. PackageErrorList.
+	Kind PackageErrorKind
+	// Pos is the position of the error as "file:line:column", "file:line"
+	// or "file", or empty if it is unknown.
+	Pos string
+	// Msg is the error message, without position.
+	Msg string
+}
+
+func (e PackageError) Error() string {
+	if e.Pos == "" {
+		return fmt.Sprintf("%s: %s", e.Package, e.Msg)
+	}
+	return fmt.Sprintf("%s: %s: %s", e.Package, e.Pos, e.Msg)
+}
+
+// Errors returns the errors of all packages in the order packages are
+// visited, dependencies first, e.g. to present or filter them.
+func (e PackagesError) Errors() []PackageError {
+	var errs []PackageError
+	packages.Visit(e.pkgs, nil, func(pkg *packages.Package) {
+		for _, err := range pkg.Errors {
+			errs = append(errs, PackageError{
+				Package: pkg.PkgPath,
+				Kind:    packageErrorKind(err.Kind),
+				Pos:     err.Pos,
+				Msg:     err.Msg,
+			})
+		}
+	})
+	return errs
+}
+
+// packageErrorKind returns the PackageErrorKind of a packages.ErrorKind.
+func packageErrorKind(kind packages.ErrorKind) PackageErrorKind {
+	switch kind {
+	case packages.ListError:
+		return PackageErrorList
+	case packages.ParseError:
+		return PackageErrorParse
+	case packages.TypeError:
+		return PackageErrorType
+	}
+	return PackageErrorUnknown
+}
+
+// Options configures how LibrariesWithOptions loads packages.
+// The zero value loads packages for the host platform.
+type Options struct {
+	// Dir is the directory in which packages are loaded and go commands run,
+	// e.g. the root of a repo. Defaults to the current directory.
+	Dir string
+	// GOOS is the target operating system. Defaults to the environment's GOOS.
+	GOOS string
+	// GOARCH is the target architecture. Defaults to the environment's GOARCH.
+	GOARCH string
+	// BuildTags are additional build tags considered satisfied while loading packages.
+	BuildTags []string
+	// IncludeTests includes dependencies only imported by tests of the
+	// analyzed packages. Test-only dependencies are excluded by default,
+	// because they are not shipped with the analyzed packages.
+	IncludeTests bool
+	// IncludeTools also analyzes tool dependencies of the main module, i.e.
+	// tool directives in go.mod and blank imports in files built with the
+	// "tools" build tag. Only tool packages are loaded with the tag, so
+	// that it does not change the dependencies of other packages.
+	IncludeTools bool
+	// Vendor loads packages with -mod=vendor and maps vendored packages back
+	// to their modules using vendor/modules.txt, instead of treating them as
+	// part of the main module.
+	Vendor bool
+	// DownloadModules runs `go mod download` for modules missing from the
+	// module cache, instead of reporting them without a license.
+	DownloadModules bool
+	// Mod is the -mod build flag used to load packages, e.g. "readonly".
+	// Vendor implies "vendor".
+	Mod string
+	// IncludeStdLib adds a single library for the Go distribution when the
+	// standard library is used, because some attribution policies require
+	// listing it. Standard library packages are excluded by default.
+	IncludeStdLib bool
+	// VerifyModules checks that module directories match their checksums in
+	// go.sum, or in the build info of binaries.
+	VerifyModules bool
+	// Ignore lists import path prefixes of packages and modules to exclude
+	// from the result. Dependencies of ignored packages are still included.
+	Ignore []string
+	// ModuleWarnings checks whether the modules of libraries are retracted or
+	// deprecated, which requires querying the module proxy.
+	ModuleWarnings bool
+	// ImportChains sets Library.ImportChain of libraries.
+	ImportChains bool
+	// Shard only returns the libraries of a subset of modules, see Shard.
+	// Packages are still loaded for all modules, but only the licenses of
+	// the shard are searched for and identified.
+	Shard Shard
+	// Limits bound the search for license files, e.g. the size of license
+	// candidates.
+	Limits ScanLimits
+	// ModuleGranularity returns a library per module in the build list of the
+	// main module, listed by `go list -m -json all`, whose license is found at
+	// the root of the module, instead of loading the packages of the import
+	// paths. It is faster on large dependency graphs, but less precise: the
+	// build list may include modules none of the packages import, import
+	// paths and options only relevant to loading packages are ignored, and
+	// vendor mode is not supported.
+	ModuleGranularity bool
+	// Env holds additional "KEY=value" environment variables for all go
+	// commands, e.g. GOFLAGS or GOPROXY. They take precedence over the
+	// environment of the current process.
+	Env []string
+	// Progress is called with progress events of long running scans, if set.
+	// It may be called concurrently.
+	Progress func(Progress)
+	// Metrics is called with the duration and number of items of each
+	// completed phase of scans, if set. It may be called concurrently.
+	Metrics func(Measurement)
+	// Jobs is the number of packages scanned concurrently. It defaults to
+	// the number of CPUs.
+	Jobs int
+	// Cache caches module info and HTTP responses across runs, if set.
+	Cache *Cache
+	// HTTPClient makes HTTP requests of Library.LicenseURL, e.g. to add
+	// proxies, authentication or instrumentation. It defaults to a client
+	// shared by the whole run, see NewHTTPClient.
+	HTTPClient *http.Client
+	// ValidateHashes validates license URLs of repos hosted on GitHub or
+	// GitLab by comparing the git blob hash of the local license file with
+	// the one of the remote file, queried from the API of the host, instead
+	// of downloading the remote file. Validation falls back to downloading
+	// if the API fails, e.g. when rate limited. Requests to the GitHub API are
+	// authenticated with the GITHUB_TOKEN environment variable, if set.
+	ValidateHashes bool
+	// RequestsPerSecond limits the rate of requests of the default HTTP
+	// client to each host, e.g. raw.githubusercontent.com, so that large
+	// scans are not throttled. It defaults to DefaultRequestsPerSecond, and
+	// is unlimited if negative. It is ignored if HTTPClient is set.
+	RequestsPerSecond float64
+}
+
+// httpClient returns the HTTP client of libraries, see HTTPClient.
+func (o Options) httpClient() *http.Client {
+	if o.HTTPClient != nil {
+		return o.HTTPClient
+	}
+	return sharedHTTPClient(o.RequestsPerSecond)
+}
+
+// packagesConfig returns the config for loading packages with these options.
+func (o Options) packagesConfig(ctx context.Context) *packages.Config {
+	cfg := &packages.Config{
+		Context: ctx,
+		Dir:     o.Dir,
+		Mode:    PackagesLoadMode,
+		Tests:   o.IncludeTests,
+		Env:     o.environ(),
+	}
+	if len(o.BuildTags) > 0 {
+		cfg.BuildFlags = append(cfg.BuildFlags, "-tags="+strings.Join(o.BuildTags, ","))
+	}
+	if mod := o.mod(); mod != "" {
+		cfg.BuildFlags = append(cfg.BuildFlags, "-mod="+mod)
+	}
+	return cfg
+}
+
+// ignored returns true if the package or module at path is excluded by the
+// Ignore option. A prefix matches whole path elements only.
+func (o Options) ignored(path string) bool {
+	for _, prefix := range o.Ignore {
+		prefix = strings.TrimSuffix(prefix, "/")
+		if path == prefix || strings.HasPrefix(path, prefix+"/") {
+			return true
+		}
+	}
+	return false
+}
+
+// mod returns the -mod build flag value of these options.
+func (o Options) mod() string {
+	if o.Vendor {
+		return "vendor"
+	}
+	return o.Mod
+}
+
 // Libraries returns the collection of libraries used by this package, directly or transitively.
 // A library is a collection of one or more packages covered by the same license file.
 // Packages not covered by a license will be returned as individual libraries.
 // Standard library packages will be ignored.
+// Packages are loaded for the host platform, see LibrariesWithOptions.
 func Libraries(ctx context.Context, classifier Classifier, importPaths ...string) ([]*Library, error) {
-	cfg := &packages.Config{
-		Context: ctx,
-		Mode:    packages.NeedImports | packages.NeedDeps | packages.NeedFiles | packages.NeedName | packages.NeedModule,
+	return LibrariesWithOptions(ctx, classifier, Options{}, importPaths...)
+}
+
+// LibrariesWithOptions is like Libraries, with options for loading packages
+// and identifying their libraries.
+func LibrariesWithOptions(ctx context.Context, classifier Classifier, opts Options, importPaths ...string) ([]*Library, error) {
+	libraries, err := findLibraries(ctx, classifier, opts, importPaths...)
+	if err != nil {
+		return nil, err
+	}
+	if err := identifyLibraries(ctx, classifier, opts, libraries, nil); err != nil {
+		return nil, err
 	}
+	return libraries, nil
+}
+
+// StopWalk is returned by the function passed to WalkLibraries to stop
+// walking libraries without an error.
+var StopWalk = errors.New("stop walking libraries")
 
+// WalkLibraries is like Libraries, but calls f with each library as soon as
+// its license is identified, instead of returning all libraries at the end.
+// Libraries are passed in order of their names, one at a time. If f returns
+// an error, the remaining libraries are skipped and WalkLibraries returns the
+// error, or nil for StopWalk.
+func WalkLibraries(ctx context.Context, classifier Classifier, opts Options, f func(*Library) error, importPaths ...string) error {
+	libraries, err := findLibraries(ctx, classifier, opts, importPaths...)
+	if err != nil {
+		return err
+	}
+	if err := identifyLibraries(ctx, classifier, opts, libraries, f); err != nil && err != StopWalk {
+		return err
+	}
+	return nil
+}
+
+// findLibraries returns the libraries of Libraries sorted by name, without
+// identifying their licenses.
+func findLibraries(ctx context.Context, classifier Classifier, opts Options, importPaths ...string) ([]*Library, error) {
+	if opts.Vendor && opts.Mod != "" && opts.Mod != "vendor" {
+		return nil, fmt.Errorf("vendor mode conflicts with -mod=%s", opts.Mod)
+	}
+	cfg := opts.packagesConfig(ctx)
+	if opts.ModuleGranularity {
+		return findListedModuleLibraries(ctx, classifier, opts, cfg.Dir)
+	}
+	opts.progress(PhaseLoadingPackages, 0, 0)
+	loadStart := time.Now()
 	rootPkgs, err := packages.Load(cfg, importPaths...)
+	opts.measure(PhaseLoadingPackages, loadStart, len(rootPkgs), err)
+	if err != nil {
+		return nil, err
+	}
+	var toolPkgs []*packages.Package
+	if opts.IncludeTools {
+		tools, err := ToolPackages(ctx, opts, cfg.Dir)
+		if err != nil {
+			return nil, err
+		}
+		if len(tools) > 0 {
+			// Only tool packages are loaded with the tools build tag, which
+			// would otherwise change the files, and so the imports, of
+			// other packages.
+			toolsCfg := opts.packagesConfig(ctx)
+			toolsCfg.BuildFlags = append(toolsCfg.BuildFlags, "-tags="+strings.Join(append(append([]string(nil), opts.BuildTags...), toolsBuildTag), ","))
+			loadStart := time.Now()
+			toolPkgs, err = packages.Load(toolsCfg, tools...)
+			opts.measure(PhaseLoadingPackages, loadStart, len(toolPkgs), err)
+			if err != nil {
+				return nil, err
+			}
+		}
+	}
+	return packageLibraries(ctx, classifier, opts, cfg.Dir, rootPkgs, toolPkgs)
+}
+
+// PackagesLoadMode is the mode packages must at least be loaded with to be
+// passed to PackageLibraries.
+const PackagesLoadMode = packages.NeedImports | packages.NeedDeps | packages.NeedFiles | packages.NeedName | packages.NeedModule
+
+// PackageLibraries is like Libraries, but returns the libraries of packages
+// already loaded by the caller with PackagesLoadMode, e.g. by a build system,
+// instead of loading them again with the go command. Options only relevant to
+// loading packages, e.g. GOOS or BuildTags, are ignored.
+func PackageLibraries(ctx context.Context, classifier Classifier, opts Options, pkgs []*packages.Package) ([]*Library, error) {
+	libraries, err := packageLibraries(ctx, classifier, opts, "", pkgs, nil)
 	if err != nil {
 		return nil, err
 	}
+	if err := identifyLibraries(ctx, classifier, opts, libraries, nil); err != nil {
+		return nil, err
+	}
+	return libraries, nil
+}
+
+// packageLibraries returns the libraries of loaded packages and their
+// dependencies sorted by name, without identifying their licenses. The go
+// command runs in dir for options that need it, e.g. Vendor. toolPkgs are
+// tool packages loaded separately with the tools build tag: their
+// dependencies are only visited if they are not dependencies of rootPkgs, so
+// that files with the tools build tag of those do not add imports.
+func packageLibraries(ctx context.Context, classifier Classifier, opts Options, dir string, rootPkgs, toolPkgs []*packages.Package) ([]*Library, error) {
+	if err := opts.Shard.validate(); err != nil {
+		return nil, err
+	}
+	classifier = newMemoClassifier(classifier)
+	var vendored map[string]*Module
+	if opts.Vendor {
+		var err error
+		vendored, err = mainVendorModules(ctx, opts, dir)
+		if err != nil {
+			return nil, err
+		}
+	}
+	// moduleOf returns module info of a package, mapping vendored packages to
+	// their modules in vendor mode.
+	moduleOf := func(p *packages.Package) *Module {
+		if p.Module != nil {
+			if m, ok := vendored[p.Module.Path]; ok {
+				vendoredModule := *m
+				return &vendoredModule
+			}
+		}
+		return newModule(p.Module)
+	}
 
 	pkgs := map[string]*packages.Package{}
-	pkgsByLicense := make(map[string][]*packages.Package)
+	// Packages to find licenses of, with their directories.
+	var scanned []*packages.Package
+	var scannedDirs []string
 	errorOccurred := false
-	packages.Visit(rootPkgs, func(p *packages.Package) bool {
+	usesStdLib := false
+	visit := func(p *packages.Package) bool {
 		if len(p.Errors) > 0 {
 			errorOccurred = true
 			return false
 		}
 		if isStdLib(p) {
 			// No license requirements for the Go standard library.
+			usesStdLib = true
 			return false
 		}
+		if isTestMain(p) || isExternalTest(p) {
+			// Generated main package of a test binary, or external test
+			// package, nothing to report except its dependencies.
+			return true
+		}
+		if opts.ignored(p.PkgPath) {
+			// Not reported, but its dependencies may be.
+			return true
+		}
+		if _, ok := pkgs[p.PkgPath]; ok {
+			// A test variant of an already visited package, which may
+			// import additional packages.
+			return true
+		}
 		if len(p.OtherFiles) > 0 {
-			glog.Warningf("%q contains non-Go code that can't be inspected for further dependencies:\n%s", p.PkgPath, strings.Join(p.OtherFiles, "\n"))
-		}
-		var pkgDir string
-		switch {
-		case len(p.GoFiles) > 0:
-			pkgDir = filepath.Dir(p.GoFiles[0])
-		case len(p.CompiledGoFiles) > 0:
-			pkgDir = filepath.Dir(p.CompiledGoFiles[0])
-		case len(p.OtherFiles) > 0:
-			pkgDir = filepath.Dir(p.OtherFiles[0])
-		default:
+			logging.Module(p.PkgPath).Warningf("%q contains non-Go code that can't be inspected for further dependencies:\n%s", p.PkgPath, strings.Join(p.OtherFiles, "\n"))
+		}
+		pkgDir := packageDir(p)
+		if pkgDir == "" {
 			// This package is empty - nothing to do.
 			return true
 		}
-		licensePath, err := Find(pkgDir, p.Module.Dir, classifier)
-		if err != nil {
-			glog.Errorf("Failed to find license for %s: %v", p.PkgPath, err)
+		if m := moduleOf(p); m != nil && !opts.Shard.Contains(m.Path) {
+			// In another shard, so its license is not searched for.
+			return true
 		}
 		pkgs[p.PkgPath] = p
-		pkgsByLicense[licensePath] = append(pkgsByLicense[licensePath], p)
+		scanned = append(scanned, p)
+		scannedDirs = append(scannedDirs, pkgDir)
 		return true
+	}
+	// visited records the packages of the graph of rootPkgs.
+	visited := make(map[string]bool)
+	packages.Visit(rootPkgs, func(p *packages.Package) bool {
+		visited[p.PkgPath] = true
+		return visit(p)
 	}, nil)
+	packages.Visit(toolPkgs, func(p *packages.Package) bool {
+		if visited[p.PkgPath] {
+			return false
+		}
+		return visit(p)
+	}, nil)
+	opts.progress(PhaseLoadingPackages, len(scanned), len(scanned))
+
+	// Find licenses of packages concurrently, then collect them in the
+	// order packages were visited.
+	type scanResult struct {
+		licensePath string
+		candidates  []LicenseCandidate
+		embedded    []*Library
+		nonGo       *NonGoComponent
+	}
+	results := make([]scanResult, len(scanned))
+	var mu sync.Mutex
+	done := 0
+	// Packages of a module share its directory tree, which is walked once.
+	find := newFinder(classifier, opts.Limits)
+	findStart := time.Now()
+	parallel.For(opts.jobs(), len(scanned), func(i int) {
+		p, pkgDir := scanned[i], scannedDirs[i]
+		var rootDir string
+		if m := moduleOf(p); m != nil {
+			rootDir = m.Dir
+		} else {
+			// Not in a module, e.g. in GOPATH mode. Search up to the
+			// directory import paths are relative to.
+			rootDir = importRoot(pkgDir, p.PkgPath)
+		}
+		r := scanResult{}
+		candidates, err := find.findAll(pkgDir, rootDir)
+		if err != nil {
+			logging.Module(p.PkgPath).Errorf("Failed to find license for %s: %v", p.PkgPath, err)
+		} else {
+			r.licensePath = candidates[0].Path
+		}
+		r.candidates = candidates
+		for _, lib := range embeddedLibraries(p, pkgDir, rootDir, classifier, opts.Limits) {
+			lib.module = moduleOf(p)
+			r.embedded = append(r.embedded, lib)
+		}
+		if c, err := nonGoComponent(p); err != nil {
+			logging.Module(p.PkgPath).Errorf("Failed to detect non-Go code in %s: %v", p.PkgPath, err)
+		} else {
+			r.nonGo = c
+		}
+		results[i] = r
+		mu.Lock()
+		done++
+		opts.progress(PhaseFindingLicenses, done, len(scanned))
+		mu.Unlock()
+	})
+	opts.measure(PhaseFindingLicenses, findStart, len(scanned), nil)
+	nonGo := make(map[string]*NonGoComponent)
+	// Embedded assets with their own license, keyed by license path.
+	embedded := make(map[string]*Library)
+	pkgsByLicense := make(map[string][]*packages.Package)
+	candidates := make(map[string][]LicenseCandidate)
+	for i, p := range scanned {
+		candidates[p.PkgPath] = results[i].candidates
+		for _, lib := range results[i].embedded {
+			embedded[lib.LicensePath] = lib
+		}
+		if results[i].nonGo != nil {
+			nonGo[p.PkgPath] = results[i].nonGo
+		}
+		pkgsByLicense[results[i].licensePath] = append(pkgsByLicense[results[i].licensePath], p)
+	}
 	if errorOccurred {
 		return nil, PackagesError{
 			pkgs: rootPkgs,
@@ -114,31 +591,56 @@ func Libraries(ctx context.Context, classifier Classifier, importPaths ...string
 	}
 
 	var libraries []*Library
+	// inShard records libraries of packages in modules, which are in the
+	// shard already. Others, e.g. of packages in GOPATH mode, are filtered
+	// by name once their packages are grouped.
+	inShard := make(map[*Library]bool)
 	for licensePath, pkgs := range pkgsByLicense {
 		if licensePath == "" {
 			// No license for these packages - return each one as a separate library.
 			for _, p := range pkgs {
-				libraries = append(libraries, &Library{
-					Packages: []string{p.PkgPath},
-					module:   newModule(p.Module),
-				})
+				lib := &Library{
+					Packages:          []string{p.PkgPath},
+					LicenseCandidates: candidates[p.PkgPath],
+					module:            moduleOf(p),
+				}
+				inShard[lib] = lib.module != nil
+				if c := nonGo[p.PkgPath]; c != nil {
+					lib.NonGoComponents = append(lib.NonGoComponents, c)
+				}
+				libraries = append(libraries, lib)
 			}
 			continue
 		}
 		lib := &Library{
 			LicensePath: licensePath,
 		}
+		top := ""
 		for _, pkg := range pkgs {
 			lib.Packages = append(lib.Packages, pkg.PkgPath)
+			if top == "" || len(pkg.PkgPath) < len(top) {
+				top = pkg.PkgPath
+				lib.LicenseCandidates = candidates[pkg.PkgPath]
+			}
+			if c := nonGo[pkg.PkgPath]; c != nil {
+				lib.NonGoComponents = append(lib.NonGoComponents, c)
+			}
 			if lib.module == nil {
 				// All the sub packages should belong to the same module.
-				lib.module = newModule(pkg.Module)
+				lib.module = moduleOf(pkg)
+				inShard[lib] = lib.module != nil
+			}
+			if lib.module == nil {
+				// Not in a module, e.g. in GOPATH mode. Treat the directory
+				// of the license file as a module, so that its repository
+				// can be inferred from its import path.
+				lib.module = licenseDirModule(pkg, licensePath)
 			}
 			if lib.module != nil && lib.module.Path != "" && lib.module.Dir == "" {
 				// A known cause is that the module is vendored, so some information is lost.
 				splits := strings.SplitN(lib.LicensePath, "/vendor/", 2)
 				if len(splits) != 2 {
-					glog.Warningf("module %s does not have dir and it's not vendored, cannot discover the license URL. Report to go-licenses developer if you see this.", lib.module.Path)
+					logging.Module(lib.module.Path).Warningf("module %s does not have dir and it's not vendored, cannot discover the license URL. Report to go-licenses developer if you see this.", lib.module.Path)
 				} else {
 					// This is vendored. Handle this known special case.
 					parentModDir := splits[0]
@@ -150,7 +652,7 @@ func Libraries(ctx context.Context, classifier Classifier, importPaths ...string
 						}
 					}
 					if parentPkg == nil {
-						glog.Warningf("cannot find parent package of vendored module %s", lib.module.Path)
+						logging.Module(lib.module.Path).Warningf("cannot find parent package of vendored module %s", lib.module.Path)
 					} else {
 						// Vendored modules should be commited in the parent module, so it counts as part of the
 						// parent module.
@@ -161,13 +663,214 @@ func Libraries(ctx context.Context, classifier Classifier, importPaths ...string
 		}
 		libraries = append(libraries, lib)
 	}
-	// Sort libraries to produce a stable result for snapshot diffing.
-	sort.Slice(libraries, func(i, j int) bool {
-		return libraries[i].Name() < libraries[j].Name()
-	})
+	for licensePath, lib := range embedded {
+		if _, ok := pkgsByLicense[licensePath]; ok {
+			// Covered by the license of a package already.
+			continue
+		}
+		inShard[lib] = lib.module != nil
+		libraries = append(libraries, lib)
+	}
+	if opts.IncludeStdLib && usesStdLib {
+		lib, err := stdLibrary(ctx, opts)
+		if err != nil {
+			return nil, err
+		}
+		libraries = append(libraries, lib)
+	}
+	libraries = opts.Shard.libraries(libraries, inShard)
+	if opts.VerifyModules {
+		if err := setModuleSums(ctx, opts, dir, libraries); err != nil {
+			return nil, err
+		}
+		verifyLibraries(libraries)
+	}
+	if opts.ImportChains {
+		setImportChains(rootPkgs, libraries)
+	}
+	if err := setModuleWarnings(ctx, opts, dir, libraries); err != nil {
+		return nil, err
+	}
+	useOptions(libraries, opts)
+	sortLibraries(libraries)
+	return libraries, nil
+}
+
+// findListedModuleLibraries returns a library for each module in the build
+// list of the main module in dir, whose license is found at the root of the
+// module, without loading packages. See Options.ModuleGranularity.
+func findListedModuleLibraries(ctx context.Context, classifier Classifier, opts Options, dir string) ([]*Library, error) {
+	if opts.Vendor {
+		return nil, fmt.Errorf("module granularity does not support vendor mode")
+	}
+	opts.progress(PhaseListingModules, 0, 0)
+	start := time.Now()
+	modules, err := ListModules(ctx, opts, dir)
+	opts.measure(PhaseListingModules, start, len(modules), err)
+	if err != nil {
+		return nil, err
+	}
+	opts.progress(PhaseListingModules, len(modules), len(modules))
+	libraries, err := findModuleLibraries(ctx, classifier, opts, modules)
+	if err != nil {
+		return nil, err
+	}
+	if opts.VerifyModules {
+		if err := setModuleSums(ctx, opts, dir, libraries); err != nil {
+			return nil, err
+		}
+		verifyLibraries(libraries)
+	}
+	if err := setModuleWarnings(ctx, opts, dir, libraries); err != nil {
+		return nil, err
+	}
 	return libraries, nil
 }
 
+// setModuleWarnings sets ModuleWarning of libraries whose module versions are
+// retracted or deprecated, if opts.ModuleWarnings is set.
+func setModuleWarnings(ctx context.Context, opts Options, dir string, libraries []*Library) error {
+	if !opts.ModuleWarnings {
+		return nil
+	}
+	warnings, err := ModuleWarnings(ctx, opts, dir)
+	if err != nil {
+		return err
+	}
+	applyModuleWarnings(libraries, warnings)
+	return nil
+}
+
+// applyModuleWarnings sets ModuleWarning of libraries from warnings keyed by
+// module path, see ModuleWarnings. go list -m reports replaced modules by the
+// path required by go.mod, so they are looked up by OriginalPath.
+func applyModuleWarnings(libraries []*Library, warnings map[string]*ModuleWarning) {
+	for _, lib := range libraries {
+		if lib.module == nil {
+			continue
+		}
+		path := lib.module.Path
+		if lib.module.Replaced() {
+			path = lib.module.OriginalPath
+		}
+		lib.ModuleWarning = warnings[path]
+	}
+}
+
+// useOptions sets the cache and HTTP client of libraries, used by LicenseURL.
+func useOptions(libraries []*Library, opts Options) {
+	for _, lib := range libraries {
+		lib.cache = opts.Cache
+		lib.httpClient = opts.httpClient()
+		lib.validateHashes = opts.ValidateHashes
+		lib.metrics = opts.Metrics
+	}
+}
+
+// embeddedLibraries returns a library for each license file in the assets
+// embedded by a package with //go:embed. The library is named after the
+// directory of its license file, relative to the package.
+func embeddedLibraries(p *packages.Package, pkgDir, rootDir string, classifier Classifier, limits ScanLimits) []*Library {
+	patterns, err := embedPatterns(p.GoFiles)
+	if err != nil {
+		logging.Module(p.PkgPath).Errorf("Failed to read //go:embed directives of %s: %v", p.PkgPath, err)
+		return nil
+	}
+	if len(patterns) == 0 {
+		return nil
+	}
+	licensePaths, err := embeddedLicenses(pkgDir, rootDir, patterns, classifier, limits)
+	if err != nil {
+		logging.Module(p.PkgPath).Errorf("Failed to find licenses of files embedded by %s: %v", p.PkgPath, err)
+		return nil
+	}
+	var libs []*Library
+	for _, licensePath := range licensePaths {
+		rel, err := filepath.Rel(pkgDir, filepath.Dir(licensePath))
+		if err != nil {
+			logging.Module(p.PkgPath).Errorf("Failed to find licenses of files embedded by %s: %v", p.PkgPath, err)
+			continue
+		}
+		name := p.PkgPath
+		if rel != "." {
+			name += "/" + filepath.ToSlash(rel)
+		}
+		libs = append(libs, &Library{
+			LicensePath: licensePath,
+			Packages:    []string{name},
+			EmbeddedBy:  p.PkgPath,
+		})
+	}
+	return libs
+}
+
+// packageDir returns the directory of a package, or "" if the package is empty.
+func packageDir(p *packages.Package) string {
+	switch {
+	case len(p.GoFiles) > 0:
+		return filepath.Dir(p.GoFiles[0])
+	case len(p.CompiledGoFiles) > 0:
+		return filepath.Dir(p.CompiledGoFiles[0])
+	case len(p.OtherFiles) > 0:
+		return filepath.Dir(p.OtherFiles[0])
+	default:
+		return ""
+	}
+}
+
+// importRoot returns the directory that the import path of a package in dir
+// is relative to, e.g. $GOPATH/src. If dir does not end with the import path,
+// dir itself is returned.
+func importRoot(dir string, importPath string) string {
+	suffix := string(filepath.Separator) + filepath.FromSlash(importPath)
+	if !strings.HasSuffix(dir, suffix) {
+		return dir
+	}
+	return strings.TrimSuffix(dir, suffix)
+}
+
+// licenseDirModule returns a module without version rooted at the directory
+// of licensePath, for a package that does not belong to a module. It returns
+// nil if the directory has no import path.
+func licenseDirModule(p *packages.Package, licensePath string) *Module {
+	pkgDir := packageDir(p)
+	root := importRoot(pkgDir, p.PkgPath)
+	if root == pkgDir {
+		return nil
+	}
+	rel, err := filepath.Rel(root, filepath.Dir(licensePath))
+	if err != nil || rel == "." || strings.HasPrefix(rel, "..") {
+		return nil
+	}
+	return &Module{
+		Path: filepath.ToSlash(rel),
+		Dir:  filepath.Dir(licensePath),
+	}
+}
+
+// sortLibraries sorts libraries by name to produce a stable result for snapshot diffing.
+// The packages and non-Go components of each library are sorted too, and
+// libraries with the same name are ordered by license path.
+func sortLibraries(libraries []*Library) {
+	for _, lib := range libraries {
+		sort.Strings(lib.Packages)
+		sort.Slice(lib.NonGoComponents, func(i, j int) bool {
+			return lib.NonGoComponents[i].Package < lib.NonGoComponents[j].Package
+		})
+	}
+	sort.SliceStable(libraries, func(i, j int) bool {
+		if libraries[i].Name() != libraries[j].Name() {
+			return libraries[i].Name() < libraries[j].Name()
+		}
+		return libraries[i].LicensePath < libraries[j].LicensePath
+	})
+}
+
+// Module returns the Go module of this library, or nil if it is unknown.
+func (l *Library) Module() *Module {
+	return l.module
+}
+
 // Name is the common prefix of the import paths for all of the packages in this library.
 func (l *Library) Name() string {
 	return commonAncestor(l.Packages)
@@ -202,11 +905,23 @@ func (l *Library) String() string {
 // because we cannot easily set up actual license files on disk.
 var testOnlySkipValidation = false
 
-// LicenseURL attempts to determine the URL for the license file in this library
-// using go module name and version.
-func (l *Library) LicenseURL(ctx context.Context) (string, error) {
+// licenseURL is the URL of a library's license file, constructed from the
+// source repository of its module.
+type licenseURL struct {
+	remote *source.Info
+	// relativePath is the path of the license file in the module.
+	relativePath string
+	// url is where the license file is viewed, rawURL where its content is
+	// downloaded from, or empty if the repository has no raw URLs.
+	url, rawURL string
+}
+
+// constructURL constructs the URL of the license file of this library. When
+// offline, source info of the module is only looked up in the cache or
+// inferred from the module path, without network access.
+func (l *Library) constructURL(ctx context.Context, offline bool) (*licenseURL, error) {
 	if l == nil {
-		return "", fmt.Errorf("library is nil")
+		return nil, fmt.Errorf("library is nil")
 	}
 	filePath := l.LicensePath
 	wrap := func(err error) error {
@@ -214,15 +929,25 @@ func (l *Library) LicenseURL(ctx context.Context) (string, error) {
 	}
 	m := l.module
 	if m == nil {
-		return "", wrap(fmt.Errorf("empty go module info"))
+		return nil, wrap(fmt.Errorf("empty go module info"))
 	}
 	if m.Dir == "" {
-		return "", wrap(fmt.Errorf("empty go module dir"))
+		return nil, wrap(fmt.Errorf("empty go module dir"))
+	}
+	client := source.NewClientFromHTTPClient(l.client())
+	if offline {
+		// A client without HTTP client fails all requests.
+		client = source.NewClientForTesting()
+	}
+	remote, err := moduleSource(ctx, client, l.cache, m.Path, m.Version, offline)
+	if errors.Is(err, derrors.NotFound) {
+		return nil, wrap(fmt.Errorf("%w: %v", ErrUnsupportedHost, err))
 	}
-	client := source.NewClient(time.Second * 20)
-	remote, err := source.ModuleInfo(ctx, client, m.Path, m.Version)
 	if err != nil {
-		return "", wrap(err)
+		return nil, wrap(err)
+	}
+	if remote == nil {
+		return nil, wrap(fmt.Errorf("%w: no source info of module %s", ErrUnsupportedHost, m.Path))
 	}
 	if m.Version == "" {
 		// This always happens for the module in development.
@@ -244,16 +969,83 @@ func (l *Library) LicenseURL(ctx context.Context) (string, error) {
 		// * https://github.com/google/licenseclassifier/blob/HEAD/LICENSE
 		// points to latest commit of main branch.
 		remote.SetCommit("HEAD")
-		glog.Warningf("module %s has empty version, defaults to HEAD. The license URL may be incorrect. Please verify!", m.Path)
+		logging.Module(m.Path).Warningf("module %s has empty version, defaults to HEAD. The license URL may be incorrect. Please verify!", m.Path)
 	}
 	relativePath, err := filepath.Rel(m.Dir, filePath)
 	if err != nil {
-		return "", wrap(err)
+		return nil, wrap(err)
+	}
+	fileURL, rawURLOf := remote.FileURL, remote.RawURL
+	if m.Path == stdlib.ModulePath {
+		// Dir of the standard library is GOROOT, the root of the Go repo,
+		// while its source info is relative to GOROOT/src.
+		fileURL, rawURLOf = remote.RepoFileURL, remote.RepoRawURL
+	}
+	url := fileURL(relativePath)
+	if url == "" {
+		return nil, wrap(fmt.Errorf("%w: no file URLs known for %s", ErrUnsupportedHost, remote))
+	}
+	return &licenseURL{
+		remote:       remote,
+		relativePath: relativePath,
+		url:          url,
+		rawURL:       rawURLOf(relativePath),
+	}, nil
+}
+
+// LicenseURLNoValidate returns the URL of the license file in this library
+// and the raw URL its content would be downloaded from, constructed from go
+// module name and version like LicenseURL, but without validating them. It
+// does not access the network, so rawURL is empty if the host of the module
+// does not support raw URLs, and modules only found by querying their go-import
+// meta tags are ErrUnsupportedHost.
+func (l *Library) LicenseURLNoValidate(ctx context.Context) (url, rawURL string, err error) {
+	u, err := l.constructURL(ctx, true)
+	if err != nil {
+		return "", "", err
+	}
+	return u.url, u.rawURL, nil
+}
+
+// LicenseURL attempts to determine the URL for the license file in this library
+// using go module name and version.
+func (l *Library) LicenseURL(ctx context.Context) (string, error) {
+	u, err := l.constructURL(ctx, false)
+	if err != nil {
+		return "", err
 	}
-	url := remote.FileURL(relativePath)
 	if testOnlySkipValidation {
-		return url, nil
+		return u.url, nil
 	}
+	// Module versions are immutable, so validated URLs of their license
+	// files are cached, unless the local file differs, e.g. when vendored.
+	// Keys hold the hash of the local file, not its content.
+	var key string
+	if m := l.module; m.Version != "" {
+		if content, err := ioutil.ReadFile(l.LicensePath); err == nil {
+			sum := sha256.Sum256(content)
+			key = m.Path + "@" + m.Version + "\x00" + u.relativePath + "\x00" + hex.EncodeToString(sum[:])
+			if data, ok := l.cache.Get(CacheURLs, key); ok {
+				return string(data), nil
+			}
+		}
+	}
+	start := time.Now()
+	url, err := l.validateURL(u)
+	measure(l.metrics, PhaseValidatingURLs, start, 1, err)
+	if err == nil && key != "" {
+		if err := l.cache.Put(CacheURLs, key, []byte(url)); err != nil {
+			logging.Warningf("Failed to cache license URL %s: %v", url, err)
+		}
+	}
+	return url, err
+}
+
+// validateURL returns the URL of the license file whose remote content
+// matches the local license file, trying the root of the repository if the
+// license file at the root of the module does not match.
+func (l *Library) validateURL(u *licenseURL) (string, error) {
+	remote, relativePath, url := u.remote, u.relativePath, u.url
 	// An error during validation, the URL may still be valid.
 	validationError := func(err error) error {
 		return fmt.Errorf("failed to validate %s: %w", url, err)
@@ -264,9 +1056,9 @@ func (l *Library) LicenseURL(ctx context.Context) (string, error) {
 	}
 	localContent := string(localContentBytes)
 	// Attempt 1
-	rawURL := remote.RawURL(relativePath)
+	rawURL := u.rawURL
 	if rawURL == "" {
-		glog.Warningf(
+		logging.Warningf(
 			"Skipping license URL validation, because %s. Please verify whether %s matches content of %s manually!",
 			validationError(fmt.Errorf("remote repo %s does not support raw URL", remote)),
 			url,
@@ -274,7 +1066,7 @@ func (l *Library) LicenseURL(ctx context.Context) (string, error) {
 		)
 		return url, nil
 	}
-	validationError1 := validate(rawURL, localContent)
+	validationError1 := l.validateFile(remote, remote.RepoPath(relativePath), rawURL, localContent)
 	if validationError1 == nil {
 		// The found URL is valid!
 		return url, nil
@@ -294,38 +1086,83 @@ func (l *Library) LicenseURL(ctx context.Context) (string, error) {
 		return "", validationError1
 	}
 	// For the same remote, no need to check rawURL != "" again.
-	validationError2 := validate(rawURL2, localContent)
+	validationError2 := l.validateFile(remote, "LICENSE", rawURL2, localContent)
 	if validationError2 == nil {
 		return url2, nil
 	}
-	return "", fmt.Errorf("cannot infer remote URL for %s, failed attempts:\n\tattempt 1: %s\n\tattempt 2: %s", l.LicensePath, validationError1, validationError2)
+	return "", fmt.Errorf("cannot infer remote URL for %s, failed attempts:\n\tattempt 1: %w\n\tattempt 2: %s", l.LicensePath, validationError1, validationError2)
+}
+
+// moduleSource returns the source repository info of a module version, see
+// source.ModuleInfo. Info of tagged versions is cached, because it does not
+// change, unless it was looked up offline and may be inaccurate.
+func moduleSource(ctx context.Context, client *source.Client, cache *Cache, path, version string, offline bool) (*source.Info, error) {
+	key := path + "@" + version
+	if version != "" {
+		if data, ok := cache.Get(CacheSources, key); ok {
+			info := &source.Info{}
+			if err := json.Unmarshal(data, info); err == nil {
+				return info, nil
+			}
+		}
+	}
+	info, err := source.ModuleInfo(ctx, client, path, version)
+	if err != nil || info == nil || version == "" || offline {
+		return info, err
+	}
+	if data, err := json.Marshal(info); err == nil {
+		if err := cache.Put(CacheSources, key, data); err != nil {
+			logging.Warningf("Failed to cache source info of %s: %v", key, err)
+		}
+	}
+	return info, nil
+}
+
+// validateFile validates that the remote file at repoPath, whose raw content
+// is served at rawURL, matches localContent. With Options.ValidateHashes, it
+// compares git blob hashes instead of downloading the file, if the host of
+// the repo exposes them.
+func (l *Library) validateFile(remote *source.Info, repoPath, rawURL, localContent string) error {
+	if l.validateHashes {
+		if ok, err := validateHash(l.client(), l.cache, remote, repoPath, []byte(localContent)); ok {
+			return err
+		}
+	}
+	return validate(l.client(), l.cache, rawURL, localContent)
 }
 
 // validate validates content of rawURL matches localContent.
-func validate(rawURL string, localContent string) error {
-	remoteContent, err := download(rawURL)
+func validate(client *http.Client, cache *Cache, rawURL string, localContent string) error {
+	if remoteContent, ok := cache.Get(CacheHTTP, rawURL); ok && string(remoteContent) == localContent {
+		return nil
+	}
+	remoteContent, err := download(client, rawURL)
 	if err != nil {
 		// Retry after 1 sec.
 		time.Sleep(time.Second)
-		remoteContent, err = download(rawURL)
+		remoteContent, err = download(client, rawURL)
 		if err != nil {
 			return err
 		}
 	}
+	if err := cache.Put(CacheHTTP, rawURL, []byte(remoteContent)); err != nil {
+		logging.Warningf("Failed to cache %s: %v", rawURL, err)
+	}
 	if remoteContent != localContent {
-		return fmt.Errorf("local license file content does not match remote license URL %s", rawURL)
+		return fmt.Errorf("%w %s", ErrValidationMismatch, rawURL)
 	}
 	return nil
 }
 
-func download(url string) (string, error) {
-	resp, err := http.Get(url)
+// download returns the content of url.
+func download(client *http.Client, url string) (string, error) {
+	resp, err := client.Get(url)
 	if err != nil {
 		return "", fmt.Errorf("download(%q): %w", url, err)
 	}
 	defer resp.Body.Close()
 	if resp.StatusCode >= 400 {
-		return "", fmt.Errorf("download(%q): response status code %v not OK", url, resp.StatusCode)
+		return "", &HTTPError{URL: url, StatusCode: resp.StatusCode}
 	}
 	bodyBytes, err := ioutil.ReadAll(resp.Body)
 	if err != nil {
@@ -334,6 +1171,18 @@ func download(url string) (string, error) {
 	return string(bodyBytes), nil
 }
 
+// isTestMain returns true if this package is the generated main package of a test binary.
+func isTestMain(pkg *packages.Package) bool {
+	return pkg.Name == "main" && strings.HasSuffix(pkg.PkgPath, ".test")
+}
+
+// isExternalTest returns true if this package is an external test package,
+// i.e. of _test.go files in package p_test, whose import path is the one of
+// package p with a _test suffix.
+func isExternalTest(pkg *packages.Package) bool {
+	return strings.HasSuffix(pkg.Name, "_test") && strings.HasSuffix(pkg.PkgPath, "_test")
+}
+
 // isStdLib returns true if this package is part of the Go standard library.
 func isStdLib(pkg *packages.Package) bool {
 	if pkg.Name == "unsafe" {
@@ -16,11 +16,17 @@ package licenses
 
 import (
 	"context"
+	"errors"
+	"io/ioutil"
+	"net/http"
 	"os"
+	"path/filepath"
+	"strings"
 	"testing"
 
 	"github.com/google/go-cmp/cmp"
 	"github.com/google/go-cmp/cmp/cmpopts"
+	"golang.org/x/tools/go/packages"
 )
 
 func TestLibraries(t *testing.T) {
@@ -90,6 +96,181 @@ func TestLibraries(t *testing.T) {
 	}
 }
 
+func TestLibrariesWithOptions(t *testing.T) {
+	classifier := classifierStub{
+		licenseNames: map[string]string{
+			"testdata/LICENSE":          "foo",
+			"testdata/direct/LICENSE":   "foo",
+			"testdata/indirect/LICENSE": "foo",
+		},
+		licenseTypes: map[string]Type{
+			"testdata/LICENSE":          Notice,
+			"testdata/direct/LICENSE":   Notice,
+			"testdata/indirect/LICENSE": Notice,
+		},
+	}
+
+	for _, test := range []struct {
+		desc       string
+		importPath string
+		opts       Options
+		wantLibs   []string
+	}{
+		{
+			desc:       "Build tagged package with build tags option",
+			importPath: "github.com/Bobgy/go-licenses/v2/licenses/testdata/tags",
+			opts:       Options{BuildTags: []string{"tags"}},
+			wantLibs: []string{
+				"github.com/Bobgy/go-licenses/v2/licenses/testdata/tags",
+				"github.com/Bobgy/go-licenses/v2/licenses/testdata/indirect",
+			},
+		},
+		{
+			desc:       "Platform specific package for host platform",
+			importPath: "github.com/Bobgy/go-licenses/v2/licenses/testdata/platform",
+			opts:       Options{GOOS: "linux", GOARCH: "amd64"},
+			wantLibs: []string{
+				"github.com/Bobgy/go-licenses/v2/licenses/testdata/platform",
+			},
+		},
+		{
+			desc:       "Platform specific package for another platform",
+			importPath: "github.com/Bobgy/go-licenses/v2/licenses/testdata/platform",
+			opts:       Options{GOOS: "windows", GOARCH: "amd64"},
+			wantLibs: []string{
+				"github.com/Bobgy/go-licenses/v2/licenses/testdata/platform",
+				"github.com/Bobgy/go-licenses/v2/licenses/testdata/indirect",
+			},
+		},
+		{
+			desc:       "Excludes test-only dependency",
+			importPath: "github.com/Bobgy/go-licenses/v2/licenses/testdata/testonly",
+			wantLibs: []string{
+				"github.com/Bobgy/go-licenses/v2/licenses/testdata/testonly",
+			},
+		},
+		{
+			desc:       "Includes test-only dependency",
+			importPath: "github.com/Bobgy/go-licenses/v2/licenses/testdata/testonly",
+			opts:       Options{IncludeTests: true},
+			wantLibs: []string{
+				"github.com/Bobgy/go-licenses/v2/licenses/testdata/testonly",
+				"github.com/Bobgy/go-licenses/v2/licenses/testdata/indirect",
+			},
+		},
+		{
+			desc:       "Excludes external test package",
+			importPath: "github.com/Bobgy/go-licenses/v2/licenses/testdata/xtest",
+			opts:       Options{IncludeTests: true},
+			wantLibs: []string{
+				"github.com/Bobgy/go-licenses/v2/licenses/testdata/xtest",
+				"github.com/Bobgy/go-licenses/v2/licenses/testdata/indirect",
+			},
+		},
+		{
+			desc:       "Ignores package but not its dependencies",
+			importPath: "github.com/Bobgy/go-licenses/v2/licenses/testdata/direct",
+			opts:       Options{Ignore: []string{"github.com/Bobgy/go-licenses/v2/licenses/testdata/direct"}},
+			wantLibs: []string{
+				"github.com/Bobgy/go-licenses/v2/licenses/testdata/indirect",
+			},
+		},
+	} {
+		t.Run(test.desc, func(t *testing.T) {
+			gotLibs, err := LibrariesWithOptions(context.Background(), classifier, test.opts, test.importPath)
+			if err != nil {
+				t.Fatalf("LibrariesWithOptions(_, %+v, %q) = (_, %q), want (_, nil)", test.opts, test.importPath, err)
+			}
+			var gotLibNames []string
+			for _, lib := range gotLibs {
+				gotLibNames = append(gotLibNames, lib.Name())
+			}
+			if diff := cmp.Diff(test.wantLibs, gotLibNames, cmpopts.SortSlices(func(x, y string) bool { return x < y })); diff != "" {
+				t.Errorf("LibrariesWithOptions(_, %+v, %q): diff (-want +got)\n%s", test.opts, test.importPath, diff)
+			}
+		})
+	}
+}
+
+func TestPackagesErrors(t *testing.T) {
+	dep := &packages.Package{
+		PkgPath: "example.com/dep",
+		Errors:  []packages.Error{{Pos: "/src/dep/dep.go:3:1", Msg: "expected 'package', found 'func'", Kind: packages.ParseError}},
+	}
+	root := &packages.Package{
+		PkgPath: "example.com/root",
+		Imports: map[string]*packages.Package{"example.com/dep": dep},
+		Errors:  []packages.Error{{Msg: "no required module provides package example.com/missing", Kind: packages.ListError}},
+	}
+	err := PackagesError{pkgs: []*packages.Package{root}}
+	want := []PackageError{
+		{Package: "example.com/dep", Kind: PackageErrorParse, Pos: "/src/dep/dep.go:3:1", Msg: "expected 'package', found 'func'"},
+		{Package: "example.com/root", Kind: PackageErrorList, Msg: "no required module provides package example.com/missing"},
+	}
+	if diff := cmp.Diff(want, err.Errors()); diff != "" {
+		t.Errorf("Errors() diff (-want +got):\n%s", diff)
+	}
+	if got, want := want[0].Error(), "example.com/dep: /src/dep/dep.go:3:1: expected 'package', found 'func'"; got != want {
+		t.Errorf("PackageError.Error() = %q, want %q", got, want)
+	}
+}
+
+func TestOptionsIgnored(t *testing.T) {
+	opts := Options{Ignore: []string{"github.com/mycorp", "example.com/internal/"}}
+	for _, test := range []struct {
+		path string
+		want bool
+	}{
+		{path: "github.com/mycorp", want: true},
+		{path: "github.com/mycorp/lib/pkg", want: true},
+		{path: "github.com/mycorporation/lib", want: false},
+		{path: "example.com/internal/pkg", want: true},
+		{path: "example.com/other", want: false},
+	} {
+		if got := opts.ignored(test.path); got != test.want {
+			t.Errorf("ignored(%q) = %t, want %t", test.path, got, test.want)
+		}
+	}
+}
+
+func TestLicenseDirModule(t *testing.T) {
+	gopath := filepath.FromSlash("/home/user/go/src")
+	for _, test := range []struct {
+		desc        string
+		pkg         *packages.Package
+		licensePath string
+		want        *Module
+	}{
+		{
+			desc: "License at repository root",
+			pkg: &packages.Package{
+				PkgPath: "github.com/foo/bar/pkg/util",
+				GoFiles: []string{filepath.Join(gopath, "github.com/foo/bar/pkg/util/util.go")},
+			},
+			licensePath: filepath.Join(gopath, "github.com/foo/bar/LICENSE"),
+			want: &Module{
+				Path: "github.com/foo/bar",
+				Dir:  filepath.Join(gopath, "github.com/foo/bar"),
+			},
+		},
+		{
+			desc: "Directory does not match import path",
+			pkg: &packages.Package{
+				PkgPath: "github.com/foo/bar",
+				GoFiles: []string{filepath.FromSlash("/src/bar/bar.go")},
+			},
+			licensePath: filepath.FromSlash("/src/bar/LICENSE"),
+		},
+	} {
+		t.Run(test.desc, func(t *testing.T) {
+			got := licenseDirModule(test.pkg, test.licensePath)
+			if diff := cmp.Diff(test.want, got); diff != "" {
+				t.Errorf("licenseDirModule(): diff (-want +got)\n%s", diff)
+			}
+		})
+	}
+}
+
 func TestLibraryName(t *testing.T) {
 	for _, test := range []struct {
 		desc     string
@@ -138,6 +319,25 @@ func TestLibraryName(t *testing.T) {
 	}
 }
 
+func TestSortLibraries(t *testing.T) {
+	libs := []*Library{
+		{LicensePath: "/b/LICENSE", Packages: []string{"example.com/b"}},
+		{LicensePath: "/c/LICENSE", Packages: []string{"example.com/c/y", "example.com/c/x"}, NonGoComponents: []*NonGoComponent{{Package: "example.com/c/y"}, {Package: "example.com/c/x"}}},
+		{LicensePath: "/a/LICENSE.2", Packages: []string{"example.com/a"}},
+		{LicensePath: "/a/LICENSE.1", Packages: []string{"example.com/a"}},
+	}
+	sortLibraries(libs)
+	want := []*Library{
+		{LicensePath: "/a/LICENSE.1", Packages: []string{"example.com/a"}},
+		{LicensePath: "/a/LICENSE.2", Packages: []string{"example.com/a"}},
+		{LicensePath: "/b/LICENSE", Packages: []string{"example.com/b"}},
+		{LicensePath: "/c/LICENSE", Packages: []string{"example.com/c/x", "example.com/c/y"}, NonGoComponents: []*NonGoComponent{{Package: "example.com/c/x"}, {Package: "example.com/c/y"}}},
+	}
+	if diff := cmp.Diff(want, libs, cmp.AllowUnexported(Library{})); diff != "" {
+		t.Errorf("sortLibraries() diff (-want +got):\n%s", diff)
+	}
+}
+
 func TestLibraryFileURL(t *testing.T) {
 	for _, test := range []struct {
 		desc    string
@@ -239,3 +439,133 @@ func TestLibraryFileURL(t *testing.T) {
 		})
 	}
 }
+
+func TestLicenseURLNoValidate(t *testing.T) {
+	noNetwork := &http.Client{Transport: roundTripperFunc(func(req *http.Request) (*http.Response, error) {
+		t.Errorf("LicenseURLNoValidate() requested %s", req.URL)
+		return nil, errors.New("no network")
+	})}
+	for _, test := range []struct {
+		desc       string
+		lib        *Library
+		wantURL    string
+		wantRawURL string
+		wantErr    bool
+	}{
+		{
+			desc: "Library on GitHub",
+			lib: &Library{
+				LicensePath: "/go/modcache/github.com/google/trillian/LICENSE",
+				module: &Module{
+					Path:    "github.com/google/trillian",
+					Dir:     "/go/modcache/github.com/google/trillian",
+					Version: "v1.2.3",
+				},
+				httpClient: noNetwork,
+			},
+			wantURL:    "https://github.com/google/trillian/blob/v1.2.3/LICENSE",
+			wantRawURL: "https://github.com/google/trillian/raw/v1.2.3/LICENSE",
+		},
+		{
+			desc: "Library on k8s.io needs go-import meta tags",
+			lib: &Library{
+				LicensePath: "/go/modcache/k8s.io/api/LICENSE",
+				module: &Module{
+					Path:    "k8s.io/api",
+					Dir:     "/go/modcache/k8s.io/api",
+					Version: "v0.23.1",
+				},
+				httpClient: noNetwork,
+			},
+			wantErr: true,
+		},
+		{
+			desc: "Library without module",
+			lib: &Library{
+				LicensePath: "/go/src/foo/LICENSE",
+				httpClient:  noNetwork,
+			},
+			wantErr: true,
+		},
+	} {
+		t.Run(test.desc, func(t *testing.T) {
+			url, rawURL, err := test.lib.LicenseURLNoValidate(context.Background())
+			if test.wantErr {
+				if err == nil {
+					t.Fatalf("LicenseURLNoValidate() = (%q, %q, nil), want error", url, rawURL)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("LicenseURLNoValidate() = %v", err)
+			}
+			if url != test.wantURL || rawURL != test.wantRawURL {
+				t.Errorf("LicenseURLNoValidate() = (%q, %q), want (%q, %q)", url, rawURL, test.wantURL, test.wantRawURL)
+			}
+		})
+	}
+}
+
+func TestLicenseURLCache(t *testing.T) {
+	defer func(skip bool) { testOnlySkipValidation = skip }(testOnlySkipValidation)
+	testOnlySkipValidation = false
+	dir := t.TempDir()
+	if err := ioutil.WriteFile(filepath.Join(dir, "LICENSE"), []byte("MIT License"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	var requested []string
+	client := &http.Client{Transport: roundTripperFunc(func(req *http.Request) (*http.Response, error) {
+		requested = append(requested, req.URL.String())
+		return &http.Response{StatusCode: http.StatusOK, Body: ioutil.NopCloser(strings.NewReader("MIT License"))}, nil
+	})}
+	validations := 0
+	lib := &Library{
+		Packages:    []string{"github.com/google/trillian"},
+		LicensePath: filepath.Join(dir, "LICENSE"),
+		module:      &Module{Path: "github.com/google/trillian", Dir: dir, Version: "v1.2.3"},
+		cache:       NewCache("", 0),
+		httpClient:  client,
+		metrics: func(m Measurement) {
+			validations++
+		},
+	}
+	want := "https://github.com/google/trillian/blob/v1.2.3/LICENSE"
+	for i := 0; i < 2; i++ {
+		if got, err := lib.LicenseURL(context.Background()); err != nil || got != want {
+			t.Fatalf("LicenseURL() = (%q, %v), want (%q, nil)", got, err, want)
+		}
+	}
+	if validations != 1 || len(requested) != 1 {
+		t.Errorf("LicenseURL() validated %d times with requests %q, want once", validations, requested)
+	}
+}
+
+// roundTripperFunc stubs network access of an HTTP client.
+type roundTripperFunc func(*http.Request) (*http.Response, error)
+
+func (f roundTripperFunc) RoundTrip(req *http.Request) (*http.Response, error) {
+	return f(req)
+}
+
+func TestValidateHTTPClient(t *testing.T) {
+	var requested []string
+	client := &http.Client{Transport: roundTripperFunc(func(req *http.Request) (*http.Response, error) {
+		requested = append(requested, req.URL.String())
+		if req.URL.Path == "/limited/LICENSE" {
+			return &http.Response{StatusCode: http.StatusTooManyRequests, Body: ioutil.NopCloser(strings.NewReader(""))}, nil
+		}
+		return &http.Response{StatusCode: http.StatusOK, Body: ioutil.NopCloser(strings.NewReader("MIT License"))}, nil
+	})}
+	if err := validate(client, nil, "https://example.com/mit/LICENSE", "MIT License"); err != nil {
+		t.Errorf("validate() of matching license = %v, want nil", err)
+	}
+	if err := validate(client, nil, "https://example.com/mit/LICENSE", "Apache License"); !errors.Is(err, ErrValidationMismatch) {
+		t.Errorf("validate() of other license = %v, want ErrValidationMismatch", err)
+	}
+	if _, err := download(client, "https://example.com/limited/LICENSE"); !errors.Is(err, ErrRateLimited) {
+		t.Errorf("download() of rate limited URL = %v, want ErrRateLimited", err)
+	}
+	if len(requested) != 3 {
+		t.Errorf("client made %d requests, want 3: %v", len(requested), requested)
+	}
+}
@@ -0,0 +1,90 @@
+// Copyright 2022 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package licenses
+
+import (
+	"github.com/Bobgy/go-licenses/v2/internal/logging"
+)
+
+const (
+	// DefaultMaxFileSize is the size in bytes of the largest file classified
+	// as a license candidate. License files are a few KiB, larger files are
+	// rather data files matching the candidate names, e.g. README.csv.
+	DefaultMaxFileSize = 1 << 20
+	// DefaultMaxDepth is the number of nested directories searched for
+	// license files below an embedded directory.
+	DefaultMaxDepth = 8
+)
+
+// DefaultSkipDirs are the names of directories not searched for license
+// files below an embedded directory.
+var DefaultSkipDirs = []string{".git", "node_modules", "testdata"}
+
+// ScanLimits bound the search for license files, so that scans do not stall
+// on modules containing huge data files or deep directory trees. The zero
+// value uses the defaults.
+type ScanLimits struct {
+	// MaxFileSize is the size in bytes of the largest file classified as a
+	// license candidate. It defaults to DefaultMaxFileSize, and is unlimited
+	// if negative.
+	MaxFileSize int64
+	// MaxDepth is the number of nested directories searched below embedded
+	// directories, see Library.LicensePath of embedded assets. Searches up
+	// from packages are always bounded by their module. It defaults to
+	// DefaultMaxDepth, and is unlimited if negative.
+	MaxDepth int
+	// SkipDirs are the names of directories not searched below embedded
+	// directories. It defaults to DefaultSkipDirs if nil, and skips no
+	// directories if empty.
+	SkipDirs []string
+}
+
+// tooLarge reports whether a file is too large to be a license candidate,
+// logging skipped files.
+func (l ScanLimits) tooLarge(path string, size int64) bool {
+	max := l.MaxFileSize
+	if max == 0 {
+		max = DefaultMaxFileSize
+	}
+	if max < 0 || size <= max {
+		return false
+	}
+	logging.Warningf("Skipping license candidate %s: its size of %d bytes exceeds the limit of %d bytes", path, size, max)
+	return true
+}
+
+// tooDeep reports whether a directory nested depth directories below the
+// searched directory is not searched.
+func (l ScanLimits) tooDeep(depth int) bool {
+	max := l.MaxDepth
+	if max == 0 {
+		max = DefaultMaxDepth
+	}
+	return max >= 0 && depth > max
+}
+
+// skipped reports whether directories named name are not searched.
+func (l ScanLimits) skipped(name string) bool {
+	skipDirs := l.SkipDirs
+	if skipDirs == nil {
+		skipDirs = DefaultSkipDirs
+	}
+	for _, d := range skipDirs {
+		if d == name {
+			return true
+		}
+	}
+	return false
+}
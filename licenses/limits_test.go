@@ -0,0 +1,111 @@
+// Copyright 2022 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package licenses
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+// writeFiles writes files of sizes, by slash-separated path, in dir.
+func writeFiles(t *testing.T, dir string, sizes map[string]int) {
+	t.Helper()
+	for path, size := range sizes {
+		path = filepath.Join(dir, filepath.FromSlash(path))
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := ioutil.WriteFile(path, []byte(strings.Repeat("x", size)), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestEmbeddedLicensesLimits(t *testing.T) {
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string]int{
+		"static/LICENSE":                   10,
+		"static/big/LICENSE":               100,
+		"static/a/b/c/LICENSE":             10,
+		"static/node_modules/left/LICENSE": 10,
+		"static/testdata/LICENSE":          10,
+	})
+	for _, test := range []struct {
+		desc   string
+		limits ScanLimits
+		want   []string
+	}{
+		{
+			desc:   "defaults",
+			limits: ScanLimits{},
+			want:   []string{"static/LICENSE", "static/a/b/c/LICENSE", "static/big/LICENSE"},
+		},
+		{
+			desc:   "limited",
+			limits: ScanLimits{MaxFileSize: 50, MaxDepth: 2},
+			want:   []string{"static/LICENSE"},
+		},
+		{
+			desc:   "unlimited",
+			limits: ScanLimits{MaxFileSize: -1, MaxDepth: -1, SkipDirs: []string{}},
+			want:   []string{"static/LICENSE", "static/a/b/c/LICENSE", "static/big/LICENSE", "static/node_modules/left/LICENSE", "static/testdata/LICENSE"},
+		},
+	} {
+		t.Run(test.desc, func(t *testing.T) {
+			paths, err := embeddedLicenses(dir, dir, []string{"static"}, &countingClassifier{}, test.limits)
+			if err != nil {
+				t.Fatalf("embeddedLicenses() = (_, %v), want (_, nil)", err)
+			}
+			var got []string
+			for _, path := range paths {
+				rel, err := filepath.Rel(dir, path)
+				if err != nil {
+					t.Fatal(err)
+				}
+				got = append(got, filepath.ToSlash(rel))
+			}
+			if diff := cmp.Diff(test.want, got); diff != "" {
+				t.Errorf("embeddedLicenses(): diff (-want +got)\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestFindAllMaxFileSize(t *testing.T) {
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string]int{"LICENSE": 10, "pkg/README.csv": 100})
+	classifier := &countingClassifier{}
+	candidates, err := newFinder(classifier, ScanLimits{MaxFileSize: 50}).findAll(filepath.Join(dir, "pkg"), dir)
+	if err != nil {
+		t.Fatalf("findAll() = (_, %v), want (_, nil)", err)
+	}
+	if len(candidates) != 1 || candidates[0].Path != filepath.Join(dir, "LICENSE") {
+		t.Errorf("findAll() = %+v, want only LICENSE", candidates)
+	}
+	if classifier.calls != 1 {
+		t.Errorf("classifier called %d times, want once for LICENSE", classifier.calls)
+	}
+
+	_, err = newFinder(classifier, ScanLimits{MaxFileSize: 5}).findAll(filepath.Join(dir, "pkg"), dir)
+	if !errors.Is(err, ErrNoLicenseFound) {
+		t.Errorf("findAll() of too large files = %v, want ErrNoLicenseFound", err)
+	}
+}
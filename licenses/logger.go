@@ -0,0 +1,41 @@
+// Copyright 2022 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package licenses
+
+import "github.com/Bobgy/go-licenses/v2/internal/logging"
+
+// Logger receives the logs of go-licenses, see SetLogger. It may be called
+// concurrently.
+type Logger = logging.Logger
+
+// LogLevel is the level of a log entry.
+type LogLevel = logging.Level
+
+// LogFields are structured fields of a log entry, e.g. the module it is about.
+type LogFields = logging.Fields
+
+// Log levels
+const (
+	LogInfo    = logging.Info
+	LogWarning = logging.Warning
+	LogError   = logging.Error
+)
+
+// SetLogger routes the logs of go-licenses to l, e.g. into the logging stack
+// of a program embedding go-licenses, instead of glog. A nil l restores glog.
+// Like glog, the logger is global to the program.
+func SetLogger(l Logger) {
+	logging.SetLogger(l)
+}
@@ -0,0 +1,51 @@
+// Copyright 2022 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package licenses
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// recordingLogger records log entries.
+type recordingLogger struct {
+	mu      sync.Mutex
+	entries []string
+}
+
+func (l *recordingLogger) Log(level LogLevel, fields LogFields, msg string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.entries = append(l.entries, string(level)+" "+fields.Module+": "+msg)
+}
+
+func TestSetLogger(t *testing.T) {
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	logger := &recordingLogger{}
+	SetLogger(logger)
+	defer SetLogger(nil)
+	classifier := classifierStub{errors: map[string]error{"unknown/LICENSE": errors.New("unknown license")}}
+	identifyLibrary(classifier, &Library{LicensePath: filepath.Join(wd, "unknown/LICENSE"), Packages: []string{"example.com/unknown"}})
+	if len(logger.entries) != 1 || !strings.HasPrefix(logger.entries[0], "info example.com/unknown: Cannot identify license") {
+		t.Errorf("SetLogger() logger got %q, want an info entry about example.com/unknown", logger.entries)
+	}
+}
@@ -0,0 +1,61 @@
+// Copyright 2022 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package licenses
+
+import "time"
+
+// Phases of measurements, in addition to the phases of progress events.
+const (
+	// PhaseListingModules lists the modules embedded in a binary.
+	PhaseListingModules = "listing modules"
+	// PhaseDownloadingModules downloads a module missing from the module
+	// cache, see Options.DownloadModules.
+	PhaseDownloadingModules = "downloading modules"
+	// PhaseValidatingURLs validates the URL of a library's license file by
+	// downloading it, see Library.LicenseURL.
+	PhaseValidatingURLs = "validating license URLs"
+)
+
+// Measurement reports the duration and number of items of a completed phase
+// of a scan, e.g. to export metrics of long-running services. Each scan
+// measures PhaseFindingLicenses and PhaseClassifyingLicenses once, after
+// PhaseLoadingPackages or PhaseListingModules unless packages or modules were
+// passed by the caller. Each module downloaded measures
+// PhaseDownloadingModules, and each call of Library.LicenseURL measures
+// PhaseValidatingURLs, with one item.
+type Measurement struct {
+	// Phase is the measured phase, e.g. PhaseClassifyingLicenses.
+	Phase string
+	// Duration is how long the phase took.
+	Duration time.Duration
+	// Items is the number of items processed in the phase, e.g. packages or
+	// libraries.
+	Items int
+	// Err is the error the phase failed with, if any.
+	Err error
+}
+
+// measure reports a measurement of a phase started at start to the Metrics
+// option, if set.
+func (o Options) measure(phase string, start time.Time, items int, err error) {
+	measure(o.Metrics, phase, start, items, err)
+}
+
+// measure calls f, if set, with a measurement of a phase started at start.
+func measure(f func(Measurement), phase string, start time.Time, items int, err error) {
+	if f != nil {
+		f(Measurement{Phase: phase, Duration: time.Since(start), Items: items, Err: err})
+	}
+}
@@ -24,12 +24,25 @@ import (
 type Module struct {
 	// Differences from packages.Module:
 	// * Replace field is removed, it's only an implementation detail in this package.
-	//   If a module is replaced, we'll directly return the replaced module.
+	//   If a module is replaced, we'll directly return the replacement module
+	//   and keep the replaced module path in OriginalPath.
 	// * Version field +incompatible suffix is trimmed.
-	// * Main, ModuleError, Time, Indirect, GoMod, GoVersion fields are removed, because they are not used.
+	// * Main, ModuleError, Time, GoMod, GoVersion fields are removed, because they are not used.
 	Path    string // module path
 	Version string // module version
 	Dir     string // directory holding files for this module, if any
+	// OriginalPath is the module path required by go.mod, when this module
+	// replaces it by a replace directive. It is empty for modules that are
+	// not replaced.
+	OriginalPath string
+	// OriginalVersion is the module version required by go.mod, when this
+	// module replaces it by a replace directive and the version is known.
+	OriginalVersion string
+	// Sum is the checksum of the module contents, e.g. "h1:...", if known.
+	Sum string
+	// Indirect reports whether the module is only required indirectly by the
+	// main module, i.e. marked "// indirect" in its go.mod.
+	Indirect bool
 }
 
 func newModule(mod *packages.Module) *Module {
@@ -55,15 +68,42 @@ func newModule(mod *packages.Module) *Module {
 	// Haven't confirmed, but we may also need to override the
 	// entire struct when using replace directive with local folders.
 	tmp := *mod
+	originalPath, originalVersion, indirect := "", "", mod.Indirect
 	if tmp.Replace != nil {
+		originalPath = tmp.Path
+		originalVersion = strings.TrimSuffix(tmp.Version, "+incompatible")
 		tmp = *tmp.Replace
 	}
 	// The +incompatible suffix does not affect module version.
 	// ref: https://golang.org/ref/mod#incompatible-versions
 	tmp.Version = strings.TrimSuffix(tmp.Version, "+incompatible")
 	return &Module{
-		Path:    tmp.Path,
-		Version: tmp.Version,
-		Dir:     tmp.Dir,
+		Path:            tmp.Path,
+		Version:         tmp.Version,
+		Dir:             tmp.Dir,
+		OriginalPath:    originalPath,
+		OriginalVersion: originalVersion,
+		Indirect:        indirect,
 	}
 }
+
+// Replaced reports whether the module replaces the module required by go.mod
+// by a replace directive.
+func (m *Module) Replaced() bool {
+	return m.OriginalPath != ""
+}
+
+// LocalReplace reports whether the module is replaced by a local directory,
+// which has no version.
+func (m *Module) LocalReplace() bool {
+	return m.Replaced() && m.Version == ""
+}
+
+// SumKey returns the "path@version" key of the module in go.sum, see
+// ReadGoSum, or "" if the module has no version.
+func (m *Module) SumKey() string {
+	if m.Version == "" {
+		return ""
+	}
+	return m.Path + "@" + moduleVersion(m.Path, m.Version)
+}
@@ -0,0 +1,114 @@
+// Copyright 2022 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package licenses
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"golang.org/x/tools/go/packages"
+)
+
+func TestNewModule(t *testing.T) {
+	for _, test := range []struct {
+		desc             string
+		mod              *packages.Module
+		want             *Module
+		wantReplaced     bool
+		wantLocalReplace bool
+	}{
+		{
+			desc: "not replaced",
+			mod:  &packages.Module{Path: "github.com/example/old", Version: "v2.0.0+incompatible", Dir: "/mod/old"},
+			want: &Module{Path: "github.com/example/old", Version: "v2.0.0", Dir: "/mod/old"},
+		},
+		{
+			desc: "replaced by a module",
+			mod: &packages.Module{Path: "k8s.io/kubernetes", Version: "v0.17.9", Replace: &packages.Module{
+				Path: "github.com/example/kubernetes", Version: "v1.11.1", Dir: "/mod/kubernetes",
+			}},
+			want:         &Module{Path: "github.com/example/kubernetes", Version: "v1.11.1", Dir: "/mod/kubernetes", OriginalPath: "k8s.io/kubernetes", OriginalVersion: "v0.17.9"},
+			wantReplaced: true,
+		},
+		{
+			desc: "replaced by a directory",
+			mod: &packages.Module{Path: "example.com/local", Version: "v0.0.0-00010101000000-000000000000", Replace: &packages.Module{
+				Path: "./local", Dir: "/src/local",
+			}},
+			want:             &Module{Path: "./local", Dir: "/src/local", OriginalPath: "example.com/local", OriginalVersion: "v0.0.0-00010101000000-000000000000"},
+			wantReplaced:     true,
+			wantLocalReplace: true,
+		},
+	} {
+		t.Run(test.desc, func(t *testing.T) {
+			got := newModule(test.mod)
+			if diff := cmp.Diff(test.want, got); diff != "" {
+				t.Errorf("newModule(): diff (-want +got)\n%s", diff)
+			}
+			if got.Replaced() != test.wantReplaced {
+				t.Errorf("Replaced() = %t, want %t", got.Replaced(), test.wantReplaced)
+			}
+			if got.LocalReplace() != test.wantLocalReplace {
+				t.Errorf("LocalReplace() = %t, want %t", got.LocalReplace(), test.wantLocalReplace)
+			}
+		})
+	}
+}
+
+func TestParseModules(t *testing.T) {
+	out := []byte(`{
+	"Path": "example.com/main",
+	"Main": true,
+	"Dir": "/src/main"
+}
+{
+	"Path": "github.com/example/old",
+	"Version": "v2.0.0+incompatible",
+	"Dir": "/mod/old"
+}
+{
+	"Path": "k8s.io/kubernetes",
+	"Version": "v0.17.9",
+	"Replace": {
+		"Path": "github.com/example/kubernetes",
+		"Version": "v1.11.1",
+		"Dir": "/mod/kubernetes"
+	}
+}
+{
+	"Path": "example.com/missing",
+	"Version": "v1.0.0",
+	"Error": {
+		"Err": "module lookup disabled"
+	}
+}
+`)
+	want := []*Module{
+		{Path: "example.com/main", Dir: "/src/main"},
+		{Path: "github.com/example/old", Version: "v2.0.0", Dir: "/mod/old"},
+		{Path: "github.com/example/kubernetes", Version: "v1.11.1", Dir: "/mod/kubernetes", OriginalPath: "k8s.io/kubernetes", OriginalVersion: "v0.17.9"},
+		{Path: "example.com/missing", Version: "v1.0.0"},
+	}
+	got, err := parseModules(out)
+	if err != nil {
+		t.Fatalf("parseModules() = %v", err)
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("parseModules(): diff (-want +got)\n%s", diff)
+	}
+	if _, err := parseModules([]byte(`{"Path": `)); err == nil {
+		t.Errorf("parseModules(truncated) = nil error, want error")
+	}
+}
@@ -0,0 +1,125 @@
+// Copyright 2022 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package licenses
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"strings"
+
+	"github.com/Bobgy/go-licenses/v2/internal/logging"
+)
+
+// ModuleWarning reports a module version that should not be shipped for
+// reasons other than its license.
+type ModuleWarning struct {
+	// Path and Version identify the module version used by the build.
+	Path    string
+	Version string
+	// Retracted holds the reasons given by the module author for retracting
+	// this version. It is non-empty if the version is retracted.
+	Retracted []string
+	// Deprecated is the deprecation message of the module, if it is deprecated.
+	Deprecated string
+}
+
+func (w *ModuleWarning) String() string {
+	var msgs []string
+	if len(w.Retracted) > 0 {
+		msgs = append(msgs, fmt.Sprintf("%s@%s is retracted: %s", w.Path, w.Version, strings.Join(w.Retracted, "; ")))
+	}
+	if w.Deprecated != "" {
+		msgs = append(msgs, fmt.Sprintf("%s is deprecated: %s", w.Path, w.Deprecated))
+	}
+	return strings.Join(msgs, "; ")
+}
+
+// ModuleWarnings lists the modules in the build list of the main module in dir
+// and returns warnings for retracted and deprecated module versions, keyed by
+// module path. It runs `go list -m -u -retracted -json all`, which queries the
+// module proxy for the latest versions of modules. go list -m -u does not
+// support vendor mode, so modules are listed with -mod=readonly instead, and
+// the checks are skipped with a warning if that fails for a vendored build,
+// e.g. offline.
+func ModuleWarnings(ctx context.Context, opts Options, dir string) (map[string]*ModuleWarning, error) {
+	goMod, err := goEnv(ctx, opts, dir, "GOMOD")
+	if err != nil {
+		return nil, err
+	}
+	if goMod == "" {
+		logging.Warningf("Skipping retracted and deprecated module checks, because modules are disabled (GOPATH mode)")
+		return nil, nil
+	}
+	mod := opts.mod()
+	args := []string{"list", "-m", "-u", "-retracted", "-json", "-mod=readonly"}
+	if mod != "" && mod != "vendor" {
+		args[len(args)-1] = "-mod=" + mod
+	}
+	// The build list is determined by go.mod and go.sum, while answers of the
+	// module proxy may change, which the TTL of the cache accounts for.
+	key := strings.Join(args, " ") + " " + goMod
+	for _, name := range []string{goMod, strings.TrimSuffix(goMod, ".mod") + ".sum"} {
+		data, _ := ioutil.ReadFile(name)
+		key += "\x00" + string(data)
+	}
+	if out, ok := opts.Cache.Get(CacheModules, key); ok {
+		return parseModuleWarnings(out)
+	}
+	out, err := opts.goCommand(ctx, dir, append(args, "all")...).Output()
+	if err != nil {
+		if mod == "vendor" {
+			logging.Warningf("Skipping retracted and deprecated module checks of vendored modules: go list -m -u -retracted -json all: %v", err)
+			return nil, nil
+		}
+		return nil, fmt.Errorf("go list -m -u -retracted -json all: %w", err)
+	}
+	if err := opts.Cache.Put(CacheModules, key, out); err != nil {
+		logging.Warningf("Failed to cache module info: %v", err)
+	}
+	return parseModuleWarnings(out)
+}
+
+// parseModuleWarnings parses the output of `go list -m -u -retracted -json`.
+func parseModuleWarnings(out []byte) (map[string]*ModuleWarning, error) {
+	warnings := make(map[string]*ModuleWarning)
+	dec := json.NewDecoder(bytes.NewReader(out))
+	for {
+		var m struct {
+			Path       string
+			Version    string
+			Retracted  []string
+			Deprecated string
+		}
+		if err := dec.Decode(&m); err == io.EOF {
+			break
+		} else if err != nil {
+			return nil, fmt.Errorf("parsing go list output: %w", err)
+		}
+		if len(m.Retracted) == 0 && m.Deprecated == "" {
+			continue
+		}
+		warnings[m.Path] = &ModuleWarning{
+			Path:       m.Path,
+			Version:    m.Version,
+			Retracted:  m.Retracted,
+			Deprecated: m.Deprecated,
+		}
+	}
+	return warnings, nil
+}
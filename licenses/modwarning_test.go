@@ -0,0 +1,86 @@
+// Copyright 2022 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package licenses
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestParseModuleWarnings(t *testing.T) {
+	out := `{
+	"Path": "github.com/foo/bar",
+	"Main": true
+}
+{
+	"Path": "github.com/spf13/cobra",
+	"Version": "v1.3.0"
+}
+{
+	"Path": "example.com/retracted",
+	"Version": "v1.0.1",
+	"Retracted": ["contains a security bug"]
+}
+{
+	"Path": "example.com/deprecated",
+	"Version": "v0.1.0",
+	"Deprecated": "use example.com/new instead"
+}
+`
+	warnings, err := parseModuleWarnings([]byte(out))
+	if err != nil {
+		t.Fatalf("parseModuleWarnings() = (_, %q), want (_, nil)", err)
+	}
+	want := map[string]*ModuleWarning{
+		"example.com/retracted": {
+			Path:      "example.com/retracted",
+			Version:   "v1.0.1",
+			Retracted: []string{"contains a security bug"},
+		},
+		"example.com/deprecated": {
+			Path:       "example.com/deprecated",
+			Version:    "v0.1.0",
+			Deprecated: "use example.com/new instead",
+		},
+	}
+	if diff := cmp.Diff(want, warnings); diff != "" {
+		t.Errorf("parseModuleWarnings(): diff (-want +got)\n%s", diff)
+	}
+	if got, want := warnings["example.com/retracted"].String(), "example.com/retracted@v1.0.1 is retracted: contains a security bug"; got != want {
+		t.Errorf("ModuleWarning.String() = %q, want %q", got, want)
+	}
+}
+
+func TestApplyModuleWarnings(t *testing.T) {
+	retracted := &ModuleWarning{Path: "example.com/retracted", Version: "v1.0.1", Retracted: []string{"broken"}}
+	forked := &ModuleWarning{Path: "example.com/upstream", Version: "v1.2.0", Deprecated: "moved"}
+	warnings := map[string]*ModuleWarning{
+		"example.com/retracted": retracted,
+		"example.com/upstream":  forked,
+	}
+	libs := []*Library{
+		{Packages: []string{"example.com/retracted"}, module: &Module{Path: "example.com/retracted", Version: "v1.0.1"}},
+		{Packages: []string{"example.com/fork"}, module: &Module{Path: "example.com/fork", Version: "v1.2.1", OriginalPath: "example.com/upstream", OriginalVersion: "v1.2.0"}},
+		{Packages: []string{"example.com/upstream"}, module: &Module{Path: "example.com/upstream", OriginalPath: "example.com/other"}},
+		{Packages: []string{"example.com/nomodule"}},
+	}
+	applyModuleWarnings(libs, warnings)
+	for i, want := range []*ModuleWarning{retracted, forked, nil, nil} {
+		if got := libs[i].ModuleWarning; got != want {
+			t.Errorf("ModuleWarning of %s = %v, want %v", libs[i].Name(), got, want)
+		}
+	}
+}
@@ -0,0 +1,142 @@
+// Copyright 2022 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package licenses
+
+import (
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"golang.org/x/tools/go/packages"
+)
+
+// NonGoComponent describes non-Go code used by a package. Its license
+// requirements cannot be inspected by go-licenses and must be reviewed manually.
+type NonGoComponent struct {
+	// Package is the import path of the package.
+	Package string
+	// Cgo is true if the package uses cgo, i.e. imports "C".
+	Cgo bool
+	// LinkedLibraries are native libraries linked by #cgo LDFLAGS (-l flags)
+	// and #cgo pkg-config directives.
+	LinkedLibraries []string
+	// Sources are paths of bundled C, C++, Objective-C, Fortran and SWIG
+	// sources and headers, and of prebuilt .syso objects, see
+	// nativeSourceExts. Go assembly is not included, it is part of Go code.
+	Sources []string
+}
+
+// nativeSourceExts are the extensions of files the go command builds or links
+// with a native toolchain, as opposed to Go assembly (.s) files.
+var nativeSourceExts = map[string]bool{
+	".c": true, ".cc": true, ".cpp": true, ".cxx": true,
+	".h": true, ".hh": true, ".hpp": true, ".hxx": true,
+	".m": true,
+	".f": true, ".F": true, ".for": true, ".f90": true,
+	".swig": true, ".swigcxx": true,
+	".syso": true,
+}
+
+// nonGoComponent returns the non-Go component of a package, or nil if the
+// package consists of Go code only.
+func nonGoComponent(p *packages.Package) (*NonGoComponent, error) {
+	c := &NonGoComponent{Package: p.PkgPath}
+	for _, path := range p.OtherFiles {
+		if nativeSourceExts[filepath.Ext(path)] {
+			c.Sources = append(c.Sources, path)
+		}
+	}
+	for _, path := range p.GoFiles {
+		cgo, libs, err := cgoDirectives(path)
+		if err != nil {
+			return nil, err
+		}
+		c.Cgo = c.Cgo || cgo
+		c.LinkedLibraries = append(c.LinkedLibraries, libs...)
+	}
+	if !c.Cgo && len(c.Sources) == 0 {
+		return nil, nil
+	}
+	return c, nil
+}
+
+// cgoDirectives reports whether the Go file at path imports "C" and returns
+// the native libraries linked by #cgo directives in its preamble.
+func cgoDirectives(path string) (cgo bool, libs []string, err error) {
+	f, err := parser.ParseFile(token.NewFileSet(), path, nil, parser.ImportsOnly|parser.ParseComments)
+	if err != nil {
+		return false, nil, err
+	}
+	for _, decl := range f.Decls {
+		d, ok := decl.(*ast.GenDecl)
+		if !ok || d.Tok != token.IMPORT {
+			continue
+		}
+		for _, spec := range d.Specs {
+			s := spec.(*ast.ImportSpec)
+			if importPath, err := strconv.Unquote(s.Path.Value); err != nil || importPath != "C" {
+				continue
+			}
+			cgo = true
+			// The preamble is the comment immediately preceding import "C".
+			doc := s.Doc
+			if doc == nil && len(d.Specs) == 1 {
+				doc = d.Doc
+			}
+			if doc != nil {
+				libs = append(libs, linkedLibraries(doc.Text())...)
+			}
+		}
+	}
+	return cgo, libs, nil
+}
+
+// linkedLibraries parses #cgo directives in a cgo preamble like:
+//
+//	#cgo linux LDFLAGS: -L/usr/lib -lssl
+//	#cgo pkg-config: libpng
+func linkedLibraries(preamble string) []string {
+	var libs []string
+	for _, line := range strings.Split(preamble, "\n") {
+		line = strings.TrimSpace(line)
+		if !strings.HasPrefix(line, "#cgo ") {
+			continue
+		}
+		i := strings.Index(line, ":")
+		if i < 0 {
+			continue
+		}
+		directive := strings.Fields(line[:i])
+		args := strings.Fields(line[i+1:])
+		switch directive[len(directive)-1] {
+		case "LDFLAGS":
+			for _, arg := range args {
+				if strings.HasPrefix(arg, "-l") && len(arg) > 2 {
+					libs = append(libs, strings.TrimPrefix(arg, "-l"))
+				}
+			}
+		case "pkg-config":
+			for _, arg := range args {
+				if !strings.HasPrefix(arg, "-") {
+					libs = append(libs, arg)
+				}
+			}
+		}
+	}
+	return libs
+}
@@ -0,0 +1,82 @@
+// Copyright 2022 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package licenses
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"golang.org/x/tools/go/packages"
+)
+
+func TestNonGoComponent(t *testing.T) {
+	for _, test := range []struct {
+		desc string
+		pkg  *packages.Package
+		want *NonGoComponent
+	}{
+		{
+			desc: "Cgo package",
+			pkg: &packages.Package{
+				PkgPath:    "example.com/cgo",
+				GoFiles:    []string{"testdata/cgo/cgo.go"},
+				OtherFiles: []string{"testdata/cgo/hello.h", "testdata/cgo/hello_amd64.s"},
+			},
+			want: &NonGoComponent{
+				Package:         "example.com/cgo",
+				Cgo:             true,
+				LinkedLibraries: []string{"ssl", "crypto", "libpng"},
+				Sources:         []string{"testdata/cgo/hello.h"},
+			},
+		},
+		{
+			desc: "Go package",
+			pkg: &packages.Package{
+				PkgPath: "example.com/direct",
+				GoFiles: []string{"testdata/direct/direct.go"},
+			},
+		},
+		{
+			desc: "Go assembly",
+			pkg: &packages.Package{
+				PkgPath:    "example.com/asm",
+				GoFiles:    []string{"testdata/direct/direct.go"},
+				OtherFiles: []string{"testdata/asm/sum_amd64.s", "testdata/asm/sum_arm64.s"},
+			},
+		},
+		{
+			desc: "Bundled C sources and objects",
+			pkg: &packages.Package{
+				PkgPath:    "example.com/bundled",
+				GoFiles:    []string{"testdata/direct/direct.go"},
+				OtherFiles: []string{"testdata/bundled/zlib.c", "testdata/bundled/sum_amd64.s", "testdata/bundled/rsrc_windows_amd64.syso"},
+			},
+			want: &NonGoComponent{
+				Package: "example.com/bundled",
+				Sources: []string{"testdata/bundled/zlib.c", "testdata/bundled/rsrc_windows_amd64.syso"},
+			},
+		},
+	} {
+		t.Run(test.desc, func(t *testing.T) {
+			got, err := nonGoComponent(test.pkg)
+			if err != nil {
+				t.Fatalf("nonGoComponent() = (_, %q), want (_, nil)", err)
+			}
+			if diff := cmp.Diff(test.want, got); diff != "" {
+				t.Errorf("nonGoComponent(): diff (-want +got)\n%s", diff)
+			}
+		})
+	}
+}
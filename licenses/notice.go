@@ -0,0 +1,53 @@
+// Copyright 2022 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package licenses
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"regexp"
+	"sort"
+)
+
+// noticeFileRegexp matches names of files that may be NOTICE files, e.g.
+// NOTICE, NOTICE.txt or notice.rst.
+var noticeFileRegexp = regexp.MustCompile(`(?i)^notice([._-].*)?$`)
+
+// NoticeFiles returns the paths of NOTICE files of a library, as required to
+// be redistributed by licenses like Apache-2.0. They are searched for in the
+// directory of the library's license file and at the root of its module.
+func NoticeFiles(lib *Library) ([]string, error) {
+	var dirs []string
+	if lib.LicensePath != "" {
+		dirs = append(dirs, filepath.Dir(lib.LicensePath))
+	}
+	if lib.module != nil && lib.module.Dir != "" && (len(dirs) == 0 || dirs[0] != lib.module.Dir) {
+		dirs = append(dirs, lib.module.Dir)
+	}
+	var notices []string
+	for _, dir := range dirs {
+		files, err := ioutil.ReadDir(dir)
+		if err != nil {
+			return nil, err
+		}
+		for _, f := range files {
+			if !f.IsDir() && noticeFileRegexp.MatchString(f.Name()) {
+				notices = append(notices, filepath.Join(dir, f.Name()))
+			}
+		}
+	}
+	sort.Strings(notices)
+	return notices, nil
+}
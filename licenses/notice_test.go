@@ -0,0 +1,36 @@
+// Copyright 2022 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package licenses
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestNoticeFiles(t *testing.T) {
+	lib := &Library{
+		LicensePath: "testdata/notice_files/sub/LICENSE",
+		module:      &Module{Path: "github.com/foo/notice", Dir: "testdata/notice_files"},
+	}
+	got, err := NoticeFiles(lib)
+	if err != nil {
+		t.Fatalf("NoticeFiles() = %v", err)
+	}
+	want := []string{"testdata/notice_files/NOTICE.rst", "testdata/notice_files/sub/NOTICE"}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("NoticeFiles(): diff (-want +got)\n%s", diff)
+	}
+}
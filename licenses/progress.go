@@ -0,0 +1,40 @@
+// Copyright 2022 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package licenses
+
+// Phases of progress events.
+const (
+	PhaseLoadingPackages     = "loading packages"
+	PhaseFindingLicenses     = "finding licenses"
+	PhaseClassifyingLicenses = "classifying licenses"
+)
+
+// Progress is an event reporting the progress of a phase of a scan, e.g. to
+// show that scanning a large dependency tree is not hung.
+type Progress struct {
+	// Phase is the current phase, e.g. PhaseFindingLicenses.
+	Phase string `json:"phase"`
+	// Done is the number of items processed in the phase, e.g. packages.
+	Done int `json:"done"`
+	// Total is the number of items of the phase, or 0 if it is unknown.
+	Total int `json:"total"`
+}
+
+// progress reports a progress event to the Progress option, if set.
+func (o Options) progress(phase string, done, total int) {
+	if o.Progress != nil {
+		o.Progress(Progress{Phase: phase, Done: done, Total: total})
+	}
+}
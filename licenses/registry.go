@@ -0,0 +1,169 @@
+// Copyright 2022 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package licenses
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// DefaultClassifier is the name of the classifier based on
+// github.com/google/licenseclassifier, used unless another one is selected.
+const DefaultClassifier = "licenseclassifier"
+
+// Match is a license identified in the contents of a file.
+type Match struct {
+	// Name is the name of the license, preferably an SPDX ID, e.g. "MIT".
+	Name string
+	// Type is the type of the license. If Unknown, it is the type of Name
+	// according to LicenseType.
+	Type Type
+	// Confidence is the confidence of the match, between 0 and 1.
+	Confidence float64
+}
+
+// ContentClassifier identifies licenses in the contents of files. Custom
+// classifiers, e.g. one based on machine learning, implement it and are
+// registered with RegisterClassifier.
+type ContentClassifier interface {
+	// IdentifyContent returns the licenses matching the contents of the file
+	// at path, best first. It returns no matches if it identifies no license.
+	IdentifyContent(path string, contents []byte) ([]Match, error)
+}
+
+// ClassifierFactory creates a content classifier that only matches licenses
+// with at least a confidence threshold, between 0 and 1.
+type ClassifierFactory func(confidenceThreshold float64) (ContentClassifier, error)
+
+var (
+	registryMu sync.Mutex
+	registry   = make(map[string]ClassifierFactory)
+)
+
+func init() {
+	RegisterClassifier(DefaultClassifier, func(confidenceThreshold float64) (ContentClassifier, error) {
+		return &googleClassifier{confidenceThreshold: confidenceThreshold}, nil
+	})
+}
+
+// RegisterClassifier makes a classifier available by name, e.g. to the
+// --classifier flag of a program wrapping the go-licenses commands. It is
+// meant to be called from init functions, and panics if name is registered
+// twice or factory is nil.
+func RegisterClassifier(name string, factory ClassifierFactory) {
+	registryMu.Lock()
+	defer registryMu.Unlock()
+	if factory == nil {
+		panic("licenses: RegisterClassifier factory is nil")
+	}
+	if _, ok := registry[name]; ok {
+		panic("licenses: RegisterClassifier called twice for classifier " + name)
+	}
+	registry[name] = factory
+}
+
+// Classifiers returns the names of registered classifiers, sorted.
+func Classifiers() []string {
+	registryMu.Lock()
+	defer registryMu.Unlock()
+	names := make([]string, 0, len(registry))
+	for name := range registry {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// NewNamedClassifier creates the classifier registered by name, which only
+// matches licenses with at least a confidence threshold.
+func NewNamedClassifier(name string, confidenceThreshold float64) (ConfidenceClassifier, error) {
+	registryMu.Lock()
+	factory, ok := registry[name]
+	registryMu.Unlock()
+	if !ok {
+		return nil, fmt.Errorf("unknown classifier %q, must be one of %s", name, strings.Join(Classifiers(), ", "))
+	}
+	c, err := factory(confidenceThreshold)
+	if err != nil {
+		return nil, err
+	}
+	return FromContentClassifier(c), nil
+}
+
+// FromContentClassifier returns a classifier reading license files and
+// identifying them with c, by their best match. The classifier still
+// implements ContentClassifier, e.g. for FindAllFS.
+func FromContentClassifier(c ContentClassifier) ConfidenceClassifier {
+	if cc, ok := c.(ConfidenceClassifier); ok {
+		return cc
+	}
+	return contentClassifier{c}
+}
+
+// contentClassifier adapts a ContentClassifier to a ConfidenceClassifier.
+type contentClassifier struct {
+	classifier ContentClassifier
+}
+
+// Identify returns the name and type of a license, given its file path.
+func (c contentClassifier) Identify(licensePath string) (string, Type, error) {
+	name, typ, _, err := c.IdentifyConfidence(licensePath)
+	return name, typ, err
+}
+
+// IdentifyConfidence returns the name, type and confidence of a license, given
+// its file path.
+func (c contentClassifier) IdentifyConfidence(licensePath string) (string, Type, float64, error) {
+	if licensePath == "" {
+		return "", Unknown, 0, nil
+	}
+	contents, err := ioutil.ReadFile(licensePath)
+	if err != nil {
+		return "", "", 0, err
+	}
+	return identifyContent(c.classifier, licensePath, contents)
+}
+
+// IdentifyContent returns the licenses matching the contents of a file.
+func (c contentClassifier) IdentifyContent(path string, contents []byte) ([]Match, error) {
+	return c.classifier.IdentifyContent(path, contents)
+}
+
+// DatabaseVersion returns the version of the license database of the content
+// classifier, if it is a VersionedClassifier.
+func (c contentClassifier) DatabaseVersion() string {
+	return databaseVersion(c.classifier)
+}
+
+// identifyContent returns the name, type and confidence of the best license
+// matching the contents of a file.
+func identifyContent(c ContentClassifier, path string, contents []byte) (string, Type, float64, error) {
+	matches, err := c.IdentifyContent(path, contents)
+	if err != nil {
+		return "", "", 0, err
+	}
+	if len(matches) == 0 {
+		return "", "", 0, errors.New("unknown license")
+	}
+	m := matches[0]
+	if m.Type == Unknown {
+		m.Type = LicenseType(m.Name)
+	}
+	return m.Name, m.Type, m.Confidence, nil
+}
@@ -0,0 +1,108 @@
+// Copyright 2022 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package licenses
+
+import (
+	"errors"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// keywordClassifier matches licenses by keywords in their contents.
+type keywordClassifier struct {
+	threshold float64
+}
+
+func (c keywordClassifier) IdentifyContent(_ string, contents []byte) ([]Match, error) {
+	switch {
+	case strings.Contains(string(contents), "broken"):
+		return nil, errors.New("model unavailable")
+	case strings.Contains(string(contents), "Permission is hereby granted"):
+		return []Match{{Name: "MIT", Confidence: 0.97}, {Name: "X11", Type: Notice, Confidence: c.threshold}}, nil
+	}
+	return nil, nil
+}
+
+func TestNamedClassifier(t *testing.T) {
+	RegisterClassifier("keywords", func(threshold float64) (ContentClassifier, error) {
+		return keywordClassifier{threshold: threshold}, nil
+	})
+	defer func() {
+		registryMu.Lock()
+		defer registryMu.Unlock()
+		delete(registry, "keywords")
+	}()
+	if got := Classifiers(); len(got) != 2 || got[0] != "keywords" || got[1] != DefaultClassifier {
+		t.Errorf("Classifiers() = %q, want [keywords %s]", got, DefaultClassifier)
+	}
+
+	c, err := NewNamedClassifier("keywords", 0.8)
+	if err != nil {
+		t.Fatalf("NewNamedClassifier() = (_, %v), want (_, nil)", err)
+	}
+	dir := t.TempDir()
+	for _, test := range []struct {
+		desc           string
+		contents       string
+		wantName       string
+		wantType       Type
+		wantConfidence float64
+		wantErr        bool
+	}{
+		{
+			desc:           "Best match",
+			contents:       "Permission is hereby granted, free of charge",
+			wantName:       "MIT",
+			wantType:       LicenseType("MIT"),
+			wantConfidence: 0.97,
+		},
+		{
+			desc:     "No match",
+			contents: "All rights reserved",
+			wantErr:  true,
+		},
+		{
+			desc:     "Classifier error",
+			contents: "broken",
+			wantErr:  true,
+		},
+	} {
+		t.Run(test.desc, func(t *testing.T) {
+			path := filepath.Join(dir, "LICENSE")
+			if err := ioutil.WriteFile(path, []byte(test.contents), 0644); err != nil {
+				t.Fatal(err)
+			}
+			name, typ, confidence, err := c.IdentifyConfidence(path)
+			if gotErr := err != nil; gotErr != test.wantErr {
+				t.Fatalf("IdentifyConfidence() = (_, _, _, %v), want error? %t", err, test.wantErr)
+			}
+			if name != test.wantName || typ != test.wantType || confidence != test.wantConfidence {
+				t.Errorf("IdentifyConfidence() = (%q, %q, %v, _), want (%q, %q, %v, _)", name, typ, confidence, test.wantName, test.wantType, test.wantConfidence)
+			}
+		})
+	}
+
+	if _, err := NewNamedClassifier("unknown", 0.8); err == nil {
+		t.Errorf("NewNamedClassifier(unknown) = nil error, want error")
+	}
+	defer func() {
+		if recover() == nil {
+			t.Errorf("RegisterClassifier() twice did not panic")
+		}
+	}()
+	RegisterClassifier("keywords", func(float64) (ContentClassifier, error) { return keywordClassifier{}, nil })
+}
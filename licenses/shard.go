@@ -0,0 +1,103 @@
+// Copyright 2022 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package licenses
+
+import (
+	"fmt"
+	"hash/fnv"
+	"strconv"
+	"strings"
+)
+
+// Shard selects a deterministic subset of modules, so that separate jobs can
+// each scan one shard of a very large dependency graph and merge their
+// reports. All libraries of a module are in the same shard. The zero value
+// selects all modules.
+type Shard struct {
+	// Index is the number of the shard, from 1 to Count.
+	Index int
+	// Count is the number of shards.
+	Count int
+}
+
+// ParseShard parses a shard written as "N/M", e.g. "2/4" for the second of
+// four shards.
+func ParseShard(s string) (Shard, error) {
+	parts := strings.Split(s, "/")
+	if len(parts) != 2 {
+		return Shard{}, fmt.Errorf("invalid shard %q, must be N/M, e.g. 1/4", s)
+	}
+	index, err1 := strconv.Atoi(parts[0])
+	count, err2 := strconv.Atoi(parts[1])
+	if err1 != nil || err2 != nil {
+		return Shard{}, fmt.Errorf("invalid shard %q, must be N/M, e.g. 1/4", s)
+	}
+	shard := Shard{Index: index, Count: count}
+	if err := shard.validate(); err != nil {
+		return Shard{}, err
+	}
+	return shard, nil
+}
+
+func (s Shard) String() string {
+	return fmt.Sprintf("%d/%d", s.Index, s.Count)
+}
+
+// validate returns an error for shards out of range.
+func (s Shard) validate() error {
+	if s == (Shard{}) {
+		return nil
+	}
+	if s.Count < 1 || s.Index < 1 || s.Index > s.Count {
+		return fmt.Errorf("invalid shard %s, must be N/M with 1 <= N <= M", s)
+	}
+	return nil
+}
+
+// Contains reports whether a module, or a library without module by its
+// name, is in the shard.
+func (s Shard) Contains(modulePath string) bool {
+	if s.Count <= 1 {
+		return true
+	}
+	h := fnv.New32a()
+	h.Write([]byte(modulePath))
+	return int(h.Sum32()%uint32(s.Count)) == s.Index-1
+}
+
+// libraries returns the libraries in the shard, see Contains. Libraries in
+// inShard are known to be in the shard.
+func (s Shard) libraries(libraries []*Library, inShard map[*Library]bool) []*Library {
+	if s.Count <= 1 {
+		return libraries
+	}
+	var selected []*Library
+	for _, lib := range libraries {
+		if inShard[lib] || s.library(lib) {
+			selected = append(selected, lib)
+		}
+	}
+	return selected
+}
+
+// library reports whether a library is in the shard, by the path of its
+// module, or by its name if it has none.
+func (s Shard) library(lib *Library) bool {
+	key := lib.Name()
+	if lib.module != nil {
+		key = lib.module.Path
+	}
+	return s.Contains(key)
+}
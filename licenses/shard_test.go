@@ -0,0 +1,80 @@
+// Copyright 2022 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package licenses
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestParseShard(t *testing.T) {
+	for _, test := range []struct {
+		shard   string
+		want    Shard
+		wantErr bool
+	}{
+		{shard: "1/4", want: Shard{Index: 1, Count: 4}},
+		{shard: "4/4", want: Shard{Index: 4, Count: 4}},
+		{shard: "1/1", want: Shard{Index: 1, Count: 1}},
+		{shard: "0/4", wantErr: true},
+		{shard: "5/4", wantErr: true},
+		{shard: "1/0", wantErr: true},
+		{shard: "1", wantErr: true},
+		{shard: "a/b", wantErr: true},
+		{shard: "1/2/3", wantErr: true},
+	} {
+		got, err := ParseShard(test.shard)
+		if gotErr := err != nil; gotErr != test.wantErr {
+			t.Errorf("ParseShard(%q) = (_, %v), want err? %t", test.shard, err, test.wantErr)
+			continue
+		}
+		if got != test.want {
+			t.Errorf("ParseShard(%q) = %v, want %v", test.shard, got, test.want)
+		}
+	}
+}
+
+func TestShardPartitionsModules(t *testing.T) {
+	const count = 4
+	var libraries []*Library
+	for i := 0; i < 100; i++ {
+		path := fmt.Sprintf("example.com/mod%d", i)
+		libraries = append(libraries,
+			&Library{Packages: []string{path}, module: &Module{Path: path}},
+			&Library{Packages: []string{path + "/assets"}, module: &Module{Path: path}},
+		)
+	}
+	shards := make(map[string]int)
+	total := 0
+	for index := 1; index <= count; index++ {
+		selected := Shard{Index: index, Count: count}.libraries(libraries, nil)
+		if len(selected) == 0 {
+			t.Errorf("shard %d/%d is empty", index, count)
+		}
+		total += len(selected)
+		for _, lib := range selected {
+			if s, ok := shards[lib.module.Path]; ok && s != index {
+				t.Errorf("module %s in shards %d and %d", lib.module.Path, s, index)
+			}
+			shards[lib.module.Path] = index
+		}
+	}
+	if total != len(libraries) {
+		t.Errorf("shards have %d libraries, want %d", total, len(libraries))
+	}
+	if got := (Shard{}).libraries(libraries, nil); len(got) != len(libraries) {
+		t.Errorf("zero Shard selected %d libraries, want all %d", len(got), len(libraries))
+	}
+}
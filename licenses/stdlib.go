@@ -0,0 +1,70 @@
+// Copyright 2022 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package licenses
+
+import (
+	"context"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/Bobgy/go-licenses/v2/internal/third_party/pkgsite/stdlib"
+)
+
+// stdLibrary returns a library for the Go distribution, i.e. the standard
+// library of the Go toolchain used to load packages.
+func stdLibrary(ctx context.Context, opts Options) (*Library, error) {
+	goroot, err := goEnv(ctx, opts, "", "GOROOT")
+	if err != nil {
+		return nil, err
+	}
+	goVersion, err := goEnv(ctx, opts, "", "GOVERSION")
+	if err != nil {
+		return nil, err
+	}
+	return &Library{
+		LicensePath: filepath.Join(goroot, "LICENSE"),
+		Packages:    []string{stdlib.ModulePath},
+		module: &Module{
+			Path:    stdlib.ModulePath,
+			Version: goSemver(goVersion),
+			Dir:     goroot,
+		},
+	}, nil
+}
+
+var goVersionRegexp = regexp.MustCompile(`^go(\d+)(?:\.(\d+))?(?:\.(\d+))?(?:(alpha|beta|rc)(\d+))?$`)
+
+// goSemver converts a Go version like "go1.17.6" or "go1.18beta1" to a
+// semantic version like "v1.17.6" or "v1.18.0-beta.1". It returns "" for
+// versions that cannot be converted, e.g. development versions.
+func goSemver(goVersion string) string {
+	m := goVersionRegexp.FindStringSubmatch(strings.TrimSpace(goVersion))
+	if m == nil {
+		return ""
+	}
+	minor, patch := m[2], m[3]
+	if minor == "" {
+		minor = "0"
+	}
+	if patch == "" {
+		patch = "0"
+	}
+	v := "v" + m[1] + "." + minor + "." + patch
+	if m[4] != "" {
+		v += "-" + m[4] + "." + m[5]
+	}
+	return v
+}
@@ -0,0 +1,34 @@
+// Copyright 2022 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package licenses
+
+import "testing"
+
+func TestGoSemver(t *testing.T) {
+	for _, test := range []struct {
+		goVersion string
+		want      string
+	}{
+		{goVersion: "go1.17.6", want: "v1.17.6"},
+		{goVersion: "go1.18", want: "v1.18.0"},
+		{goVersion: "go1.18beta1", want: "v1.18.0-beta.1"},
+		{goVersion: "go1.21rc2", want: "v1.21.0-rc.2"},
+		{goVersion: "devel go1.19-abcdef Mon Jan 1 00:00:00 2022", want: ""},
+	} {
+		if got := goSemver(test.goVersion); got != test.want {
+			t.Errorf("goSemver(%q) = %q, want %q", test.goVersion, got, test.want)
+		}
+	}
+}
@@ -0,0 +1,177 @@
+// Copyright 2022 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package licenses
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/Bobgy/go-licenses/v2/internal/logging"
+	"golang.org/x/mod/sumdb/dirhash"
+)
+
+// ReadGoSum parses a go.sum file and returns the hashes of module contents
+// keyed by "path@version". Hashes of go.mod files are skipped.
+func ReadGoSum(path string) (map[string]string, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return ParseGoSum(data, path)
+}
+
+// ReadGoSumAt parses a go.sum file as of a git revision, e.g. "HEAD~1" or
+// "origin/main", see ReadGoSum.
+func ReadGoSumAt(ctx context.Context, path, rev string) (map[string]string, error) {
+	cmd := exec.CommandContext(ctx, "git", "show", rev+":./"+filepath.Base(path))
+	cmd.Dir = filepath.Dir(path)
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	data, err := cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("reading %s at %s: %w: %s", path, rev, err, strings.TrimSpace(stderr.String()))
+	}
+	return ParseGoSum(data, path+"@"+rev)
+}
+
+// ParseGoSum parses the content of a go.sum file named name, see ReadGoSum.
+func ParseGoSum(data []byte, name string) (map[string]string, error) {
+	sums := make(map[string]string)
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) == 0 {
+			continue
+		}
+		if len(fields) != 3 {
+			return nil, fmt.Errorf("invalid line %q in %s", scanner.Text(), name)
+		}
+		if strings.HasSuffix(fields[1], "/go.mod") {
+			continue
+		}
+		sums[fields[0]+"@"+fields[1]] = fields[2]
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return sums, nil
+}
+
+// GoSumFile returns the path of go.sum of the main module in dir, which may
+// not exist, or "" if there is no main module.
+func GoSumFile(ctx context.Context, opts Options, dir string) (string, error) {
+	goMod, err := goEnv(ctx, opts, dir, "GOMOD")
+	if err != nil {
+		return "", err
+	}
+	if goMod == "" || goMod == os.DevNull {
+		return "", nil
+	}
+	return filepath.Join(filepath.Dir(goMod), "go.sum"), nil
+}
+
+// ChangedModules returns the "path@version" keys of module versions in
+// current whose hash is not in previous or differs, sorted, given hashes of
+// go.sum files, see ReadGoSum.
+func ChangedModules(previous, current map[string]string) []string {
+	var changed []string
+	for key, sum := range current {
+		if previous[key] != sum {
+			changed = append(changed, key)
+		}
+	}
+	sort.Strings(changed)
+	return changed
+}
+
+// mainModuleSums reads go.sum of the main module in dir. It returns nil if
+// there is no main module or no go.sum.
+func mainModuleSums(ctx context.Context, opts Options, dir string) (map[string]string, error) {
+	path, err := GoSumFile(ctx, opts, dir)
+	if err != nil || path == "" {
+		return nil, err
+	}
+	sums, err := ReadGoSum(path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	return sums, err
+}
+
+// setModuleSums sets Module.Sum of libraries from go.sum of the main module
+// in dir. Vendored modules are skipped, because their directories only hold
+// a subset of the module contents.
+func setModuleSums(ctx context.Context, opts Options, dir string, libs []*Library) error {
+	if opts.Vendor {
+		logging.Warningf("Skipping module checksum verification in vendor mode")
+		return nil
+	}
+	sums, err := mainModuleSums(ctx, opts, dir)
+	if err != nil {
+		return err
+	}
+	for _, lib := range libs {
+		if m := lib.module; m != nil && m.Version != "" {
+			m.Sum = sums[m.Path+"@"+moduleVersion(m.Path, m.Version)]
+		}
+	}
+	return nil
+}
+
+// verifyLibraries checks that the module directories of libraries match the
+// hashes in Module.Sum, and sets IntegrityError of libraries whose module
+// contents differ. Modules without a known hash are not verified.
+func verifyLibraries(libs []*Library) {
+	// Hashing a module is expensive, and often several libraries belong to
+	// the same module.
+	errs := make(map[string]error)
+	for _, lib := range libs {
+		m := lib.module
+		if m == nil || m.Sum == "" || m.Dir == "" {
+			continue
+		}
+		key := m.Path + "@" + m.Version
+		err, ok := errs[key]
+		if !ok {
+			err = verifyModule(m)
+			errs[key] = err
+			if err != nil {
+				logging.Module(m.Path).Errorf("%v", err)
+			}
+		}
+		lib.IntegrityError = err
+	}
+}
+
+// verifyModule checks that the directory of a module matches its hash.
+func verifyModule(m *Module) error {
+	version := moduleVersion(m.Path, m.Version)
+	hash, err := dirhash.HashDir(m.Dir, m.Path+"@"+version, dirhash.Hash1)
+	if err != nil {
+		return fmt.Errorf("hashing module %s@%s: %w", m.Path, version, err)
+	}
+	if hash != m.Sum {
+		return fmt.Errorf("module %s@%s in %s does not match its checksum: got %s, want %s", m.Path, version, m.Dir, hash, m.Sum)
+	}
+	return nil
+}
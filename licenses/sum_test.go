@@ -0,0 +1,87 @@
+// Copyright 2022 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package licenses
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestReadGoSum(t *testing.T) {
+	sums, err := ReadGoSum("testdata/sum/go.sum")
+	if err != nil {
+		t.Fatalf("ReadGoSum() = (_, %q), want (_, nil)", err)
+	}
+	want := map[string]string{
+		"example.com/mod@v1.0.0": "h1:YkE4NRWEkb8Av3vr4Lo/h9of6ynVL108fK/z2u3/vOo=",
+	}
+	if diff := cmp.Diff(want, sums); diff != "" {
+		t.Errorf("ReadGoSum(): diff (-want +got)\n%s", diff)
+	}
+}
+
+func TestChangedModules(t *testing.T) {
+	previous, err := ParseGoSum([]byte(`example.com/a v1.0.0 h1:a=
+example.com/a v1.0.0/go.mod h1:amod=
+example.com/b v1.0.0 h1:b=
+example.com/c v1.0.0 h1:c=
+`), "previous")
+	if err != nil {
+		t.Fatalf("ParseGoSum() = (_, %v)", err)
+	}
+	current, err := ParseGoSum([]byte(`example.com/a v1.0.0 h1:a=
+example.com/b v1.1.0 h1:b11=
+example.com/c v1.0.0 h1:tampered=
+example.com/d v0.1.0 h1:d=
+`), "current")
+	if err != nil {
+		t.Fatalf("ParseGoSum() = (_, %v)", err)
+	}
+	want := []string{"example.com/b@v1.1.0", "example.com/c@v1.0.0", "example.com/d@v0.1.0"}
+	if diff := cmp.Diff(want, ChangedModules(previous, current)); diff != "" {
+		t.Errorf("ChangedModules(): diff (-want +got)\n%s", diff)
+	}
+	if changed := ChangedModules(nil, current); len(changed) != len(current) {
+		t.Errorf("ChangedModules() without previous go.sum = %q, want all modules", changed)
+	}
+	if _, err := ParseGoSum([]byte("example.com/a v1.0.0\n"), "invalid"); err == nil {
+		t.Errorf("ParseGoSum() of invalid line = nil error, want error")
+	}
+}
+
+func TestVerifyLibraries(t *testing.T) {
+	dir := "testdata/sum/example.com/mod@v1.0.0"
+	for _, test := range []struct {
+		desc    string
+		sum     string
+		wantErr bool
+	}{
+		{desc: "Matching checksum", sum: "h1:YkE4NRWEkb8Av3vr4Lo/h9of6ynVL108fK/z2u3/vOo="},
+		{desc: "Mismatching checksum", sum: "h1:AAAANRWEkb8Av3vr4Lo/h9of6ynVL108fK/z2u3/vOo=", wantErr: true},
+		{desc: "Unknown checksum"},
+	} {
+		t.Run(test.desc, func(t *testing.T) {
+			lib := &Library{
+				Packages: []string{"example.com/mod"},
+				module:   &Module{Path: "example.com/mod", Version: "v1.0.0", Dir: dir, Sum: test.sum},
+			}
+			verifyLibraries([]*Library{lib})
+			if gotErr := lib.IntegrityError != nil; gotErr != test.wantErr {
+				t.Errorf("verifyLibraries(): IntegrityError = %v, want err? %t", lib.IntegrityError, test.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,15 @@
+package cgo
+
+/*
+#cgo CFLAGS: -I${SRCDIR}/include
+#cgo linux LDFLAGS: -L/usr/lib -lssl -lcrypto
+#cgo pkg-config: libpng
+#include "hello.h"
+*/
+import "C"
+
+import "fmt"
+
+func Hello() {
+	fmt.Println(C.hello())
+}
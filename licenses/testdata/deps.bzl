@@ -0,0 +1,23 @@
+load("@bazel_gazelle//:deps.bzl", "go_repository")
+
+def go_dependencies():
+    go_repository(
+        name = "com_github_spf13_cobra",
+        importpath = "github.com/spf13/cobra",
+        sum = "h1:abc=",
+        version = "v1.3.0",
+    )
+    go_repository(
+        name = "io_k8s_kubernetes",
+        build_directives = ["gazelle:exclude vendor"],  # (excluded)
+        importpath = "k8s.io/kubernetes",
+        replace = "github.com/example/kubernetes",
+        sum = "h1:def=",
+        version = "v1.11.1",
+    )
+    go_repository(
+        name = "com_github_example_vcs",
+        commit = "0123456789abcdef",
+        # version = "v0.1.0",
+        importpath = "github.com/example/vcs",
+    )
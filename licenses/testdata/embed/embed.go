@@ -0,0 +1,6 @@
+package embed
+
+import "embed"
+
+//go:embed static "index.html"
+var assets embed.FS
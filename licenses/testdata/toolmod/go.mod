@@ -0,0 +1,9 @@
+module example.com/toolmod
+
+go 1.24
+
+tool golang.org/x/tools/cmd/stringer
+
+tool (
+	example.com/generator/cmd/gen
+)
@@ -0,0 +1,21 @@
+// Copyright 2022 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package xtest_test
+
+import (
+	// This import should only be detected when including test dependencies.
+	_ "github.com/Bobgy/go-licenses/v2/licenses/testdata/indirect"
+	_ "github.com/Bobgy/go-licenses/v2/licenses/testdata/xtest"
+)
@@ -0,0 +1,172 @@
+// Copyright 2022 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package licenses
+
+import (
+	"bufio"
+	"context"
+	"go/build/constraint"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"golang.org/x/mod/modfile"
+)
+
+// toolsBuildTag is the build tag conventionally used by tools.go files to
+// track tool dependencies without importing them into the final binary.
+const toolsBuildTag = "tools"
+
+// ToolPackages returns the packages declaring tool dependencies of the main
+// module in dir. It includes:
+// * tool packages declared by tool directives in go.mod (Go 1.24+).
+// * directories of packages containing files only built with the "tools"
+// build tag, e.g. tools.go files blank importing tools.
+// To load the tool dependencies of the latter, the "tools" build tag must be set.
+func ToolPackages(ctx context.Context, opts Options, dir string) ([]string, error) {
+	goMod, err := goEnv(ctx, opts, dir, "GOMOD")
+	if err != nil {
+		return nil, err
+	}
+	if goMod == "" || goMod == os.DevNull {
+		return nil, nil
+	}
+	tools, err := readToolDirectives(goMod)
+	if err != nil {
+		return nil, err
+	}
+	toolsDirs, err := findToolsDirs(filepath.Dir(goMod))
+	if err != nil {
+		return nil, err
+	}
+	return append(tools, toolsDirs...), nil
+}
+
+// readToolDirectives returns package paths declared by tool directives in a go.mod file.
+func readToolDirectives(goModPath string) ([]string, error) {
+	data, err := ioutil.ReadFile(goModPath)
+	if err != nil {
+		return nil, err
+	}
+	// golang.org/x/mod/modfile does not know the tool directive, but the lax
+	// parser keeps unknown statements in the syntax tree.
+	f, err := modfile.ParseLax(goModPath, data, nil)
+	if err != nil {
+		return nil, err
+	}
+	var tools []string
+	add := func(tool string) {
+		if unquoted, err := strconv.Unquote(tool); err == nil {
+			tool = unquoted
+		}
+		tools = append(tools, tool)
+	}
+	for _, stmt := range f.Syntax.Stmt {
+		switch x := stmt.(type) {
+		case *modfile.Line:
+			if len(x.Token) == 2 && x.Token[0] == "tool" {
+				add(x.Token[1])
+			}
+		case *modfile.LineBlock:
+			if len(x.Token) == 1 && x.Token[0] == "tool" {
+				for _, l := range x.Line {
+					if len(l.Token) == 1 {
+						add(l.Token[0])
+					}
+				}
+			}
+		}
+	}
+	return tools, nil
+}
+
+// findToolsDirs returns absolute paths of directories in the module at modDir
+// containing Go files that are only built with the "tools" build tag.
+func findToolsDirs(modDir string) ([]string, error) {
+	dirs := make(map[string]bool)
+	err := filepath.Walk(modDir, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if info.IsDir() {
+			if path == modDir {
+				return nil
+			}
+			name := info.Name()
+			if name == "vendor" || name == "testdata" || strings.HasPrefix(name, ".") || strings.HasPrefix(name, "_") {
+				return filepath.SkipDir
+			}
+			if _, err := os.Stat(filepath.Join(path, "go.mod")); err == nil {
+				// A nested module.
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if !strings.HasSuffix(path, ".go") || strings.HasSuffix(path, "_test.go") {
+			return nil
+		}
+		onlyTools, err := onlyBuiltWithToolsTag(path)
+		if err != nil {
+			return err
+		}
+		if onlyTools {
+			dirs[filepath.Dir(path)] = true
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	var result []string
+	for dir := range dirs {
+		result = append(result, dir)
+	}
+	sort.Strings(result)
+	return result, nil
+}
+
+// onlyBuiltWithToolsTag reports whether the build constraints of a Go file are
+// only satisfied when the "tools" build tag is set.
+func onlyBuiltWithToolsTag(path string) (bool, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return false, err
+	}
+	defer f.Close()
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "//") && !constraint.IsGoBuild(line) && !constraint.IsPlusBuild(line) {
+			continue
+		}
+		if !strings.HasPrefix(line, "//") {
+			// Build constraints must appear before the package clause.
+			return false, scanner.Err()
+		}
+		expr, err := constraint.Parse(line)
+		if err != nil {
+			continue
+		}
+		withTools := expr.Eval(func(tag string) bool { return tag == toolsBuildTag })
+		withoutTools := expr.Eval(func(tag string) bool { return false })
+		if withTools && !withoutTools {
+			return true, nil
+		}
+	}
+	return false, scanner.Err()
+}
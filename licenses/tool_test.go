@@ -0,0 +1,49 @@
+// Copyright 2022 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package licenses
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestReadToolDirectives(t *testing.T) {
+	tools, err := readToolDirectives("testdata/toolmod/go.mod")
+	if err != nil {
+		t.Fatalf("readToolDirectives() = (_, %q), want (_, nil)", err)
+	}
+	want := []string{"golang.org/x/tools/cmd/stringer", "example.com/generator/cmd/gen"}
+	if diff := cmp.Diff(want, tools); diff != "" {
+		t.Errorf("readToolDirectives(): diff (-want +got)\n%s", diff)
+	}
+}
+
+func TestFindToolsDirs(t *testing.T) {
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatalf("Cannot get working directory: %v", err)
+	}
+	dirs, err := findToolsDirs(filepath.Join(wd, "testdata/toolmod"))
+	if err != nil {
+		t.Fatalf("findToolsDirs() = (_, %q), want (_, nil)", err)
+	}
+	want := []string{filepath.Join(wd, "testdata/toolmod/tools")}
+	if diff := cmp.Diff(want, dirs); diff != "" {
+		t.Errorf("findToolsDirs(): diff (-want +got)\n%s", diff)
+	}
+}
@@ -0,0 +1,104 @@
+// Copyright 2022 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package licenses
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// ReadVendorModules parses vendor/modules.txt in vendorDir and returns the
+// vendored modules keyed by their module path in go.mod. Dir of each module is
+// its directory inside vendorDir. Replaced modules are reported by path and
+// version of the replacement.
+//
+// modules.txt looks like:
+//
+//	# github.com/foo/bar v1.2.3
+//	## explicit; go 1.17
+//	github.com/foo/bar/pkg
+//	# k8s.io/kubernetes v0.17.9 => k8s.io/kubernetes v1.11.1
+//	k8s.io/kubernetes/pkg/util
+//	# example.com/local v0.0.0 => ./local
+func ReadVendorModules(vendorDir string) (map[string]*Module, error) {
+	f, err := os.Open(filepath.Join(vendorDir, "modules.txt"))
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	mods := make(map[string]*Module)
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if !strings.HasPrefix(line, "# ") {
+			// Package lines and "## explicit" annotations.
+			continue
+		}
+		fields := strings.Fields(strings.TrimPrefix(line, "# "))
+		if len(fields) == 0 {
+			return nil, fmt.Errorf("invalid line %q in vendor/modules.txt", line)
+		}
+		path := fields[0]
+		m := &Module{
+			Path: path,
+			Dir:  filepath.Join(vendorDir, filepath.FromSlash(path)),
+		}
+		if len(fields) >= 2 && fields[1] != "=>" {
+			m.Version = fields[1]
+		}
+		for i, field := range fields {
+			if field != "=>" || i+1 >= len(fields) {
+				continue
+			}
+			replacement := fields[i+1]
+			m.OriginalPath = path
+			m.OriginalVersion = m.Version
+			if strings.HasPrefix(replacement, ".") || filepath.IsAbs(replacement) {
+				// Replaced by a local directory, which has no version.
+				m.Version = ""
+				break
+			}
+			m.Path = replacement
+			m.Version = ""
+			if i+2 < len(fields) {
+				m.Version = fields[i+2]
+			}
+		}
+		// The +incompatible suffix does not affect module version.
+		// ref: https://golang.org/ref/mod#incompatible-versions
+		m.Version = strings.TrimSuffix(m.Version, "+incompatible")
+		mods[path] = m
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return mods, nil
+}
+
+// mainVendorModules reads vendored modules of the main module in dir.
+func mainVendorModules(ctx context.Context, opts Options, dir string) (map[string]*Module, error) {
+	goMod, err := goEnv(ctx, opts, dir, "GOMOD")
+	if err != nil {
+		return nil, err
+	}
+	if goMod == "" || goMod == os.DevNull {
+		return nil, fmt.Errorf("vendor mode requires a main module, but %q is not in a module", dir)
+	}
+	return ReadVendorModules(filepath.Join(filepath.Dir(goMod), "vendor"))
+}
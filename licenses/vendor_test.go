@@ -0,0 +1,57 @@
+// Copyright 2022 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package licenses
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestReadVendorModules(t *testing.T) {
+	vendorDir := "testdata/vendormod/vendor"
+	mods, err := ReadVendorModules(vendorDir)
+	if err != nil {
+		t.Fatalf("ReadVendorModules(%q) = (_, %q), want (_, nil)", vendorDir, err)
+	}
+	want := map[string]*Module{
+		"github.com/mitchellh/go-homedir": {
+			Path:    "github.com/mitchellh/go-homedir",
+			Version: "v1.1.0",
+			Dir:     "testdata/vendormod/vendor/github.com/mitchellh/go-homedir",
+		},
+		"github.com/example/old": {
+			Path:    "github.com/example/old",
+			Version: "v2.0.0",
+			Dir:     "testdata/vendormod/vendor/github.com/example/old",
+		},
+		"k8s.io/kubernetes": {
+			Path:            "k8s.io/kubernetes",
+			Version:         "v1.11.1",
+			Dir:             "testdata/vendormod/vendor/k8s.io/kubernetes",
+			OriginalPath:    "k8s.io/kubernetes",
+			OriginalVersion: "v0.17.9",
+		},
+		"example.com/local": {
+			Path:            "example.com/local",
+			Dir:             "testdata/vendormod/vendor/example.com/local",
+			OriginalPath:    "example.com/local",
+			OriginalVersion: "v0.0.0-00010101000000-000000000000",
+		},
+	}
+	if diff := cmp.Diff(want, mods); diff != "" {
+		t.Errorf("ReadVendorModules(%q): diff (-want +got)\n%s", vendorDir, diff)
+	}
+}
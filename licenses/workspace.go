@@ -0,0 +1,126 @@
+// Copyright 2022 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package licenses
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"strconv"
+
+	"golang.org/x/mod/modfile"
+)
+
+// Workspace describes a go.work file and the modules it uses.
+type Workspace struct {
+	// Path is the path of the go.work file.
+	Path string
+	// Modules are the workspace modules declared by use directives, in the
+	// order they appear in the go.work file.
+	Modules []*Module
+}
+
+// FindWorkspace returns the Go workspace that dir belongs to, as reported by
+// `go env GOWORK`. It returns nil when dir is not in workspace mode.
+func FindWorkspace(ctx context.Context, opts Options, dir string) (*Workspace, error) {
+	path, err := goEnv(ctx, opts, dir, "GOWORK")
+	if err != nil {
+		return nil, err
+	}
+	if path == "" || path == "off" {
+		return nil, nil
+	}
+	return ReadWorkspace(path)
+}
+
+// ReadWorkspace parses the go.work file at path and reads the module path of
+// every module it uses.
+func ReadWorkspace(path string) (*Workspace, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	// golang.org/x/mod/modfile does not know the use directive, but the lax
+	// parser keeps unknown statements in the syntax tree.
+	f, err := modfile.ParseLax(path, data, nil)
+	if err != nil {
+		return nil, err
+	}
+	var useDirs []string
+	for _, stmt := range f.Syntax.Stmt {
+		switch x := stmt.(type) {
+		case *modfile.Line:
+			if len(x.Token) == 2 && x.Token[0] == "use" {
+				useDirs = append(useDirs, x.Token[1])
+			}
+		case *modfile.LineBlock:
+			if len(x.Token) == 1 && x.Token[0] == "use" {
+				for _, l := range x.Line {
+					if len(l.Token) == 1 {
+						useDirs = append(useDirs, l.Token[0])
+					}
+				}
+			}
+		}
+	}
+	ws := &Workspace{Path: path}
+	for _, useDir := range useDirs {
+		if unquoted, err := strconv.Unquote(useDir); err == nil {
+			useDir = unquoted
+		}
+		dir := useDir
+		if !filepath.IsAbs(dir) {
+			dir = filepath.Join(filepath.Dir(path), dir)
+		}
+		goMod, err := ioutil.ReadFile(filepath.Join(dir, "go.mod"))
+		if err != nil {
+			return nil, fmt.Errorf("reading workspace module %q: %w", useDir, err)
+		}
+		modPath := modfile.ModulePath(goMod)
+		if modPath == "" {
+			return nil, fmt.Errorf("workspace module %q: go.mod has no module directive", useDir)
+		}
+		ws.Modules = append(ws.Modules, &Module{Path: modPath, Dir: dir})
+	}
+	return ws, nil
+}
+
+// Patterns returns package patterns matching all packages of the workspace
+// modules. When modulePaths is not empty, only those modules are included and
+// each of them must be a workspace module.
+func (w *Workspace) Patterns(modulePaths ...string) ([]string, error) {
+	var patterns []string
+	if len(modulePaths) == 0 {
+		for _, m := range w.Modules {
+			patterns = append(patterns, m.Path+"/...")
+		}
+		return patterns, nil
+	}
+	for _, path := range modulePaths {
+		found := false
+		for _, m := range w.Modules {
+			if m.Path == path {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return nil, fmt.Errorf("module %s is not used by workspace %s", path, w.Path)
+		}
+		patterns = append(patterns, path+"/...")
+	}
+	return patterns, nil
+}
@@ -0,0 +1,61 @@
+// Copyright 2022 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package licenses
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestWorkspacePatterns(t *testing.T) {
+	ws, err := ReadWorkspace("testdata/workspace/go.work")
+	if err != nil {
+		t.Fatalf("ReadWorkspace() = (_, %q), want (_, nil)", err)
+	}
+	for _, test := range []struct {
+		desc         string
+		modulePaths  []string
+		wantPatterns []string
+		wantErr      bool
+	}{
+		{
+			desc:         "All workspace modules",
+			wantPatterns: []string{"example.com/a/...", "example.com/b/..."},
+		},
+		{
+			desc:         "Scoped to one module",
+			modulePaths:  []string{"example.com/b"},
+			wantPatterns: []string{"example.com/b/..."},
+		},
+		{
+			desc:        "Module not in workspace",
+			modulePaths: []string{"example.com/c"},
+			wantErr:     true,
+		},
+	} {
+		t.Run(test.desc, func(t *testing.T) {
+			patterns, err := ws.Patterns(test.modulePaths...)
+			if gotErr := err != nil; gotErr != test.wantErr {
+				t.Fatalf("Patterns(%q) = (_, %q), want err? %t", test.modulePaths, err, test.wantErr)
+			} else if gotErr {
+				return
+			}
+			if diff := cmp.Diff(test.wantPatterns, patterns); diff != "" {
+				t.Errorf("Patterns(%q): diff (-want +got)\n%s", test.modulePaths, diff)
+			}
+		})
+	}
+}
@@ -15,11 +15,19 @@
 package main
 
 import (
+	"context"
+	"errors"
 	"flag"
 	"fmt"
 	"os"
+	"runtime"
 	"strings"
+	"time"
 
+	"github.com/Bobgy/go-licenses/v2/config"
+	"github.com/Bobgy/go-licenses/v2/internal/logging"
+	"github.com/Bobgy/go-licenses/v2/licenses"
+	"github.com/Bobgy/go-licenses/v2/policy"
 	"github.com/golang/glog"
 	"github.com/spf13/cobra"
 )
@@ -27,10 +35,113 @@ import (
 var (
 	rootCmd = &cobra.Command{
 		Use: "licenses",
+		PersistentPreRunE: func(*cobra.Command, []string) error {
+			var err error
+			switch logFormat {
+			case "text":
+			case "json":
+				logging.SetJSON(os.Stderr)
+			default:
+				return fmt.Errorf("unknown --log_format %q, must be text or json", logFormat)
+			}
+			if showProgress {
+				progress = newProgressReporter(os.Stderr)
+			}
+			if shardFlag != "" {
+				if shard, err = licenses.ParseShard(shardFlag); err != nil {
+					return err
+				}
+			}
+			cfg, err = loadConfig()
+			return err
+		},
+		PersistentPostRun: func(*cobra.Command, []string) {
+			printTimings()
+			printSummary()
+		},
 	}
 
+	// cfg is the loaded config file, or an empty config without --config.
+	cfg *config.Config
+	// startTime is the time of the run, e.g. written in report headers.
+	startTime = time.Now()
+
 	// Flags shared between subcommands
 	confidenceThreshold float64
+	// classifierName is the name of the registered classifier identifying
+	// licenses.
+	classifierName string
+	// configPath is the path of the go-licenses config file.
+	configPath string
+	// configLayers are config files layered over configPath.
+	configLayers []string
+	// includeWorkspace adds all modules of the current Go workspace to the
+	// packages being analyzed.
+	includeWorkspace bool
+	// workspaceModules limits the workspace modules being analyzed.
+	workspaceModules []string
+	// goos, goarch and buildTags select the build configuration used to
+	// resolve dependencies.
+	goos      string
+	goarch    string
+	buildTags []string
+	// includeTests includes dependencies only imported by tests.
+	includeTests bool
+	// includeTools includes tool dependencies of the main module.
+	includeTools bool
+	// vendorMode resolves dependencies from the vendor directory.
+	vendorMode bool
+	// downloadModules downloads modules missing from the module cache.
+	downloadModules bool
+	// goFlags, goProxy, goNoSumDB and modFlag override the Go environment
+	// of go commands.
+	goFlags   string
+	goProxy   string
+	goNoSumDB string
+	modFlag   string
+	// moduleColumns adds module version and replacement columns to CSV reports.
+	moduleColumns bool
+	// moduleWarnings reports retracted and deprecated module versions.
+	moduleWarnings bool
+	// staticLinking adds copyleft scope and obligation columns to CSV reports.
+	staticLinking bool
+	// includeStdLib reports the Go distribution as a library.
+	includeStdLib bool
+	// verifyModules checks module contents against their checksums.
+	verifyModules bool
+	// moduleGranularity reports modules of the build list without loading packages.
+	moduleGranularity bool
+	// cacheDir is the directory of caches kept across runs.
+	cacheDir string
+	// porcelain prints stable, tab-separated output for scripts.
+	porcelain bool
+	// logFormat is the format of logs, text or json.
+	logFormat string
+	// jobs is the number of concurrent workers, see numJobs.
+	jobs int
+	// requestsPerSecond limits the rate of HTTP requests to each host.
+	requestsPerSecond float64
+	// validateHashes validates license URLs by comparing git blob hashes.
+	validateHashes bool
+	// shardFlag selects a shard of modules as N/M, parsed into shard.
+	shardFlag string
+	shard     licenses.Shard
+	// maxFileSize, maxDepth and skipDirs bound the search for license files.
+	maxFileSize int64
+	maxDepth    int
+	skipDirs    []string
+	// noColor disables colors of the summary printed after a run.
+	noColor bool
+	// showProgress reports the progress of scans on stderr.
+	showProgress bool
+	// showTimings prints the duration of each phase of a run on stderr.
+	showTimings bool
+	// dryRun performs the full scan, but reports files that would be written
+	// instead of writing them.
+	dryRun bool
+	// ignorePrefixes excludes packages and modules from reports, in addition
+	// to ignore in the config file.
+	ignorePrefixes []string
 )
 
 func init() {
@@ -48,6 +159,43 @@ func init() {
 		os.Exit(1)
 	}
 	rootCmd.PersistentFlags().Float64Var(&confidenceThreshold, "confidence_threshold", 0.9, "Minimum confidence required in order to positively identify a license.")
+	rootCmd.PersistentFlags().StringVar(&classifierName, "classifier", licenses.DefaultClassifier, fmt.Sprintf("Name of the classifier identifying licenses, one of %s.", strings.Join(licenses.Classifiers(), ", ")))
+	rootCmd.PersistentFlags().StringVar(&configPath, "config", "", "Path to the go-licenses YAML config file.")
+	rootCmd.PersistentFlags().StringArrayVar(&configLayers, "config_layer", nil, "Path to a config file layered over --config, e.g. per environment or local settings. Can be repeated, later layers take precedence.")
+	rootCmd.PersistentFlags().BoolVar(&includeWorkspace, "workspace", false, "Also analyze all packages of every module in the current Go workspace (go.work).")
+	rootCmd.PersistentFlags().StringArrayVar(&workspaceModules, "workspace_module", nil, "Analyze packages of this Go workspace module, implies --workspace. Can be repeated.")
+	rootCmd.PersistentFlags().StringVar(&goos, "goos", "", "Target operating system used to resolve dependencies. Defaults to $GOOS.")
+	rootCmd.PersistentFlags().StringVar(&goarch, "goarch", "", "Target architecture used to resolve dependencies. Defaults to $GOARCH.")
+	rootCmd.PersistentFlags().StringSliceVar(&buildTags, "tags", nil, "Comma-separated build tags to satisfy when resolving dependencies.")
+	rootCmd.PersistentFlags().BoolVar(&includeTests, "include_tests", false, "Include dependencies only imported by tests. They are excluded by default, because tests are not shipped.")
+	rootCmd.PersistentFlags().BoolVar(&includeTools, "include_tools", false, "Include tool dependencies of the main module, declared by tool directives in go.mod or by tools.go files with the \"tools\" build tag.")
+	rootCmd.PersistentFlags().BoolVar(&vendorMode, "vendor", false, "Resolve dependencies from the vendor directory (-mod=vendor) and report vendored packages under their own modules, as listed in vendor/modules.txt.")
+	rootCmd.PersistentFlags().BoolVar(&downloadModules, "download_modules", false, "Download modules missing from the module cache with go mod download, e.g. on fresh CI machines.")
+	rootCmd.PersistentFlags().StringVar(&goFlags, "goflags", "", "Overrides $GOFLAGS for all go commands run by go-licenses.")
+	rootCmd.PersistentFlags().StringVar(&goProxy, "goproxy", "", "Overrides $GOPROXY for all go commands run by go-licenses.")
+	rootCmd.PersistentFlags().StringVar(&goNoSumDB, "gonosumdb", "", "Overrides $GONOSUMDB for all go commands run by go-licenses.")
+	rootCmd.PersistentFlags().BoolVar(&moduleColumns, "module_columns", false, "Add module version, whether the module is replaced and the original module path of replaced modules as CSV columns.")
+	rootCmd.PersistentFlags().BoolVar(&moduleWarnings, "module_warnings", false, "Warn about retracted and deprecated module versions. Requires access to the module proxy.")
+	rootCmd.PersistentFlags().BoolVar(&staticLinking, "static_linking", false, "Add the copyleft scope (file, library or program) of each license in a statically linked Go binary and the resulting obligation as CSV columns.")
+	rootCmd.PersistentFlags().BoolVar(&includeStdLib, "include_stdlib", false, "Report the Go distribution (standard library) as a single library with its toolchain version.")
+	rootCmd.PersistentFlags().BoolVar(&moduleGranularity, "module_granularity", false, "Report every module in the build list of the main module (go list -m all) without loading packages. Faster on large dependency graphs, but may include modules that no package imports.")
+	rootCmd.PersistentFlags().BoolVar(&verifyModules, "verify_modules", false, "Verify that scanned module directories match their checksums in go.sum or in the binary, and report mismatches.")
+	rootCmd.PersistentFlags().StringArrayVar(&ignorePrefixes, "ignore", nil, "Import path prefix of packages and modules to exclude from reports, e.g. company-internal modules. Can be repeated.")
+	rootCmd.PersistentFlags().StringVar(&cacheDir, "cache_dir", "", "Directory of caches kept across runs. Defaults to cache.dir in the config file, or go-licenses in the user cache directory.")
+	rootCmd.PersistentFlags().BoolVar(&porcelain, "porcelain", false, "Print stable, tab-separated output for scripts, one record per line without padding or comments. Logs are only written to stderr.")
+	rootCmd.PersistentFlags().StringVar(&logFormat, "log_format", "text", "Format of logs on stderr: text, or json for a JSON line per entry with module, phase and code fields.")
+	rootCmd.PersistentFlags().IntVar(&jobs, "jobs", 0, "Number of concurrent workers scanning packages, identifying licenses and validating license URLs. Defaults to jobs in the config file, or the number of CPUs.")
+	rootCmd.PersistentFlags().Float64Var(&requestsPerSecond, "requests_per_second", licenses.DefaultRequestsPerSecond, "Maximum number of HTTP requests per second to each host, e.g. raw.githubusercontent.com, when validating license URLs. Negative for no limit.")
+	rootCmd.PersistentFlags().BoolVar(&validateHashes, "validate_hashes", false, "Validate license URLs of repos on GitHub or GitLab by comparing git blob hashes from their APIs instead of downloading license files. Falls back to downloading when the API fails. GITHUB_TOKEN authenticates GitHub API requests.")
+	rootCmd.PersistentFlags().StringVar(&shardFlag, "shard", "", "Only report the libraries of shard N of M of the modules, written as N/M, e.g. 1/4, so that separate jobs can scan a very large workspace in parallel. Combine their reports with merge.")
+	rootCmd.PersistentFlags().Int64Var(&maxFileSize, "max_file_size", licenses.DefaultMaxFileSize, "Size in bytes of the largest file classified as a license, to skip huge data files named like license files. Negative for no limit.")
+	rootCmd.PersistentFlags().IntVar(&maxDepth, "max_depth", licenses.DefaultMaxDepth, "Number of nested directories searched for license files below embedded directories. Negative for no limit.")
+	rootCmd.PersistentFlags().StringSliceVar(&skipDirs, "skip_dirs", licenses.DefaultSkipDirs, "Names of directories not searched for license files below embedded directories.")
+	rootCmd.PersistentFlags().BoolVar(&noColor, "no_color", false, "Do not colorize the summary printed to terminals after a run. Also disabled by the NO_COLOR environment variable.")
+	rootCmd.PersistentFlags().BoolVar(&showProgress, "progress", false, "Report the progress of scans on stderr, as a progress bar on terminals or as JSON lines otherwise, with the estimated time remaining in each phase.")
+	rootCmd.PersistentFlags().BoolVar(&showTimings, "timings", false, "Print the duration of each phase of the run on stderr at its end, e.g. loading packages, downloading modules, classifying licenses and validating license URLs, to find where time goes.")
+	rootCmd.PersistentFlags().BoolVar(&dryRun, "dry_run", false, "Perform the full scan, but only print which files would be written or changed, with diffs of existing files, instead of touching disk.")
+	rootCmd.PersistentFlags().StringVar(&modFlag, "mod", "", "The -mod build flag used to load packages: mod, readonly or vendor.")
 }
 
 func main() {
@@ -55,7 +203,233 @@ func main() {
 	rootCmd.PersistentFlags().AddGoFlagSet(flag.CommandLine)
 
 	if err := rootCmd.Execute(); err != nil {
-		glog.Exit(err)
+		code, exit := errorCode(err), 1
+		if cfg != nil {
+			exit = cfg.ExitCode(code)
+		}
+		if exit == 1 && logFormat != "json" {
+			glog.Exit(err)
+		}
+		logging.Fields{Code: code}.Errorf("%v", err)
+		glog.Flush()
+		os.Exit(exit)
+	}
+}
+
+// errorCode returns the key of exitCodes in the config file for an error
+// aborting a command: the code of its kind if exitCodes maps it, or
+// config.ExitCodeError.
+func errorCode(err error) string {
+	for _, kind := range []struct {
+		err  error
+		code string
+	}{
+		{licenses.ErrNoLicenseFound, config.ExitCodeNoLicenseFound},
+		{licenses.ErrUnsupportedHost, config.ExitCodeUnsupportedHost},
+		{licenses.ErrRateLimited, config.ExitCodeRateLimited},
+		{licenses.ErrValidationMismatch, config.ExitCodeValidationMismatch},
+	} {
+		if !errors.Is(err, kind.err) {
+			continue
+		}
+		if cfg == nil {
+			return config.ExitCodeError
+		}
+		if _, ok := cfg.ExitCodes[kind.code]; ok {
+			return kind.code
+		}
+	}
+	return config.ExitCodeError
+}
+
+// loadConfig loads the config file set by --config, with the layers set by
+// --config_layer. Without either, it returns an empty config.
+func loadConfig() (*config.Config, error) {
+	var paths []string
+	if configPath != "" {
+		paths = append(paths, configPath)
+	}
+	paths = append(paths, configLayers...)
+	if len(paths) == 0 {
+		return &config.Config{}, nil
+	}
+	return config.LoadLayers(paths...)
+}
+
+// identifyLicense returns the name and type of a library's license, as
+// declared by an override in the config file or identified by classifier.
+// Of dual licenses, it returns the license elected by preferredLicenses of the
+// config file.
+func identifyLicense(classifier licenses.Classifier, lib *licenses.Library) (string, licenses.Type, error) {
+	if o := cfg.Override(lib.Name()); o != nil {
+		// Elect a license of each license file.
+		elected := &config.Override{Licenses: o.Licenses}
+		if len(o.Licenses) == 0 {
+			elected.Licenses = []string{o.SpdxID}
+		}
+		elected.Licenses = append([]string(nil), elected.Licenses...)
+		for i, l := range elected.Licenses {
+			name, ok := policy.Elect(l, cfg.PreferredLicenses)
+			if !ok {
+				logging.Module(lib.Name()).Warningf("No preferred license of %s for %s, add one to preferredLicenses in the config file", l, lib.Name())
+			} else if name != l {
+				logging.Module(lib.Name()).Infof("Elected license %s of %s for %s", name, l, lib.Name())
+			}
+			elected.Licenses[i] = name
+		}
+		name := elected.Expression()
+		return name, licenses.LicenseType(name), nil
+	}
+	if lib.LicensePath == "" {
+		return "", licenses.Unknown, fmt.Errorf("%w for %s", licenses.ErrNoLicenseFound, lib.Name())
+	}
+	return lib.Identify(classifier)
+}
+
+// licenseConfidence returns the confidence of classifier in the license of a
+// library. Licenses declared by overrides in the config file, and licenses
+// identified by classifiers not reporting confidence, have confidence 1.
+func licenseConfidence(classifier licenses.Classifier, lib *licenses.Library) float64 {
+	c, ok := classifier.(licenses.ConfidenceClassifier)
+	if !ok || cfg.Override(lib.Name()) != nil || lib.LicensePath == "" {
+		return 1
+	}
+	if lib.LicenseName != "" {
+		return lib.Confidence
+	}
+	_, _, confidence, err := c.IdentifyConfidence(lib.LicensePath)
+	if err != nil {
+		return 0
+	}
+	return confidence
+}
+
+// numJobs returns the number of concurrent workers, as set by --jobs or jobs
+// in the config file, or the number of CPUs.
+func numJobs() int {
+	if jobs > 0 {
+		return jobs
+	}
+	if cfg.Jobs > 0 {
+		return cfg.Jobs
+	}
+	return runtime.NumCPU()
+}
+
+// cacheDirectory returns the directory of caches, as set by --cache_dir or
+// cache.dir in the config file.
+func cacheDirectory() (string, error) {
+	if cacheDir != "" {
+		return cacheDir, nil
+	}
+	if cfg.Cache != nil && cfg.Cache.Dir != "" {
+		return cfg.Cache.Dir, nil
+	}
+	return licenses.DefaultCacheDir()
+}
+
+// libraryCache returns the cache of classifications, module info and HTTP
+// responses, or nil if it is disabled or unavailable.
+func libraryCache() *licenses.Cache {
+	if dryRun || (cfg.Cache != nil && cfg.Cache.Disabled) {
+		return nil
+	}
+	dir, err := cacheDirectory()
+	if err != nil {
+		logging.Warningf("Not caching results across runs: %v", err)
+		return nil
+	}
+	ttl, err := cfg.Cache.ParseTTL()
+	if err != nil {
+		logging.Warningf("Not caching results across runs: %v", err)
+		return nil
+	}
+	return licenses.NewCache(dir, ttl)
+}
+
+// newClassifier returns the classifier selected by --classifier with a
+// confidence threshold, caching its classifications in libraryCache.
+func newClassifier(confidenceThreshold float64) (licenses.Classifier, error) {
+	c, err := licenses.NewNamedClassifier(classifierName, confidenceThreshold)
+	if err != nil {
+		return nil, err
+	}
+	key := fmt.Sprintf("threshold=%g", confidenceThreshold)
+	if classifierName != licenses.DefaultClassifier {
+		key = "classifier=" + classifierName + " " + key
+	}
+	return licenses.NewCachedClassifier(c, libraryCache(), key), nil
+}
+
+// packageArgs requires at least one package argument, unless packages are
+// discovered from the Go workspace instead.
+func packageArgs(cmd *cobra.Command, args []string) error {
+	if includeWorkspace || len(workspaceModules) > 0 {
+		return nil
+	}
+	return cobra.MinimumNArgs(1)(cmd, args)
+}
+
+// expandPackages appends package patterns of Go workspace modules to args, if
+// requested by flags.
+func expandPackages(ctx context.Context, args []string) ([]string, error) {
+	if !includeWorkspace && len(workspaceModules) == 0 {
+		return args, nil
+	}
+	ws, err := licenses.FindWorkspace(ctx, libraryOptions(), ".")
+	if err != nil {
+		return nil, err
+	}
+	if ws == nil {
+		return nil, fmt.Errorf("--workspace and --workspace_module require a go.work file, but the current directory is not in workspace mode")
+	}
+	patterns, err := ws.Patterns(workspaceModules...)
+	if err != nil {
+		return nil, err
+	}
+	return append(args, patterns...), nil
+}
+
+// libraryOptions returns options for loading libraries, as configured by flags
+// and the config file.
+func libraryOptions() licenses.Options {
+	var env []string
+	if goFlags != "" {
+		env = append(env, "GOFLAGS="+goFlags)
+	}
+	if goProxy != "" {
+		env = append(env, "GOPROXY="+goProxy)
+	}
+	if goNoSumDB != "" {
+		env = append(env, "GONOSUMDB="+goNoSumDB)
+	}
+	return licenses.Options{
+		GOOS:              goos,
+		GOARCH:            goarch,
+		BuildTags:         buildTags,
+		IncludeTests:      includeTests,
+		IncludeTools:      includeTools,
+		Vendor:            vendorMode,
+		DownloadModules:   downloadModules,
+		Mod:               modFlag,
+		ModuleWarnings:    moduleWarnings,
+		IncludeStdLib:     includeStdLib,
+		VerifyModules:     verifyModules,
+		ModuleGranularity: moduleGranularity,
+		Ignore:            append(append([]string(nil), cfg.Ignore...), ignorePrefixes...),
+		Env:               env,
+		Progress:          reportProgress,
+		Metrics:           recordMeasurement,
+		Jobs:              numJobs(),
+		RequestsPerSecond: requestsPerSecond,
+		ValidateHashes:    validateHashes,
+		Shard:             shard,
+		Limits: licenses.ScanLimits{
+			MaxFileSize: maxFileSize,
+			MaxDepth:    maxDepth,
+			SkipDirs:    skipDirs,
+		},
+		Cache: libraryCache(),
 	}
 }
 
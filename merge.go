@@ -0,0 +1,136 @@
+// Copyright 2022 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"sort"
+
+	"github.com/Bobgy/go-licenses/v2/report"
+	"github.com/spf13/cobra"
+	"gopkg.in/yaml.v3"
+)
+
+var (
+	mergeCmd = &cobra.Command{
+		Use:   "merge <report>...",
+		Short: "Combines the reports of shards into a single report",
+		Long: `Combines the reports of shards into a single report.
+
+Each report is written by csv with --shard N/M, in the same --format. Rows of
+all reports are printed once, sorted by library, as if all shards were
+scanned by a single run. csv reports are read by their columns, so merge must
+be run with the config file and flags selecting columns, e.g.
+--module_columns and --porcelain, of the csv runs. Their comment lines are
+replaced by the header of the config file, if any.`,
+		Args: cobra.MinimumNArgs(1),
+		RunE: mergeMain,
+	}
+
+	// mergeFormat is the format of merged reports: csv, json or yaml.
+	mergeFormat string
+)
+
+func init() {
+	mergeCmd.Flags().StringVar(&mergeFormat, "format", "csv", "Format of the reports: csv, json or yaml, as written by csv --format.")
+
+	rootCmd.AddCommand(mergeCmd)
+}
+
+func mergeMain(_ *cobra.Command, args []string) error {
+	switch mergeFormat {
+	case "csv":
+		return mergeCSVReports(os.Stdout, args)
+	case "json", "yaml":
+		return mergeReports(os.Stdout, mergeFormat, args)
+	}
+	return fmt.Errorf("unknown --format %q, must be csv, json or yaml", mergeFormat)
+}
+
+// mergeCSVReports writes the distinct rows of csv reports at paths, sorted
+// like the rows of a single run, after the header of the config file. Rows are
+// read back into records by the columns of the config file and flags, which
+// must be those the reports were written with.
+func mergeCSVReports(w io.Writer, paths []string) error {
+	seen := make(map[report.Record]bool)
+	var records []report.Record
+	for _, path := range paths {
+		f, err := os.Open(path)
+		if err != nil {
+			return err
+		}
+		rows, err := readCSVRows(f)
+		f.Close()
+		if err != nil {
+			return fmt.Errorf("reading report %s: %w", path, err)
+		}
+		for i, columns := range rows {
+			record, err := rowRecord(columns)
+			if err != nil {
+				return fmt.Errorf("reading report %s, row %d: %w", path, i+1, err)
+			}
+			if !seen[record] {
+				seen[record] = true
+				records = append(records, record)
+			}
+		}
+	}
+	sort.SliceStable(records, func(i, j int) bool {
+		return recordLess(records[i], records[j])
+	})
+	return writeCSVRows(w, records)
+}
+
+// mergeReports writes the distinct records of json or yaml reports at paths,
+// sorted by library, as a report of the same format.
+func mergeReports(w io.Writer, format string, paths []string) error {
+	seen := make(map[report.Record]bool)
+	var records []report.Record
+	for _, path := range paths {
+		data, err := ioutil.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		var r report.Report
+		if format == "json" {
+			err = json.Unmarshal(data, &r)
+		} else {
+			err = yaml.Unmarshal(data, &r)
+		}
+		if err != nil {
+			return fmt.Errorf("reading report %s: %w", path, err)
+		}
+		if r.SchemaVersion > report.SchemaVersion {
+			return fmt.Errorf("report %s has schema version %d, newer than %d supported by this go-licenses", path, r.SchemaVersion, report.SchemaVersion)
+		}
+		for _, record := range r.Records {
+			if !seen[record] {
+				seen[record] = true
+				records = append(records, record)
+			}
+		}
+	}
+	sort.SliceStable(records, func(i, j int) bool {
+		return recordLess(records[i], records[j])
+	})
+	if format == "json" {
+		return writeJSONReport(w, records)
+	}
+	return writeYAMLReport(w, records)
+}
@@ -0,0 +1,83 @@
+// Copyright 2022 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/Bobgy/go-licenses/v2/config"
+	"github.com/spf13/cobra"
+	"gopkg.in/yaml.v3"
+)
+
+var (
+	migrateCmd = &cobra.Command{
+		Use:   "migrate [--from_csv=<report>] -- <v1 command>...",
+		Short: "Converts a v1 invocation and saved reports into a config file",
+		Long: `Converts a v1 invocation and saved reports into a config file.
+
+The v1 command after -- is converted into an equivalent config file, printed
+to stdout: --ignore into ignore, check into a policy forbidding the forbidden
+license type, --disallowed_types into forbidden categories and
+--allowed_licenses into allowed licenses. With --from_csv, the licenses of a
+csv report saved by go-licenses, e.g. reviewed by hand, are pinned as
+overrides. The rest of the command is printed to stderr, to keep passing it
+with --config.`,
+		PersistentPreRunE: func(*cobra.Command, []string) error { return nil },
+		RunE:              migrateMain,
+	}
+
+	// migrateCSV is a csv report to convert into overrides.
+	migrateCSV string
+)
+
+func init() {
+	migrateCmd.Flags().StringVar(&migrateCSV, "from_csv", "", "Path of a csv report saved by go-licenses, whose licenses are converted into overrides.")
+	rootCmd.AddCommand(migrateCmd)
+}
+
+func migrateMain(_ *cobra.Command, args []string) error {
+	c, rest, err := config.Migrate(args)
+	if err != nil {
+		return err
+	}
+	if migrateCSV != "" {
+		f, err := os.Open(migrateCSV)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		overrides, err := config.MigrateCSV(f)
+		if err != nil {
+			return fmt.Errorf("converting %s: %w", migrateCSV, err)
+		}
+		c.Overrides = append(c.Overrides, overrides...)
+	}
+	fmt.Println("# go-licenses config, converted by go-licenses migrate.")
+	enc := yaml.NewEncoder(os.Stdout)
+	enc.SetIndent(2)
+	if err := enc.Encode(c); err != nil {
+		return err
+	}
+	if err := enc.Close(); err != nil {
+		return err
+	}
+	if len(rest) > 0 {
+		fmt.Fprintf(os.Stderr, "Run with the config file saved as licenses.yaml:\ngo-licenses %s --config=licenses.yaml\n", strings.Join(rest, " "))
+	}
+	return nil
+}
@@ -0,0 +1,131 @@
+// Copyright 2022 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/Bobgy/go-licenses/v2/internal/logging"
+	"github.com/Bobgy/go-licenses/v2/licenses"
+	"github.com/Bobgy/go-licenses/v2/policy"
+	"github.com/spf13/cobra"
+)
+
+var (
+	obligationsCmd = &cobra.Command{
+		Use:   "obligations <package>...",
+		Short: "Prints what each license of a Go package's dependencies requires on redistribution",
+		Long: `Prints what each license of a Go package's dependencies requires on redistribution.
+
+Each row is a checklist item with a license, an obligation ID, its description
+and the libraries under the license, separated by ";". Obligations are
+include-license, state-changes, provide-source and preserve-notice.
+
+With --statements, it prints the legal text blocks required for distribution
+instead, e.g. an attribution block, an LGPL relinking notice and an MPL source
+availability statement, ready to paste into product documentation.`,
+		Args: packageArgs,
+		RunE: obligationsMain,
+	}
+
+	// statements prints legal text blocks instead of the obligation checklist.
+	statements bool
+)
+
+func init() {
+	obligationsCmd.Flags().BoolVar(&statements, "statements", false, "Print the legal text blocks required for distribution, grouped by obligation, instead of the CSV checklist.")
+	rootCmd.AddCommand(obligationsCmd)
+}
+
+func obligationsMain(_ *cobra.Command, args []string) error {
+	classifier, err := newClassifier(confidenceThreshold)
+	if err != nil {
+		return err
+	}
+
+	importPaths, err := expandPackages(context.Background(), args)
+	if err != nil {
+		return err
+	}
+	libs, err := licenses.LibrariesWithOptions(context.Background(), classifier, libraryOptions(), importPaths...)
+	if err != nil {
+		return err
+	}
+	if statements {
+		return writeStatements(os.Stdout, classifier, libs)
+	}
+	return writeObligations(os.Stdout, classifier, libs)
+}
+
+// writeStatements writes the legal text blocks required for distributing
+// libs, separated by blank lines.
+func writeStatements(w io.Writer, classifier licenses.Classifier, libs []*licenses.Library) error {
+	var components []policy.Component
+	for _, lib := range libs {
+		name, typ := "Unknown", licenses.Unknown
+		if n, t, err := identifyLicense(classifier, lib); err == nil {
+			name, typ = n, t
+		} else if lib.LicensePath != "" {
+			logging.Module(lib.Name()).Errorf("Error identifying license in %q: %v", lib.LicensePath, err)
+		}
+		components = append(components, policy.Component{Name: lib.Name(), License: name, Category: strings.ToLower(typ.String())})
+	}
+	for i, s := range policy.Statements(components) {
+		if i > 0 {
+			if _, err := fmt.Fprintln(w); err != nil {
+				return err
+			}
+		}
+		if _, err := fmt.Fprintf(w, "%s\n\n%s\n", s.Title, s.Text); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// writeObligations writes a csv row for each obligation of each license of
+// libs, sorted by license.
+func writeObligations(w io.Writer, classifier licenses.Classifier, libs []*licenses.Library) error {
+	libsByLicense := make(map[string][]string)
+	categories := make(map[string]string)
+	for _, lib := range libs {
+		name, typ := "Unknown", licenses.Unknown
+		if n, t, err := identifyLicense(classifier, lib); err == nil {
+			name, typ = n, t
+		} else if lib.LicensePath != "" {
+			logging.Module(lib.Name()).Errorf("Error identifying license in %q: %v", lib.LicensePath, err)
+		}
+		libsByLicense[name] = append(libsByLicense[name], lib.Name())
+		categories[name] = strings.ToLower(typ.String())
+	}
+	names := make([]string, 0, len(libsByLicense))
+	for name := range libsByLicense {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		for _, o := range policy.Obligations(name, categories[name]) {
+			if err := writeCSVRow(w, name, string(o), o.Description(), strings.Join(libsByLicense[name], ";")); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
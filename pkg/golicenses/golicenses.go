@@ -0,0 +1,622 @@
+// Copyright 2022 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package golicenses is the supported API for embedding go-licenses in other
+// tools, e.g. release builders or compliance services, instead of running the
+// go-licenses command.
+//
+// A Scanner finds the libraries that Go packages or binaries depend on, and
+// identifies their licenses:
+//
+//	s, err := golicenses.New(golicenses.WithConfig(cfg))
+//	if err != nil {
+//		return err
+//	}
+//	report, err := s.Scan(ctx, "./cmd/server")
+//	if err != nil {
+//		return err
+//	}
+//	for _, lib := range report.Libraries {
+//		fmt.Println(lib.Name, lib.License)
+//	}
+//
+// Libraries are sorted by name. Licenses declared by overrides in the config
+// take precedence over identified ones, and libraries are checked against the
+// config's policy, if any.
+package golicenses
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/Bobgy/go-licenses/v2/config"
+	"github.com/Bobgy/go-licenses/v2/licenses"
+	"github.com/Bobgy/go-licenses/v2/policy"
+	"golang.org/x/tools/go/packages"
+)
+
+// DefaultConfidenceThreshold is the minimum confidence of license
+// classifications, unless set by WithConfidenceThreshold.
+const DefaultConfidenceThreshold = 0.9
+
+// Unknown is the license of libraries whose license cannot be identified.
+const Unknown = "Unknown"
+
+// Report is the result of a scan.
+type Report struct {
+	// Libraries are the libraries found, sorted by name.
+	Libraries []*Library
+}
+
+// Violations returns the policy results of libraries that are not allowed.
+func (r *Report) Violations() []*policy.Result {
+	var violations []*policy.Result
+	for _, lib := range r.Libraries {
+		if lib.Policy != nil && lib.Policy.Verdict != policy.Allowed {
+			violations = append(violations, lib.Policy)
+		}
+	}
+	return violations
+}
+
+// Library is one or more Go packages that share a license file.
+type Library struct {
+	// Name is the common prefix of the import paths of the library's
+	// packages, e.g. "github.com/google/trillian".
+	Name string
+	// Packages are import paths of the library's packages.
+	Packages []string
+	// Module is the Go module of the library, if known.
+	Module *Module
+	// LicensePath is the path of the library's license file, or empty if it
+	// has none.
+	LicensePath string
+	// LicenseURL is the URL of the license file, only resolved with
+	// WithLicenseURLs.
+	LicenseURL string
+	// License is the SPDX ID of the license, an SPDX expression of several
+	// licenses declared by an override, or Unknown.
+	License string
+	// Category is the category of the license, e.g. "notice", see
+	// policy.Categories.
+	Category string
+	// Confidence is the confidence of the classifier in License, between 0
+	// and 1. Licenses declared by overrides have confidence 1.
+	Confidence float64
+	// Overridden is true if License is declared by an override in the config.
+	Overridden bool
+	// ImportChain is the shortest chain of imports from a scanned package to
+	// a package of the library, only set with WithImportChains and not for
+	// binaries.
+	ImportChain []string
+	// Warnings describe problems with the library that do not prevent
+	// reporting it, e.g. a retracted module version or a license that could
+	// not be identified.
+	Warnings []string
+	// Policy is the result of checking the library against the config's
+	// policy, or nil if there is no policy.
+	Policy *policy.Result
+}
+
+// Module is a Go module.
+type Module struct {
+	// Path is the module path.
+	Path string
+	// Version is the module version, empty for the main module.
+	Version string
+	// Dir is the directory of the module's files, if available.
+	Dir string
+	// OriginalPath is the module path required by go.mod, if this module
+	// replaces it by a replace directive.
+	OriginalPath string
+	// OriginalVersion is the module version required by go.mod, if this
+	// module replaces it by a replace directive and the version is known.
+	OriginalVersion string
+	// Sum is the checksum of the module contents, e.g. "h1:...", if known.
+	Sum string
+	// Indirect reports whether the module is only required indirectly by the
+	// main module.
+	Indirect bool
+}
+
+// Replaced reports whether the module replaces the module required by go.mod
+// by a replace directive. Modules replaced by a local directory have no
+// Version.
+func (m *Module) Replaced() bool {
+	return m.OriginalPath != ""
+}
+
+// Scanner scans Go packages and binaries for libraries and their licenses.
+// A Scanner is safe for concurrent use.
+type Scanner struct {
+	opts           licenses.Options
+	threshold      float64
+	classifier     licenses.Classifier
+	classifierName string
+	config         *config.Config
+	licenseURLs    bool
+}
+
+// Option configures a Scanner.
+type Option func(*Scanner)
+
+// WithConfig uses the overrides, preferred licenses, ignored packages and
+// policy of a config.
+func WithConfig(c *config.Config) Option {
+	return func(s *Scanner) {
+		s.config = c
+	}
+}
+
+// WithConfidenceThreshold sets the minimum confidence of license
+// classifications, between 0 and 1.
+func WithConfidenceThreshold(threshold float64) Option {
+	return func(s *Scanner) {
+		s.threshold = threshold
+	}
+}
+
+// WithClassifier identifies licenses with a classifier instead of the
+// default one. WithConfidenceThreshold does not apply to it.
+func WithClassifier(c licenses.Classifier) Option {
+	return func(s *Scanner) {
+		s.classifier = c
+	}
+}
+
+// WithNamedClassifier identifies licenses with a classifier registered by
+// licenses.RegisterClassifier, e.g. a custom one, instead of the default one.
+// WithConfidenceThreshold applies to it.
+func WithNamedClassifier(name string) Option {
+	return func(s *Scanner) {
+		s.classifierName = name
+	}
+}
+
+// WithPlatform scans packages as built for an operating system and
+// architecture, instead of those of the environment.
+func WithPlatform(goos, goarch string) Option {
+	return func(s *Scanner) {
+		s.opts.GOOS, s.opts.GOARCH = goos, goarch
+	}
+}
+
+// WithBuildTags considers build tags satisfied while loading packages.
+func WithBuildTags(tags ...string) Option {
+	return func(s *Scanner) {
+		s.opts.BuildTags = append(s.opts.BuildTags, tags...)
+	}
+}
+
+// WithTests includes dependencies only imported by tests.
+func WithTests() Option {
+	return func(s *Scanner) {
+		s.opts.IncludeTests = true
+	}
+}
+
+// WithTools includes tool dependencies of the main module.
+func WithTools() Option {
+	return func(s *Scanner) {
+		s.opts.IncludeTools = true
+	}
+}
+
+// WithIgnore excludes packages and modules by import path prefix, in addition
+// to those ignored by the config.
+func WithIgnore(prefixes ...string) Option {
+	return func(s *Scanner) {
+		s.opts.Ignore = append(s.opts.Ignore, prefixes...)
+	}
+}
+
+// WithDir loads packages and runs go commands in dir, e.g. the root of a
+// repo, instead of the current directory.
+func WithDir(dir string) Option {
+	return func(s *Scanner) {
+		s.opts.Dir = dir
+	}
+}
+
+// WithEnv sets "KEY=value" environment variables of go commands, e.g. GOFLAGS
+// or GOPROXY.
+func WithEnv(env ...string) Option {
+	return func(s *Scanner) {
+		s.opts.Env = append(s.opts.Env, env...)
+	}
+}
+
+// WithModuleWarnings reports retracted and deprecated module versions, which
+// requires querying the module proxy.
+func WithModuleWarnings() Option {
+	return func(s *Scanner) {
+		s.opts.ModuleWarnings = true
+	}
+}
+
+// WithImportChains sets Library.ImportChain of libraries of Go packages, the
+// shortest chain of imports explaining why each library is a dependency.
+func WithImportChains() Option {
+	return func(s *Scanner) {
+		s.opts.ImportChains = true
+	}
+}
+
+// WithModuleGranularity reports a library per module in the build list of the
+// main module without loading packages, see
+// licenses.Options.ModuleGranularity.
+func WithModuleGranularity() Option {
+	return func(s *Scanner) {
+		s.opts.ModuleGranularity = true
+	}
+}
+
+// WithLicenseURLs resolves the URLs of license files, which requires network
+// access to validate them.
+func WithLicenseURLs() Option {
+	return func(s *Scanner) {
+		s.licenseURLs = true
+	}
+}
+
+// WithJobs sets the number of concurrent workers, defaulting to the number
+// of CPUs.
+func WithJobs(jobs int) Option {
+	return func(s *Scanner) {
+		s.opts.Jobs = jobs
+	}
+}
+
+// WithRequestsPerSecond limits the rate of HTTP requests validating license
+// URLs to each host, see licenses.Options.RequestsPerSecond.
+func WithRequestsPerSecond(requestsPerSecond float64) Option {
+	return func(s *Scanner) {
+		s.opts.RequestsPerSecond = requestsPerSecond
+	}
+}
+
+// WithScanLimits bounds the search for license files, e.g. to skip huge data
+// files or deep directory trees, see licenses.ScanLimits.
+func WithScanLimits(limits licenses.ScanLimits) Option {
+	return func(s *Scanner) {
+		s.opts.Limits = limits
+	}
+}
+
+// WithHashValidation validates license URLs by comparing git blob hashes
+// instead of downloading license files, see licenses.Options.ValidateHashes.
+func WithHashValidation() Option {
+	return func(s *Scanner) {
+		s.opts.ValidateHashes = true
+	}
+}
+
+// WithShard only scans the libraries of one shard of the modules, so that
+// separate processes can each scan a part of a very large workspace, see
+// licenses.Shard.
+func WithShard(shard licenses.Shard) Option {
+	return func(s *Scanner) {
+		s.opts.Shard = shard
+	}
+}
+
+// WithCache caches results across scans in a directory, using entries for
+// ttl, or forever if ttl is 0.
+func WithCache(dir string, ttl time.Duration) Option {
+	return func(s *Scanner) {
+		s.opts.Cache = licenses.NewCache(dir, ttl)
+	}
+}
+
+// WithSharedCache caches results in c, which may be shared by Scanners of a
+// long-running process, e.g. a server, so they don't repeat work across
+// requests. See licenses.NewCache.
+func WithSharedCache(c *licenses.Cache) Option {
+	return func(s *Scanner) {
+		s.opts.Cache = c
+	}
+}
+
+// WithHTTPClient makes HTTP requests with c, e.g. to add proxies,
+// authentication or instrumentation, or to stub network access in tests.
+func WithHTTPClient(c *http.Client) Option {
+	return func(s *Scanner) {
+		s.opts.HTTPClient = c
+	}
+}
+
+// WithProgress calls f with progress events of scans. It may be called
+// concurrently.
+func WithProgress(f func(licenses.Progress)) Option {
+	return func(s *Scanner) {
+		s.opts.Progress = f
+	}
+}
+
+// WithMetrics calls f with the duration and number of items of each
+// completed phase of scans, e.g. to export metrics of long-running services.
+// It may be called concurrently.
+func WithMetrics(f func(licenses.Measurement)) Option {
+	return func(s *Scanner) {
+		s.opts.Metrics = f
+	}
+}
+
+// New returns a Scanner configured by options.
+func New(opts ...Option) (*Scanner, error) {
+	s := &Scanner{threshold: DefaultConfidenceThreshold, classifierName: licenses.DefaultClassifier}
+	for _, opt := range opts {
+		opt(s)
+	}
+	if s.threshold < 0 || s.threshold > 1 {
+		return nil, fmt.Errorf("confidence threshold must be between 0 and 1, got %v", s.threshold)
+	}
+	if s.config == nil {
+		s.config = &config.Config{}
+	}
+	if s.config.Policy != nil {
+		if err := s.config.Policy.Validate(); err != nil {
+			return nil, err
+		}
+	}
+	s.opts.Ignore = append(append([]string(nil), s.config.Ignore...), s.opts.Ignore...)
+	if s.classifier == nil {
+		c, err := licenses.NewNamedClassifier(s.classifierName, s.threshold)
+		if err != nil {
+			return nil, err
+		}
+		key := fmt.Sprintf("threshold=%g", s.threshold)
+		if s.classifierName != licenses.DefaultClassifier {
+			key = "classifier=" + s.classifierName + " " + key
+		}
+		s.classifier = licenses.NewCachedClassifier(c, s.opts.Cache, key)
+	}
+	return s, nil
+}
+
+// Scan reports the libraries that packages depend on, given as import paths
+// or patterns relative to the current directory, e.g. "./...".
+func (s *Scanner) Scan(ctx context.Context, patterns ...string) (*Report, error) {
+	libs, err := licenses.LibrariesWithOptions(ctx, s.classifier, s.opts, patterns...)
+	if err != nil {
+		return nil, err
+	}
+	return s.report(ctx, libs), nil
+}
+
+// StopScan is returned by the function passed to ScanFunc to stop scanning
+// without an error.
+var StopScan = licenses.StopWalk
+
+// ScanFunc is like Scan, but calls f with each library as soon as its license
+// is identified and checked, instead of returning a report at the end, e.g.
+// to show progress or to stop at the first violation. Libraries are passed in
+// order of their names, one at a time. If f returns an error, the remaining
+// libraries are skipped and ScanFunc returns the error, or nil for StopScan.
+func (s *Scanner) ScanFunc(ctx context.Context, f func(*Library) error, patterns ...string) error {
+	return licenses.WalkLibraries(ctx, s.classifier, s.opts, func(l *licenses.Library) error {
+		return f(s.library(ctx, l))
+	}, patterns...)
+}
+
+// ScanBinary reports the libraries of the modules a Go binary was built with,
+// as recorded in its build info.
+func (s *Scanner) ScanBinary(ctx context.Context, path string) (*Report, error) {
+	libs, err := licenses.BinaryLibraries(ctx, s.classifier, s.opts, path)
+	if err != nil {
+		return nil, err
+	}
+	return s.report(ctx, libs), nil
+}
+
+// ScanPackages is like Scan, but reports the libraries of packages already
+// loaded by the caller with licenses.PackagesLoadMode, e.g. by a build system,
+// instead of loading them again.
+func (s *Scanner) ScanPackages(ctx context.Context, pkgs []*packages.Package) (*Report, error) {
+	libs, err := licenses.PackageLibraries(ctx, s.classifier, s.opts, pkgs)
+	if err != nil {
+		return nil, err
+	}
+	return s.report(ctx, libs), nil
+}
+
+// ScanModules reports the libraries of modules already listed by the caller,
+// e.g. by a build system, whose licenses are found at the root of each module
+// like ScanBinary. Modules need Dir to find their licenses.
+func (s *Scanner) ScanModules(ctx context.Context, modules []*Module) (*Report, error) {
+	mods := make([]*licenses.Module, len(modules))
+	for i, m := range modules {
+		mods[i] = &licenses.Module{
+			Path:            m.Path,
+			Version:         m.Version,
+			Dir:             m.Dir,
+			OriginalPath:    m.OriginalPath,
+			OriginalVersion: m.OriginalVersion,
+			Sum:             m.Sum,
+			Indirect:        m.Indirect,
+		}
+	}
+	libs, err := licenses.ModuleLibraries(ctx, s.classifier, s.opts, mods)
+	if err != nil {
+		return nil, err
+	}
+	return s.report(ctx, libs), nil
+}
+
+// BinaryInfo is the build info embedded in a Go binary.
+type BinaryInfo struct {
+	// GoVersion is the version of Go that built the binary, e.g. "go1.18.2".
+	GoVersion string
+	// Path is the import path of the binary's main package.
+	Path string
+	// Main is the module of the main package, or nil if the binary was not
+	// built in module mode.
+	Main *Module
+	// Deps are the modules the binary depends on, with replacements applied.
+	Deps []*Module
+	// Settings are the build settings of the binary by key, e.g. "-trimpath",
+	// "GOOS" or "vcs.revision". Go versions before 1.18 record none.
+	Settings map[string]string
+	// VCSRevision is the version control revision the binary was built from,
+	// e.g. a git commit hash, if recorded.
+	VCSRevision string
+	// VCSModified reports whether the source tree had local modifications.
+	VCSModified bool
+	// Trimpath reports whether the binary was built with -trimpath.
+	Trimpath bool
+}
+
+// BinaryInfo reads the build info embedded in a Go binary, without scanning
+// its libraries.
+func (s *Scanner) BinaryInfo(ctx context.Context, path string) (*BinaryInfo, error) {
+	bi, err := licenses.ReadBinaryInfo(ctx, s.opts, path)
+	if err != nil {
+		return nil, err
+	}
+	info := &BinaryInfo{
+		GoVersion:   bi.GoVersion,
+		Path:        bi.Path,
+		Main:        newModule(bi.Main),
+		Settings:    make(map[string]string),
+		VCSRevision: bi.VCSRevision(),
+		VCSModified: bi.VCSModified(),
+		Trimpath:    bi.Trimpath(),
+	}
+	for _, m := range bi.Deps {
+		info.Deps = append(info.Deps, newModule(m))
+	}
+	for _, setting := range bi.Settings {
+		info.Settings[setting.Key] = setting.Value
+	}
+	return info, nil
+}
+
+// newModule returns the result model of a module, or nil for a nil module.
+func newModule(m *licenses.Module) *Module {
+	if m == nil {
+		return nil
+	}
+	return &Module{
+		Path:            m.Path,
+		Version:         m.Version,
+		Dir:             m.Dir,
+		OriginalPath:    m.OriginalPath,
+		OriginalVersion: m.OriginalVersion,
+		Sum:             m.Sum,
+		Indirect:        m.Indirect,
+	}
+}
+
+// report identifies the licenses of libraries and checks them against the
+// policy.
+func (s *Scanner) report(ctx context.Context, libs []*licenses.Library) *Report {
+	report := &Report{Libraries: make([]*Library, 0, len(libs))}
+	for _, l := range libs {
+		report.Libraries = append(report.Libraries, s.library(ctx, l))
+	}
+	return report
+}
+
+// library returns the result model of a library.
+func (s *Scanner) library(ctx context.Context, l *licenses.Library) *Library {
+	lib := &Library{
+		Name:        l.Name(),
+		Packages:    append([]string(nil), l.Packages...),
+		ImportChain: append([]string(nil), l.ImportChain...),
+		LicensePath: l.LicensePath,
+		License:     Unknown,
+		Category:    strings.ToLower(licenses.Unknown.String()),
+	}
+	lib.Module = newModule(l.Module())
+	if l.ModuleWarning != nil {
+		lib.Warnings = append(lib.Warnings, l.ModuleWarning.String())
+	}
+	if l.IntegrityError != nil {
+		lib.Warnings = append(lib.Warnings, l.IntegrityError.Error())
+	}
+	if err := s.identify(lib, l); err != nil {
+		lib.Warnings = append(lib.Warnings, err.Error())
+	}
+	if s.licenseURLs && l.LicensePath != "" {
+		if url, err := l.LicenseURL(ctx); err != nil {
+			lib.Warnings = append(lib.Warnings, err.Error())
+		} else {
+			lib.LicenseURL = url
+		}
+	}
+	if p := s.config.Policy; p != nil {
+		policyLib := policy.Library{
+			Name:       lib.Name,
+			License:    lib.License,
+			Category:   lib.Category,
+			Confidence: lib.Confidence,
+		}
+		if lib.Module != nil {
+			policyLib.Module, policyLib.Version, policyLib.Indirect = lib.Module.Path, lib.Module.Version, lib.Module.Indirect
+		}
+		lib.Policy = p.CheckLibrary(policyLib, time.Now())
+	}
+	return lib
+}
+
+// identify sets the license of a library, as declared by an override or
+// identified by the classifier.
+func (s *Scanner) identify(lib *Library, l *licenses.Library) error {
+	if o := s.config.Override(lib.Name); o != nil {
+		elected := &config.Override{Licenses: o.Licenses}
+		if len(o.Licenses) == 0 {
+			elected.Licenses = []string{o.SpdxID}
+		}
+		elected.Licenses = append([]string(nil), elected.Licenses...)
+		var err error
+		for i, l := range elected.Licenses {
+			name, ok := policy.Elect(l, s.config.PreferredLicenses)
+			if !ok && err == nil {
+				err = fmt.Errorf("no preferred license of %s for %s, add one to preferredLicenses in the config", l, lib.Name)
+			}
+			elected.Licenses[i] = name
+		}
+		lib.License = elected.Expression()
+		lib.Category = strings.ToLower(licenses.LicenseType(lib.License).String())
+		lib.Confidence = 1
+		lib.Overridden = true
+		return err
+	}
+	if lib.LicensePath == "" {
+		return fmt.Errorf("%w for %s", licenses.ErrNoLicenseFound, lib.Name)
+	}
+	if l.LicenseName != "" {
+		lib.License, lib.Category, lib.Confidence = l.LicenseName, strings.ToLower(l.LicenseType.String()), l.Confidence
+		return nil
+	}
+	var (
+		name       string
+		typ        licenses.Type
+		confidence = 1.0
+		err        error
+	)
+	if c, ok := s.classifier.(licenses.ConfidenceClassifier); ok {
+		name, typ, confidence, err = c.IdentifyConfidence(lib.LicensePath)
+	} else {
+		name, typ, err = s.classifier.Identify(lib.LicensePath)
+	}
+	if err != nil {
+		return fmt.Errorf("identifying license in %s: %w", lib.LicensePath, err)
+	}
+	lib.License, lib.Category, lib.Confidence = name, strings.ToLower(typ.String()), confidence
+	return nil
+}
@@ -0,0 +1,111 @@
+// Copyright 2022 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package golicenses
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/Bobgy/go-licenses/v2/config"
+	"github.com/Bobgy/go-licenses/v2/licenses"
+	"github.com/Bobgy/go-licenses/v2/policy"
+	"github.com/google/go-cmp/cmp"
+)
+
+// fakeClassifier identifies licenses by their path.
+type fakeClassifier map[string]licenses.Type
+
+func (c fakeClassifier) Identify(licensePath string) (string, licenses.Type, error) {
+	typ, ok := c[licensePath]
+	if !ok {
+		return "", licenses.Unknown, errors.New("unknown license")
+	}
+	return filepath.Base(filepath.Dir(licensePath)), typ, nil
+}
+
+func TestNew(t *testing.T) {
+	if _, err := New(WithConfidenceThreshold(1.5)); err == nil {
+		t.Errorf("New(WithConfidenceThreshold(1.5)) = nil error, want error")
+	}
+	s, err := New(
+		WithClassifier(fakeClassifier{}),
+		WithConfig(&config.Config{Ignore: []string{"example.com/internal"}}),
+		WithIgnore("example.com/vendored"),
+		WithPlatform("linux", "arm64"),
+		WithBuildTags("integration"),
+		WithJobs(2),
+		WithImportChains(),
+		WithModuleGranularity(),
+	)
+	if err != nil {
+		t.Fatalf("New() = %v", err)
+	}
+	want := licenses.Options{
+		GOOS:              "linux",
+		GOARCH:            "arm64",
+		BuildTags:         []string{"integration"},
+		Ignore:            []string{"example.com/internal", "example.com/vendored"},
+		Jobs:              2,
+		ImportChains:      true,
+		ModuleGranularity: true,
+	}
+	if diff := cmp.Diff(want, s.opts, cmp.Comparer(func(a, b func(licenses.Progress)) bool { return a == nil && b == nil })); diff != "" {
+		t.Errorf("New() options diff (-want +got):\n%s", diff)
+	}
+}
+
+func TestReport(t *testing.T) {
+	cfg := &config.Config{
+		PreferredLicenses: []string{"MIT"},
+		Overrides:         []config.Override{{Name: "example.com/dual", SpdxID: "MIT OR GPL-3.0"}},
+		Policy:            &policy.Policy{Forbidden: policy.Rules{Categories: []string{"restricted"}}},
+	}
+	s, err := New(WithConfig(cfg), WithClassifier(fakeClassifier{"/MIT/LICENSE": licenses.Notice, "/GPL-3.0/LICENSE": licenses.Restricted}))
+	if err != nil {
+		t.Fatalf("New() = %v", err)
+	}
+	report := s.report(context.Background(), []*licenses.Library{
+		{LicensePath: "/dual/LICENSE", Packages: []string{"example.com/dual"}},
+		{LicensePath: "/GPL-3.0/LICENSE", Packages: []string{"example.com/gpl"}},
+		{LicensePath: "/MIT/LICENSE", Packages: []string{"example.com/mit"}},
+		{Packages: []string{"example.com/none"}},
+	})
+	type summary struct {
+		Name, License, Category string
+		Confidence              float64
+		Overridden              bool
+		Warnings                int
+		Verdict                 policy.Verdict
+	}
+	var got []summary
+	for _, lib := range report.Libraries {
+		got = append(got, summary{lib.Name, lib.License, lib.Category, lib.Confidence, lib.Overridden, len(lib.Warnings), lib.Policy.Verdict})
+	}
+	want := []summary{
+		{"example.com/dual", "MIT", strings.ToLower(licenses.LicenseType("MIT").String()), 1, true, 0, policy.Allowed},
+		{"example.com/gpl", "GPL-3.0", "restricted", 1, false, 0, policy.Denied},
+		{"example.com/mit", "MIT", "notice", 1, false, 0, policy.Allowed},
+		{"example.com/none", Unknown, "unknown", 0, false, 1, policy.Allowed},
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("report() diff (-want +got):\n%s", diff)
+	}
+	if violations := report.Violations(); len(violations) != 1 || violations[0].Library != "example.com/gpl" {
+		t.Errorf("Violations() = %v, want a violation of example.com/gpl", violations)
+	}
+}
@@ -0,0 +1,41 @@
+// Copyright 2022 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package golicenses
+
+import "github.com/Bobgy/go-licenses/v2/licenses"
+
+// Logger receives the logs of scans, with the module they are about, if any.
+// It may be called concurrently.
+type Logger = licenses.Logger
+
+// LogLevel is the level of a log entry: "info", "warning" or "error".
+type LogLevel = licenses.LogLevel
+
+// LogFields are structured fields of a log entry.
+type LogFields = licenses.LogFields
+
+// Log levels
+const (
+	LogInfo    = licenses.LogInfo
+	LogWarning = licenses.LogWarning
+	LogError   = licenses.LogError
+)
+
+// SetLogger routes the logs of scans to l instead of glog, which writes to
+// stderr. A nil l restores glog. Like glog, the logger is global to the
+// program, not to a Scanner.
+func SetLogger(l Logger) {
+	licenses.SetLogger(l)
+}
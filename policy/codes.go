@@ -0,0 +1,79 @@
+// Copyright 2022 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package policy
+
+// Code identifies a class of policy violations. Codes are stable, so that
+// tools can route or suppress violations by code.
+type Code string
+
+const (
+	// CodeUnknownLicense is a violation by a license that cannot be
+	// identified.
+	CodeUnknownLicense = Code("GL001")
+	// CodeForbiddenLicense is a license denied by forbidden rules, or not
+	// listed by allowed rules.
+	CodeForbiddenLicense = Code("GL002")
+	// CodeNeedsReview is a license matching review rules.
+	CodeNeedsReview = Code("GL003")
+	// CodeIncompatibleLicense is a license incompatible with the project
+	// license.
+	CodeIncompatibleLicense = Code("GL004")
+	// CodeDeniedModule is a library of a denied module.
+	CodeDeniedModule = Code("GL005")
+	// CodeCustomRule is a library matching a custom rule.
+	CodeCustomRule = Code("GL006")
+	// CodeIntegrity is a module whose contents do not match its checksum.
+	CodeIntegrity = Code("GL007")
+	// CodeLicenseChanged is a module whose license differs from the license
+	// of its previous version.
+	CodeLicenseChanged = Code("GL008")
+	// CodeNoticeDropped is a NOTICE file that is not included in the
+	// attribution saved by go-licenses.
+	CodeNoticeDropped = Code("GL009")
+	// CodeHook is a library denied or needing review by a policy hook, or
+	// that a policy hook failed to decide on.
+	CodeHook = Code("GL010")
+)
+
+// Codes lists all codes.
+var Codes = []Code{CodeUnknownLicense, CodeForbiddenLicense, CodeNeedsReview, CodeIncompatibleLicense, CodeDeniedModule, CodeCustomRule, CodeIntegrity, CodeLicenseChanged, CodeNoticeDropped, CodeHook}
+
+// Description returns a short description of the violations with the code.
+func (c Code) Description() string {
+	switch c {
+	case CodeUnknownLicense:
+		return "Unknown license"
+	case CodeForbiddenLicense:
+		return "Forbidden license"
+	case CodeNeedsReview:
+		return "License needs review"
+	case CodeIncompatibleLicense:
+		return "License incompatible with the project license"
+	case CodeDeniedModule:
+		return "Denied module"
+	case CodeCustomRule:
+		return "Custom rule violation"
+	case CodeIntegrity:
+		return "Module contents do not match checksum"
+	case CodeLicenseChanged:
+		return "License changed since the previous module version"
+	case CodeNoticeDropped:
+		return "NOTICE file missing from attribution"
+	case CodeHook:
+		return "Policy hook violation"
+	default:
+		return string(c)
+	}
+}
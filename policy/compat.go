@@ -0,0 +1,99 @@
+// Copyright 2022 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package policy
+
+import (
+	"fmt"
+	"strings"
+)
+
+// compatibleProjectLicenses lists, for dependency licenses with conditions
+// on the license of the combined work, the only project licenses they can be
+// distributed with. Licenses are normalized by normalizeLicense.
+var compatibleProjectLicenses = map[string][]string{
+	"GPL-2.0":           {"GPL-2.0", "GPL-2.0-OR-LATER"},
+	"GPL-2.0-OR-LATER":  {"GPL-2.0", "GPL-2.0-OR-LATER", "GPL-3.0", "GPL-3.0-OR-LATER", "AGPL-3.0", "AGPL-3.0-OR-LATER"},
+	"GPL-3.0":           {"GPL-3.0", "GPL-3.0-OR-LATER", "AGPL-3.0", "AGPL-3.0-OR-LATER"},
+	"GPL-3.0-OR-LATER":  {"GPL-3.0", "GPL-3.0-OR-LATER", "AGPL-3.0", "AGPL-3.0-OR-LATER"},
+	"AGPL-3.0":          {"AGPL-3.0", "AGPL-3.0-OR-LATER", "GPL-3.0", "GPL-3.0-OR-LATER"},
+	"AGPL-3.0-OR-LATER": {"AGPL-3.0", "AGPL-3.0-OR-LATER", "GPL-3.0", "GPL-3.0-OR-LATER"},
+}
+
+// incompatibleProjectLicenses lists, for other dependency licenses, the
+// project licenses they cannot be distributed with. Dependency licenses in
+// neither list, e.g. permissive and weak copyleft licenses, are compatible
+// with any project license.
+var incompatibleProjectLicenses = map[string][]string{
+	// The patent termination and indemnification clauses of Apache-2.0 are
+	// additional restrictions not permitted by GPL-2.0.
+	"APACHE-2.0": {"GPL-2.0"},
+}
+
+// normalizeLicense returns an upper case SPDX ID without the "-only" suffix,
+// which is the meaning of deprecated IDs like "GPL-3.0" too.
+func normalizeLicense(license string) string {
+	return strings.TrimSuffix(strings.ToUpper(strings.TrimSpace(license)), "-ONLY")
+}
+
+// Compatible reports whether a dependency license can be distributed as part
+// of a project under projectLicense, according to a built-in compatibility
+// matrix. projectLicense may also be "proprietary" for closed source
+// distribution. Unknown licenses are considered compatible, they should be
+// caught by other policy rules.
+func Compatible(projectLicense, license string) bool {
+	project := normalizeLicense(projectLicense)
+	dep := normalizeLicense(license)
+	if project == dep {
+		return true
+	}
+	if compatible, ok := compatibleProjectLicenses[dep]; ok {
+		return contains(compatible, project)
+	}
+	return !contains(incompatibleProjectLicenses[dep], project)
+}
+
+func contains(list []string, s string) bool {
+	for _, item := range list {
+		if item == s {
+			return true
+		}
+	}
+	return false
+}
+
+// CheckCompatibility evaluates whether the license of a library is compatible
+// with ProjectLicense of the policy. All licenses of an expression like
+// "MIT AND GPL-2.0" must be compatible. It returns nil if the license is
+// compatible or ProjectLicense is not set.
+func (p *Policy) CheckCompatibility(library, license, category string) *Result {
+	if p.ProjectLicense == "" {
+		return nil
+	}
+	compatible := true
+	for _, c := range Conjuncts(license) {
+		compatible = compatible && Compatible(p.ProjectLicense, c)
+	}
+	if compatible {
+		return nil
+	}
+	return &Result{
+		Library:  library,
+		License:  license,
+		Category: category,
+		Verdict:  Denied,
+		Rule:     fmt.Sprintf("projectLicense: incompatible with %s", p.ProjectLicense),
+		Code:     CodeIncompatibleLicense,
+	}
+}
@@ -0,0 +1,58 @@
+// Copyright 2022 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package policy
+
+import "testing"
+
+func TestCompatible(t *testing.T) {
+	for _, test := range []struct {
+		project string
+		license string
+		want    bool
+	}{
+		{project: "Apache-2.0", license: "MIT", want: true},
+		{project: "proprietary", license: "BSD-3-Clause", want: true},
+		{project: "proprietary", license: "MPL-2.0", want: true},
+		{project: "Apache-2.0", license: "GPL-3.0", want: false},
+		{project: "proprietary", license: "AGPL-3.0-only", want: false},
+		{project: "GPL-3.0-or-later", license: "GPL-3.0", want: true},
+		{project: "GPL-3.0", license: "GPL-2.0-only", want: false},
+		{project: "GPL-3.0", license: "GPL-2.0-or-later", want: true},
+		{project: "GPL-2.0", license: "Apache-2.0", want: false},
+		{project: "GPL-3.0", license: "Apache-2.0", want: true},
+		{project: "MIT", license: "Unknown", want: true},
+	} {
+		if got := Compatible(test.project, test.license); got != test.want {
+			t.Errorf("Compatible(%q, %q) = %t, want %t", test.project, test.license, got, test.want)
+		}
+	}
+}
+
+func TestCheckCompatibility(t *testing.T) {
+	p := &Policy{ProjectLicense: "Apache-2.0"}
+	if got := p.CheckCompatibility("lib", "MIT", "notice"); got != nil {
+		t.Errorf("CheckCompatibility(MIT) = %v, want nil", got)
+	}
+	got := p.CheckCompatibility("lib", "GPL-3.0", "restricted")
+	if got == nil || got.Verdict != Denied {
+		t.Fatalf("CheckCompatibility(GPL-3.0) = %v, want a denied result", got)
+	}
+	if want := "projectLicense: incompatible with Apache-2.0"; got.Rule != want {
+		t.Errorf("CheckCompatibility(GPL-3.0) rule = %q, want %q", got.Rule, want)
+	}
+	if got.Code != CodeIncompatibleLicense {
+		t.Errorf("CheckCompatibility(GPL-3.0) code = %q, want %q", got.Code, CodeIncompatibleLicense)
+	}
+}
@@ -0,0 +1,91 @@
+// Copyright 2022 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package policy
+
+// CopyleftScope is the part of a statically linked Go binary a copyleft
+// license attaches obligations to. Go binaries link all packages statically,
+// so exceptions for dynamic linking in licenses like LGPL do not apply.
+type CopyleftScope string
+
+const (
+	// NoCopyleft licenses have no copyleft obligations.
+	NoCopyleft = CopyleftScope("")
+	// FileCopyleft licenses, e.g. MPL-2.0, only cover the licensed files.
+	FileCopyleft = CopyleftScope("file")
+	// LibraryCopyleft licenses, e.g. LGPL-2.1, cover the licensed library
+	// and require that users can relink the binary with a modified version
+	// of it.
+	LibraryCopyleft = CopyleftScope("library")
+	// ProgramCopyleft licenses, e.g. GPL-3.0, cover the whole binary.
+	ProgramCopyleft = CopyleftScope("program")
+)
+
+// copyleftScopes maps licenses, normalized by normalizeLicense, to their
+// scope. Other licenses have no copyleft obligations.
+var copyleftScopes = map[string]CopyleftScope{
+	"MPL-1.0":           FileCopyleft,
+	"MPL-1.1":           FileCopyleft,
+	"MPL-2.0":           FileCopyleft,
+	"EPL-1.0":           FileCopyleft,
+	"EPL-2.0":           FileCopyleft,
+	"CDDL-1.0":          FileCopyleft,
+	"CDDL-1.1":          FileCopyleft,
+	"LGPL-2.0":          LibraryCopyleft,
+	"LGPL-2.0-OR-LATER": LibraryCopyleft,
+	"LGPL-2.1":          LibraryCopyleft,
+	"LGPL-2.1-OR-LATER": LibraryCopyleft,
+	"LGPL-3.0":          LibraryCopyleft,
+	"LGPL-3.0-OR-LATER": LibraryCopyleft,
+	"GPL-2.0":           ProgramCopyleft,
+	"GPL-2.0-OR-LATER":  ProgramCopyleft,
+	"GPL-3.0":           ProgramCopyleft,
+	"GPL-3.0-OR-LATER":  ProgramCopyleft,
+	"AGPL-3.0":          ProgramCopyleft,
+	"AGPL-3.0-OR-LATER": ProgramCopyleft,
+}
+
+// scopeOrder orders copyleft scopes from the widest to the narrowest.
+var scopeOrder = []CopyleftScope{ProgramCopyleft, LibraryCopyleft, FileCopyleft, NoCopyleft}
+
+// StaticLinkingScope returns the copyleft scope of a license when statically
+// linked into a Go binary. The scope of an expression like "MIT AND LGPL-3.0"
+// is the widest scope of its licenses.
+func StaticLinkingScope(license string) CopyleftScope {
+	scopes := make(map[CopyleftScope]bool)
+	for _, c := range Conjuncts(license) {
+		scopes[copyleftScopes[normalizeLicense(c)]] = true
+	}
+	for _, s := range scopeOrder {
+		if scopes[s] {
+			return s
+		}
+	}
+	return NoCopyleft
+}
+
+// Obligation summarizes what distributing a statically linked binary
+// requires for licenses of the scope. It is empty for NoCopyleft.
+func (s CopyleftScope) Obligation() string {
+	switch s {
+	case FileCopyleft:
+		return "provide the source of the licensed files including modifications"
+	case LibraryCopyleft:
+		return "allow relinking with a modified library by providing the object files or source of the binary"
+	case ProgramCopyleft:
+		return "license the whole binary compatibly and provide its complete source"
+	default:
+		return ""
+	}
+}
@@ -0,0 +1,41 @@
+// Copyright 2022 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package policy
+
+import (
+	"testing"
+)
+
+func TestStaticLinkingScope(t *testing.T) {
+	for _, test := range []struct {
+		license string
+		want    CopyleftScope
+	}{
+		{license: "MIT", want: NoCopyleft},
+		{license: "Unknown", want: NoCopyleft},
+		{license: "MIT AND LGPL-3.0", want: LibraryCopyleft},
+		{license: "MPL-2.0", want: FileCopyleft},
+		{license: "LGPL-2.1", want: LibraryCopyleft},
+		{license: "LGPL-3.0-only", want: LibraryCopyleft},
+		{license: "GPL-3.0-or-later", want: ProgramCopyleft},
+		{license: "AGPL-3.0", want: ProgramCopyleft},
+	} {
+		t.Run(test.license, func(t *testing.T) {
+			if got := StaticLinkingScope(test.license); got != test.want {
+				t.Errorf("StaticLinkingScope(%q) = %q, want %q", test.license, got, test.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,72 @@
+// Copyright 2022 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package policy
+
+import (
+	"strings"
+)
+
+// Alternatives returns the licenses of an SPDX expression that can be chosen
+// from, e.g. ["MIT", "Apache-2.0"] for "MIT OR Apache-2.0". An expression
+// without OR is its only alternative.
+func Alternatives(expression string) []string {
+	expression = strings.TrimSpace(expression)
+	for strings.HasPrefix(expression, "(") && strings.HasSuffix(expression, ")") && !strings.Contains(expression[1:len(expression)-1], "(") {
+		expression = strings.TrimSpace(expression[1 : len(expression)-1])
+	}
+	if strings.Contains(expression, "(") {
+		// Nested expressions are not supported, treat them as a whole.
+		return []string{expression}
+	}
+	var alternatives []string
+	for _, alt := range strings.Split(expression, " OR ") {
+		alternatives = append(alternatives, strings.TrimSpace(alt))
+	}
+	return alternatives
+}
+
+// Conjuncts returns the licenses of an SPDX expression that all apply, e.g.
+// ["MIT", "BSD-3-Clause"] for "MIT AND BSD-3-Clause", e.g. of a library with
+// several license files. An expression without AND is its only conjunct.
+func Conjuncts(expression string) []string {
+	alternatives := Alternatives(expression)
+	if len(alternatives) != 1 || strings.Contains(alternatives[0], "(") {
+		return []string{strings.TrimSpace(expression)}
+	}
+	var conjuncts []string
+	for _, c := range strings.Split(alternatives[0], " AND ") {
+		conjuncts = append(conjuncts, strings.TrimSpace(c))
+	}
+	return conjuncts
+}
+
+// Elect chooses a license from an SPDX expression of dual licenses, e.g.
+// "Apache-2.0 OR GPL-2.0", by the order of preferred licenses. An expression
+// with a single alternative elects it. If no alternative is preferred, or the
+// expression is too complex, it returns the expression itself and false.
+func Elect(expression string, preferred []string) (string, bool) {
+	alternatives := Alternatives(expression)
+	if len(alternatives) == 1 {
+		return alternatives[0], !strings.Contains(alternatives[0], " OR ")
+	}
+	for _, p := range preferred {
+		for _, alt := range alternatives {
+			if strings.EqualFold(alt, p) {
+				return alt, true
+			}
+		}
+	}
+	return expression, false
+}
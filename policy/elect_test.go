@@ -0,0 +1,61 @@
+// Copyright 2022 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package policy
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestElect(t *testing.T) {
+	preferred := []string{"apache-2.0", "MIT"}
+	for _, test := range []struct {
+		expression string
+		want       string
+		wantOK     bool
+	}{
+		{expression: "BSD-3-Clause", want: "BSD-3-Clause", wantOK: true},
+		{expression: "GPL-2.0 OR Apache-2.0", want: "Apache-2.0", wantOK: true},
+		{expression: "(MIT OR GPL-2.0)", want: "MIT", wantOK: true},
+		{expression: "MIT OR Apache-2.0", want: "Apache-2.0", wantOK: true},
+		{expression: "GPL-2.0 OR LGPL-2.1", want: "GPL-2.0 OR LGPL-2.1", wantOK: false},
+		{expression: "(MIT AND BSD-2-Clause) OR GPL-2.0", want: "(MIT AND BSD-2-Clause) OR GPL-2.0", wantOK: false},
+	} {
+		t.Run(test.expression, func(t *testing.T) {
+			got, ok := Elect(test.expression, preferred)
+			if got != test.want || ok != test.wantOK {
+				t.Errorf("Elect(%q) = (%q, %t), want (%q, %t)", test.expression, got, ok, test.want, test.wantOK)
+			}
+		})
+	}
+}
+
+func TestConjuncts(t *testing.T) {
+	for _, test := range []struct {
+		expression string
+		want       []string
+	}{
+		{expression: "MIT", want: []string{"MIT"}},
+		{expression: "MIT AND BSD-3-Clause", want: []string{"MIT", "BSD-3-Clause"}},
+		{expression: "(MIT AND BSD-3-Clause)", want: []string{"MIT", "BSD-3-Clause"}},
+		{expression: "MIT AND BSD-3-Clause OR GPL-2.0", want: []string{"MIT AND BSD-3-Clause OR GPL-2.0"}},
+		{expression: "MIT AND (Apache-2.0 OR GPL-2.0)", want: []string{"MIT AND (Apache-2.0 OR GPL-2.0)"}},
+	} {
+		if diff := cmp.Diff(test.want, Conjuncts(test.expression)); diff != "" {
+			t.Errorf("Conjuncts(%q) diff (-want +got):\n%s", test.expression, diff)
+		}
+	}
+}
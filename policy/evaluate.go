@@ -0,0 +1,61 @@
+// Copyright 2022 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package policy
+
+import (
+	"sort"
+	"time"
+)
+
+// Finding is a library that a policy denies or requires reviewing.
+type Finding struct {
+	// Record is the library as passed to Evaluate.
+	Record Library
+	// Result is the result of evaluating the library, whose Verdict is not
+	// Allowed.
+	*Result
+}
+
+// Evaluate evaluates libraries against a policy with the rules the check
+// command enforces, see CheckLibrary, and returns the findings sorted by
+// library and code. Libraries allowed, including by an exception, have no
+// finding. It returns an error if the policy is invalid. A nil policy allows
+// every library.
+func Evaluate(records []Library, p *Policy) ([]Finding, error) {
+	return evaluate(records, p, time.Now())
+}
+
+func evaluate(records []Library, p *Policy, now time.Time) ([]Finding, error) {
+	if p == nil {
+		return nil, nil
+	}
+	if err := p.Validate(); err != nil {
+		return nil, err
+	}
+	var findings []Finding
+	for _, record := range records {
+		if r := p.CheckLibrary(record, now); r.Verdict != Allowed {
+			findings = append(findings, Finding{Record: record, Result: r})
+		}
+	}
+	sort.SliceStable(findings, func(i, j int) bool {
+		a, b := findings[i], findings[j]
+		if a.Library != b.Library {
+			return a.Library < b.Library
+		}
+		return a.Code < b.Code
+	})
+	return findings, nil
+}
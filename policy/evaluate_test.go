@@ -0,0 +1,68 @@
+// Copyright 2022 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package policy
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestEvaluate(t *testing.T) {
+	p := &Policy{
+		Forbidden:     Rules{Categories: []string{"restricted"}},
+		Review:        Rules{Licenses: []string{"MPL-2.0"}},
+		DeniedModules: []string{"github.com/foo/fork"},
+		Exceptions:    []Exception{{Module: "github.com/foo/waived", Justification: "only used by tests"}},
+	}
+	records := []Library{
+		{Name: "github.com/foo/mpl", License: "MPL-2.0", Category: "reciprocal"},
+		{Name: "github.com/foo/mit", License: "MIT", Category: "notice"},
+		{Name: "github.com/foo/gpl", License: "GPL-3.0", Category: "restricted"},
+		{Name: "github.com/foo/waived", Module: "github.com/foo/waived", License: "GPL-3.0", Category: "restricted"},
+		{Name: "github.com/foo/fork/pkg", Module: "github.com/foo/fork", License: "MIT", Category: "notice"},
+	}
+	findings, err := Evaluate(records, p)
+	if err != nil {
+		t.Fatalf("Evaluate() = (_, %v), want (_, nil)", err)
+	}
+	type summary struct {
+		Library string
+		Verdict Verdict
+		Code    Code
+	}
+	var got []summary
+	for _, f := range findings {
+		if f.Record.Name != f.Library {
+			t.Errorf("Evaluate() finding of %s has record of %s", f.Library, f.Record.Name)
+		}
+		got = append(got, summary{f.Library, f.Verdict, f.Code})
+	}
+	want := []summary{
+		{"github.com/foo/fork/pkg", Denied, CodeDeniedModule},
+		{"github.com/foo/gpl", Denied, CodeForbiddenLicense},
+		{"github.com/foo/mpl", NeedsReview, CodeNeedsReview},
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("Evaluate(): diff (-want +got)\n%s", diff)
+	}
+
+	if _, err := Evaluate(records, &Policy{Forbidden: Rules{Categories: []string{"viral"}}}); err == nil {
+		t.Errorf("Evaluate() with an invalid policy = nil error, want error")
+	}
+	if findings, err := Evaluate(records, nil); err != nil || findings != nil {
+		t.Errorf("Evaluate() with a nil policy = (%v, %v), want (nil, nil)", findings, err)
+	}
+}
@@ -0,0 +1,126 @@
+// Copyright 2022 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package policy
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// dateLayout is the layout of dates in policies, e.g. "2022-12-31".
+const dateLayout = "2006-01-02"
+
+// Exception waives policy violations of a module, e.g. while it is being
+// replaced. Exceptions are recorded with a justification and an optional
+// approver and expiration date, so that waivers stay auditable.
+type Exception struct {
+	// Module is the module path, or an import path prefix, of the libraries
+	// the exception applies to.
+	Module string `yaml:"module"`
+	// License limits the exception to a license, so that it no longer
+	// applies when the license of the module changes.
+	License string `yaml:"license,omitempty"`
+	// Versions limits the exception to a range of module versions, e.g.
+	// "v1.x" or ">=v1.2.0 <v2", so that upgrades out of the range are
+	// reviewed again. See matchVersions.
+	Versions string `yaml:"versions,omitempty"`
+	// Justification explains why the violation is acceptable.
+	Justification string `yaml:"justification"`
+	// Approver is who approved the exception.
+	Approver string `yaml:"approver,omitempty"`
+	// Expires is the date from which the exception no longer applies,
+	// formatted as YYYY-MM-DD, in UTC.
+	Expires string `yaml:"expires,omitempty"`
+}
+
+func (e *Exception) String() string {
+	s := fmt.Sprintf("exception for %s: %s", e.Module, e.Justification)
+	if e.Versions != "" {
+		s = fmt.Sprintf("exception for %s %s: %s", e.Module, e.Versions, e.Justification)
+	}
+	if e.Approver != "" {
+		s += ", approved by " + e.Approver
+	}
+	if e.Expires != "" {
+		s += ", expires " + e.Expires
+	}
+	return s
+}
+
+// validate returns an error if a required field is missing, or the version
+// range or expiration date is invalid.
+func (e *Exception) validate() error {
+	if e.Module == "" {
+		return fmt.Errorf("policy: exception without module")
+	}
+	if e.Justification == "" {
+		return fmt.Errorf("policy: exception for %s without justification", e.Module)
+	}
+	if e.Versions != "" {
+		if err := validateVersions(e.Versions); err != nil {
+			return fmt.Errorf("policy: exception for %s: %w", e.Module, err)
+		}
+	}
+	if err := validateDate(e.Expires); err != nil {
+		return fmt.Errorf("%w in exception for %s", err, e.Module)
+	}
+	return nil
+}
+
+// expired reports whether the exception no longer applies at now.
+func (e *Exception) expired(now time.Time) bool {
+	return e.Expires != "" && !before(now, e.Expires)
+}
+
+// validateDate returns an error if date is neither empty nor formatted as
+// YYYY-MM-DD.
+func validateDate(date string) error {
+	if date == "" {
+		return nil
+	}
+	if _, err := time.Parse(dateLayout, date); err != nil {
+		return fmt.Errorf("policy: invalid date %q, want YYYY-MM-DD", date)
+	}
+	return nil
+}
+
+// before reports whether now is before a date formatted as YYYY-MM-DD.
+// Invalid dates, rejected by validateDate, are in the past.
+func before(now time.Time, date string) bool {
+	t, err := time.Parse(dateLayout, date)
+	if err != nil {
+		return false
+	}
+	return now.Before(t)
+}
+
+// matches reports whether the exception applies to a library.
+func (e *Exception) matches(lib Library) bool {
+	if e.License != "" && !strings.EqualFold(e.License, lib.License) {
+		return false
+	}
+	if e.Versions != "" && !matchVersions(e.Versions, lib.Version) {
+		return false
+	}
+	return e.Module == lib.Module || matchPathPrefix(e.Module, lib.Name)
+}
+
+// matchPathPrefix reports whether path equals prefix or is below it, matching
+// whole path elements only.
+func matchPathPrefix(prefix, path string) bool {
+	prefix = strings.TrimSuffix(prefix, "/")
+	return path == prefix || strings.HasPrefix(path, prefix+"/")
+}
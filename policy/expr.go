@@ -0,0 +1,205 @@
+// Copyright 2022 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package policy
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/google/cel-go/cel"
+	"github.com/google/cel-go/checker/decls"
+	"github.com/google/cel-go/common/types"
+	"github.com/google/cel-go/common/types/ref"
+	"github.com/google/cel-go/interpreter/functions"
+	exprpb "google.golang.org/genproto/googleapis/api/expr/v1alpha1"
+)
+
+// CustomRule decides the verdict for libraries matching an expression. It
+// expresses rules that cannot be written as license and category lists, e.g.
+// "AGPL-3.0 is allowed only in internal tools":
+//
+//	license == "AGPL-3.0" && hasPathPrefix(library, "example.com/tools")
+//
+// Expressions are CEL (https://github.com/google/cel-spec) expressions
+// evaluating to a bool. They can use the string variables library, module,
+// version, license and category of the library, the double variable
+// confidence of its license classification, the bool variable indirect, which
+// is true for modules only required indirectly, the CEL standard functions,
+// e.g. startsWith, endsWith, contains, matches and in, and the function
+// hasPathPrefix(path, prefix), which matches whole path elements.
+type CustomRule struct {
+	// Name identifies the rule in results.
+	Name string `yaml:"name"`
+	// Expr is the expression matching libraries.
+	Expr string `yaml:"expr"`
+	// Verdict is the verdict for matching libraries: "allowed", "needs
+	// review" or "denied".
+	Verdict Verdict `yaml:"verdict"`
+	// Severity of violations of the rule. Defaults to the severity of the
+	// verdict, see DefaultSeverity.
+	Severity Severity `yaml:"severity,omitempty"`
+	// WarnUntil is the date from which violations of the rule are enforced,
+	// see Rules.WarnUntil.
+	WarnUntil string `yaml:"warnUntil,omitempty"`
+
+	// compiled is Expr compiled by validate, so that it is not parsed again
+	// for each library.
+	compiled *compiledExpr
+}
+
+// validate returns an error if the rule has no name, an invalid verdict or an
+// expression that does not compile to a bool, and compiles the expression.
+func (r *CustomRule) validate() error {
+	if r.Name == "" {
+		return fmt.Errorf("policy: custom rule without name")
+	}
+	switch r.Verdict {
+	case Allowed, NeedsReview, Denied:
+	default:
+		return fmt.Errorf("policy: custom rule %s has invalid verdict %q, must be one of %q, %q, %q", r.Name, r.Verdict, Allowed, NeedsReview, Denied)
+	}
+	if err := r.Severity.validate(); err != nil {
+		return fmt.Errorf("%w in custom rule %s", err, r.Name)
+	}
+	if err := validateDate(r.WarnUntil); err != nil {
+		return fmt.Errorf("%w in custom rule %s", err, r.Name)
+	}
+	compiled, err := compileExpr(r.Expr)
+	if err != nil {
+		return fmt.Errorf("policy: custom rule %s: %w", r.Name, err)
+	}
+	r.compiled = compiled
+	return nil
+}
+
+// match reports whether the rule's expression is true for a library. The
+// expression is compiled on each call if the rule was not validated.
+func (r *CustomRule) match(lib Library) (bool, error) {
+	compiled := r.compiled
+	if compiled == nil || compiled.source != r.Expr {
+		var err error
+		if compiled, err = compileExpr(r.Expr); err != nil {
+			return false, err
+		}
+	}
+	v, _, err := compiled.program.Eval(variables(lib))
+	if err != nil {
+		return false, err
+	}
+	matched, ok := v.Value().(bool)
+	if !ok {
+		return false, fmt.Errorf("expression %q evaluated to %v, want bool", r.Expr, v)
+	}
+	return matched, nil
+}
+
+// variables returns the value of each variable available to expressions, the
+// activation of their programs.
+func variables(lib Library) map[string]interface{} {
+	return map[string]interface{}{
+		"library":    lib.Name,
+		"module":     lib.Module,
+		"version":    lib.Version,
+		"license":    lib.License,
+		"category":   lib.Category,
+		"confidence": lib.Confidence,
+		"indirect":   lib.Indirect,
+	}
+}
+
+// hasPathPrefixOverload is the overload ID of hasPathPrefix.
+const hasPathPrefixOverload = "hasPathPrefix_string_string"
+
+var (
+	exprEnvOnce sync.Once
+	exprEnv     *cel.Env
+	exprEnvErr  error
+)
+
+// newExprEnv returns the CEL environment of expressions, declaring the
+// variables of variables and the functions of exprFuncs.
+func newExprEnv() (*cel.Env, error) {
+	exprEnvOnce.Do(func() {
+		exprEnv, exprEnvErr = cel.NewEnv(
+			// Allow comparing the double confidence with int literals, e.g.
+			// confidence <= 1.
+			cel.CrossTypeNumericComparisons(true),
+			cel.Declarations(
+				decls.NewVar("library", decls.String),
+				decls.NewVar("module", decls.String),
+				decls.NewVar("version", decls.String),
+				decls.NewVar("license", decls.String),
+				decls.NewVar("category", decls.String),
+				decls.NewVar("confidence", decls.Double),
+				decls.NewVar("indirect", decls.Bool),
+				decls.NewFunction("hasPathPrefix",
+					decls.NewOverload(hasPathPrefixOverload, []*exprpb.Type{decls.String, decls.String}, decls.Bool)),
+			),
+		)
+	})
+	return exprEnv, exprEnvErr
+}
+
+// exprFuncs are the implementations of the functions declared by newExprEnv
+// beyond the CEL standard functions.
+var exprFuncs = []*functions.Overload{
+	{
+		Operator: hasPathPrefixOverload,
+		Binary: func(path, prefix ref.Val) ref.Val {
+			p, ok := path.(types.String)
+			if !ok {
+				return types.MaybeNoSuchOverloadErr(path)
+			}
+			pre, ok := prefix.(types.String)
+			if !ok {
+				return types.MaybeNoSuchOverloadErr(prefix)
+			}
+			return types.Bool(matchPathPrefix(string(pre), string(p)))
+		},
+	},
+}
+
+// compiledExpr is an expression checked and planned by compileExpr.
+type compiledExpr struct {
+	// source is the expression compiled.
+	source  string
+	program cel.Program
+}
+
+// compileExpr parses an expression, checks that it is a valid bool
+// expression and plans its program. Constant regexps of matches are compiled
+// with the program, so that invalid ones are reported here.
+func compileExpr(s string) (*compiledExpr, error) {
+	if strings.TrimSpace(s) == "" {
+		return nil, fmt.Errorf("empty expression")
+	}
+	env, err := newExprEnv()
+	if err != nil {
+		return nil, err
+	}
+	ast, issues := env.Compile(s)
+	if issues != nil && issues.Err() != nil {
+		return nil, fmt.Errorf("invalid expression %q: %w", s, issues.Err())
+	}
+	if typ := ast.ResultType(); typ.GetPrimitive() != exprpb.Type_BOOL {
+		return nil, fmt.Errorf("invalid expression %q: evaluates to %s, want bool", s, cel.FormatType(typ))
+	}
+	program, err := env.Program(ast, cel.Functions(exprFuncs...), cel.EvalOptions(cel.OptOptimize))
+	if err != nil {
+		return nil, fmt.Errorf("invalid expression %q: %w", s, err)
+	}
+	return &compiledExpr{source: s, program: program}, nil
+}
@@ -0,0 +1,129 @@
+// Copyright 2022 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package policy
+
+import (
+	"testing"
+)
+
+func TestCustomRuleMatch(t *testing.T) {
+	lib := Library{
+		Name:       "example.com/tools/admin/db",
+		Module:     "example.com/tools",
+		Version:    "v1.2.0",
+		License:    "AGPL-3.0",
+		Category:   "restricted",
+		Confidence: 0.85,
+		Indirect:   true,
+	}
+	for _, test := range []struct {
+		expr string
+		want bool
+	}{
+		{expr: `license == "AGPL-3.0"`, want: true},
+		{expr: `license != "AGPL-3.0"`, want: false},
+		{expr: `license == "AGPL-3.0" && hasPathPrefix(library, "example.com/tools")`, want: true},
+		{expr: `hasPathPrefix(library, "example.com/tool")`, want: false},
+		{expr: `!(category == "restricted") || module.startsWith("example.com/")`, want: true},
+		{expr: `library.endsWith("/db") && module.contains("tools")`, want: true},
+		{expr: `version.matches("^v1\\.")`, want: true},
+		{expr: `license in ["GPL-3.0", "AGPL-3.0"]`, want: true},
+		{expr: `license in ["MIT"]`, want: false},
+		{expr: `(version >= "v1.0.0") == true`, want: true},
+		{expr: `false || category < "z"`, want: true},
+		{expr: `confidence < 0.9`, want: true},
+		{expr: `confidence >= 0.85 && confidence <= 1`, want: true},
+		{expr: `confidence > 0.85 || confidence == 1.0`, want: false},
+		{expr: `indirect && !(confidence != 0.85)`, want: true},
+		{expr: `indirect == false`, want: false},
+		{expr: `library.matches("/admin/")`, want: true},
+		{expr: `size(license) == 8 && license + "!" == "AGPL-3.0!"`, want: true},
+	} {
+		t.Run(test.expr, func(t *testing.T) {
+			rule := &CustomRule{Name: "test", Expr: test.expr, Verdict: Allowed}
+			if err := rule.validate(); err != nil {
+				t.Fatalf("validate() = %v", err)
+			}
+			got, err := rule.match(lib)
+			if err != nil {
+				t.Fatalf("match() = %v", err)
+			}
+			if got != test.want {
+				t.Errorf("match() = %t, want %t", got, test.want)
+			}
+		})
+	}
+}
+
+func TestCustomRuleValidate(t *testing.T) {
+	for _, rule := range []CustomRule{
+		{Expr: `license == "MIT"`, Verdict: Allowed},
+		{Name: "verdict", Expr: `license == "MIT"`, Verdict: "fine"},
+		{Name: "empty", Verdict: Allowed},
+		{Name: "syntax", Expr: `license ==`, Verdict: Allowed},
+		{Name: "not bool", Expr: `license`, Verdict: Allowed},
+		{Name: "unknown variable", Expr: `author == "me"`, Verdict: Allowed},
+		{Name: "unknown function", Expr: `isGood(license)`, Verdict: Allowed},
+		{Name: "arguments", Expr: `hasPathPrefix(license)`, Verdict: Allowed},
+		{Name: "mismatched types", Expr: `license == true`, Verdict: Allowed},
+		{Name: "number", Expr: `license == 3`, Verdict: Allowed},
+		{Name: "number variable", Expr: `confidence == "high"`, Verdict: Allowed},
+		{Name: "invalid regexp", Expr: `license.matches("(")`, Verdict: Allowed},
+		{Name: "operator", Expr: `license * 2 == "MITMIT"`, Verdict: Allowed},
+	} {
+		if err := rule.validate(); err == nil {
+			t.Errorf("validate() of %+v = nil, want error", rule)
+		}
+	}
+}
+
+func TestCustomRuleCompiledOnce(t *testing.T) {
+	rule := &CustomRule{Name: "test", Expr: `license.matches("^GPL")`, Verdict: Denied}
+	if err := rule.validate(); err != nil {
+		t.Fatalf("validate() = %v", err)
+	}
+	compiled := rule.compiled
+	if compiled == nil || compiled.program == nil {
+		t.Fatalf("validate() compiled %+v, want a program", compiled)
+	}
+	if got, err := rule.match(Library{License: "GPL-2.0"}); err != nil || !got {
+		t.Errorf("match() = %t, %v, want true", got, err)
+	}
+	if rule.compiled != compiled {
+		t.Error("match() recompiled the validated expression")
+	}
+	rule.Expr = `license == "MIT"`
+	if got, err := rule.match(Library{License: "GPL-2.0"}); err != nil || got {
+		t.Errorf("match() after changing Expr = %t, %v, want false", got, err)
+	}
+}
+
+func TestCheckCustom(t *testing.T) {
+	p := &Policy{
+		Forbidden: Rules{Licenses: []string{"AGPL-3.0"}},
+		Custom: []CustomRule{
+			{Name: "agpl-internal-tools", Expr: `license == "AGPL-3.0" && hasPathPrefix(module, "example.com/tools")`, Verdict: Allowed},
+			{Name: "bad-regexp", Expr: `license.matches(module)`, Verdict: Allowed},
+		},
+	}
+	lib := Library{Name: "example.com/tools/admin", Module: "example.com/tools", License: "AGPL-3.0", Category: "restricted"}
+	if got := p.CheckCustom(lib); got == nil || got.Verdict != Allowed || got.Rule != "custom.agpl-internal-tools" {
+		t.Errorf("CheckCustom(%+v) = %v, want allowed by custom.agpl-internal-tools", lib, got)
+	}
+	lib = Library{Name: "example.com/server", Module: "example.com/server(", License: "AGPL-3.0", Category: "restricted"}
+	if got := p.CheckCustom(lib); got == nil || got.Verdict != Denied {
+		t.Errorf("CheckCustom(%+v) = %v, want denied by invalid regexp", lib, got)
+	}
+}
@@ -0,0 +1,131 @@
+// Copyright 2022 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package policy
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os/exec"
+	"strings"
+	"time"
+)
+
+// hookTimeout is how long a hook may take to decide on a library.
+const hookTimeout = time.Minute
+
+// Hook is an external program deciding on each library, e.g. a client of an
+// internal compliance service. It receives a JSON hookRequest on stdin, and
+// prints a JSON hookResponse on stdout.
+type Hook struct {
+	// Command is the program and its arguments.
+	Command []string `yaml:"command"`
+	// Severity of violations reported by the hook. Defaults to the severity
+	// of the verdict, see DefaultSeverity.
+	Severity Severity `yaml:"severity,omitempty"`
+}
+
+// hookRequest describes a library to a hook, together with the result of the
+// other rules of the policy.
+type hookRequest struct {
+	Library  string  `json:"library"`
+	Module   string  `json:"module,omitempty"`
+	Version  string  `json:"version,omitempty"`
+	License  string  `json:"license"`
+	Category string  `json:"category"`
+	Verdict  Verdict `json:"verdict"`
+	Rule     string  `json:"rule,omitempty"`
+}
+
+// hookResponse is the decision of a hook. An empty verdict keeps the result of
+// the other rules.
+type hookResponse struct {
+	// Verdict is "allow", "deny", "need-review" or empty.
+	Verdict string `json:"verdict"`
+	// Reason explains the verdict.
+	Reason string `json:"reason,omitempty"`
+}
+
+// hookVerdicts maps verdicts of hook responses to policy verdicts.
+var hookVerdicts = map[string]Verdict{
+	"allow":       Allowed,
+	"deny":        Denied,
+	"need-review": NeedsReview,
+}
+
+// validate returns an error if the hook has no command or an invalid severity.
+func (h *Hook) validate() error {
+	if len(h.Command) == 0 || h.Command[0] == "" {
+		return fmt.Errorf("policy: hook without command")
+	}
+	if err := h.Severity.validate(); err != nil {
+		return fmt.Errorf("%w in hook", err)
+	}
+	return nil
+}
+
+// run asks the hook to decide on a library, given the result of the other
+// rules. It returns nil if the hook keeps that result.
+func (h *Hook) run(lib Library, result *Result) (*Result, error) {
+	req, err := json.Marshal(hookRequest{
+		Library:  lib.Name,
+		Module:   lib.Module,
+		Version:  lib.Version,
+		License:  lib.License,
+		Category: lib.Category,
+		Verdict:  result.Verdict,
+		Rule:     result.Rule,
+	})
+	if err != nil {
+		return nil, err
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), hookTimeout)
+	defer cancel()
+	cmd := exec.CommandContext(ctx, h.Command[0], h.Command[1:]...)
+	cmd.Stdin = bytes.NewReader(req)
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w: %s", strings.Join(h.Command, " "), err, strings.TrimSpace(stderr.String()))
+	}
+	var resp hookResponse
+	if err := json.Unmarshal(out, &resp); err != nil {
+		return nil, fmt.Errorf("%s: parsing response %q: %w", strings.Join(h.Command, " "), out, err)
+	}
+	if resp.Verdict == "" {
+		return nil, nil
+	}
+	verdict, ok := hookVerdicts[resp.Verdict]
+	if !ok {
+		return nil, fmt.Errorf("%s: unknown verdict %q, must be allow, deny or need-review", strings.Join(h.Command, " "), resp.Verdict)
+	}
+	r := &Result{
+		Library:  lib.Name,
+		License:  lib.License,
+		Category: lib.Category,
+		Verdict:  verdict,
+		Rule:     "hook",
+	}
+	if resp.Reason != "" {
+		r.Rule += ": " + resp.Reason
+	}
+	if verdict != Allowed {
+		r.Severity = h.Severity
+		r.Code = CodeHook
+	}
+	return r, nil
+}
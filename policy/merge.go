@@ -0,0 +1,125 @@
+// Copyright 2022 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package policy
+
+// Merge returns the policy of top layered over base, e.g. a repository's
+// policy over an organization-wide one. Forbidden and review rules are
+// combined, so that top can only add to those of base, see mergeRules. Allowed rules and
+// settings set in top replace those of base, custom rules of top are
+// evaluated before those of base, and denied modules, license categories and
+// exceptions are combined. Either policy may be nil.
+func Merge(base, top *Policy) *Policy {
+	if base == nil {
+		return top
+	}
+	if top == nil {
+		return base
+	}
+	merged := *base
+	if !top.Allowed.empty() {
+		merged.Allowed = top.Allowed
+	}
+	merged.Forbidden = mergeRules(base.Forbidden, top.Forbidden, DefaultSeverity(Denied))
+	merged.Review = mergeRules(base.Review, top.Review, DefaultSeverity(NeedsReview))
+	if top.ProjectLicense != "" {
+		merged.ProjectLicense = top.ProjectLicense
+	}
+	if top.MinConfidence != 0 {
+		merged.MinConfidence = top.MinConfidence
+	}
+	if top.Hook != nil {
+		merged.Hook = top.Hook
+	}
+	if top.NoticeSeverity != "" {
+		merged.NoticeSeverity = top.NoticeSeverity
+	}
+	merged.DeniedModules = append(append([]string(nil), base.DeniedModules...), top.DeniedModules...)
+	merged.Custom = append(append([]CustomRule(nil), top.Custom...), base.Custom...)
+	merged.Exceptions = append(append([]Exception(nil), top.Exceptions...), base.Exceptions...)
+	if len(base.LicenseCategories)+len(top.LicenseCategories) > 0 {
+		merged.LicenseCategories = make(map[string]string)
+		for license, category := range base.LicenseCategories {
+			merged.LicenseCategories[license] = category
+		}
+		for license, category := range top.LicenseCategories {
+			merged.LicenseCategories[license] = category
+		}
+	}
+	return &merged
+}
+
+// mergeRules returns the licenses and categories of base and top, without
+// duplicates, with the more severe of their severities and the earlier of
+// their warnUntil dates, so that top cannot weaken the rules of base. An
+// unset severity is def, the severity of the verdict of the rules, and an
+// unset warnUntil is enforced already. Rules without any license, category
+// or setting do not change the other rules.
+func mergeRules(base, top Rules, def Severity) Rules {
+	if top.empty() {
+		return base
+	}
+	if base.empty() {
+		return top
+	}
+	merged := Rules{
+		Licenses:   union(base.Licenses, top.Licenses),
+		Categories: union(base.Categories, top.Categories),
+		Severity:   base.Severity,
+		WarnUntil:  base.WarnUntil,
+	}
+	b, t := severityRank(base.Severity, def), severityRank(top.Severity, def)
+	if t > b || t == b && base.Severity == "" {
+		merged.Severity = top.Severity
+	}
+	if base.WarnUntil != "" && (top.WarnUntil == "" || top.WarnUntil < base.WarnUntil) {
+		merged.WarnUntil = top.WarnUntil
+	}
+	return merged
+}
+
+// severityRank orders severities from the least to the most severe, with
+// def for an unset severity.
+func severityRank(s, def Severity) int {
+	if s == "" {
+		s = def
+	}
+	switch s {
+	case Info:
+		return 1
+	case Warn:
+		return 2
+	case Error:
+		return 3
+	}
+	return 0
+}
+
+// union returns the elements of a and b in order, without duplicates.
+func union(a, b []string) []string {
+	var u []string
+	seen := make(map[string]bool)
+	for _, s := range append(append([]string(nil), a...), b...) {
+		if !seen[s] {
+			seen[s] = true
+			u = append(u, s)
+		}
+	}
+	return u
+}
+
+// empty reports whether no rule or setting is set.
+func (r Rules) empty() bool {
+	return len(r.Licenses) == 0 && len(r.Categories) == 0 && r.Severity == "" && r.WarnUntil == ""
+}
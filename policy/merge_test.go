@@ -0,0 +1,110 @@
+// Copyright 2022 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package policy
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-cmp/cmp/cmpopts"
+)
+
+func TestMerge(t *testing.T) {
+	base := &Policy{
+		Allowed:           Rules{Categories: []string{"notice"}},
+		Forbidden:         Rules{Licenses: []string{"AGPL-3.0"}},
+		DeniedModules:     []string{"example.com/fork"},
+		Custom:            []CustomRule{{Name: "base", Expr: "true", Verdict: Allowed}},
+		LicenseCategories: map[string]string{"MPL-2.0": "reciprocal"},
+		Exceptions:        []Exception{{Module: "example.com/base", Justification: "base"}},
+	}
+	top := &Policy{
+		Allowed:           Rules{Categories: []string{"notice", "permissive"}},
+		Forbidden:         Rules{Licenses: []string{"SSPL-1.0", "AGPL-3.0"}, Severity: Error},
+		Review:            Rules{Categories: []string{"reciprocal"}},
+		ProjectLicense:    "Apache-2.0",
+		Custom:            []CustomRule{{Name: "top", Expr: "true", Verdict: Denied}},
+		LicenseCategories: map[string]string{"MPL-2.0": "notice"},
+		Exceptions:        []Exception{{Module: "example.com/top", Justification: "top"}},
+	}
+	want := &Policy{
+		Allowed:           Rules{Categories: []string{"notice", "permissive"}},
+		Forbidden:         Rules{Licenses: []string{"AGPL-3.0", "SSPL-1.0"}, Severity: Error},
+		Review:            Rules{Categories: []string{"reciprocal"}},
+		ProjectLicense:    "Apache-2.0",
+		DeniedModules:     []string{"example.com/fork"},
+		Custom:            []CustomRule{{Name: "top", Expr: "true", Verdict: Denied}, {Name: "base", Expr: "true", Verdict: Allowed}},
+		LicenseCategories: map[string]string{"MPL-2.0": "notice"},
+		Exceptions:        []Exception{{Module: "example.com/top", Justification: "top"}, {Module: "example.com/base", Justification: "base"}},
+	}
+	if diff := cmp.Diff(want, Merge(base, top), cmpopts.IgnoreUnexported(CustomRule{})); diff != "" {
+		t.Errorf("Merge() diff (-want +got):\n%s", diff)
+	}
+	if got := Merge(nil, top); got != top {
+		t.Errorf("Merge(nil, top) = %v, want top", got)
+	}
+	if got := Merge(base, nil); got != base {
+		t.Errorf("Merge(base, nil) = %v, want base", got)
+	}
+}
+
+func TestMergeRules(t *testing.T) {
+	for _, test := range []struct {
+		name      string
+		base, top Rules
+		want      Rules
+	}{
+		{
+			name: "top sets severity and warnUntil",
+			base: Rules{Licenses: []string{"AGPL-3.0"}, Severity: Warn, WarnUntil: "2030-01-01"},
+			top:  Rules{Licenses: []string{"SSPL-1.0"}, Severity: Error, WarnUntil: "2025-01-01"},
+			want: Rules{Licenses: []string{"AGPL-3.0", "SSPL-1.0"}, Severity: Error, WarnUntil: "2025-01-01"},
+		},
+		{
+			name: "top cannot lower severity or postpone enforcement",
+			base: Rules{Licenses: []string{"AGPL-3.0"}, Severity: Error, WarnUntil: "2025-01-01"},
+			top:  Rules{Severity: Info, WarnUntil: "2030-01-01"},
+			want: Rules{Licenses: []string{"AGPL-3.0"}, Severity: Error, WarnUntil: "2025-01-01"},
+		},
+		{
+			name: "top cannot lower the default severity or add a grace period",
+			base: Rules{Licenses: []string{"AGPL-3.0"}},
+			top:  Rules{Severity: Warn, WarnUntil: "2030-01-01"},
+			want: Rules{Licenses: []string{"AGPL-3.0"}},
+		},
+		{
+			name: "empty top",
+			base: Rules{Licenses: []string{"AGPL-3.0"}, Severity: Warn, WarnUntil: "2030-01-01"},
+			want: Rules{Licenses: []string{"AGPL-3.0"}, Severity: Warn, WarnUntil: "2030-01-01"},
+		},
+		{
+			name: "empty base",
+			top:  Rules{Licenses: []string{"AGPL-3.0"}, Severity: Warn, WarnUntil: "2030-01-01"},
+			want: Rules{Licenses: []string{"AGPL-3.0"}, Severity: Warn, WarnUntil: "2030-01-01"},
+		},
+		{
+			name: "unset severity of top is the default",
+			base: Rules{Licenses: []string{"AGPL-3.0"}, Severity: Info, WarnUntil: "2030-01-01"},
+			top:  Rules{Licenses: []string{"AGPL-3.0"}},
+			want: Rules{Licenses: []string{"AGPL-3.0"}},
+		},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			if diff := cmp.Diff(test.want, mergeRules(test.base, test.top, Error)); diff != "" {
+				t.Errorf("mergeRules() diff (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
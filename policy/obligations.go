@@ -0,0 +1,115 @@
+// Copyright 2022 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package policy
+
+// Obligation is something a license requires when distributing software
+// using it.
+type Obligation string
+
+const (
+	// IncludeLicense requires distributing a copy of the license, including
+	// its copyright notices.
+	IncludeLicense = Obligation("include-license")
+	// StateChanges requires stating significant changes made to the
+	// software.
+	StateChanges = Obligation("state-changes")
+	// ProvideSource requires making the source code available, see
+	// StaticLinkingScope for how much of it.
+	ProvideSource = Obligation("provide-source")
+	// PreserveNotice requires distributing the NOTICE file of the software,
+	// if any.
+	PreserveNotice = Obligation("preserve-notice")
+)
+
+// Description returns a checklist item describing the obligation.
+func (o Obligation) Description() string {
+	switch o {
+	case IncludeLicense:
+		return "Include a copy of the license and copyright notices"
+	case StateChanges:
+		return "State significant changes made to the software"
+	case ProvideSource:
+		return "Make the source code available"
+	case PreserveNotice:
+		return "Preserve the NOTICE file"
+	default:
+		return string(o)
+	}
+}
+
+// licenseObligations maps licenses, normalized by normalizeLicense, to their
+// obligations. Other licenses get the obligations of their category.
+var licenseObligations = map[string][]Obligation{
+	"APACHE-2.0":        {IncludeLicense, StateChanges, PreserveNotice},
+	"MPL-2.0":           {IncludeLicense, ProvideSource},
+	"EPL-2.0":           {IncludeLicense, ProvideSource},
+	"LGPL-2.1":          {IncludeLicense, StateChanges, ProvideSource},
+	"LGPL-2.1-OR-LATER": {IncludeLicense, StateChanges, ProvideSource},
+	"LGPL-3.0":          {IncludeLicense, StateChanges, ProvideSource},
+	"LGPL-3.0-OR-LATER": {IncludeLicense, StateChanges, ProvideSource},
+	"GPL-2.0":           {IncludeLicense, StateChanges, ProvideSource},
+	"GPL-2.0-OR-LATER":  {IncludeLicense, StateChanges, ProvideSource},
+	"GPL-3.0":           {IncludeLicense, StateChanges, ProvideSource},
+	"GPL-3.0-OR-LATER":  {IncludeLicense, StateChanges, ProvideSource},
+	"AGPL-3.0":          {IncludeLicense, StateChanges, ProvideSource},
+	"AGPL-3.0-OR-LATER": {IncludeLicense, StateChanges, ProvideSource},
+}
+
+// categoryObligations maps license categories to the obligations of their
+// licenses, for licenses not in licenseObligations.
+var categoryObligations = map[string][]Obligation{
+	"restricted": {IncludeLicense, StateChanges, ProvideSource},
+	"reciprocal": {IncludeLicense, ProvideSource},
+	"notice":     {IncludeLicense},
+	"forbidden":  {IncludeLicense, StateChanges, ProvideSource},
+	// Unknown licenses may require anything, so at least keep the license.
+	"unknown": {IncludeLicense},
+}
+
+// Obligations returns the obligations of a license, by SPDX ID or else by
+// category, see Categories. Permissive and unencumbered licenses have none.
+// The obligations of an expression like "MIT AND Apache-2.0" are those of all
+// its licenses.
+func Obligations(license, category string) []Obligation {
+	conjuncts := Conjuncts(license)
+	if len(conjuncts) == 1 {
+		return licenseObligationsOrCategory(license, category)
+	}
+	var obligations []Obligation
+	for _, c := range conjuncts {
+		for _, o := range licenseObligationsOrCategory(c, category) {
+			if !hasObligationIn(obligations, o) {
+				obligations = append(obligations, o)
+			}
+		}
+	}
+	return obligations
+}
+
+func licenseObligationsOrCategory(license, category string) []Obligation {
+	if obligations, ok := licenseObligations[normalizeLicense(license)]; ok {
+		return obligations
+	}
+	return categoryObligations[category]
+}
+
+func hasObligationIn(obligations []Obligation, o Obligation) bool {
+	for _, obligation := range obligations {
+		if obligation == o {
+			return true
+		}
+	}
+	return false
+}
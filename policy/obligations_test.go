@@ -0,0 +1,42 @@
+// Copyright 2022 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package policy
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestObligations(t *testing.T) {
+	for _, test := range []struct {
+		license, category string
+		want              []Obligation
+	}{
+		{license: "Apache-2.0", category: "notice", want: []Obligation{IncludeLicense, StateChanges, PreserveNotice}},
+		{license: "MIT", category: "notice", want: []Obligation{IncludeLicense}},
+		{license: "MPL-2.0", category: "reciprocal", want: []Obligation{IncludeLicense, ProvideSource}},
+		{license: "GPL-3.0-only", category: "restricted", want: []Obligation{IncludeLicense, StateChanges, ProvideSource}},
+		{license: "Unlicense", category: "unencumbered", want: nil},
+		{license: "Unknown", category: "unknown", want: []Obligation{IncludeLicense}},
+		{license: "MIT AND Apache-2.0", category: "notice", want: []Obligation{IncludeLicense, StateChanges, PreserveNotice}},
+	} {
+		t.Run(test.license, func(t *testing.T) {
+			if diff := cmp.Diff(test.want, Obligations(test.license, test.category)); diff != "" {
+				t.Errorf("Obligations(%q, %q): diff (-want +got)\n%s", test.license, test.category, diff)
+			}
+		})
+	}
+}
@@ -0,0 +1,489 @@
+// Copyright 2022 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package policy decides which licenses are acceptable for a project.
+package policy
+
+import (
+	"fmt"
+	"path"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Categories are the license categories a policy can refer to. They are the
+// license types of github.com/google/licenseclassifier.
+var Categories = []string{"restricted", "reciprocal", "notice", "permissive", "unencumbered", "forbidden", "unknown"}
+
+// Policy lists allowed, forbidden and needs-review licenses, by SPDX ID or
+// by category. Forbidden rules take precedence over review rules, which take
+// precedence over allowed rules. When any allowed rule is set, licenses not
+// matching one are violations too.
+type Policy struct {
+	Allowed   Rules `yaml:"allowed,omitempty"`
+	Forbidden Rules `yaml:"forbidden,omitempty"`
+	Review    Rules `yaml:"review,omitempty"`
+	// ProjectLicense is the SPDX ID of the project's own license, or
+	// "proprietary". When set, dependency licenses incompatible with it
+	// are violations, see Compatible.
+	ProjectLicense string `yaml:"projectLicense,omitempty"`
+	// DeniedModules are module paths or path prefixes, e.g. of known
+	// problematic forks, that must not be used whatever their license.
+	DeniedModules []string `yaml:"deniedModules,omitempty"`
+	// Custom rules are evaluated in order, and the first one matching a
+	// library decides its verdict instead of the license rules above.
+	Custom []CustomRule `yaml:"custom,omitempty"`
+	// MinConfidence is the confidence below which license classifications are
+	// treated as unknown licenses, regardless of --confidence_threshold.
+	MinConfidence float64 `yaml:"minConfidence,omitempty"`
+	// Hook is an external program deciding on each library after all rules
+	// above, if set.
+	Hook *Hook `yaml:"hook,omitempty"`
+	// LicenseCategories overrides the category of licenses, by SPDX ID.
+	// Categories are otherwise those of github.com/google/licenseclassifier.
+	LicenseCategories map[string]string `yaml:"licenseCategories,omitempty"`
+	// NoticeSeverity is the severity of NOTICE files of Apache-2.0 libraries
+	// that would be missing from the saved attribution. Defaults to Error.
+	NoticeSeverity Severity `yaml:"noticeSeverity,omitempty"`
+	// Exceptions waive violations of specific modules.
+	Exceptions []Exception `yaml:"exceptions,omitempty"`
+}
+
+// Rules match licenses by SPDX ID, e.g. "Apache-2.0", or by category, e.g.
+// "notice". Both are matched case-insensitively. SPDX IDs can also be patterns
+// of license families, e.g. "AGPL-*" or "*-only", in the syntax of path.Match.
+type Rules struct {
+	Licenses   []string `yaml:"licenses,omitempty"`
+	Categories []string `yaml:"categories,omitempty"`
+	// Severity of violations of the rules. For allowed rules, it applies to
+	// licenses not listed. Defaults to the severity of the verdict, see
+	// DefaultSeverity.
+	Severity Severity `yaml:"severity,omitempty"`
+	// WarnUntil is the date from which violations of the rules are enforced,
+	// formatted as YYYY-MM-DD, in UTC. Before, they are at most warnings, so
+	// that new rules can be rolled out without breaking builds.
+	WarnUntil string `yaml:"warnUntil,omitempty"`
+}
+
+// Library is a library to evaluate a policy on.
+type Library struct {
+	// Name is the name of the library, usually an import path.
+	Name string
+	// Module is the path of the library's module, if known.
+	Module string
+	// Version is the version of the library's module, if known.
+	Version string
+	// License is the SPDX ID of the library's license, or "Unknown".
+	License string
+	// Category is the category of the license, see Categories.
+	Category string
+	// Confidence is the confidence of the license classification, between 0
+	// and 1. It is only compared to MinConfidence of the policy, if set, and
+	// by custom rules.
+	Confidence float64
+	// Indirect reports whether the library's module is only required
+	// indirectly by the main module.
+	Indirect bool
+}
+
+// Verdict is the result of evaluating a license against a policy.
+type Verdict string
+
+const (
+	// Allowed licenses can be used.
+	Allowed = Verdict("allowed")
+	// NeedsReview licenses must be reviewed manually before use.
+	NeedsReview = Verdict("needs review")
+	// Denied licenses must not be used.
+	Denied = Verdict("denied")
+)
+
+// Severity is how serious a violation is.
+type Severity string
+
+const (
+	// Info violations are only logged.
+	Info = Severity("info")
+	// Warn violations are reported, but do not fail a check unless there are
+	// too many of them.
+	Warn = Severity("warn")
+	// Error violations fail a check.
+	Error = Severity("error")
+)
+
+// DefaultSeverity returns the severity of a verdict by a rule without
+// severity: Error for Denied, Warn for NeedsReview and "" for Allowed.
+func DefaultSeverity(v Verdict) Severity {
+	switch v {
+	case Denied:
+		return Error
+	case NeedsReview:
+		return Warn
+	default:
+		return ""
+	}
+}
+
+// validate returns an error if the severity is set to an unknown value.
+func (s Severity) validate() error {
+	switch s {
+	case "", Info, Warn, Error:
+		return nil
+	}
+	return fmt.Errorf("policy: unknown severity %q, must be one of %s, %s, %s", s, Info, Warn, Error)
+}
+
+// Result describes the verdict for a library's license and the rule it is
+// based on.
+type Result struct {
+	// Library is the name of the library.
+	Library string
+	// License is the SPDX ID of the license, or "Unknown".
+	License string
+	// Category is the category of the license.
+	Category string
+	Verdict  Verdict
+	// Rule is the rule that matched, e.g. "forbidden.licenses: GPL-3.0".
+	// It is empty for licenses allowed without any allowed rule.
+	Rule string
+	// Severity is the severity of a violation. It is empty when the
+	// library is allowed.
+	Severity Severity
+	// Code identifies the class of a violation. It is empty when the
+	// library is allowed by rules.
+	Code Code
+	// Exception is the exception waiving a violation, if any. When it is
+	// expired, the violation stands.
+	Exception *Exception
+	// WarnUntil is the date from which the violated rule is enforced, if it
+	// declares one, see Rules.WarnUntil.
+	WarnUntil string
+}
+
+func (r *Result) String() string {
+	s := fmt.Sprintf("%s: license %s (%s) is %s", r.Library, r.License, r.Category, r.Verdict)
+	if r.Rule != "" {
+		s += " by rule " + r.Rule
+	}
+	if r.Exception != nil {
+		s += " (" + r.Exception.String() + ")"
+	}
+	return s
+}
+
+// Validate returns an error if the policy refers to unknown categories or has
+// invalid denied modules, custom rules or exceptions.
+func (p *Policy) Validate() error {
+	for i := range p.Custom {
+		if err := p.Custom[i].validate(); err != nil {
+			return err
+		}
+	}
+	if p.MinConfidence < 0 || p.MinConfidence > 1 {
+		return fmt.Errorf("policy: minConfidence %v must be between 0 and 1", p.MinConfidence)
+	}
+	if p.Hook != nil {
+		if err := p.Hook.validate(); err != nil {
+			return err
+		}
+	}
+	for _, module := range p.DeniedModules {
+		if strings.Trim(module, "/") == "" {
+			return fmt.Errorf("policy: empty module path in deniedModules")
+		}
+	}
+	for i := range p.Exceptions {
+		if err := p.Exceptions[i].validate(); err != nil {
+			return err
+		}
+	}
+	if err := p.NoticeSeverity.validate(); err != nil {
+		return fmt.Errorf("%w in noticeSeverity", err)
+	}
+	for _, r := range []struct {
+		name  string
+		rules Rules
+	}{{"allowed", p.Allowed}, {"forbidden", p.Forbidden}, {"review", p.Review}} {
+		name, rules := r.name, r.rules
+		if err := rules.Severity.validate(); err != nil {
+			return fmt.Errorf("%w in %s.severity", err, name)
+		}
+		if err := validateDate(rules.WarnUntil); err != nil {
+			return fmt.Errorf("%w in %s.warnUntil", err, name)
+		}
+		for _, category := range rules.Categories {
+			if !containsFold(Categories, category) {
+				return fmt.Errorf("policy: unknown category %q in %s.categories, must be one of %s", category, name, strings.Join(Categories, ", "))
+			}
+		}
+		for _, pattern := range rules.Licenses {
+			if _, err := path.Match(pattern, ""); err != nil {
+				return fmt.Errorf("policy: invalid license pattern %q in %s.licenses: %w", pattern, name, err)
+			}
+		}
+	}
+	for _, license := range sortedKeys(p.LicenseCategories) {
+		if category := p.LicenseCategories[license]; !containsFold(Categories, category) {
+			return fmt.Errorf("policy: unknown category %q for %s in licenseCategories, must be one of %s", category, license, strings.Join(Categories, ", "))
+		}
+	}
+	return nil
+}
+
+// CheckLibrary evaluates a library against all rules of the policy, including
+// denied modules, custom rules, the hook, license compatibility and
+// exceptions. Licenses classified with less than MinConfidence are evaluated
+// as unknown. now decides whether exceptions have expired.
+func (p *Policy) CheckLibrary(lib Library, now time.Time) *Result {
+	lib.Category = p.category(lib.License, lib.Category)
+	unconfident := ""
+	if p.MinConfidence > 0 && lib.Confidence < p.MinConfidence && lib.Category != "unknown" {
+		unconfident = fmt.Sprintf("; minConfidence: %s identified with confidence %.2f", lib.License, lib.Confidence)
+		lib.License, lib.Category = "Unknown", "unknown"
+	}
+	result := p.Check(lib.Name, lib.License, lib.Category)
+	if conflict := p.CheckCompatibility(lib.Name, lib.License, lib.Category); conflict != nil {
+		result = conflict
+	}
+	if custom := p.CheckCustom(lib); custom != nil {
+		result = custom
+	}
+	if p.Hook != nil {
+		hooked, err := p.Hook.run(lib, result)
+		if err != nil {
+			hooked = &Result{
+				Library:  lib.Name,
+				License:  lib.License,
+				Category: lib.Category,
+				Verdict:  Denied,
+				Rule:     fmt.Sprintf("hook: %v", err),
+				Code:     CodeHook,
+			}
+		}
+		if hooked != nil {
+			result = hooked
+		}
+	}
+	if denied := p.CheckModule(lib); denied != nil {
+		result = denied
+	}
+	if result.Verdict == Allowed {
+		return result
+	}
+	result.Rule += unconfident
+	if result.Severity == "" {
+		result.Severity = DefaultSeverity(result.Verdict)
+	}
+	if result.WarnUntil != "" && before(now, result.WarnUntil) {
+		if result.Severity == Error {
+			result.Severity = Warn
+		}
+		result.Rule += "; enforced from " + result.WarnUntil
+	}
+	for i := range p.Exceptions {
+		e := &p.Exceptions[i]
+		if !e.matches(lib) {
+			continue
+		}
+		result.Exception = e
+		if e.expired(now) {
+			result.Rule += "; exception expired on " + e.Expires
+			return result
+		}
+		result.Verdict = Allowed
+		result.Severity = ""
+		return result
+	}
+	return result
+}
+
+// CheckModule returns a denied result if the library belongs to a module
+// listed in DeniedModules, regardless of its license. It returns nil
+// otherwise.
+func (p *Policy) CheckModule(lib Library) *Result {
+	for _, module := range p.DeniedModules {
+		if matchPathPrefix(module, lib.Module) || matchPathPrefix(module, lib.Name) {
+			return &Result{
+				Library:  lib.Name,
+				License:  lib.License,
+				Category: lib.Category,
+				Verdict:  Denied,
+				Rule:     "deniedModules: " + module,
+				Code:     CodeDeniedModule,
+			}
+		}
+	}
+	return nil
+}
+
+// CheckCustom returns the result of the first custom rule matching the
+// library, or nil if none matches. A rule that fails to evaluate denies the
+// library.
+func (p *Policy) CheckCustom(lib Library) *Result {
+	for i := range p.Custom {
+		rule := &p.Custom[i]
+		r := &Result{Library: lib.Name, License: lib.License, Category: lib.Category}
+		ok, err := rule.match(lib)
+		if err != nil {
+			r.Verdict = Denied
+			r.Code = CodeCustomRule
+			r.Rule = fmt.Sprintf("custom.%s: %v", rule.Name, err)
+			return r
+		}
+		if ok {
+			r.Verdict = rule.Verdict
+			r.Rule = "custom." + rule.Name
+			r.Severity = rule.Severity
+			if r.Verdict != Allowed {
+				r.Code = CodeCustomRule
+				r.WarnUntil = rule.WarnUntil
+			}
+			return r
+		}
+	}
+	return nil
+}
+
+// verdictOrder orders verdicts from the most to the least severe.
+var verdictOrder = []Verdict{Denied, NeedsReview, Allowed}
+
+// Check evaluates the license of a library. license is an SPDX ID, or an
+// expression of licenses that all apply, e.g. "MIT AND BSD-3-Clause", and
+// category a license category, see Categories. Each license of an expression
+// is evaluated with category, which should be the most restrictive category
+// of its licenses, and the most severe result applies.
+func (p *Policy) Check(library, license, category string) *Result {
+	conjuncts := Conjuncts(license)
+	if len(conjuncts) == 1 {
+		return p.checkLicense(library, license, category)
+	}
+	var worst *Result
+	for _, c := range conjuncts {
+		r := p.checkLicense(library, c, category)
+		if worst == nil || severer(r.Verdict, worst.Verdict) {
+			worst = r
+		}
+	}
+	worst.License = license
+	return worst
+}
+
+// severer reports whether verdict a is more severe than b.
+func severer(a, b Verdict) bool {
+	for _, v := range verdictOrder {
+		if v == a || v == b {
+			return v == a && a != b
+		}
+	}
+	return false
+}
+
+// checkLicense evaluates a single license of a library.
+func (p *Policy) checkLicense(library, license, category string) *Result {
+	r := &Result{Library: library, License: license, Category: category}
+	for _, rule := range []struct {
+		name    string
+		rules   Rules
+		verdict Verdict
+	}{
+		{name: "forbidden", rules: p.Forbidden, verdict: Denied},
+		{name: "review", rules: p.Review, verdict: NeedsReview},
+		{name: "allowed", rules: p.Allowed, verdict: Allowed},
+	} {
+		if m := rule.rules.match(license, category); m != "" {
+			r.Verdict = rule.verdict
+			r.Rule = rule.name + "." + m
+			if r.Verdict != Allowed {
+				r.Severity = rule.rules.Severity
+				r.Code = licenseCode(r.Verdict, category)
+				r.WarnUntil = rule.rules.WarnUntil
+			}
+			return r
+		}
+	}
+	if len(p.Allowed.Licenses) > 0 || len(p.Allowed.Categories) > 0 {
+		r.Verdict = Denied
+		r.Rule = "allowed: not listed"
+		r.Severity = p.Allowed.Severity
+		r.WarnUntil = p.Allowed.WarnUntil
+		r.Code = licenseCode(r.Verdict, category)
+		return r
+	}
+	r.Verdict = Allowed
+	return r
+}
+
+// category returns the category of a license, as overridden by
+// LicenseCategories, or else its default category.
+func (p *Policy) category(license, category string) string {
+	if c, ok := p.LicenseCategories[license]; ok {
+		return strings.ToLower(c)
+	}
+	// Keys differing only in case are tried in a stable order.
+	for _, l := range sortedKeys(p.LicenseCategories) {
+		if strings.EqualFold(l, license) {
+			return strings.ToLower(p.LicenseCategories[l])
+		}
+	}
+	return category
+}
+
+// sortedKeys returns the keys of m in ascending order.
+func sortedKeys(m map[string]string) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// licenseCode returns the code of a violation of license rules.
+func licenseCode(v Verdict, category string) Code {
+	switch {
+	case category == "unknown":
+		return CodeUnknownLicense
+	case v == NeedsReview:
+		return CodeNeedsReview
+	default:
+		return CodeForbiddenLicense
+	}
+}
+
+// match returns the matching rule, e.g. "licenses: MIT", or "" if no rule
+// matches.
+func (r Rules) match(license, category string) string {
+	if containsFold(r.Licenses, license) {
+		return "licenses: " + license
+	}
+	for _, pattern := range r.Licenses {
+		if ok, _ := path.Match(strings.ToLower(pattern), strings.ToLower(license)); ok {
+			return "licenses: " + pattern
+		}
+	}
+	if containsFold(r.Categories, category) {
+		return "categories: " + category
+	}
+	return ""
+}
+
+func containsFold(list []string, s string) bool {
+	for _, item := range list {
+		if strings.EqualFold(item, s) {
+			return true
+		}
+	}
+	return false
+}
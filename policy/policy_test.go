@@ -0,0 +1,422 @@
+// Copyright 2022 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package policy
+
+import (
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestCheck(t *testing.T) {
+	p := &Policy{
+		Allowed: Rules{
+			Licenses:   []string{"MIT"},
+			Categories: []string{"notice"},
+		},
+		Forbidden: Rules{
+			Licenses:   []string{"WTFPL"},
+			Categories: []string{"restricted"},
+		},
+		Review: Rules{
+			Licenses: []string{"MPL-2.0"},
+		},
+	}
+	for _, test := range []struct {
+		desc     string
+		license  string
+		category string
+		want     *Result
+	}{
+		{
+			desc:     "Allowed license",
+			license:  "mit",
+			category: "notice",
+			want:     &Result{Library: "lib", License: "mit", Category: "notice", Verdict: Allowed, Rule: "allowed.licenses: mit"},
+		},
+		{
+			desc:     "Allowed category",
+			license:  "Apache-2.0",
+			category: "notice",
+			want:     &Result{Library: "lib", License: "Apache-2.0", Category: "notice", Verdict: Allowed, Rule: "allowed.categories: notice"},
+		},
+		{
+			desc:     "Forbidden category",
+			license:  "GPL-3.0",
+			category: "restricted",
+			want:     &Result{Library: "lib", License: "GPL-3.0", Category: "restricted", Verdict: Denied, Rule: "forbidden.categories: restricted", Code: CodeForbiddenLicense},
+		},
+		{
+			desc:     "Forbidden takes precedence",
+			license:  "WTFPL",
+			category: "notice",
+			want:     &Result{Library: "lib", License: "WTFPL", Category: "notice", Verdict: Denied, Rule: "forbidden.licenses: WTFPL", Code: CodeForbiddenLicense},
+		},
+		{
+			desc:     "Needs review",
+			license:  "MPL-2.0",
+			category: "reciprocal",
+			want:     &Result{Library: "lib", License: "MPL-2.0", Category: "reciprocal", Verdict: NeedsReview, Rule: "review.licenses: MPL-2.0", Code: CodeNeedsReview},
+		},
+		{
+			desc:     "Not allowed",
+			license:  "Unknown",
+			category: "unknown",
+			want:     &Result{Library: "lib", License: "Unknown", Category: "unknown", Verdict: Denied, Rule: "allowed: not listed", Code: CodeUnknownLicense},
+		},
+	} {
+		t.Run(test.desc, func(t *testing.T) {
+			got := p.Check("lib", test.license, test.category)
+			if diff := cmp.Diff(test.want, got); diff != "" {
+				t.Errorf("Check(%q, %q): diff (-want +got)\n%s", test.license, test.category, diff)
+			}
+		})
+	}
+}
+
+func TestCategoryCase(t *testing.T) {
+	p := &Policy{LicenseCategories: map[string]string{"mit": "restricted", "MIT": "Notice", "Mit": "reciprocal"}}
+	for i := 0; i < 10; i++ {
+		if got := p.category("MIT", "notice"); got != "notice" {
+			t.Fatalf("category(MIT) = %q, want notice", got)
+		}
+		// Keys differing in case are tried in ascending order.
+		if got := p.category("mIT", "notice"); got != "notice" {
+			t.Fatalf("category(mIT) = %q, want notice", got)
+		}
+	}
+}
+
+func TestCheckEmptyPolicy(t *testing.T) {
+	got := (&Policy{}).Check("lib", "GPL-3.0", "restricted")
+	if got.Verdict != Allowed {
+		t.Errorf("Check() verdict = %q, want %q", got.Verdict, Allowed)
+	}
+}
+
+func TestValidate(t *testing.T) {
+	if err := (&Policy{Forbidden: Rules{Categories: []string{"Restricted", "unknown"}}}).Validate(); err != nil {
+		t.Errorf("Validate() = %q, want nil", err)
+	}
+	if err := (&Policy{Review: Rules{Categories: []string{"copyleft"}}}).Validate(); err == nil {
+		t.Errorf("Validate() = nil, want error for unknown category")
+	}
+	if err := (&Policy{Forbidden: Rules{Licenses: []string{"GPL-3.0"}, Severity: "fatal"}}).Validate(); err == nil {
+		t.Errorf("Validate() = nil, want error for unknown severity")
+	}
+}
+
+func TestCheckLibraryExceptions(t *testing.T) {
+	p := &Policy{
+		Forbidden: Rules{Categories: []string{"restricted"}},
+		Exceptions: []Exception{
+			{Module: "github.com/foo/gpl", Justification: "being replaced", Approver: "legal@example.com", Expires: "2022-06-01"},
+			{Module: "github.com/foo/relicensed", License: "GPL-2.0", Justification: "only used internally"},
+			{Module: "github.com/foo/pinned", Versions: "v1.x", Justification: "reviewed up to v1"},
+		},
+	}
+	now := time.Date(2022, 5, 31, 12, 0, 0, 0, time.UTC)
+	for _, test := range []struct {
+		desc          string
+		lib           Library
+		now           time.Time
+		wantVerdict   Verdict
+		wantException bool
+	}{
+		{
+			desc:          "Waived by exception",
+			lib:           Library{Name: "github.com/foo/gpl/pkg", Module: "github.com/foo/gpl", License: "GPL-3.0", Category: "restricted"},
+			now:           now,
+			wantVerdict:   Allowed,
+			wantException: true,
+		},
+		{
+			desc:          "Expired exception",
+			lib:           Library{Name: "github.com/foo/gpl/pkg", Module: "github.com/foo/gpl", License: "GPL-3.0", Category: "restricted"},
+			now:           now.AddDate(0, 0, 1),
+			wantVerdict:   Denied,
+			wantException: true,
+		},
+		{
+			desc:        "Exception for another license",
+			lib:         Library{Name: "github.com/foo/relicensed", License: "GPL-3.0", Category: "restricted"},
+			now:         now,
+			wantVerdict: Denied,
+		},
+		{
+			desc:          "Exception for version range",
+			lib:           Library{Name: "github.com/foo/pinned", Module: "github.com/foo/pinned", Version: "v1.4.0", License: "GPL-3.0", Category: "restricted"},
+			now:           now,
+			wantVerdict:   Allowed,
+			wantException: true,
+		},
+		{
+			desc:        "Exception for another version range",
+			lib:         Library{Name: "github.com/foo/pinned/v2", Module: "github.com/foo/pinned/v2", Version: "v2.0.0", License: "GPL-3.0", Category: "restricted"},
+			now:         now,
+			wantVerdict: Denied,
+		},
+		{
+			desc:        "No exception",
+			lib:         Library{Name: "github.com/foo/other", License: "GPL-3.0", Category: "restricted"},
+			now:         now,
+			wantVerdict: Denied,
+		},
+	} {
+		t.Run(test.desc, func(t *testing.T) {
+			got := p.CheckLibrary(test.lib, test.now)
+			if got.Verdict != test.wantVerdict {
+				t.Errorf("CheckLibrary() verdict = %q, want %q", got.Verdict, test.wantVerdict)
+			}
+			if gotException := got.Exception != nil; gotException != test.wantException {
+				t.Errorf("CheckLibrary() exception = %v, want exception? %t", got.Exception, test.wantException)
+			}
+		})
+	}
+}
+
+func TestValidateExceptions(t *testing.T) {
+	for _, e := range []Exception{
+		{Justification: "no module"},
+		{Module: "github.com/foo/bar"},
+		{Module: "github.com/foo/bar", Justification: "bad date", Expires: "June 1st"},
+		{Module: "github.com/foo/bar", Justification: "bad versions", Versions: "1.x"},
+	} {
+		if err := (&Policy{Exceptions: []Exception{e}}).Validate(); err == nil {
+			t.Errorf("Validate() with exception %+v = nil, want error", e)
+		}
+	}
+}
+
+func TestCheckModule(t *testing.T) {
+	p := &Policy{DeniedModules: []string{"github.com/evil/fork", "example.com/bad/"}}
+	for _, test := range []struct {
+		desc       string
+		lib        Library
+		wantDenied bool
+	}{
+		{
+			desc:       "Denied module",
+			lib:        Library{Name: "github.com/evil/fork/pkg", Module: "github.com/evil/fork", License: "MIT"},
+			wantDenied: true,
+		},
+		{
+			desc:       "Denied path prefix",
+			lib:        Library{Name: "example.com/bad/lib", License: "MIT"},
+			wantDenied: true,
+		},
+		{
+			desc: "Prefix of another path element",
+			lib:  Library{Name: "github.com/evil/forked", Module: "github.com/evil/forked", License: "MIT"},
+		},
+	} {
+		t.Run(test.desc, func(t *testing.T) {
+			got := p.CheckModule(test.lib)
+			if gotDenied := got != nil; gotDenied != test.wantDenied {
+				t.Errorf("CheckModule(%+v) = %v, want denied? %t", test.lib, got, test.wantDenied)
+			}
+		})
+	}
+}
+
+func TestCheckLibraryDeniedModule(t *testing.T) {
+	p := &Policy{
+		Allowed:       Rules{Licenses: []string{"MIT"}},
+		DeniedModules: []string{"github.com/evil/fork"},
+	}
+	got := p.CheckLibrary(Library{Name: "github.com/evil/fork", Module: "github.com/evil/fork", License: "MIT", Category: "notice"}, time.Now())
+	if got.Verdict != Denied || got.Rule != "deniedModules: github.com/evil/fork" || got.Code != CodeDeniedModule {
+		t.Errorf("CheckLibrary() = %v, want denied by deniedModules with code %s", got, CodeDeniedModule)
+	}
+}
+
+func TestCheckLibrarySeverity(t *testing.T) {
+	p := &Policy{
+		Allowed:   Rules{Categories: []string{"notice"}, Severity: Warn},
+		Forbidden: Rules{Licenses: []string{"AGPL-3.0"}},
+		Review:    Rules{Licenses: []string{"MPL-2.0"}, Severity: Info},
+		Custom:    []CustomRule{{Name: "wtfpl", Expr: `license == "WTFPL"`, Verdict: Denied, Severity: Info}},
+	}
+	for _, test := range []struct {
+		license, category string
+		want              Severity
+	}{
+		{license: "MIT", category: "notice", want: ""},
+		{license: "AGPL-3.0", category: "restricted", want: Error},
+		{license: "MPL-2.0", category: "reciprocal", want: Info},
+		{license: "GPL-3.0", category: "restricted", want: Warn},
+		{license: "WTFPL", category: "unencumbered", want: Info},
+	} {
+		t.Run(test.license, func(t *testing.T) {
+			got := p.CheckLibrary(Library{Name: "github.com/foo/bar", License: test.license, Category: test.category}, time.Now())
+			if got.Severity != test.want {
+				t.Errorf("CheckLibrary() severity = %q, want %q", got.Severity, test.want)
+			}
+		})
+	}
+}
+
+func TestCheckLicensePatterns(t *testing.T) {
+	p := &Policy{
+		Forbidden: Rules{Licenses: []string{"AGPL-*", "*-only"}},
+		Review:    Rules{Categories: []string{"restricted"}},
+		LicenseCategories: map[string]string{
+			"MPL-2.0": "Restricted",
+		},
+	}
+	for _, test := range []struct {
+		license, category string
+		wantVerdict       Verdict
+		wantRule          string
+	}{
+		{license: "AGPL-3.0", category: "restricted", wantVerdict: Denied, wantRule: "forbidden.licenses: AGPL-*"},
+		{license: "agpl-3.0-or-later", category: "restricted", wantVerdict: Denied, wantRule: "forbidden.licenses: AGPL-*"},
+		{license: "GPL-2.0-only", category: "restricted", wantVerdict: Denied, wantRule: "forbidden.licenses: *-only"},
+		{license: "GPL-2.0", category: "restricted", wantVerdict: NeedsReview, wantRule: "review.categories: restricted"},
+		{license: "MPL-2.0", category: "reciprocal", wantVerdict: NeedsReview, wantRule: "review.categories: restricted"},
+		{license: "MIT", category: "notice", wantVerdict: Allowed},
+	} {
+		t.Run(test.license, func(t *testing.T) {
+			got := p.CheckLibrary(Library{Name: "lib", License: test.license, Category: test.category}, time.Now())
+			if got.Verdict != test.wantVerdict || got.Rule != test.wantRule {
+				t.Errorf("CheckLibrary() = (%q, %q), want (%q, %q)", got.Verdict, got.Rule, test.wantVerdict, test.wantRule)
+			}
+		})
+	}
+	if err := (&Policy{Forbidden: Rules{Licenses: []string{"GPL-["}}}).Validate(); err == nil {
+		t.Errorf("Validate() = nil, want error for invalid pattern")
+	}
+	if err := (&Policy{LicenseCategories: map[string]string{"MPL-2.0": "copyleft"}}).Validate(); err == nil {
+		t.Errorf("Validate() = nil, want error for unknown category in licenseCategories")
+	}
+}
+
+func TestCheckLibraryHook(t *testing.T) {
+	p := &Policy{Hook: &Hook{Command: []string{"testdata/hook.sh"}}}
+	got := p.CheckLibrary(Library{Name: "lib", License: "GPL-3.0", Category: "restricted"}, time.Now())
+	if got.Verdict != Denied || got.Rule != "hook: rejected by compliance service" || got.Code != CodeHook {
+		t.Errorf("CheckLibrary(GPL-3.0) = %v, want denied by hook", got)
+	}
+	got = p.CheckLibrary(Library{Name: "lib", License: "MIT", Category: "notice"}, time.Now())
+	if got.Verdict != Allowed {
+		t.Errorf("CheckLibrary(MIT) = %v, want allowed", got)
+	}
+	p = &Policy{Hook: &Hook{Command: []string{"testdata/non-existent"}}}
+	got = p.CheckLibrary(Library{Name: "lib", License: "MIT", Category: "notice"}, time.Now())
+	if got.Verdict != Denied || got.Code != CodeHook {
+		t.Errorf("CheckLibrary() with failing hook = %v, want denied", got)
+	}
+}
+
+func TestCheckLibraryMinConfidence(t *testing.T) {
+	p := &Policy{
+		Allowed:       Rules{Categories: []string{"notice"}},
+		MinConfidence: 0.95,
+	}
+	tests := []struct {
+		confidence float64
+		want       Verdict
+		wantRule   string
+	}{
+		{confidence: 1, want: Allowed, wantRule: "allowed.categories: notice"},
+		{confidence: 0.95, want: Allowed, wantRule: "allowed.categories: notice"},
+		{confidence: 0.9, want: Denied, wantRule: "allowed: not listed; minConfidence: MIT identified with confidence 0.90"},
+	}
+	for _, test := range tests {
+		got := p.CheckLibrary(Library{Name: "lib", License: "MIT", Category: "notice", Confidence: test.confidence}, time.Now())
+		if got.Verdict != test.want || got.Rule != test.wantRule {
+			t.Errorf("CheckLibrary(confidence %v) = %v (%q), want %v (%q)", test.confidence, got.Verdict, got.Rule, test.want, test.wantRule)
+		}
+		if test.want == Denied && got.Code != CodeUnknownLicense {
+			t.Errorf("CheckLibrary(confidence %v).Code = %v, want %v", test.confidence, got.Code, CodeUnknownLicense)
+		}
+	}
+}
+
+func TestCheckLibraryWarnUntil(t *testing.T) {
+	p := &Policy{
+		Forbidden: Rules{Licenses: []string{"GPL-3.0"}, WarnUntil: "2022-06-01"},
+		Custom: []CustomRule{
+			{Name: "no-wtfpl", Expr: `license == "WTFPL"`, Verdict: Denied, WarnUntil: "2022-06-01"},
+		},
+	}
+	for _, test := range []struct {
+		desc         string
+		license      string
+		now          time.Time
+		wantSeverity Severity
+		wantRule     string
+	}{
+		{
+			desc:         "Grace period",
+			license:      "GPL-3.0",
+			now:          time.Date(2022, 5, 31, 23, 0, 0, 0, time.UTC),
+			wantSeverity: Warn,
+			wantRule:     "forbidden.licenses: GPL-3.0; enforced from 2022-06-01",
+		},
+		{
+			desc:         "Enforced",
+			license:      "GPL-3.0",
+			now:          time.Date(2022, 6, 1, 0, 0, 0, 0, time.UTC),
+			wantSeverity: Error,
+			wantRule:     "forbidden.licenses: GPL-3.0",
+		},
+		{
+			desc:         "Custom rule grace period",
+			license:      "WTFPL",
+			now:          time.Date(2022, 5, 1, 0, 0, 0, 0, time.UTC),
+			wantSeverity: Warn,
+			wantRule:     "custom.no-wtfpl; enforced from 2022-06-01",
+		},
+	} {
+		t.Run(test.desc, func(t *testing.T) {
+			got := p.CheckLibrary(Library{Name: "lib", License: test.license, Category: "restricted"}, test.now)
+			if got.Verdict != Denied || got.Severity != test.wantSeverity || got.Rule != test.wantRule {
+				t.Errorf("CheckLibrary() = %v [%s], want denied [%s] by rule %q", got, got.Severity, test.wantSeverity, test.wantRule)
+			}
+		})
+	}
+	if err := (&Policy{Review: Rules{WarnUntil: "June"}}).Validate(); err == nil {
+		t.Error("Validate() with invalid warnUntil = nil, want error")
+	}
+}
+
+func TestCheckConjuncts(t *testing.T) {
+	p := &Policy{
+		Allowed:   Rules{Categories: []string{"notice"}},
+		Forbidden: Rules{Licenses: []string{"GPL-3.0"}},
+		Review:    Rules{Licenses: []string{"MPL-2.0"}},
+	}
+	for _, test := range []struct {
+		license  string
+		category string
+		want     Verdict
+		wantRule string
+	}{
+		{license: "MIT AND BSD-3-Clause", category: "notice", want: Allowed, wantRule: "allowed.categories: notice"},
+		{license: "MIT AND MPL-2.0", category: "notice", want: NeedsReview, wantRule: "review.licenses: MPL-2.0"},
+		{license: "MPL-2.0 AND GPL-3.0", category: "restricted", want: Denied, wantRule: "forbidden.licenses: GPL-3.0"},
+	} {
+		got := p.Check("lib", test.license, test.category)
+		if got.Verdict != test.want || got.Rule != test.wantRule || got.License != test.license {
+			t.Errorf("Check(%q) = %v, want %s by rule %q", test.license, got, test.want, test.wantRule)
+		}
+	}
+	p = &Policy{ProjectLicense: "GPL-2.0"}
+	if got := p.CheckCompatibility("lib", "MIT AND Apache-2.0", "notice"); got == nil {
+		t.Error("CheckCompatibility(GPL-2.0, MIT AND Apache-2.0) = nil, want incompatible")
+	}
+}
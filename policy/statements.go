@@ -0,0 +1,108 @@
+// Copyright 2022 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package policy
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Component is a library distributed as part of a product, see Statements.
+type Component struct {
+	// Name is the name of the library, usually an import path.
+	Name string
+	// License is the SPDX ID of the library's license.
+	License string
+	// Category is the category of the license, see Categories.
+	Category string
+}
+
+// Statement is a block of legal text required for distributing components,
+// ready to paste into product documentation.
+type Statement struct {
+	// Title is a heading for the statement.
+	Title string
+	// Text is the statement, ending with a list of the components it is
+	// about.
+	Text string
+}
+
+// statementIntros introduce the statement of each kind of obligation, in the
+// order of statements.
+var statementIntros = []struct {
+	title string
+	intro string
+	// about selects the components the statement is about.
+	about func(Component) bool
+}{
+	{
+		title: "Third-party software",
+		intro: "This product includes the following third-party software. Copies of their licenses and copyright notices are distributed with the product.",
+		about: func(c Component) bool { return hasObligation(c, IncludeLicense) },
+	},
+	{
+		title: "NOTICE files",
+		intro: "The following software is licensed under licenses requiring its NOTICE files to be preserved. They are distributed with the product, next to the licenses.",
+		about: func(c Component) bool { return hasObligation(c, PreserveNotice) },
+	},
+	{
+		title: "Source code availability",
+		intro: "The following software is licensed under file-level copyleft licenses. The source code of its files, including any modifications, is available on request, or from the upstream project if unmodified.",
+		about: func(c Component) bool { return StaticLinkingScope(c.License) == FileCopyleft },
+	},
+	{
+		title: "Relinking",
+		intro: "The following libraries are licensed under the GNU Lesser General Public License and are statically linked into the product. On request, we provide the object files or source code of the product needed to relink it with modified versions of these libraries.",
+		about: func(c Component) bool { return StaticLinkingScope(c.License) == LibraryCopyleft },
+	},
+	{
+		title: "Complete corresponding source",
+		intro: "The product includes the following software licensed under the GNU General Public License. The product as a whole is distributed under compatible terms, and its complete corresponding source code is available on request.",
+		about: func(c Component) bool { return StaticLinkingScope(c.License) == ProgramCopyleft },
+	},
+}
+
+func hasObligation(c Component, o Obligation) bool {
+	return hasObligationIn(Obligations(c.License, c.Category), o)
+}
+
+// Statements assembles the legal text blocks required for distributing
+// components statically linked into a Go binary: an attribution block, a
+// NOTICE file statement, and statements for each copyleft scope, see
+// CopyleftScope. Only statements about at least one component are returned.
+func Statements(components []Component) []Statement {
+	sorted := append([]Component(nil), components...)
+	sort.Slice(sorted, func(i, j int) bool {
+		if sorted[i].License != sorted[j].License {
+			return sorted[i].License < sorted[j].License
+		}
+		return sorted[i].Name < sorted[j].Name
+	})
+	var statements []Statement
+	for _, s := range statementIntros {
+		var b strings.Builder
+		for _, c := range sorted {
+			if s.about(c) {
+				fmt.Fprintf(&b, "\n  - %s (%s)", c.Name, c.License)
+			}
+		}
+		if b.Len() == 0 {
+			continue
+		}
+		statements = append(statements, Statement{Title: s.title, Text: s.intro + "\n" + b.String()})
+	}
+	return statements
+}
@@ -0,0 +1,44 @@
+// Copyright 2022 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package policy
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestStatements(t *testing.T) {
+	components := []Component{
+		{Name: "github.com/foo/mpl", License: "MPL-2.0", Category: "reciprocal"},
+		{Name: "github.com/foo/mit", License: "MIT", Category: "notice"},
+		{Name: "github.com/foo/lgpl", License: "LGPL-3.0", Category: "restricted"},
+		{Name: "github.com/foo/unlicense", License: "Unlicense", Category: "unencumbered"},
+	}
+	var titles []string
+	for _, s := range Statements(components) {
+		titles = append(titles, s.Title)
+	}
+	want := []string{"Third-party software", "Source code availability", "Relinking"}
+	if got := strings.Join(titles, ", "); got != strings.Join(want, ", ") {
+		t.Fatalf("Statements() titles = %s, want %s", got, strings.Join(want, ", "))
+	}
+	attribution := Statements(components)[0].Text
+	if !strings.HasSuffix(attribution, "\n  - github.com/foo/lgpl (LGPL-3.0)\n  - github.com/foo/mit (MIT)\n  - github.com/foo/mpl (MPL-2.0)") {
+		t.Errorf("Statements()[0].Text = %q, want sorted components with obligation to include the license", attribution)
+	}
+	if got := Statements(nil); len(got) != 0 {
+		t.Errorf("Statements(nil) = %v, want none", got)
+	}
+}
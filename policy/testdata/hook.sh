@@ -0,0 +1,7 @@
+#!/bin/sh
+# A policy hook denying GPL-3.0 and abstaining otherwise.
+if grep -q '"license":"GPL-3.0"'; then
+  echo '{"verdict": "deny", "reason": "rejected by compliance service"}'
+else
+  echo '{}'
+fi
@@ -0,0 +1,98 @@
+// Copyright 2022 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package policy
+
+import (
+	"fmt"
+	"strings"
+
+	"golang.org/x/mod/semver"
+)
+
+// versionOperators are the comparison operators of version constraints,
+// longest first.
+var versionOperators = []string{"<=", ">=", "<", ">", "="}
+
+// validateVersions returns an error if versions is not a valid version range.
+func validateVersions(versions string) error {
+	if strings.TrimSpace(versions) == "" {
+		return fmt.Errorf("empty version range")
+	}
+	for _, c := range strings.Fields(versions) {
+		op, v := splitConstraint(c)
+		if op == "" && (strings.HasSuffix(v, ".x") || strings.HasSuffix(v, ".*")) {
+			v = v[:len(v)-2]
+		}
+		if !semver.IsValid(v) {
+			return fmt.Errorf("invalid version %q in range %q", v, versions)
+		}
+	}
+	return nil
+}
+
+// matchVersions reports whether a module version is in a range of versions.
+// A range is a space separated list of constraints, which all have to hold.
+// Each constraint is a semantic version, optionally prefixed by one of the
+// operators <, <=, >, >= or =, e.g. ">=v1.2.0 <v2". A version without an
+// operator matches exactly, or a prefix if suffixed by .x, e.g. "v1.x" or
+// "v1.4.x".
+func matchVersions(versions, version string) bool {
+	if !semver.IsValid(version) {
+		return false
+	}
+	for _, c := range strings.Fields(versions) {
+		op, v := splitConstraint(c)
+		var ok bool
+		switch {
+		case op == "" && (strings.HasSuffix(v, ".x") || strings.HasSuffix(v, ".*")):
+			prefix := v[:len(v)-2]
+			ok = semver.Compare(version, prefix) >= 0 && matchVersionPrefix(prefix, semver.Canonical(version))
+		case op == "" || op == "=":
+			ok = semver.Compare(version, v) == 0
+		case op == "<":
+			ok = semver.Compare(version, v) < 0
+		case op == "<=":
+			ok = semver.Compare(version, v) <= 0
+		case op == ">":
+			ok = semver.Compare(version, v) > 0
+		case op == ">=":
+			ok = semver.Compare(version, v) >= 0
+		}
+		if !ok {
+			return false
+		}
+	}
+	return true
+}
+
+// splitConstraint splits a version constraint into its operator, if any, and
+// its version.
+func splitConstraint(c string) (op, version string) {
+	for _, op := range versionOperators {
+		if strings.HasPrefix(c, op) {
+			return op, strings.TrimPrefix(c, op)
+		}
+	}
+	return "", c
+}
+
+// matchVersionPrefix reports whether a canonical version has the major, or
+// major and minor, version of prefix, e.g. "v1" or "v1.4".
+func matchVersionPrefix(prefix, version string) bool {
+	if strings.Count(prefix, ".") == 0 {
+		return semver.Major(version) == prefix
+	}
+	return semver.MajorMinor(version) == prefix
+}
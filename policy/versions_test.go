@@ -0,0 +1,56 @@
+// Copyright 2022 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package policy
+
+import "testing"
+
+func TestMatchVersions(t *testing.T) {
+	for _, test := range []struct {
+		versions string
+		version  string
+		want     bool
+	}{
+		{versions: "v1.2.3", version: "v1.2.3", want: true},
+		{versions: "=v1.2.3", version: "v1.2.4", want: false},
+		{versions: "v1.x", version: "v1.9.0", want: true},
+		{versions: "v1.x", version: "v2.0.0", want: false},
+		{versions: "v1.4.x", version: "v1.4.2", want: true},
+		{versions: "v1.4.x", version: "v1.5.0", want: false},
+		{versions: "<v2", version: "v1.99.0", want: true},
+		{versions: "<v2", version: "v2.0.0", want: false},
+		{versions: ">=v1.2.0 <v2", version: "v1.1.9", want: false},
+		{versions: ">=v1.2.0 <v2", version: "v1.2.0", want: true},
+		{versions: "<=v0.3.0", version: "v0.3.0", want: true},
+		{versions: ">v0.3.0", version: "v0.3.0", want: false},
+		{versions: "v1.x", version: "", want: false},
+	} {
+		if got := matchVersions(test.versions, test.version); got != test.want {
+			t.Errorf("matchVersions(%q, %q) = %t, want %t", test.versions, test.version, got, test.want)
+		}
+	}
+}
+
+func TestValidateVersions(t *testing.T) {
+	for _, versions := range []string{"", "1.2.3", "<=latest", "v1.x.x"} {
+		if err := validateVersions(versions); err == nil {
+			t.Errorf("validateVersions(%q) = nil, want error", versions)
+		}
+	}
+	for _, versions := range []string{"v1.x", ">=v1.2.0 <v2", "v0.3.1-rc.1"} {
+		if err := validateVersions(versions); err != nil {
+			t.Errorf("validateVersions(%q) = %v, want nil", versions, err)
+		}
+	}
+}
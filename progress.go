@@ -0,0 +1,134 @@
+// Copyright 2022 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/Bobgy/go-licenses/v2/internal/logging"
+	"github.com/Bobgy/go-licenses/v2/licenses"
+)
+
+// phaseIdentifyingLicenses is the phase of progress events while identifying
+// the licenses of libraries.
+const phaseIdentifyingLicenses = "identifying licenses"
+
+// phaseWarmingCache is the phase of progress events while the warm command
+// validates license URLs.
+const phaseWarmingCache = "warming cache"
+
+// progressBarWidth is the number of characters of progress bars.
+const progressBarWidth = 30
+
+// progressReporter reports progress events on stderr, as a progress bar on
+// terminals, or as JSON lines otherwise.
+type progressReporter struct {
+	w   io.Writer
+	bar bool
+	// last is the last event reported, to limit JSON lines to one per
+	// percent of progress and to end progress bars of finished phases.
+	last    licenses.Progress
+	percent int
+	// phaseStart is when the phase of last started, to estimate the time
+	// remaining.
+	phaseStart time.Time
+	// open is true when the progress bar line is not ended yet.
+	open bool
+}
+
+// progress is the reporter of --progress, or nil.
+var progress *progressReporter
+
+// newProgressReporter returns a reporter writing to f, with a progress bar if
+// f is a terminal.
+func newProgressReporter(f *os.File) *progressReporter {
+	r := &progressReporter{w: f}
+	if info, err := f.Stat(); err == nil && info.Mode()&os.ModeCharDevice != 0 {
+		r.bar = true
+	}
+	return r
+}
+
+// reportProgress reports a progress event with --progress, and sets the phase
+// of log entries.
+func reportProgress(p licenses.Progress) {
+	logging.SetPhase(p.Phase)
+	if progress != nil {
+		progress.report(p)
+	}
+}
+
+// report reports a progress event.
+func (r *progressReporter) report(p licenses.Progress) {
+	percent := -1
+	if p.Total > 0 {
+		percent = p.Done * 100 / p.Total
+	}
+	if p.Phase == r.last.Phase && percent == r.percent && p.Done != p.Total {
+		return
+	}
+	phaseChanged := p.Phase != r.last.Phase
+	if phaseChanged {
+		r.phaseStart = time.Now()
+	}
+	r.last, r.percent = p, percent
+	eta := r.eta(p)
+	if !r.bar {
+		line, err := json.Marshal(struct {
+			licenses.Progress
+			ETASeconds int `json:"etaSeconds,omitempty"`
+		}{p, int(eta.Seconds())})
+		if err == nil {
+			fmt.Fprintf(r.w, "%s\n", line)
+		}
+		return
+	}
+	if r.open && phaseChanged {
+		fmt.Fprintln(r.w)
+	}
+	if percent < 0 {
+		fmt.Fprintf(r.w, "\r%s...", p.Phase)
+	} else {
+		filled := progressBarWidth * p.Done / p.Total
+		fmt.Fprintf(r.w, "\r%s [%s%s] %d/%d", p.Phase, strings.Repeat("=", filled), strings.Repeat(" ", progressBarWidth-filled), p.Done, p.Total)
+		estimate := ""
+		if eta > 0 {
+			estimate = "ETA " + eta.String()
+		}
+		// Padded to overwrite the estimate of the previous event.
+		fmt.Fprintf(r.w, " %-12s", estimate)
+	}
+	r.open = true
+	if p.Total > 0 && p.Done == p.Total {
+		fmt.Fprintln(r.w)
+		r.open = false
+	}
+}
+
+// eta estimates the time remaining in the phase of a progress event from the
+// rate of the phase so far. It returns 0 if unknown, e.g. before the first
+// item is done.
+func (r *progressReporter) eta(p licenses.Progress) time.Duration {
+	if p.Total == 0 || p.Done == 0 || p.Done >= p.Total {
+		return 0
+	}
+	elapsed := time.Since(r.phaseStart)
+	return (elapsed * time.Duration(p.Total-p.Done) / time.Duration(p.Done)).Round(time.Second)
+}
@@ -0,0 +1,210 @@
+// Copyright 2022 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package report is the data model of go-licenses reports, shared by all
+// report formats, so that external tools can rely on its shape.
+package report
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/Bobgy/go-licenses/v2/config"
+	"github.com/Bobgy/go-licenses/v2/policy"
+)
+
+// SchemaVersion is the version of the shape of reports. It is incremented on
+// changes that are not backward compatible, e.g. removed or renamed fields.
+const SchemaVersion = 1
+
+// DefaultColumns are the columns of CSV reports unless configured otherwise.
+var DefaultColumns = []string{config.ColumnLibrary, config.ColumnURL, config.ColumnLicense}
+
+// Report is a report of the libraries of Go packages or binaries.
+type Report struct {
+	// SchemaVersion is the version of the shape of the report, see the
+	// SchemaVersion constant. It is set when marshalling a report.
+	SchemaVersion int `json:"schemaVersion" yaml:"schemaVersion"`
+	// Records are the libraries reported, one per library.
+	Records []Record `json:"records" yaml:"records"`
+}
+
+// Record is a library in a report. Its fields are named after the columns of
+// CSV reports, see config.Columns. Records are comparable.
+type Record struct {
+	// Library is the name of the library, e.g. "github.com/google/trillian".
+	Library string `json:"library" yaml:"library"`
+	// URL is the URL of the library's license, or "Unknown".
+	URL string `json:"url" yaml:"url"`
+	// License is the name of the license, e.g. "Apache-2.0", or "Unknown".
+	License string `json:"license" yaml:"license"`
+	// Category is the category of the license, e.g. "notice", see
+	// policy.Categories.
+	Category string `json:"category" yaml:"category"`
+	// Confidence is the confidence of the classifier in License, between 0
+	// and 1, if reported.
+	Confidence float64 `json:"confidence,omitempty" yaml:"confidence,omitempty"`
+	// Module is the path of the library's module, if known.
+	Module string `json:"module,omitempty" yaml:"module,omitempty"`
+	// Version is the version of the library's module, if known.
+	Version string `json:"version,omitempty" yaml:"version,omitempty"`
+	// Replaced is true if the library's module is replaced by a replace
+	// directive.
+	Replaced bool `json:"replaced,omitempty" yaml:"replaced,omitempty"`
+	// OriginalPath is the module path required by go.mod, if the library's
+	// module is replaced.
+	OriginalPath string `json:"originalPath,omitempty" yaml:"originalPath,omitempty"`
+	// CopyleftScope is the copyleft scope of the license in a statically
+	// linked binary, if reported.
+	CopyleftScope policy.CopyleftScope `json:"copyleftScope,omitempty" yaml:"copyleftScope,omitempty"`
+	// Obligation is the obligation of CopyleftScope, if reported.
+	Obligation string `json:"obligation,omitempty" yaml:"obligation,omitempty"`
+	// ModuleWarning is a warning about the version of the library's module,
+	// e.g. that it is retracted, if reported.
+	ModuleWarning string `json:"moduleWarning,omitempty" yaml:"moduleWarning,omitempty"`
+	// Notes are warnings about the library, separated by "; ".
+	Notes string `json:"notes,omitempty" yaml:"notes,omitempty"`
+}
+
+// Column returns the value of a column of CSV reports, see config.Columns, or
+// "" for an unknown column.
+func (r Record) Column(name string) string {
+	switch name {
+	case config.ColumnLibrary:
+		return r.Library
+	case config.ColumnURL:
+		return r.URL
+	case config.ColumnLicense:
+		return r.License
+	case config.ColumnCategory:
+		return r.Category
+	case config.ColumnConfidence:
+		return strconv.FormatFloat(r.Confidence, 'f', 2, 64)
+	case config.ColumnModule:
+		return r.Module
+	case config.ColumnVersion:
+		return r.Version
+	case config.ColumnReplaced:
+		return strconv.FormatBool(r.Replaced)
+	case config.ColumnOriginalPath:
+		return r.OriginalPath
+	case config.ColumnCopyleftScope:
+		return string(r.CopyleftScope)
+	case config.ColumnObligation:
+		return r.Obligation
+	case config.ColumnNotes:
+		return r.Notes
+	default:
+		return ""
+	}
+}
+
+// SetColumn sets the field of a column of CSV reports to value, parsed like
+// Column formats it, e.g. to read the rows of CSV reports back.
+func (r *Record) SetColumn(name, value string) error {
+	var err error
+	switch name {
+	case config.ColumnLibrary:
+		r.Library = value
+	case config.ColumnURL:
+		r.URL = value
+	case config.ColumnLicense:
+		r.License = value
+	case config.ColumnCategory:
+		r.Category = value
+	case config.ColumnConfidence:
+		r.Confidence, err = strconv.ParseFloat(value, 64)
+	case config.ColumnModule:
+		r.Module = value
+	case config.ColumnVersion:
+		r.Version = value
+	case config.ColumnReplaced:
+		r.Replaced, err = strconv.ParseBool(value)
+	case config.ColumnOriginalPath:
+		r.OriginalPath = value
+	case config.ColumnCopyleftScope:
+		r.CopyleftScope = policy.CopyleftScope(value)
+	case config.ColumnObligation:
+		r.Obligation = value
+	case config.ColumnNotes:
+		r.Notes = value
+	default:
+		return fmt.Errorf("unknown column %q", name)
+	}
+	if err != nil {
+		return fmt.Errorf("invalid %s column %q: %w", name, value, err)
+	}
+	return nil
+}
+
+// versioned is a Report without its marshalling methods, with SchemaVersion
+// set.
+type versioned Report
+
+func (r Report) versioned() versioned {
+	if r.SchemaVersion == 0 {
+		r.SchemaVersion = SchemaVersion
+	}
+	if r.Records == nil {
+		r.Records = []Record{}
+	}
+	return versioned(r)
+}
+
+// MarshalJSON marshals the report as a JSON object, setting its schema
+// version.
+func (r Report) MarshalJSON() ([]byte, error) {
+	return json.Marshal(r.versioned())
+}
+
+// MarshalYAML marshals the report as a YAML mapping, setting its schema
+// version.
+func (r Report) MarshalYAML() (interface{}, error) {
+	return r.versioned(), nil
+}
+
+// MarshalCSV marshals the records of the report as CSV rows with columns,
+// DefaultColumns if empty, like the csv command. Values are separated by ", ".
+func (r Report) MarshalCSV(columns []string) ([]byte, error) {
+	if len(columns) == 0 {
+		columns = DefaultColumns
+	}
+	for _, c := range columns {
+		if !contains(config.Columns, c) {
+			return nil, fmt.Errorf("unknown column %q, must be one of %s", c, strings.Join(config.Columns, ", "))
+		}
+	}
+	var b bytes.Buffer
+	for _, record := range r.Records {
+		values := make([]string, len(columns))
+		for i, c := range columns {
+			values[i] = record.Column(c)
+		}
+		b.WriteString(strings.Join(values, ", "))
+		b.WriteByte('\n')
+	}
+	return b.Bytes(), nil
+}
+
+func contains(list []string, s string) bool {
+	for _, item := range list {
+		if item == s {
+			return true
+		}
+	}
+	return false
+}
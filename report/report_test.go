@@ -0,0 +1,133 @@
+// Copyright 2022 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package report
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/Bobgy/go-licenses/v2/config"
+	"github.com/Bobgy/go-licenses/v2/policy"
+	"github.com/google/go-cmp/cmp"
+)
+
+var testReport = Report{Records: []Record{
+	{Library: "github.com/foo/mit", URL: "https://github.com/foo/mit/blob/v1.0.0/LICENSE", License: "MIT", Category: "notice", Module: "github.com/foo/mit", Version: "v1.0.0"},
+	{Library: "github.com/foo/gpl", URL: "Unknown", License: "GPL-3.0", Category: "restricted", Confidence: 0.95, CopyleftScope: policy.StaticLinkingScope("GPL-3.0"), Notes: "retracted"},
+}}
+
+func TestMarshalJSON(t *testing.T) {
+	data, err := json.Marshal(testReport)
+	if err != nil {
+		t.Fatalf("json.Marshal() = (_, %v), want (_, nil)", err)
+	}
+	var got Report
+	if err := json.Unmarshal(data, &got); err != nil {
+		t.Fatalf("json.Unmarshal() = %v", err)
+	}
+	want := testReport
+	want.SchemaVersion = SchemaVersion
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("JSON round trip: diff (-want +got)\n%s", diff)
+	}
+	var fields map[string]interface{}
+	if err := json.Unmarshal(data, &fields); err != nil {
+		t.Fatal(err)
+	}
+	if fields["schemaVersion"] != float64(SchemaVersion) {
+		t.Errorf("json.Marshal() = %s, want schemaVersion %d", data, SchemaVersion)
+	}
+
+	data, err = json.Marshal(Report{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := `{"schemaVersion":1,"records":[]}`; string(data) != want {
+		t.Errorf("json.Marshal() of empty report = %s, want %s", data, want)
+	}
+}
+
+func TestMarshalCSV(t *testing.T) {
+	for _, test := range []struct {
+		desc    string
+		columns []string
+		want    string
+		wantErr bool
+	}{
+		{
+			desc: "Default columns",
+			want: "github.com/foo/mit, https://github.com/foo/mit/blob/v1.0.0/LICENSE, MIT\n" +
+				"github.com/foo/gpl, Unknown, GPL-3.0\n",
+		},
+		{
+			desc:    "Configured columns",
+			columns: []string{config.ColumnLibrary, config.ColumnCategory, config.ColumnConfidence, config.ColumnVersion, config.ColumnNotes},
+			want: "github.com/foo/mit, notice, 0.00, v1.0.0, \n" +
+				"github.com/foo/gpl, restricted, 0.95, , retracted\n",
+		},
+		{
+			desc:    "Unknown column",
+			columns: []string{"spdx"},
+			wantErr: true,
+		},
+	} {
+		t.Run(test.desc, func(t *testing.T) {
+			got, err := testReport.MarshalCSV(test.columns)
+			if gotErr := err != nil; gotErr != test.wantErr {
+				t.Fatalf("MarshalCSV() = (_, %v), want error? %t", err, test.wantErr)
+			}
+			if diff := cmp.Diff(test.want, string(got)); diff != "" {
+				t.Errorf("MarshalCSV(): diff (-want +got)\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestColumn(t *testing.T) {
+	record := Record{
+		Library:       "github.com/foo/lgpl",
+		URL:           "https://github.com/foo/lgpl/blob/v1.0.0/COPYING",
+		License:       "LGPL-3.0",
+		Category:      "restricted",
+		Confidence:    0.9,
+		Module:        "github.com/bar/lgpl",
+		Version:       "v1.0.0",
+		Replaced:      true,
+		OriginalPath:  "github.com/foo/lgpl",
+		CopyleftScope: policy.StaticLinkingScope("LGPL-3.0"),
+		Obligation:    policy.StaticLinkingScope("LGPL-3.0").Obligation(),
+		Notes:         "retracted",
+	}
+	for _, c := range config.Columns {
+		if record.Column(c) == "" {
+			t.Errorf("Column(%q) = empty, want a value", c)
+		}
+	}
+	var got Record
+	for _, c := range config.Columns {
+		if err := got.SetColumn(c, record.Column(c)); err != nil {
+			t.Errorf("SetColumn(%q, %q) = %v, want nil", c, record.Column(c), err)
+		}
+	}
+	if got != record {
+		t.Errorf("SetColumn() of all columns = %+v, want %+v", got, record)
+	}
+	if err := got.SetColumn(config.ColumnReplaced, "maybe"); err == nil {
+		t.Error("SetColumn() of invalid bool = nil, want error")
+	}
+	if err := got.SetColumn("size", "1"); err == nil {
+		t.Error("SetColumn() of unknown column = nil, want error")
+	}
+}
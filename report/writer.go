@@ -0,0 +1,124 @@
+// Copyright 2022 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package report
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Writer writes a report record by record, so that reports of thousands of
+// libraries are never held in memory as a whole document. Close must be
+// called after the last record to complete the document.
+type Writer interface {
+	// Write writes a record of the report.
+	Write(Record) error
+	// Close completes the report. It does not close the underlying writer.
+	Close() error
+}
+
+// NewJSONWriter returns a Writer of an indented JSON report, the same as
+// json.MarshalIndent(report, "", "  ") followed by a line break.
+func NewJSONWriter(w io.Writer) Writer {
+	return &jsonWriter{w: w}
+}
+
+type jsonWriter struct {
+	w       io.Writer
+	records int
+}
+
+func (j *jsonWriter) Write(r Record) error {
+	data, err := json.MarshalIndent(r, "    ", "  ")
+	if err != nil {
+		return err
+	}
+	prefix := ",\n    "
+	if j.records == 0 {
+		prefix = fmt.Sprintf("{\n  \"schemaVersion\": %d,\n  \"records\": [\n    ", SchemaVersion)
+	}
+	j.records++
+	if _, err := io.WriteString(j.w, prefix); err != nil {
+		return err
+	}
+	_, err = j.w.Write(data)
+	return err
+}
+
+func (j *jsonWriter) Close() error {
+	if j.records == 0 {
+		_, err := fmt.Fprintf(j.w, "{\n  \"schemaVersion\": %d,\n  \"records\": []\n}\n", SchemaVersion)
+		return err
+	}
+	_, err := io.WriteString(j.w, "\n  ]\n}\n")
+	return err
+}
+
+// NewYAMLWriter returns a Writer of a YAML report, formatted like
+// yaml.Marshal(report).
+func NewYAMLWriter(w io.Writer) Writer {
+	return &yamlWriter{w: w}
+}
+
+type yamlWriter struct {
+	w       io.Writer
+	records int
+}
+
+// yamlIndent is the indentation of yaml.Marshal.
+const yamlIndent = "    "
+
+func (y *yamlWriter) Write(r Record) error {
+	// A sequence of a single record, indented below the "records" key.
+	data, err := yaml.Marshal([]Record{r})
+	if err != nil {
+		return err
+	}
+	var b bytes.Buffer
+	if y.records == 0 {
+		fmt.Fprintf(&b, "schemaVersion: %d\nrecords:\n", SchemaVersion)
+	}
+	y.records++
+	for _, line := range bytes.SplitAfter(data, []byte("\n")) {
+		if len(bytes.TrimSpace(line)) > 0 {
+			b.WriteString(yamlIndent)
+		}
+		b.Write(line)
+	}
+	_, err = y.w.Write(b.Bytes())
+	return err
+}
+
+func (y *yamlWriter) Close() error {
+	if y.records == 0 {
+		_, err := fmt.Fprintf(y.w, "schemaVersion: %d\nrecords: []\n", SchemaVersion)
+		return err
+	}
+	return nil
+}
+
+// WriteRecords writes records with a Writer and closes it.
+func WriteRecords(w Writer, records []Record) error {
+	for _, r := range records {
+		if err := w.Write(r); err != nil {
+			return err
+		}
+	}
+	return w.Close()
+}
@@ -0,0 +1,74 @@
+// Copyright 2022 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package report
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"gopkg.in/yaml.v3"
+)
+
+func TestJSONWriter(t *testing.T) {
+	for _, test := range []struct {
+		desc   string
+		report Report
+	}{
+		{desc: "records", report: testReport},
+		{desc: "empty", report: Report{}},
+	} {
+		t.Run(test.desc, func(t *testing.T) {
+			var b bytes.Buffer
+			if err := WriteRecords(NewJSONWriter(&b), test.report.Records); err != nil {
+				t.Fatalf("WriteRecords() = %v", err)
+			}
+			want, err := json.MarshalIndent(test.report, "", "  ")
+			if err != nil {
+				t.Fatal(err)
+			}
+			want = append(want, '\n')
+			if diff := cmp.Diff(string(want), b.String()); diff != "" {
+				t.Errorf("WriteRecords(NewJSONWriter()): diff (-want +got)\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestYAMLWriter(t *testing.T) {
+	for _, test := range []struct {
+		desc   string
+		report Report
+	}{
+		{desc: "records", report: testReport},
+		{desc: "empty", report: Report{}},
+	} {
+		t.Run(test.desc, func(t *testing.T) {
+			var b bytes.Buffer
+			if err := WriteRecords(NewYAMLWriter(&b), test.report.Records); err != nil {
+				t.Fatalf("WriteRecords() = %v", err)
+			}
+			var got Report
+			if err := yaml.Unmarshal(b.Bytes(), &got); err != nil {
+				t.Fatalf("yaml.Unmarshal(%s) = %v", b.String(), err)
+			}
+			want := test.report.versioned()
+			if diff := cmp.Diff(Report(want), got); diff != "" {
+				t.Errorf("WriteRecords(NewYAMLWriter()) round trip: diff (-want +got)\n%s", diff)
+			}
+		})
+	}
+}
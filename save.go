@@ -31,9 +31,9 @@ import (
 
 var (
 	saveCmd = &cobra.Command{
-		Use:   "save <package>",
+		Use:   "save <package>...",
 		Short: "Saves licenses, copyright notices and source code, as required by a Go package's dependencies, to a directory.",
-		Args:  cobra.MinimumNArgs(1),
+		Args:  packageArgs,
 		RunE:  saveMain,
 	}
 
@@ -63,17 +63,23 @@ func init() {
 func saveMain(_ *cobra.Command, args []string) error {
 
 	if overwriteSavePath {
-		if err := os.RemoveAll(savePath); err != nil {
+		if dryRun {
+			dryRunf("would delete %s, changes are relative to its current files", savePath)
+		} else if err := os.RemoveAll(savePath); err != nil {
 			return err
 		}
 	}
 
-	classifier, err := licenses.NewClassifier(confidenceThreshold)
+	classifier, err := newClassifier(confidenceThreshold)
 	if err != nil {
 		return err
 	}
 
-	libs, err := licenses.Libraries(context.Background(), classifier, args...)
+	importPaths, err := expandPackages(context.Background(), args)
+	if err != nil {
+		return err
+	}
+	libs, err := licenses.LibrariesWithOptions(context.Background(), classifier, libraryOptions(), importPaths...)
 	if err != nil {
 		return err
 	}
@@ -82,7 +88,9 @@ func saveMain(_ *cobra.Command, args []string) error {
 	// existing files and the output of this command.
 	if d, err := os.Open(savePath); err == nil {
 		d.Close()
-		return fmt.Errorf("%s already exists", savePath)
+		if !dryRun || !overwriteSavePath {
+			return fmt.Errorf("%s already exists", savePath)
+		}
 	} else if !os.IsNotExist(err) {
 		return err
 	}
@@ -126,14 +134,14 @@ func copySrc(src, dest string) error {
 		},
 		AddPermission: 0600,
 	}
-	if err := copy.Copy(src, dest, opt); err != nil {
+	if err := copyPath(src, dest, opt); err != nil {
 		return err
 	}
 	return nil
 }
 
 func copyNotices(licensePath, dest string) error {
-	if err := copy.Copy(licensePath, filepath.Join(dest, filepath.Base(licensePath))); err != nil {
+	if err := copyPath(licensePath, filepath.Join(dest, filepath.Base(licensePath))); err != nil {
 		return err
 	}
 
@@ -144,10 +152,47 @@ func copyNotices(licensePath, dest string) error {
 	}
 	for _, f := range files {
 		if fName := f.Name(); !f.IsDir() && noticeRegexp.MatchString(fName) {
-			if err := copy.Copy(filepath.Join(src, fName), filepath.Join(dest, fName)); err != nil {
+			if err := copyPath(filepath.Join(src, fName), filepath.Join(dest, fName)); err != nil {
 				return err
 			}
 		}
 	}
 	return nil
 }
+
+// copyPath copies a file or directory tree from src to dest. With --dry_run, it
+// reports the files that would be written instead, see writeFile.
+func copyPath(src, dest string, opt ...copy.Options) error {
+	if !dryRun {
+		return copy.Copy(src, dest, opt...)
+	}
+	return filepath.Walk(src, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		for _, o := range opt {
+			if o.Skip == nil {
+				continue
+			}
+			if skip, err := o.Skip(path); err != nil {
+				return err
+			} else if skip && info.IsDir() {
+				return filepath.SkipDir
+			} else if skip {
+				return nil
+			}
+		}
+		if !info.Mode().IsRegular() {
+			return nil
+		}
+		rel, err := filepath.Rel(src, path)
+		if err != nil {
+			return err
+		}
+		content, err := ioutil.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		return writeFile(filepath.Join(dest, rel), content, info.Mode())
+	})
+}
@@ -0,0 +1,92 @@
+// Copyright 2022 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/Bobgy/go-licenses/v2/config"
+	"github.com/Bobgy/go-licenses/v2/internal/logging"
+	"github.com/Bobgy/go-licenses/v2/licenses"
+	"github.com/spf13/cobra"
+)
+
+var (
+	scanDirCmd = &cobra.Command{
+		Use:   "scan-dir <dir>",
+		Short: "Prints all licenses that apply to the Go binaries found in a directory tree",
+		Long: `Prints all licenses that apply to the Go binaries found in a directory tree.
+
+All Go binaries in the directory are discovered and reported together like the
+binary command does. With --output_dir, a report is also written for each
+binary, named after its path relative to <dir>.`,
+		Args: cobra.ExactArgs(1),
+		RunE: scanDirMain,
+	}
+
+	// scanDirOutputDir is where per-binary reports are written to.
+	scanDirOutputDir string
+)
+
+func init() {
+	scanDirCmd.Flags().StringVar(&scanDirOutputDir, "output_dir", "", "Directory into which a csv report for each binary is written")
+
+	rootCmd.AddCommand(scanDirCmd)
+}
+
+func scanDirMain(_ *cobra.Command, args []string) error {
+	binaries, err := binariesInDir(args[0], scanDirOutputDir)
+	if err != nil {
+		return err
+	}
+	return reportBinaries(binaries)
+}
+
+// binariesInDir returns all Go binaries in the directory tree at dir. When
+// outputDir is set, each binary's report is written to outputDir, named after
+// the binary's path relative to dir.
+func binariesInDir(dir string, outputDir string) ([]config.Binary, error) {
+	paths, err := licenses.FindBinaries(context.Background(), libraryOptions(), dir)
+	if err != nil {
+		return nil, err
+	}
+	if len(paths) == 0 {
+		return nil, fmt.Errorf("no Go binaries found in %s", dir)
+	}
+	if outputDir != "" && !dryRun {
+		if err := os.MkdirAll(outputDir, 0755); err != nil {
+			return nil, err
+		}
+	}
+	var binaries []config.Binary
+	for _, path := range paths {
+		logging.Infof("Found Go binary %s", path)
+		binary := config.Binary{Path: path}
+		if outputDir != "" {
+			rel, err := filepath.Rel(dir, path)
+			if err != nil {
+				return nil, err
+			}
+			name := strings.ReplaceAll(filepath.ToSlash(rel), "/", "_")
+			binary.Output = filepath.Join(outputDir, name+".licenses.csv")
+		}
+		binaries = append(binaries, binary)
+	}
+	return binaries, nil
+}
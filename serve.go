@@ -0,0 +1,313 @@
+// Copyright 2022 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"os"
+	"os/exec"
+	"os/signal"
+	"path/filepath"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/Bobgy/go-licenses/v2/internal/logging"
+	"github.com/Bobgy/go-licenses/v2/licenses"
+	"github.com/Bobgy/go-licenses/v2/report"
+	"github.com/spf13/cobra"
+)
+
+const (
+	// daemonStartTimeout is how long serve --daemon waits for the server to
+	// accept requests.
+	daemonStartTimeout = 30 * time.Second
+	// maxServedReports is the number of reports of commits kept in memory.
+	maxServedReports = 100
+)
+
+var (
+	serveCmd = &cobra.Command{
+		Use:   "serve",
+		Short: "Serves scan requests over a local socket, keeping caches warm",
+		Long: `Serves scan requests over a local socket, keeping caches warm.
+
+The server keeps the compiled license database, HTTP connections and reports
+in memory between requests, so that platforms scanning every pull request of
+many repos do not pay for them on each scan. Requests are POSTed as JSON to
+/scan, e.g.
+
+  curl --unix-socket /tmp/go-licenses.sock http://localhost/scan \
+    -d '{"dir": "/src/repo", "ref": "origin/main", "packages": ["./..."]}'
+
+dir is the absolute path of a local repo. With ref, the commit it resolves to
+is scanned in a temporary git worktree, and its report is reused by later
+requests for the same commit. Without ref, the files in dir are scanned.
+packages default to ./..., and format, csv, json or yaml, to json. Scans use
+the flags and config file of serve, and run one at a time.`,
+		Args: cobra.NoArgs,
+		RunE: serveMain,
+	}
+
+	// serveSocket is the path of the unix socket requests are served on.
+	serveSocket string
+	// serveDaemon starts the server in the background.
+	serveDaemon bool
+)
+
+func init() {
+	serveCmd.Flags().StringVar(&serveSocket, "socket", filepath.Join(os.TempDir(), "go-licenses.sock"), "Path of the unix socket scan requests are served on.")
+	serveCmd.Flags().BoolVar(&serveDaemon, "daemon", false, "Start the server in the background and return once it accepts requests. Its log is appended to the socket path with a .log suffix.")
+
+	rootCmd.AddCommand(serveCmd)
+}
+
+// scanRequest is a request to scan the packages of a repo.
+type scanRequest struct {
+	// Dir is the absolute path of the repo.
+	Dir string `json:"dir"`
+	// Ref is the git revision to scan, e.g. a branch or commit. The files in
+	// Dir are scanned if empty.
+	Ref string `json:"ref,omitempty"`
+	// Packages are the package patterns, relative to Dir, to scan. They
+	// default to ./... if empty.
+	Packages []string `json:"packages,omitempty"`
+	// Format is the format of the report: csv, json or yaml. It defaults to
+	// json if empty.
+	Format string `json:"format,omitempty"`
+}
+
+func serveMain(_ *cobra.Command, _ []string) error {
+	if conn, err := net.Dial("unix", serveSocket); err == nil {
+		conn.Close()
+		return fmt.Errorf("a server is already listening on %s", serveSocket)
+	}
+	if serveDaemon {
+		return startDaemon()
+	}
+	// The socket of a server that did not shut down, if any.
+	if err := os.Remove(serveSocket); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	classifier, err := newClassifier(confidenceThreshold)
+	if err != nil {
+		return err
+	}
+	l, err := net.Listen("unix", serveSocket)
+	if err != nil {
+		return err
+	}
+	defer l.Close()
+	srv := &http.Server{Handler: &scanServer{
+		classifier: classifier,
+		reports:    make(map[string][]report.Record),
+	}}
+	// Keep serving when the terminal serve --daemon was started from closes.
+	signal.Ignore(syscall.SIGHUP)
+	stop := make(chan os.Signal, 1)
+	signal.Notify(stop, os.Interrupt, syscall.SIGTERM)
+	go func() {
+		<-stop
+		logging.Infof("Shutting down the server on %s", serveSocket)
+		srv.Close()
+	}()
+	logging.Infof("Serving scan requests on %s", serveSocket)
+	if err := srv.Serve(l); err != http.ErrServerClosed {
+		return err
+	}
+	return nil
+}
+
+// startDaemon runs serve without --daemon in a background process, and waits
+// until it accepts requests.
+func startDaemon() error {
+	exe, err := os.Executable()
+	if err != nil {
+		return err
+	}
+	var args []string
+	for _, arg := range os.Args[1:] {
+		if arg != "--daemon" && arg != "-daemon" && !strings.HasPrefix(arg, "--daemon=") && !strings.HasPrefix(arg, "-daemon=") {
+			args = append(args, arg)
+		}
+	}
+	logPath := serveSocket + ".log"
+	logFile, err := os.OpenFile(logPath, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return err
+	}
+	defer logFile.Close()
+	cmd := exec.Command(exe, args...)
+	cmd.Stdout, cmd.Stderr = logFile, logFile
+	if err := cmd.Start(); err != nil {
+		return err
+	}
+	exited := make(chan error, 1)
+	go func() { exited <- cmd.Wait() }()
+	for deadline := time.Now().Add(daemonStartTimeout); time.Now().Before(deadline); {
+		select {
+		case err := <-exited:
+			return fmt.Errorf("the server exited on start, see %s: %v", logPath, err)
+		case <-time.After(100 * time.Millisecond):
+		}
+		if conn, err := net.Dial("unix", serveSocket); err == nil {
+			conn.Close()
+			fmt.Printf("Serving scan requests on %s, pid %d, log %s\n", serveSocket, cmd.Process.Pid, logPath)
+			return nil
+		}
+	}
+	return fmt.Errorf("the server did not accept requests on %s within %s, see %s", serveSocket, daemonStartTimeout, logPath)
+}
+
+// scanServer serves scan requests, see serveCmd.
+type scanServer struct {
+	classifier licenses.Classifier
+
+	// mu serializes scans, each of which already uses all workers, and
+	// guards reports.
+	mu sync.Mutex
+	// reports holds the rows of reports of commits by scanKey, and keys the
+	// order they were added in, to evict the oldest.
+	reports map[string][]report.Record
+	keys    []string
+}
+
+func (s *scanServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Path != "/scan" {
+		http.NotFound(w, r)
+		return
+	}
+	if r.Method != http.MethodPost {
+		http.Error(w, "scan requests must be POSTed", http.StatusMethodNotAllowed)
+		return
+	}
+	var req scanRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, fmt.Sprintf("invalid scan request: %v", err), http.StatusBadRequest)
+		return
+	}
+	if !filepath.IsAbs(req.Dir) {
+		http.Error(w, fmt.Sprintf("invalid scan request: dir %q must be an absolute path", req.Dir), http.StatusBadRequest)
+		return
+	}
+	if strings.HasPrefix(req.Ref, "-") {
+		// git would parse the ref as an option.
+		http.Error(w, fmt.Sprintf("invalid scan request: ref %q must not start with -", req.Ref), http.StatusBadRequest)
+		return
+	}
+	if len(req.Packages) == 0 {
+		req.Packages = []string{"./..."}
+	}
+	var write func(io.Writer, []report.Record) error
+	switch req.Format {
+	case "", "json":
+		write = writeJSONReport
+	case "yaml":
+		write = writeYAMLReport
+	case "csv":
+		write = writeCSVRows
+	default:
+		http.Error(w, fmt.Sprintf("invalid scan request: unknown format %q, must be csv, json or yaml", req.Format), http.StatusBadRequest)
+		return
+	}
+	start := time.Now()
+	rows, err := s.scan(r.Context(), req)
+	if err != nil {
+		logging.Errorf("Failed to scan %s at %q: %v", req.Dir, req.Ref, err)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	var b bytes.Buffer
+	if err := write(&b, rows); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	logging.Infof("Scanned %s at %q in %s", req.Dir, req.Ref, time.Since(start).Round(time.Millisecond))
+	w.Write(b.Bytes())
+}
+
+// scan returns the rows of the report of a scan request. Reports of commits
+// are reused.
+func (s *scanServer) scan(ctx context.Context, req scanRequest) ([]report.Record, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if req.Ref == "" {
+		return s.scanDir(ctx, req.Dir, req.Packages)
+	}
+	commit, err := gitOutput(ctx, req.Dir, "rev-parse", "--verify", req.Ref+"^{commit}")
+	if err != nil {
+		return nil, err
+	}
+	key := strings.Join(append([]string{req.Dir, commit}, req.Packages...), "\x00")
+	if rows, ok := s.reports[key]; ok {
+		return rows, nil
+	}
+	worktree, err := ioutil.TempDir("", "go-licenses-worktree")
+	if err != nil {
+		return nil, err
+	}
+	if _, err := gitOutput(ctx, req.Dir, "worktree", "add", "--detach", worktree, commit); err != nil {
+		os.RemoveAll(worktree)
+		return nil, err
+	}
+	defer func() {
+		if _, err := gitOutput(context.Background(), req.Dir, "worktree", "remove", "--force", worktree); err != nil {
+			logging.Warningf("Failed to remove worktree %s: %v", worktree, err)
+		}
+	}()
+	rows, err := s.scanDir(ctx, worktree, req.Packages)
+	if err != nil {
+		return nil, err
+	}
+	if len(s.keys) == maxServedReports {
+		delete(s.reports, s.keys[0])
+		s.keys = s.keys[1:]
+	}
+	s.reports[key] = rows
+	s.keys = append(s.keys, key)
+	return rows, nil
+}
+
+// scanDir returns the rows of the report of packages in dir.
+func (s *scanServer) scanDir(ctx context.Context, dir string, packages []string) ([]report.Record, error) {
+	opts := libraryOptions()
+	opts.Dir = dir
+	libs, err := licenses.LibrariesWithOptions(ctx, s.classifier, opts, packages...)
+	if err != nil {
+		return nil, err
+	}
+	return libraryRows(s.classifier, libs), nil
+}
+
+// gitOutput runs git in dir, and returns its trimmed output.
+func gitOutput(ctx context.Context, dir string, args ...string) (string, error) {
+	cmd := exec.CommandContext(ctx, "git", args...)
+	cmd.Dir = dir
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		return "", fmt.Errorf("git %s: %w: %s", strings.Join(args, " "), err, strings.TrimSpace(stderr.String()))
+	}
+	return strings.TrimSpace(string(out)), nil
+}
@@ -0,0 +1,139 @@
+// Copyright 2022 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/Bobgy/go-licenses/v2/internal/logging"
+	"github.com/Bobgy/go-licenses/v2/licenses"
+	"github.com/spf13/cobra"
+)
+
+var (
+	suggestCmd = &cobra.Command{
+		Use:   "suggest <package>...",
+		Short: "Suggests overrides for licenses that cannot be identified confidently",
+		Long: `Suggests overrides for licenses that cannot be identified confidently.
+
+For each library whose license cannot be identified with --confidence_threshold,
+or only with less than minConfidence of the policy, it prints an override
+pre-filled with the best guess of its license and its license URL, to confirm
+and paste into the overrides of the config file.`,
+		Args: packageArgs,
+		RunE: suggestMain,
+	}
+
+	// guessThreshold is the minimum confidence of best guesses.
+	guessThreshold float64
+)
+
+func init() {
+	suggestCmd.Flags().Float64Var(&guessThreshold, "guess_threshold", 0.5, "Minimum confidence of suggested licenses, lower than --confidence_threshold.")
+	rootCmd.AddCommand(suggestCmd)
+}
+
+// suggestion is a suggested override for a library.
+type suggestion struct {
+	library    string
+	license    string
+	url        string
+	confidence float64
+	// reason explains why the license needs an override.
+	reason string
+}
+
+func suggestMain(_ *cobra.Command, args []string) error {
+	classifier, err := newClassifier(confidenceThreshold)
+	if err != nil {
+		return err
+	}
+	// Also find license files that cannot be identified confidently.
+	guesser, err := newClassifier(guessThreshold)
+	if err != nil {
+		return err
+	}
+	ctx := context.Background()
+	importPaths, err := expandPackages(ctx, args)
+	if err != nil {
+		return err
+	}
+	libs, err := licenses.LibrariesWithOptions(ctx, guesser, libraryOptions(), importPaths...)
+	if err != nil {
+		return err
+	}
+	minConfidence := confidenceThreshold
+	if cfg.Policy != nil && cfg.Policy.MinConfidence > minConfidence {
+		minConfidence = cfg.Policy.MinConfidence
+	}
+	var suggestions []suggestion
+	for _, lib := range libs {
+		if cfg.Override(lib.Name()) != nil {
+			continue
+		}
+		s := suggestion{library: lib.Name()}
+		if lib.LicensePath == "" {
+			s.reason = "no license file found"
+			suggestions = append(suggestions, s)
+			continue
+		}
+		if _, _, err := classifier.Identify(lib.LicensePath); err == nil && licenseConfidence(classifier, lib) >= minConfidence {
+			continue
+		}
+		if c, ok := guesser.(licenses.ConfidenceClassifier); ok {
+			s.license, _, s.confidence, err = c.IdentifyConfidence(lib.LicensePath)
+		} else {
+			s.license, _, err = guesser.Identify(lib.LicensePath)
+		}
+		if err != nil {
+			s.reason = fmt.Sprintf("no license identified in %s", lib.LicensePath)
+		} else {
+			s.reason = fmt.Sprintf("best guess with confidence %.2f from %s", s.confidence, lib.LicensePath)
+		}
+		if url, err := lib.LicenseURL(ctx); err == nil {
+			s.url = url
+		} else {
+			logging.Module(lib.Name()).Warningf("Error discovering license URL: %s", err)
+		}
+		suggestions = append(suggestions, s)
+	}
+	return writeSuggestions(os.Stdout, suggestions)
+}
+
+// writeSuggestions writes suggested overrides as YAML, with their reasons as
+// comments. Unknown licenses are left to fill in.
+func writeSuggestions(w io.Writer, suggestions []suggestion) error {
+	if len(suggestions) == 0 {
+		_, err := fmt.Fprintln(w, "# All licenses are identified confidently.")
+		return err
+	}
+	if _, err := fmt.Fprintln(w, "overrides:"); err != nil {
+		return err
+	}
+	for _, s := range suggestions {
+		if _, err := fmt.Fprintf(w, "  # %s\n  - name: %s\n    spdxId: %s\n", s.reason, s.library, s.license); err != nil {
+			return err
+		}
+		if s.url != "" {
+			if _, err := fmt.Fprintf(w, "    url: %s\n", s.url); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,146 @@
+// Copyright 2022 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"sync"
+	"time"
+)
+
+// ANSI escape codes of summary colors.
+const (
+	colorRed    = "\x1b[31m"
+	colorYellow = "\x1b[33m"
+	colorGreen  = "\x1b[32m"
+	colorReset  = "\x1b[0m"
+)
+
+// runSummary collects what a run did, printed as a table to stderr at its end.
+var runSummary = struct {
+	mu sync.Mutex
+	// licenses are the licenses of libraries scanned, by library name.
+	licenses map[string]string
+	errors   int
+	warnings int
+	checked  bool
+}{licenses: make(map[string]string)}
+
+// recordLibrary records the license of a library scanned, "Unknown" if it is
+// not identified.
+func recordLibrary(name, license string) {
+	runSummary.mu.Lock()
+	defer runSummary.mu.Unlock()
+	runSummary.licenses[name] = license
+}
+
+// recordViolations records the numbers of errors and warnings of a check.
+func recordViolations(errors, warnings int) {
+	runSummary.mu.Lock()
+	defer runSummary.mu.Unlock()
+	runSummary.errors += errors
+	runSummary.warnings += warnings
+	runSummary.checked = true
+}
+
+// exit prints the summary of the run and exits with code.
+func exit(code int) {
+	printSummary()
+	os.Exit(code)
+}
+
+// printSummary prints the summary of the run to stderr, if it is a terminal
+// and output is not meant for scripts or log aggregation.
+func printSummary() {
+	runSummary.mu.Lock()
+	scanned := len(runSummary.licenses)
+	runSummary.mu.Unlock()
+	if scanned == 0 || porcelain || logFormat == "json" {
+		return
+	}
+	if info, err := os.Stderr.Stat(); err != nil || info.Mode()&os.ModeCharDevice == 0 {
+		return
+	}
+	color := !noColor && os.Getenv("NO_COLOR") == ""
+	if err := writeSummary(os.Stderr, color, time.Since(startTime)); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+	}
+}
+
+// writeSummary writes the number of libraries by license, the number of
+// unknown licenses and violations, and the duration of the run to w.
+func writeSummary(w io.Writer, color bool, duration time.Duration) error {
+	paint := func(code, s string) string {
+		if !color {
+			return s
+		}
+		return code + s + colorReset
+	}
+	runSummary.mu.Lock()
+	defer runSummary.mu.Unlock()
+	counts := make(map[string]int)
+	for _, license := range runSummary.licenses {
+		counts[license]++
+	}
+	names := make([]string, 0, len(counts))
+	for name := range counts {
+		names = append(names, name)
+	}
+	sort.Slice(names, func(i, j int) bool {
+		if counts[names[i]] != counts[names[j]] {
+			return counts[names[i]] > counts[names[j]]
+		}
+		return names[i] < names[j]
+	})
+
+	width := len("License")
+	for _, name := range names {
+		if len(name) > width {
+			width = len(name)
+		}
+	}
+	fmt.Fprintf(w, "\n%-*s  %9s\n", width, "License", "Libraries")
+	for _, name := range names {
+		row := fmt.Sprintf("%-*s  %9d", width, name, counts[name])
+		if name == "Unknown" {
+			row = paint(colorRed, row)
+		}
+		fmt.Fprintln(w, row)
+	}
+	fmt.Fprintf(w, "%-*s  %9d\n", width, "Total", len(runSummary.licenses))
+
+	unknown := fmt.Sprintf("Unknown licenses: %d", counts["Unknown"])
+	if counts["Unknown"] > 0 {
+		unknown = paint(colorRed, unknown)
+	}
+	fmt.Fprintln(w, unknown)
+	if runSummary.checked {
+		violations := fmt.Sprintf("Violations: %d errors, %d warnings", runSummary.errors, runSummary.warnings)
+		switch {
+		case runSummary.errors > 0:
+			violations = paint(colorRed, violations)
+		case runSummary.warnings > 0:
+			violations = paint(colorYellow, violations)
+		default:
+			violations = paint(colorGreen, violations)
+		}
+		fmt.Fprintln(w, violations)
+	}
+	_, err := fmt.Fprintf(w, "Duration: %s\n", duration.Round(100*time.Millisecond))
+	return err
+}
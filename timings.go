@@ -0,0 +1,108 @@
+// Copyright 2022 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/Bobgy/go-licenses/v2/licenses"
+)
+
+// phaseTiming is the timing of a phase of a run, over all its measurements.
+type phaseTiming struct {
+	// start and end span all measurements of the phase. Measurements of
+	// single items, e.g. validating a license URL, overlap when processed by
+	// concurrent workers.
+	start, end time.Time
+	// busy is the sum of the durations of all measurements.
+	busy         time.Duration
+	items, count int
+}
+
+// runTimings collects the timings of the phases of a run with --timings.
+var runTimings = struct {
+	mu     sync.Mutex
+	phases map[string]*phaseTiming
+}{phases: make(map[string]*phaseTiming)}
+
+// recordMeasurement records a measurement of a phase, see
+// licenses.Options.Metrics.
+func recordMeasurement(m licenses.Measurement) {
+	runTimings.mu.Lock()
+	defer runTimings.mu.Unlock()
+	end := time.Now()
+	start := end.Add(-m.Duration)
+	t, ok := runTimings.phases[m.Phase]
+	if !ok {
+		t = &phaseTiming{start: start, end: end}
+		runTimings.phases[m.Phase] = t
+	}
+	if start.Before(t.start) {
+		t.start = start
+	}
+	if end.After(t.end) {
+		t.end = end
+	}
+	t.busy += m.Duration
+	t.items += m.Items
+	t.count++
+}
+
+// printTimings prints the timings of the phases of the run to stderr with
+// --timings.
+func printTimings() {
+	if !showTimings {
+		return
+	}
+	if err := writeTimings(os.Stderr, time.Since(startTime)); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+	}
+}
+
+// writeTimings writes the wall clock duration and number of items of each
+// phase in the order they started, and the duration of the whole run. Phases
+// measured per item also show the sum of the durations of their items, which
+// exceeds the wall clock duration when items are processed concurrently.
+func writeTimings(w io.Writer, total time.Duration) error {
+	runTimings.mu.Lock()
+	defer runTimings.mu.Unlock()
+	var phases []string
+	for phase := range runTimings.phases {
+		phases = append(phases, phase)
+	}
+	sort.Slice(phases, func(i, j int) bool {
+		return runTimings.phases[phases[i]].start.Before(runTimings.phases[phases[j]].start)
+	})
+	if _, err := fmt.Fprintln(w, "Phase timings:"); err != nil {
+		return err
+	}
+	for _, phase := range phases {
+		t := runTimings.phases[phase]
+		line := fmt.Sprintf("  %-24s %10s %6d items", phase, t.end.Sub(t.start).Round(time.Millisecond), t.items)
+		if t.count > 1 {
+			line += fmt.Sprintf(", %s summed over workers", t.busy.Round(time.Millisecond))
+		}
+		if _, err := fmt.Fprintln(w, line); err != nil {
+			return err
+		}
+	}
+	_, err := fmt.Fprintf(w, "  %-24s %10s\n", "total", total.Round(time.Millisecond))
+	return err
+}
@@ -0,0 +1,182 @@
+// Copyright 2022 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+
+	"github.com/Bobgy/go-licenses/v2/policy"
+)
+
+// jsonViolation is a policy violation in JSON output.
+type jsonViolation struct {
+	Code     policy.Code     `json:"code"`
+	Severity policy.Severity `json:"severity"`
+	Verdict  policy.Verdict  `json:"verdict"`
+	Library  string          `json:"library"`
+	License  string          `json:"license"`
+	Category string          `json:"category"`
+	Rule     string          `json:"rule"`
+	Scope    string          `json:"scope,omitempty"`
+}
+
+// writeViolations writes violations in format, either "json" or "sarif".
+func writeViolations(w io.Writer, format string, violations []violation) error {
+	switch format {
+	case "json":
+		return writeJSONViolations(w, violations)
+	case "sarif":
+		return writeSARIF(w, violations)
+	default:
+		return fmt.Errorf("unknown output format %q, must be json or sarif", format)
+	}
+}
+
+// sortViolations sorts violations by scope, library, code and rule, so that
+// reports are stable regardless of the order checks ran in.
+func sortViolations(violations []violation) {
+	sort.SliceStable(violations, func(i, j int) bool {
+		a, b := violations[i], violations[j]
+		switch {
+		case a.scope != b.scope:
+			return a.scope < b.scope
+		case a.Library != b.Library:
+			return a.Library < b.Library
+		case a.Code != b.Code:
+			return a.Code < b.Code
+		default:
+			return a.Rule < b.Rule
+		}
+	})
+}
+
+// writePorcelainViolations writes a tab-separated line per violation, with its
+// code, severity, verdict, library, license, category, rule and scope.
+func writePorcelainViolations(w io.Writer, violations []violation) error {
+	for _, v := range violations {
+		row := porcelainRow(string(v.Code), string(v.Severity), string(v.Verdict), v.Library, v.License, v.Category, v.Rule, v.scope)
+		if _, err := fmt.Fprintln(w, row); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// writeJSONViolations writes violations as a JSON array.
+func writeJSONViolations(w io.Writer, violations []violation) error {
+	out := make([]jsonViolation, 0, len(violations))
+	for _, v := range violations {
+		out = append(out, jsonViolation{
+			Code:     v.Code,
+			Severity: v.Severity,
+			Verdict:  v.Verdict,
+			Library:  v.Library,
+			License:  v.License,
+			Category: v.Category,
+			Rule:     v.Rule,
+			Scope:    v.scope,
+		})
+	}
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(out)
+}
+
+// SARIF 2.1.0 types, limited to what go-licenses reports.
+// ref: https://docs.oasis-open.org/sarif/sarif/v2.1.0/sarif-v2.1.0.html
+type (
+	sarifLog struct {
+		Version string     `json:"version"`
+		Schema  string     `json:"$schema"`
+		Runs    []sarifRun `json:"runs"`
+	}
+	sarifRun struct {
+		Tool    sarifTool     `json:"tool"`
+		Results []sarifResult `json:"results"`
+	}
+	sarifTool struct {
+		Driver sarifDriver `json:"driver"`
+	}
+	sarifDriver struct {
+		Name           string      `json:"name"`
+		InformationURI string      `json:"informationUri"`
+		Rules          []sarifRule `json:"rules"`
+	}
+	sarifRule struct {
+		ID               string       `json:"id"`
+		ShortDescription sarifMessage `json:"shortDescription"`
+	}
+	sarifResult struct {
+		RuleID    string          `json:"ruleId"`
+		Level     string          `json:"level"`
+		Message   sarifMessage    `json:"message"`
+		Locations []sarifLocation `json:"locations"`
+	}
+	sarifMessage struct {
+		Text string `json:"text"`
+	}
+	sarifLocation struct {
+		PhysicalLocation sarifPhysicalLocation `json:"physicalLocation"`
+	}
+	sarifPhysicalLocation struct {
+		ArtifactLocation sarifArtifactLocation `json:"artifactLocation"`
+	}
+	sarifArtifactLocation struct {
+		URI string `json:"uri"`
+	}
+)
+
+// writeSARIF writes violations as a SARIF log, with a rule per violation code.
+// Violations are located in go.mod.
+func writeSARIF(w io.Writer, violations []violation) error {
+	driver := sarifDriver{
+		Name:           "go-licenses",
+		InformationURI: "https://github.com/Bobgy/go-licenses",
+	}
+	for _, code := range policy.Codes {
+		driver.Rules = append(driver.Rules, sarifRule{ID: string(code), ShortDescription: sarifMessage{Text: code.Description()}})
+	}
+	results := make([]sarifResult, 0, len(violations))
+	for _, v := range violations {
+		level := "error"
+		switch v.Severity {
+		case policy.Warn:
+			level = "warning"
+		case policy.Info:
+			level = "note"
+		}
+		text := v.Result.String()
+		if v.scope != "" {
+			text += " in scope " + v.scope
+		}
+		results = append(results, sarifResult{
+			RuleID:    string(v.Code),
+			Level:     level,
+			Message:   sarifMessage{Text: text},
+			Locations: []sarifLocation{{PhysicalLocation: sarifPhysicalLocation{ArtifactLocation: sarifArtifactLocation{URI: "go.mod"}}}},
+		})
+	}
+	log := sarifLog{
+		Version: "2.1.0",
+		Schema:  "https://json.schemastore.org/sarif-2.1.0.json",
+		Runs:    []sarifRun{{Tool: sarifTool{Driver: driver}, Results: results}},
+	}
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(log)
+}
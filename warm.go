@@ -0,0 +1,92 @@
+// Copyright 2022 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	"github.com/Bobgy/go-licenses/v2/internal/logging"
+	"github.com/Bobgy/go-licenses/v2/internal/parallel"
+	"github.com/Bobgy/go-licenses/v2/licenses"
+	"github.com/spf13/cobra"
+)
+
+var warmCmd = &cobra.Command{
+	Use:   "warm <package>...",
+	Short: "Fills the cache for later runs on Go packages, without a report",
+	Long: `Fills the cache for later runs on Go packages, without a report.
+
+It downloads modules missing from the module cache, classifies license files,
+queries the source repositories of modules and validates license URLs, so
+that a later csv, check or report run on the same packages with the same
+cache directory is near-instant. Run it in a background CI step, e.g. while
+tests run. With --module_warnings, module info is also queried from the module
+proxy.`,
+	Args: packageArgs,
+	RunE: warmMain,
+}
+
+func init() {
+	rootCmd.AddCommand(warmCmd)
+}
+
+func warmMain(_ *cobra.Command, args []string) error {
+	if libraryCache() == nil {
+		return fmt.Errorf("nothing to warm: the cache is disabled, or --dry_run is set")
+	}
+	classifier, err := newClassifier(confidenceThreshold)
+	if err != nil {
+		return err
+	}
+	importPaths, err := expandPackages(context.Background(), args)
+	if err != nil {
+		return err
+	}
+	opts := libraryOptions()
+	opts.DownloadModules = true
+	libs, err := licenses.LibrariesWithOptions(context.Background(), classifier, opts, importPaths...)
+	if err != nil {
+		return err
+	}
+	return warmLicenseURLs(context.Background(), libs)
+}
+
+// warmLicenseURLs resolves and validates the license URLs of libraries
+// concurrently, which caches source info, remote license files and validated
+// URLs. Failures are only logged, because later runs report them anyway.
+func warmLicenseURLs(ctx context.Context, libs []*licenses.Library) error {
+	var mu sync.Mutex
+	done, failed := 0, 0
+	parallel.For(numJobs(), len(libs), func(i int) {
+		lib := libs[i]
+		var err error
+		if o := cfg.Override(lib.Name()); lib.LicensePath != "" && (o == nil || o.URL == "") {
+			if _, err = lib.LicenseURL(ctx); err != nil {
+				logging.Module(lib.Name()).Warningf("Error discovering license URL: %s", err)
+			}
+		}
+		mu.Lock()
+		defer mu.Unlock()
+		done++
+		if err != nil {
+			failed++
+		}
+		reportProgress(licenses.Progress{Phase: phaseWarmingCache, Done: done, Total: len(libs)})
+	})
+	logging.Infof("Warmed the cache for %d libraries, %d license URLs could not be validated", len(libs), failed)
+	return nil
+}
@@ -0,0 +1,125 @@
+// Copyright 2022 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/Bobgy/go-licenses/v2/licenses"
+	"github.com/spf13/cobra"
+)
+
+var whyCmd = &cobra.Command{
+	Use:   "why <library> <package>...",
+	Short: "Prints why a library is a dependency of Go packages",
+	Long: `Prints why a library is a dependency of Go packages.
+
+For each library matching <library>, i.e. a library, module or package import
+path, it prints the library and its license, followed by the shortest chain of
+imports from one of the packages to the library, one import path per line,
+like go mod why.`,
+	Args: func(cmd *cobra.Command, args []string) error {
+		if includeWorkspace || len(workspaceModules) > 0 {
+			return cobra.MinimumNArgs(1)(cmd, args)
+		}
+		return cobra.MinimumNArgs(2)(cmd, args)
+	},
+	RunE: whyMain,
+}
+
+func init() {
+	rootCmd.AddCommand(whyCmd)
+}
+
+func whyMain(_ *cobra.Command, args []string) error {
+	classifier, err := newClassifier(confidenceThreshold)
+	if err != nil {
+		return err
+	}
+
+	importPaths, err := expandPackages(context.Background(), args[1:])
+	if err != nil {
+		return err
+	}
+	opts := libraryOptions()
+	opts.ImportChains = true
+	libs, err := licenses.LibrariesWithOptions(context.Background(), classifier, opts, importPaths...)
+	if err != nil {
+		return err
+	}
+	return writeWhy(os.Stdout, classifier, args[0], libs)
+}
+
+// writeWhy writes the import chain of each library matching name, or a note
+// if none matches.
+func writeWhy(w io.Writer, classifier licenses.Classifier, name string, libs []*licenses.Library) error {
+	found := false
+	for _, lib := range libs {
+		if !libraryMatches(lib, name) {
+			continue
+		}
+		if found {
+			if _, err := fmt.Fprintln(w); err != nil {
+				return err
+			}
+		}
+		found = true
+		license := "Unknown"
+		if n, _, err := identifyLicense(classifier, lib); err == nil {
+			license = n
+		}
+		if _, err := fmt.Fprintf(w, "# %s (%s)\n", lib.Name(), license); err != nil {
+			return err
+		}
+		if len(lib.ImportChain) == 0 {
+			if _, err := fmt.Fprintln(w, "(no import chain found)"); err != nil {
+				return err
+			}
+			continue
+		}
+		if _, err := fmt.Fprintln(w, strings.Join(lib.ImportChain, "\n")); err != nil {
+			return err
+		}
+	}
+	if !found {
+		_, err := fmt.Fprintf(w, "# %s\n(the packages do not depend on %s)\n", name, name)
+		return err
+	}
+	return nil
+}
+
+// libraryMatches reports whether a library is, or belongs to, the library,
+// module or package with import path name.
+func libraryMatches(lib *licenses.Library, name string) bool {
+	paths := []string{lib.Name()}
+	if m := lib.Module(); m != nil {
+		paths = append(paths, m.Path)
+	}
+	for _, path := range paths {
+		if path == name || strings.HasPrefix(name, path+"/") {
+			return true
+		}
+	}
+	for _, pkg := range lib.Packages {
+		if pkg == name {
+			return true
+		}
+	}
+	return false
+}